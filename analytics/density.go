@@ -0,0 +1,189 @@
+package analytics
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/store"
+	"github.com/tidwall/gjson"
+)
+
+// DensityPoint 弹幕密度时间线上的一个区间
+type DensityPoint struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// DensityTimeline 按固定间隔统计弹幕数量的时间线，可用于发现适合剪辑的高光时刻
+type DensityTimeline struct {
+	mu       sync.Mutex
+	interval time.Duration
+	max      int // 滑动窗口保留的区间数，为 0 时保留全部
+	points   []DensityPoint
+	stop     chan struct{}
+}
+
+// NewDensityTimeline 创建一个每 interval 统计一次的时间线，window > 0 时只保留最近 window 时长的区间
+//
+// 实时统计时通常使用滑动窗口，统计录制文件时 window 为 0 保留整场直播
+func NewDensityTimeline(interval time.Duration, window time.Duration) *DensityTimeline {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	t := &DensityTimeline{interval: interval}
+	if window > 0 {
+		if t.max = int(window / interval); t.max < 1 {
+			t.max = 1
+		}
+	}
+	return t
+}
+
+// Interval 获取统计间隔
+func (t *DensityTimeline) Interval() time.Duration {
+	return t.interval
+}
+
+// Attach 统计 src 中的全部弹幕
+func (t *DensityTimeline) Attach(src client.DanmakuSource) {
+	src.OnDanmaku(t.AddDanmaku)
+}
+
+// AddDanmaku 统计一条弹幕
+func (t *DensityTimeline) AddDanmaku(d *message.Danmaku) {
+	at := d.ReceivedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	t.Add(at)
+}
+
+// AddEntry 统计一条录制的事件，只统计弹幕
+func (t *DensityTimeline) AddEntry(e *record.Entry) {
+	cmd := gjson.GetBytes(e.Data, "cmd").String()
+	if i := strings.IndexByte(cmd, ':'); i >= 0 {
+		cmd = cmd[:i]
+	}
+	if cmd == "DANMU_MSG" {
+		t.Add(time.Unix(0, e.Time*int64(time.Millisecond)))
+	}
+}
+
+// Add 统计时间 at 的一条弹幕
+func (t *DensityTimeline) Add(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i := t.index(at); i >= 0 {
+		t.points[i].Count++
+	}
+}
+
+// index 获取时间 at 所在区间的下标，必要时补充空的区间，已滑出窗口时返回 -1，调用时需要持有锁
+func (t *DensityTimeline) index(at time.Time) int {
+	bt := at.Truncate(t.interval)
+	if len(t.points) == 0 {
+		t.points = append(t.points, DensityPoint{Start: bt})
+		return 0
+	}
+	first := t.points[0].Start
+	if bt.Before(first) {
+		n := int(first.Sub(bt) / t.interval)
+		if t.max > 0 && len(t.points)+n > t.max {
+			return -1
+		}
+		head := make([]DensityPoint, n, n+len(t.points))
+		for i := range head {
+			head[i].Start = bt.Add(time.Duration(i) * t.interval)
+		}
+		t.points = append(head, t.points...)
+		return 0
+	}
+	i := int(bt.Sub(first) / t.interval)
+	for len(t.points) <= i {
+		t.points = append(t.points, DensityPoint{Start: first.Add(time.Duration(len(t.points)) * t.interval)})
+	}
+	if t.max > 0 && len(t.points) > t.max {
+		drop := len(t.points) - t.max
+		t.points = append(t.points[:0:0], t.points[drop:]...)
+		i -= drop
+	}
+	return i
+}
+
+// Points 获取时间线的全部区间，没有弹幕的区间计数为 0
+func (t *DensityTimeline) Points() []DensityPoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]DensityPoint(nil), t.points...)
+}
+
+// Start 每隔 every 调用一次 f，参数为截止到当前时间的滑动窗口
+func (t *DensityTimeline) Start(every time.Duration, f func([]DensityPoint)) {
+	t.mu.Lock()
+	if t.stop != nil {
+		t.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	t.stop = stop
+	t.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				t.mu.Lock()
+				t.index(now)
+				t.mu.Unlock()
+				f(t.Points())
+			}
+		}
+	}()
+}
+
+// Stop 停止 Start 启动的定时快照
+func (t *DensityTimeline) Stop() {
+	t.mu.Lock()
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+	t.mu.Unlock()
+}
+
+// BuildDensityTimeline 读取录制文件中的全部弹幕并生成每 interval 一个区间的时间线
+func BuildDensityTimeline(r *record.Reader, interval time.Duration) (*DensityTimeline, error) {
+	t := NewDensityTimeline(interval, 0)
+	for {
+		e, err := r.Next()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.AddEntry(e)
+	}
+	return t, nil
+}
+
+// BuildStoreDensityTimeline 读取 s 中房间在时间范围内保存的弹幕并生成时间线，零值的时间不限制
+func BuildStoreDensityTimeline(s *store.Store, roomID int, start, end time.Time, interval time.Duration) (*DensityTimeline, error) {
+	t := NewDensityTimeline(interval, 0)
+	err := s.Entries(roomID, start, end, func(e *record.Entry) error {
+		t.AddEntry(e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,296 @@
+package analytics
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/api"
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+// Guard 一名舰队成员与到期时间
+type Guard struct {
+	Uid        int       `json:"uid"`
+	Uname      string    `json:"uname"`
+	GuardLevel int       `json:"guard_level"` // 1:总督 2:提督 3:舰长
+	ExpiresAt  time.Time `json:"expires_at"`  // 零值表示到期时间未知，如只从大航海列表中得到的成员
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Remaining 距离到期的时间，到期时间未知时返回 0
+func (g Guard) Remaining(now time.Time) time.Duration {
+	if g.ExpiresAt.IsZero() {
+		return 0
+	}
+	return g.ExpiresAt.Sub(now)
+}
+
+// GuardReminder 舰队即将到期的提醒
+type GuardReminder struct {
+	Guard
+	Days int // 提醒设置的天数，见 GuardTracker.SetReminderDays
+}
+
+// GuardTracker 根据上舰提示推算舰队成员的到期时间，并在到期前提醒
+//
+// 大航海列表接口不返回到期时间，到期时间需要持续统计开通与续费，应通过 SaveFile、LoadFile 保存
+type GuardTracker struct {
+	mu       sync.Mutex
+	guards   map[int]*Guard
+	days     []int
+	reminded map[int]map[int]time.Time // uid -> 提醒天数 -> 提醒时对应的到期时间
+	handlers []func(GuardReminder)
+	stop     chan struct{}
+}
+
+// NewGuardTracker 创建一个舰队到期统计，默认在到期前 7 天与 1 天提醒
+func NewGuardTracker() *GuardTracker {
+	return &GuardTracker{
+		guards:   make(map[int]*Guard),
+		days:     []int{7, 1},
+		reminded: make(map[int]map[int]time.Time),
+	}
+}
+
+// SetReminderDays 设置在到期前多少天提醒，每个天数对同一次到期只提醒一次
+func (t *GuardTracker) SetReminderDays(days ...int) {
+	t.mu.Lock()
+	t.days = append([]int(nil), days...)
+	sort.Sort(sort.Reverse(sort.IntSlice(t.days)))
+	t.mu.Unlock()
+}
+
+// OnReminder 添加到期提醒的处理器
+func (t *GuardTracker) OnReminder(f func(GuardReminder)) {
+	t.mu.Lock()
+	t.handlers = append(t.handlers, f)
+	t.mu.Unlock()
+}
+
+// Attach 统计 src 中的开通与续费提示 USER_TOAST_MSG
+func (t *GuardTracker) Attach(src client.DanmakuSource) {
+	src.OnUserToast(t.AddUserToast)
+}
+
+// AddUserToast 统计一次开通或续费，在原到期时间或当前时间之后延长购买的时长
+func (t *GuardTracker) AddUserToast(u *message.UserToast) {
+	at := time.Unix(int64(u.StartTime), 0)
+	if u.StartTime == 0 {
+		at = u.ReceivedAt
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	g := t.guards[u.Uid]
+	if g == nil {
+		g = &Guard{Uid: u.Uid}
+		t.guards[u.Uid] = g
+	}
+	g.Uname = u.Username
+	if g.GuardLevel == 0 || u.GuardLevel < g.GuardLevel {
+		g.GuardLevel = u.GuardLevel
+	}
+	from := at
+	if g.ExpiresAt.After(from) {
+		from = g.ExpiresAt
+	}
+	g.ExpiresAt = from.Add(guardDuration(u.Num, u.Unit))
+	g.UpdatedAt = at
+}
+
+// guardDuration 购买时长，单位为 月、周 或 天，一个月按 30 天计算
+func guardDuration(num int, unit string) time.Duration {
+	if num <= 0 {
+		num = 1
+	}
+	day := 24 * time.Hour
+	switch unit {
+	case "周":
+		return time.Duration(num) * 7 * day
+	case "天", "日":
+		return time.Duration(num) * day
+	}
+	return time.Duration(num) * 30 * day
+}
+
+// SetExpiry 手动设置成员的到期时间，如从主播后台导出的数据
+func (t *GuardTracker) SetExpiry(uid int, uname string, level int, expiresAt time.Time) {
+	t.mu.Lock()
+	t.guards[uid] = &Guard{Uid: uid, Uname: uname, GuardLevel: level, ExpiresAt: expiresAt, UpdatedAt: time.Now()}
+	t.mu.Unlock()
+}
+
+// Sync 从大航海列表同步成员，新成员的到期时间未知，已不在列表中且已到期的成员会被移除
+func (t *GuardTracker) Sync(a *api.Client, roomID string, ruid int) error {
+	members, err := a.GetAllGuards(roomID, ruid)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	listed := make(map[int]bool, len(members))
+	for _, m := range members {
+		listed[m.Uid] = true
+		g := t.guards[m.Uid]
+		if g == nil {
+			g = &Guard{Uid: m.Uid}
+			t.guards[m.Uid] = g
+		}
+		g.Uname, g.GuardLevel, g.UpdatedAt = m.Username, m.GuardLevel, now
+	}
+	for uid, g := range t.guards {
+		if !listed[uid] && !g.ExpiresAt.IsZero() && g.ExpiresAt.Before(now) {
+			delete(t.guards, uid)
+		}
+	}
+	return nil
+}
+
+// Guards 获取全部成员，按到期时间从早到晚排列，到期时间未知的排在最后
+func (t *GuardTracker) Guards() []Guard {
+	t.mu.Lock()
+	list := make([]Guard, 0, len(t.guards))
+	for _, g := range t.guards {
+		list = append(list, *g)
+	}
+	t.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i].ExpiresAt, list[j].ExpiresAt
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return list[i].Uid < list[j].Uid
+	})
+	return list
+}
+
+// Expiring 获取 within 时间内到期且尚未到期的成员
+func (t *GuardTracker) Expiring(within time.Duration) []Guard {
+	now := time.Now()
+	var list []Guard
+	for _, g := range t.Guards() {
+		if r := g.Remaining(now); r > 0 && r <= within {
+			list = append(list, g)
+		}
+	}
+	return list
+}
+
+// Check 检查并发出到期提醒，Start 会定时调用
+func (t *GuardTracker) Check() {
+	now := time.Now()
+	var due []GuardReminder
+	t.mu.Lock()
+	for uid, g := range t.guards {
+		r := g.Remaining(now)
+		if r <= 0 {
+			continue
+		}
+		// 只提醒剩余时间内最接近的一档，避免首次检查时同时发出多档提醒
+		for i := len(t.days) - 1; i >= 0; i-- {
+			d := t.days[i]
+			if r > time.Duration(d)*24*time.Hour {
+				continue
+			}
+			sent := t.reminded[uid]
+			if sent == nil {
+				sent = make(map[int]time.Time)
+				t.reminded[uid] = sent
+			}
+			if !sent[d].Equal(g.ExpiresAt) {
+				for _, dd := range t.days[:i+1] {
+					sent[dd] = g.ExpiresAt
+				}
+				due = append(due, GuardReminder{Guard: *g, Days: d})
+			}
+			break
+		}
+	}
+	handlers := t.handlers
+	t.mu.Unlock()
+	for _, rm := range due {
+		for _, fn := range handlers {
+			fn(rm)
+		}
+	}
+}
+
+// Start 每隔 interval 检查一次到期提醒
+func (t *GuardTracker) Start(interval time.Duration) {
+	t.mu.Lock()
+	if t.stop != nil {
+		t.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	t.stop = stop
+	t.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		t.Check()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				t.Check()
+			}
+		}
+	}()
+}
+
+// Stop 停止定时检查
+func (t *GuardTracker) Stop() {
+	t.mu.Lock()
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+	t.mu.Unlock()
+}
+
+// SaveFile 将全部成员保存为 JSON 文件
+func (t *GuardTracker) SaveFile(path string) error {
+	b, err := json.MarshalIndent(t.Guards(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadFile 读取 SaveFile 保存的成员，文件不存在时不做任何修改
+func (t *GuardTracker) LoadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var list []Guard
+	if err = json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	for i := range list {
+		g := list[i]
+		t.guards[g.Uid] = &g
+	}
+	t.mu.Unlock()
+	return nil
+}
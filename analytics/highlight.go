@@ -0,0 +1,277 @@
+package analytics
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/tidwall/gjson"
+)
+
+// Highlight 的触发原因
+const (
+	HighlightDanmaku = "danmaku" // 弹幕数量突增
+	HighlightGift    = "gift"    // 礼物、醒目留言与大航海金额突增
+)
+
+const (
+	// DefaultHighlightThreshold 默认的 z-score 阈值
+	DefaultHighlightThreshold = 3
+	// defaultHighlightSamples 每个高光时刻默认保留的弹幕数
+	defaultHighlightSamples = 5
+	// minHighlightHistory 基线少于该数量的窗口时不检测
+	minHighlightHistory = 3
+)
+
+// Highlight 一个高光时刻，窗口内的弹幕或营收明显高于之前的窗口
+type Highlight struct {
+	Reason  string    `json:"reason"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Danmaku int       `json:"danmaku"` // 窗口内的弹幕数
+	Gold    int64     `json:"gold"`    // 窗口内的营收，单位为金瓜子
+	ZScore  float64   `json:"z_score"`
+	Mean    float64   `json:"mean"`    // 之前窗口的平均值，与 Reason 对应
+	Samples []string  `json:"samples"` // 窗口内的部分弹幕
+}
+
+// highlightWindow 正在统计的窗口
+type highlightWindow struct {
+	start   time.Time
+	danmaku int
+	gold    int64
+	samples []string
+}
+
+// HighlightDetector 按固定窗口统计弹幕数与营收，与之前若干窗口相比 z-score 超过阈值时触发 OnHighlight，
+// 可供自动剪辑工具标记精彩片段
+//
+// 窗口在收到下一个窗口的事件或调用 Flush 时结束，之后才会触发
+type HighlightDetector struct {
+	mu        sync.Mutex
+	window    time.Duration
+	history   int
+	threshold float64
+	samples   int
+	cur       highlightWindow
+	danmaku   []float64
+	gold      []float64
+	handlers  []func(Highlight)
+}
+
+// NewHighlightDetector 创建检测器，window 为窗口时长，history 为基线包含的窗口数，threshold 为 z-score 阈值，<= 0 时使用 DefaultHighlightThreshold
+func NewHighlightDetector(window time.Duration, history int, threshold float64) *HighlightDetector {
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	if history < minHighlightHistory {
+		history = minHighlightHistory
+	}
+	if threshold <= 0 {
+		threshold = DefaultHighlightThreshold
+	}
+	return &HighlightDetector{window: window, history: history, threshold: threshold, samples: defaultHighlightSamples}
+}
+
+// SetSamples 设置每个高光时刻保留的弹幕数，默认为 5
+func (h *HighlightDetector) SetSamples(n int) {
+	h.mu.Lock()
+	h.samples = n
+	h.mu.Unlock()
+}
+
+// OnHighlight 添加 高光时刻 的处理器
+func (h *HighlightDetector) OnHighlight(f func(Highlight)) {
+	h.mu.Lock()
+	h.handlers = append(h.handlers, f)
+	h.mu.Unlock()
+}
+
+// Attach 统计 src 中的弹幕、礼物、醒目留言与大航海
+func (h *HighlightDetector) Attach(src client.DanmakuSource) {
+	src.OnDanmaku(h.AddDanmaku)
+	src.OnGift(h.AddGift)
+	src.OnSuperChat(h.AddSuperChat)
+	src.OnGuardBuy(h.AddGuardBuy)
+}
+
+// AddDanmaku 统计一条弹幕
+func (h *HighlightDetector) AddDanmaku(d *message.Danmaku) {
+	h.add(eventTime(d.ReceivedAt), func(w *highlightWindow) {
+		w.danmaku++
+		if len(w.samples) < h.samples {
+			w.samples = append(w.samples, d.Content)
+		}
+	})
+}
+
+// AddGift 统计一次送礼，只统计金瓜子礼物
+func (h *HighlightDetector) AddGift(g *message.Gift) {
+	gold, ok := EventGold(g)
+	if !ok {
+		return
+	}
+	h.add(eventTime(g.ReceivedAt), func(w *highlightWindow) { w.gold += gold })
+}
+
+// AddSuperChat 统计一条醒目留言
+func (h *HighlightDetector) AddSuperChat(s *message.SuperChat) {
+	gold, _ := EventGold(s)
+	h.add(eventTime(s.ReceivedAt), func(w *highlightWindow) { w.gold += gold })
+}
+
+// AddGuardBuy 统计一次上舰
+func (h *HighlightDetector) AddGuardBuy(g *message.GuardBuy) {
+	gold, _ := EventGold(g)
+	h.add(eventTime(g.ReceivedAt), func(w *highlightWindow) { w.gold += gold })
+}
+
+// AddEntry 统计一条录制的事件
+func (h *HighlightDetector) AddEntry(e *record.Entry) {
+	cmd := gjson.GetBytes(e.Data, "cmd").String()
+	if i := strings.IndexByte(cmd, ':'); i >= 0 {
+		cmd = cmd[:i]
+	}
+	t := time.Unix(0, e.Time*int64(time.Millisecond))
+	switch cmd {
+	case "DANMU_MSG":
+		d := new(message.Danmaku)
+		if d.Parse(e.Data) == nil {
+			d.ReceivedAt = t
+			h.AddDanmaku(d)
+		}
+	case "SEND_GIFT":
+		g := new(message.Gift)
+		if g.Parse(e.Data) == nil {
+			g.ReceivedAt = t
+			h.AddGift(g)
+		}
+	case "SUPER_CHAT_MESSAGE":
+		s := new(message.SuperChat)
+		if s.Parse(e.Data) == nil {
+			s.ReceivedAt = t
+			h.AddSuperChat(s)
+		}
+	case "GUARD_BUY":
+		g := new(message.GuardBuy)
+		if g.Parse(e.Data) == nil {
+			g.ReceivedAt = t
+			h.AddGuardBuy(g)
+		}
+	}
+}
+
+// Flush 结束 now 之前的窗口并检测，用于定时检查或录制文件读取完毕时
+func (h *HighlightDetector) Flush(now time.Time) {
+	h.mu.Lock()
+	found := h.advance(now.Truncate(h.window))
+	handlers := h.handlers
+	h.mu.Unlock()
+	h.emit(handlers, found)
+}
+
+func eventTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
+func (h *HighlightDetector) add(t time.Time, f func(w *highlightWindow)) {
+	h.mu.Lock()
+	found := h.advance(t.Truncate(h.window))
+	f(&h.cur)
+	handlers := h.handlers
+	h.mu.Unlock()
+	h.emit(handlers, found)
+}
+
+func (h *HighlightDetector) emit(handlers []func(Highlight), found []Highlight) {
+	for _, hl := range found {
+		for _, fn := range handlers {
+			fn(hl)
+		}
+	}
+}
+
+// advance 结束 start 之前的窗口，乱序到达的旧事件计入当前窗口，调用时需要持有锁
+func (h *HighlightDetector) advance(start time.Time) []Highlight {
+	if h.cur.start.IsZero() {
+		h.cur.start = start
+		return nil
+	}
+	if !start.After(h.cur.start) {
+		return nil
+	}
+	found := h.detect()
+	// 中间没有事件的窗口计为 0，最多补充 history 个
+	gap := int(start.Sub(h.cur.start)/h.window) - 1
+	if gap > h.history {
+		gap = h.history
+	}
+	for ; gap > 0; gap-- {
+		h.danmaku = pushHistory(h.danmaku, 0, h.history)
+		h.gold = pushHistory(h.gold, 0, h.history)
+	}
+	h.cur = highlightWindow{start: start}
+	return found
+}
+
+// detect 检测当前窗口并将其加入基线
+func (h *HighlightDetector) detect() []Highlight {
+	w := h.cur
+	var found []Highlight
+	if len(h.danmaku) >= minHighlightHistory {
+		if z, mean := zScore(float64(w.danmaku), h.danmaku, 1); z >= h.threshold {
+			found = append(found, h.highlight(HighlightDanmaku, z, mean))
+		}
+		if z, mean := zScore(float64(w.gold), h.gold, GoldPerYuan); z >= h.threshold {
+			found = append(found, h.highlight(HighlightGift, z, mean))
+		}
+	}
+	h.danmaku = pushHistory(h.danmaku, float64(w.danmaku), h.history)
+	h.gold = pushHistory(h.gold, float64(w.gold), h.history)
+	return found
+}
+
+func (h *HighlightDetector) highlight(reason string, z, mean float64) Highlight {
+	w := h.cur
+	return Highlight{
+		Reason:  reason,
+		Start:   w.start,
+		End:     w.start.Add(h.window),
+		Danmaku: w.danmaku,
+		Gold:    w.gold,
+		ZScore:  z,
+		Mean:    mean,
+		Samples: append([]string(nil), w.samples...),
+	}
+}
+
+// zScore 计算 x 相对 history 的 z-score，标准差小于 minStd 时使用 minStd，避免基线平稳时少量变化就触发
+func zScore(x float64, history []float64, minStd float64) (z float64, mean float64) {
+	for _, v := range history {
+		mean += v
+	}
+	mean /= float64(len(history))
+	var variance float64
+	for _, v := range history {
+		variance += (v - mean) * (v - mean)
+	}
+	std := math.Sqrt(variance / float64(len(history)))
+	if std < minStd {
+		std = minStd
+	}
+	return (x - mean) / std, mean
+}
+
+func pushHistory(history []float64, v float64, max int) []float64 {
+	if len(history) >= max {
+		history = append(history[:0], history[len(history)-max+1:]...)
+	}
+	return append(history, v)
+}
@@ -0,0 +1,266 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+// shortMessageRunes 默认分词时，不超过该长度的整条弹幕会作为一个词统计
+const shortMessageRunes = 10
+
+// KeywordCount 一个词在窗口内出现的次数
+type KeywordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// KeywordCounter 在滑动窗口内统计弹幕中关键词、表情的出现次数
+type KeywordCounter struct {
+	mu        sync.Mutex
+	bucket    time.Duration
+	buckets   []map[string]int
+	start     []time.Time
+	total     map[string]int
+	head      int
+	latest    time.Time
+	tokenizer func(string) []string
+	keywords  []string
+	stop      chan struct{}
+}
+
+// NewKeywordCounter 创建一个统计最近 window 时长的计数器，窗口按 bucket 粒度滑动
+func NewKeywordCounter(window, bucket time.Duration) *KeywordCounter {
+	if bucket <= 0 || bucket > window {
+		bucket = window
+	}
+	n := int(window / bucket)
+	if n < 1 {
+		n = 1
+	}
+	k := &KeywordCounter{
+		bucket:    bucket,
+		buckets:   make([]map[string]int, n),
+		start:     make([]time.Time, n),
+		total:     make(map[string]int),
+		tokenizer: Tokenize,
+	}
+	for i := range k.buckets {
+		k.buckets[i] = make(map[string]int)
+	}
+	return k
+}
+
+// SetTokenizer 设置分词函数，默认使用 Tokenize
+func (k *KeywordCounter) SetTokenizer(f func(string) []string) {
+	k.mu.Lock()
+	k.tokenizer = f
+	k.mu.Unlock()
+}
+
+// SetKeywords 设置额外统计的关键词，弹幕包含关键词时计数一次
+func (k *KeywordCounter) SetKeywords(words ...string) {
+	k.mu.Lock()
+	k.keywords = append([]string(nil), words...)
+	k.mu.Unlock()
+}
+
+// Attach 统计 src 中的全部弹幕
+func (k *KeywordCounter) Attach(src client.DanmakuSource) {
+	src.OnDanmaku(k.AddDanmaku)
+}
+
+// AddDanmaku 统计一条弹幕，表情弹幕统计表情的 ID
+func (k *KeywordCounter) AddDanmaku(d *message.Danmaku) {
+	t := d.ReceivedAt
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if d.Type == message.EmoticonDanmaku && d.Emoticon != nil && d.Emoticon.EmoticonUnique != "" {
+		k.addWords([]string{d.Emoticon.EmoticonUnique}, t)
+		return
+	}
+	k.Add(d.Content, t)
+}
+
+// Add 统计时间 t 的一段文本
+func (k *KeywordCounter) Add(text string, t time.Time) {
+	k.mu.Lock()
+	tokenizer, keywords := k.tokenizer, k.keywords
+	k.mu.Unlock()
+	words := tokenizer(text)
+	for _, kw := range keywords {
+		if strings.Contains(text, kw) {
+			words = append(words, kw)
+		}
+	}
+	k.addWords(words, t)
+}
+
+func (k *KeywordCounter) addWords(words []string, t time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.advance(t)
+	b := k.buckets[k.head]
+	seen := make(map[string]bool, len(words))
+	for _, w := range words {
+		// 同一条弹幕中重复的词只计数一次
+		if w == "" || seen[w] {
+			continue
+		}
+		seen[w] = true
+		b[w]++
+		k.total[w]++
+	}
+}
+
+// advance 将窗口滑动到时间 t，清除过期的桶并定位当前桶，调用时需要持有锁
+func (k *KeywordCounter) advance(t time.Time) {
+	bt := t.Truncate(k.bucket)
+	// 乱序到达的旧事件计入最新的桶，避免覆盖较新的计数
+	if bt.Before(k.latest) {
+		bt = k.latest
+	}
+	k.latest = bt
+	window := k.bucket * time.Duration(len(k.buckets))
+	for i, s := range k.start {
+		if !s.IsZero() && !s.After(bt.Add(-window)) {
+			k.expire(i)
+		}
+	}
+	k.head = int(bt.UnixNano()/int64(k.bucket)) % len(k.buckets)
+	if !k.start[k.head].Equal(bt) {
+		k.expire(k.head)
+		k.start[k.head] = bt
+	}
+}
+
+// expire 清空第 i 个桶并从总数中减去
+func (k *KeywordCounter) expire(i int) {
+	for w, n := range k.buckets[i] {
+		if k.total[w] -= n; k.total[w] <= 0 {
+			delete(k.total, w)
+		}
+	}
+	k.buckets[i] = make(map[string]int)
+	k.start[i] = time.Time{}
+}
+
+// TopK 获取当前窗口内出现次数最多的 n 个词
+func (k *KeywordCounter) TopK(n int) []KeywordCount {
+	k.mu.Lock()
+	k.advance(time.Now())
+	list := make([]KeywordCount, 0, len(k.total))
+	for w, c := range k.total {
+		list = append(list, KeywordCount{Word: w, Count: c})
+	}
+	k.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Word < list[j].Word
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// Start 每隔 interval 调用一次 f，参数为当前的 TopK(n)
+func (k *KeywordCounter) Start(interval time.Duration, n int, f func([]KeywordCount)) {
+	k.mu.Lock()
+	if k.stop != nil {
+		k.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	k.stop = stop
+	k.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				f(k.TopK(n))
+			}
+		}
+	}()
+}
+
+// Stop 停止 Start 启动的定时快照
+func (k *KeywordCounter) Stop() {
+	k.mu.Lock()
+	if k.stop != nil {
+		close(k.stop)
+		k.stop = nil
+	}
+	k.mu.Unlock()
+}
+
+// Tokenize 默认的分词函数
+//
+// 提取 [xxx] 形式的表情、emoji、英文单词与数字，不超过 10 个字的弹幕整条作为一个词
+func Tokenize(text string) []string {
+	text = strings.TrimSpace(text)
+	var words []string
+	if n := utf8.RuneCountInString(text); n > 0 && n <= shortMessageRunes {
+		words = append(words, collapseRepeats(text))
+	}
+	var word []rune
+	flush := func() {
+		if len(word) > 1 {
+			words = append(words, strings.ToLower(string(word)))
+		}
+		word = word[:0]
+	}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '[':
+			flush()
+			if end := strings.IndexByte(text[i:], ']'); end > 1 {
+				words = append(words, text[i:i+end+1])
+				i += end + 1
+				continue
+			}
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word = append(word, r)
+		case unicode.Is(unicode.So, r):
+			flush()
+			words = append(words, string(r))
+		default:
+			flush()
+		}
+		i += size
+	}
+	flush()
+	return words
+}
+
+// collapseRepeats 将连续重复超过 3 次的字压缩为 3 次，如 "哈哈哈哈哈" 变为 "哈哈哈"
+func collapseRepeats(s string) string {
+	var b strings.Builder
+	var last rune
+	n := 0
+	for _, r := range s {
+		if r == last {
+			n++
+		} else {
+			last, n = r, 1
+		}
+		if n <= 3 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
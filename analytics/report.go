@@ -0,0 +1,225 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/store"
+	"github.com/tidwall/gjson"
+)
+
+// Report 一场直播结束后的汇总报告
+type Report struct {
+	RoomID         int            `json:"room_id"`
+	Start          time.Time      `json:"start"`
+	End            time.Time      `json:"end"`
+	Events         int            `json:"events"`
+	Danmaku        int            `json:"danmaku"`
+	UniqueChatters int            `json:"unique_chatters"` // 发送过弹幕的用户数
+	Revenue        Revenue        `json:"revenue"`
+	RevenueYuan    float64        `json:"revenue_yuan"`
+	TopGifters     []UserRevenue  `json:"top_gifters"`
+	Words          []KeywordCount `json:"words"` // 出现次数最多的词，可用于生成词云
+}
+
+// ReportBuilder 从录制的事件中生成 Report
+type ReportBuilder struct {
+	top      int
+	words    int
+	report   Report
+	chatters map[string]bool
+	counts   map[string]int
+	revenue  *RevenueTracker
+}
+
+// NewReportBuilder 创建报告生成器，top 为贡献榜的人数，words 为词频统计保留的词数，<= 0 时保留全部
+func NewReportBuilder(top int, words int) *ReportBuilder {
+	return &ReportBuilder{
+		top:      top,
+		words:    words,
+		chatters: make(map[string]bool),
+		counts:   make(map[string]int),
+		revenue:  NewRevenueTracker(),
+	}
+}
+
+// Add 统计一条录制的事件
+func (b *ReportBuilder) Add(e *record.Entry) {
+	t := time.Unix(0, e.Time*int64(time.Millisecond))
+	if b.report.Start.IsZero() || t.Before(b.report.Start) {
+		b.report.Start = t
+	}
+	if t.After(b.report.End) {
+		b.report.End = t
+	}
+	if b.report.RoomID == 0 {
+		b.report.RoomID = e.RoomID
+	}
+	b.report.Events++
+	cmd := gjson.GetBytes(e.Data, "cmd").String()
+	if i := strings.IndexByte(cmd, ':'); i >= 0 {
+		cmd = cmd[:i]
+	}
+	switch cmd {
+	case "DANMU_MSG":
+		d := new(message.Danmaku)
+		if d.Parse(e.Data) != nil {
+			return
+		}
+		b.report.Danmaku++
+		if d.Sender != nil {
+			if d.Sender.Uid != 0 {
+				b.chatters[strconv.Itoa(d.Sender.Uid)] = true
+			} else if d.Sender.Uname != "" {
+				b.chatters["@"+d.Sender.Uname] = true
+			}
+		}
+		var words []string
+		if d.Type == message.EmoticonDanmaku && d.Emoticon != nil && d.Emoticon.EmoticonUnique != "" {
+			words = []string{d.Emoticon.EmoticonUnique}
+		} else {
+			words = Tokenize(d.Content)
+		}
+		seen := make(map[string]bool, len(words))
+		for _, w := range words {
+			// 同一条弹幕中重复的词只计数一次
+			if w != "" && !seen[w] {
+				seen[w] = true
+				b.counts[w]++
+			}
+		}
+	case "SEND_GIFT":
+		g := new(message.Gift)
+		if g.Parse(e.Data) == nil {
+			b.revenue.AddGift(g)
+		}
+	case "SUPER_CHAT_MESSAGE":
+		s := new(message.SuperChat)
+		if s.Parse(e.Data) == nil {
+			b.revenue.AddSuperChat(s)
+		}
+	case "GUARD_BUY":
+		g := new(message.GuardBuy)
+		if g.Parse(e.Data) == nil {
+			b.revenue.AddGuardBuy(g)
+		}
+	}
+}
+
+// Report 获取当前统计的报告
+func (b *ReportBuilder) Report() *Report {
+	r := b.report
+	r.UniqueChatters = len(b.chatters)
+	r.Revenue = b.revenue.Snapshot()
+	r.Revenue.StartedAt = r.Start
+	r.RevenueYuan = r.Revenue.Yuan()
+	r.TopGifters = b.revenue.Leaderboard(b.top)
+	for i := range r.TopGifters {
+		r.TopGifters[i].StartedAt = r.Start
+	}
+	r.Words = make([]KeywordCount, 0, len(b.counts))
+	for w, n := range b.counts {
+		r.Words = append(r.Words, KeywordCount{Word: w, Count: n})
+	}
+	sort.Slice(r.Words, func(i, j int) bool {
+		if r.Words[i].Count != r.Words[j].Count {
+			return r.Words[i].Count > r.Words[j].Count
+		}
+		return r.Words[i].Word < r.Words[j].Word
+	})
+	if b.words > 0 && len(r.Words) > b.words {
+		r.Words = r.Words[:b.words]
+	}
+	return &r
+}
+
+// BuildReport 读取录制文件中的全部事件并生成报告
+func BuildReport(r *record.Reader, top int, words int) (*Report, error) {
+	b := NewReportBuilder(top, words)
+	for {
+		e, err := r.Next()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		b.Add(e)
+	}
+	return b.Report(), nil
+}
+
+// WriteJSON 将报告写入为 JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV 将报告写入为 CSV，依次为汇总、贡献榜与词频三个表，表之间以空行分隔，每个表的第一行为表头
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	itoa := func(n int64) string { return strconv.FormatInt(n, 10) }
+	rows := [][]string{
+		{"metric", "value"},
+		{"room_id", strconv.Itoa(r.RoomID)},
+		{"start", r.Start.Format(time.RFC3339)},
+		{"end", r.End.Format(time.RFC3339)},
+		{"events", strconv.Itoa(r.Events)},
+		{"danmaku", strconv.Itoa(r.Danmaku)},
+		{"unique_chatters", strconv.Itoa(r.UniqueChatters)},
+		{"gold", itoa(r.Revenue.Gold)},
+		{"silver", itoa(r.Revenue.Silver)},
+		{"super_chat", itoa(r.Revenue.SuperChat)},
+		{"guard", itoa(r.Revenue.Guard)},
+		{"gift_count", strconv.Itoa(r.Revenue.GiftCount)},
+		{"super_chat_count", strconv.Itoa(r.Revenue.SuperChatCount)},
+		{"guard_count", strconv.Itoa(r.Revenue.GuardCount)},
+		{"revenue_yuan", strconv.FormatFloat(r.RevenueYuan, 'f', 2, 64)},
+		nil,
+		{"rank", "uid", "uname", "total", "gold", "super_chat", "guard"},
+	}
+	for i, u := range r.TopGifters {
+		rows = append(rows, []string{strconv.Itoa(i + 1), strconv.Itoa(u.Uid), u.Uname,
+			itoa(u.Total()), itoa(u.Gold), itoa(u.SuperChat), itoa(u.Guard)})
+	}
+	rows = append(rows, nil, []string{"word", "count"})
+	for _, k := range r.Words {
+		rows = append(rows, []string{k.Word, strconv.Itoa(k.Count)})
+	}
+	for _, row := range rows {
+		if row == nil {
+			// csv.Writer 不会写入空记录，直接写入空行
+			cw.Flush()
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// BuildStoreReport 读取 s 中房间在时间范围内保存的事件并生成报告，零值的时间不限制
+func BuildStoreReport(s *store.Store, roomID int, start, end time.Time, top int, words int) (*Report, error) {
+	b := NewReportBuilder(top, words)
+	err := s.Entries(roomID, start, end, func(e *record.Entry) error {
+		b.Add(e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.Report(), nil
+}
@@ -0,0 +1,201 @@
+package analytics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+const (
+	// GoldPerYuan 1元人民币对应的金瓜子数
+	GoldPerYuan = message.GoldPerYuan
+	// GoldPerBattery 1电池对应的金瓜子数
+	GoldPerBattery = 100
+)
+
+// GoldToYuan 将金瓜子折算为人民币
+func GoldToYuan(gold int64) float64 {
+	return float64(gold) / GoldPerYuan
+}
+
+// BatteryToYuan 将电池折算为人民币
+func BatteryToYuan(battery int64) float64 {
+	return GoldToYuan(battery * GoldPerBattery)
+}
+
+// EventGold 获取礼物、醒目留言或上舰的金额，单位为金瓜子，见 message.EventGold
+func EventGold(v interface{}) (int64, bool) {
+	return message.EventGold(v)
+}
+
+// Revenue 一场直播的营收汇总，除 Silver 外单位均为金瓜子
+type Revenue struct {
+	StartedAt      time.Time `json:"started_at"`
+	Gold           int64     `json:"gold"`       // 金瓜子礼物
+	Silver         int64     `json:"silver"`     // 银瓜子礼物
+	SuperChat      int64     `json:"super_chat"` // 醒目留言，按人民币折算为金瓜子
+	Guard          int64     `json:"guard"`      // 大航海
+	GiftCount      int       `json:"gift_count"`
+	SuperChatCount int       `json:"super_chat_count"`
+	GuardCount     int       `json:"guard_count"`
+}
+
+// Total 金瓜子礼物、醒目留言与大航海的总和，单位为金瓜子
+func (r Revenue) Total() int64 {
+	return r.Gold + r.SuperChat + r.Guard
+}
+
+// Yuan 总营收折算为人民币
+func (r Revenue) Yuan() float64 {
+	return GoldToYuan(r.Total())
+}
+
+// UserRevenue 单个用户的贡献
+type UserRevenue struct {
+	Uid   int    `json:"uid"`
+	Uname string `json:"uname"`
+	Revenue
+}
+
+// RevenueTracker 汇总礼物、醒目留言、大航海的营收，并维护用户贡献榜
+type RevenueTracker struct {
+	mu    sync.Mutex
+	total Revenue
+	users map[int]*UserRevenue
+	stop  chan struct{}
+}
+
+// NewRevenueTracker 创建一个营收统计，并开始新的场次
+func NewRevenueTracker() *RevenueTracker {
+	r := &RevenueTracker{}
+	r.Reset()
+	return r
+}
+
+// Attach 统计 src 中的礼物、醒目留言与大航海
+func (r *RevenueTracker) Attach(src client.DanmakuSource) {
+	src.OnGift(r.AddGift)
+	src.OnSuperChat(r.AddSuperChat)
+	src.OnGuardBuy(r.AddGuardBuy)
+}
+
+// AddGift 统计一次送礼
+func (r *RevenueTracker) AddGift(g *message.Gift) {
+	gold, ok := EventGold(g)
+	r.add(g.Uid, g.Uname, func(v *Revenue) {
+		if ok {
+			v.Gold += gold
+		} else {
+			v.Silver += g.Coin()
+		}
+		v.GiftCount++
+	})
+}
+
+// AddSuperChat 统计一条醒目留言
+func (r *RevenueTracker) AddSuperChat(s *message.SuperChat) {
+	gold, _ := EventGold(s)
+	r.add(s.Uid, s.UserInfo.Uname, func(v *Revenue) {
+		v.SuperChat += gold
+		v.SuperChatCount++
+	})
+}
+
+// AddGuardBuy 统计一次上舰
+func (r *RevenueTracker) AddGuardBuy(g *message.GuardBuy) {
+	gold, _ := EventGold(g)
+	r.add(g.Uid, g.Username, func(v *Revenue) {
+		v.Guard += gold
+		v.GuardCount++
+	})
+}
+
+func (r *RevenueTracker) add(uid int, uname string, f func(*Revenue)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(&r.total)
+	u, ok := r.users[uid]
+	if !ok {
+		u = &UserRevenue{Uid: uid}
+		u.StartedAt = r.total.StartedAt
+		r.users[uid] = u
+	}
+	if uname != "" {
+		u.Uname = uname
+	}
+	f(&u.Revenue)
+}
+
+// Snapshot 获取当前场次的营收汇总
+func (r *RevenueTracker) Snapshot() Revenue {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.total
+}
+
+// Leaderboard 获取当前场次贡献最多的 n 个用户，n <= 0 时返回全部
+func (r *RevenueTracker) Leaderboard(n int) []UserRevenue {
+	r.mu.Lock()
+	list := make([]UserRevenue, 0, len(r.users))
+	for _, u := range r.users {
+		list = append(list, *u)
+	}
+	r.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if a, b := list[i].Total(), list[j].Total(); a != b {
+			return a > b
+		}
+		return list[i].Uid < list[j].Uid
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// Reset 结束当前场次并开始新的场次，返回上一场次的营收汇总
+func (r *RevenueTracker) Reset() Revenue {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := r.total
+	r.total = Revenue{StartedAt: time.Now()}
+	r.users = make(map[int]*UserRevenue)
+	return last
+}
+
+// Start 每隔 interval 调用一次 f，参数为当前营收汇总与前 n 名的贡献榜
+func (r *RevenueTracker) Start(interval time.Duration, n int, f func(Revenue, []UserRevenue)) {
+	r.mu.Lock()
+	if r.stop != nil {
+		r.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	r.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				f(r.Snapshot(), r.Leaderboard(n))
+			}
+		}
+	}()
+}
+
+// Stop 停止 Start 启动的定时输出
+func (r *RevenueTracker) Stop() {
+	r.mu.Lock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.mu.Unlock()
+}
@@ -0,0 +1,241 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+// Viewer 在直播间中出现过的用户
+type Viewer struct {
+	Uid         int       `json:"uid"`
+	Uname       string    `json:"uname"`
+	GuardLevel  int       `json:"guard_level"` // 在本直播间的舰队等级，0:非舰队，1:总督，2:提督，3:舰长
+	MedalName   string    `json:"medal_name,omitempty"`
+	MedalLevel  int       `json:"medal_level,omitempty"`
+	MedalRoomID int       `json:"medal_room_id,omitempty"` // 佩戴的粉丝勋章所属的直播间
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Danmaku     int       `json:"danmaku"`
+	Gifts       int       `json:"gifts"`
+	Followed    bool      `json:"followed,omitempty"` // 本次统计期间关注了主播
+}
+
+// Roster 直播间的观众名单，记录进入直播间、发送弹幕、礼物、醒目留言与上舰的用户
+type Roster struct {
+	mu      sync.Mutex
+	viewers map[int]*Viewer
+	ttl     time.Duration
+	adds    int
+}
+
+// NewRoster 创建一个观众名单，默认不清除长时间未出现的用户
+func NewRoster() *Roster {
+	return &Roster{viewers: make(map[int]*Viewer)}
+}
+
+// SetTTL 设置用户最后一次出现 d 时间之后从名单中移除，为 0 时不移除
+func (r *Roster) SetTTL(d time.Duration) {
+	r.mu.Lock()
+	r.ttl = d
+	r.mu.Unlock()
+}
+
+// Attach 记录 src 中出现的用户
+func (r *Roster) Attach(src client.DanmakuSource) {
+	src.OnInteractWord(r.AddInteractWord)
+	src.OnDanmaku(r.AddDanmaku)
+	src.OnGift(r.AddGift)
+	src.OnSuperChat(r.AddSuperChat)
+	src.OnGuardBuy(r.AddGuardBuy)
+}
+
+// AddInteractWord 记录进入直播间、关注、分享的用户
+func (r *Roster) AddInteractWord(i *message.InteractWord) {
+	r.seen(i.Uid, i.Uname, i.ReceivedAt, func(v *Viewer) {
+		m := i.FansMedal
+		if m.MedalName != "" {
+			v.MedalName, v.MedalLevel, v.MedalRoomID = m.MedalName, m.MedalLevel, m.AnchorRoomid
+		}
+		if m.AnchorRoomid != 0 && m.AnchorRoomid == i.Roomid {
+			v.GuardLevel = m.GuardLevel
+		}
+		if i.MsgType == message.InteractFollow || i.MsgType == message.InteractSpecialFollow || i.MsgType == message.InteractMutualFollow {
+			v.Followed = true
+		}
+	})
+}
+
+// AddDanmaku 记录发送弹幕的用户
+func (r *Roster) AddDanmaku(d *message.Danmaku) {
+	if d.Sender == nil {
+		return
+	}
+	u := d.Sender
+	r.seen(u.Uid, u.Uname, d.ReceivedAt, func(v *Viewer) {
+		v.Danmaku++
+		v.GuardLevel = u.GuardLevel
+		if u.Medal != nil && u.Medal.Name != "" {
+			v.MedalName, v.MedalLevel, v.MedalRoomID = u.Medal.Name, u.Medal.Level, u.Medal.UpRoomId
+		} else {
+			v.MedalName, v.MedalLevel, v.MedalRoomID = "", 0, 0
+		}
+	})
+}
+
+// AddGift 记录送礼的用户
+func (r *Roster) AddGift(g *message.Gift) {
+	r.seen(g.Uid, g.Uname, g.ReceivedAt, func(v *Viewer) {
+		v.Gifts++
+		v.GuardLevel = g.GuardLevel
+		if m := g.MedalInfo; m.MedalName != "" {
+			v.MedalName, v.MedalLevel, v.MedalRoomID = m.MedalName, m.MedalLevel, m.AnchorRoomid
+		}
+	})
+}
+
+// AddSuperChat 记录发送醒目留言的用户
+func (r *Roster) AddSuperChat(s *message.SuperChat) {
+	r.seen(s.Uid, s.UserInfo.Uname, s.ReceivedAt, func(v *Viewer) {
+		v.Gifts++
+		v.GuardLevel = s.UserInfo.GuardLevel
+		if m := s.MedalInfo; m.MedalName != "" {
+			v.MedalName, v.MedalLevel, v.MedalRoomID = m.MedalName, m.MedalLevel, m.AnchorRoomid
+		}
+	})
+}
+
+// AddGuardBuy 记录上舰的用户
+func (r *Roster) AddGuardBuy(g *message.GuardBuy) {
+	r.seen(g.Uid, g.Username, g.ReceivedAt, func(v *Viewer) {
+		v.Gifts++
+		if v.GuardLevel == 0 || g.GuardLevel < v.GuardLevel {
+			v.GuardLevel = g.GuardLevel
+		}
+	})
+}
+
+func (r *Roster) seen(uid int, uname string, t time.Time, f func(v *Viewer)) {
+	if uid == 0 {
+		return
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.viewers[uid]
+	if v == nil {
+		v = &Viewer{Uid: uid, FirstSeen: t}
+		r.viewers[uid] = v
+		r.adds++
+		if r.ttl > 0 && r.adds%1024 == 0 {
+			r.expire(t)
+		}
+	}
+	if uname != "" {
+		v.Uname = uname
+	}
+	if t.After(v.LastSeen) {
+		v.LastSeen = t
+	}
+	f(v)
+}
+
+// expire 移除超过 ttl 未出现的用户，调用时需要持有 r.mu
+func (r *Roster) expire(now time.Time) {
+	for uid, v := range r.viewers {
+		if now.Sub(v.LastSeen) >= r.ttl {
+			delete(r.viewers, uid)
+		}
+	}
+}
+
+// Get 获取用户的记录
+func (r *Roster) Get(uid int) (Viewer, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.viewers[uid]
+	if !ok || (r.ttl > 0 && time.Since(v.LastSeen) >= r.ttl) {
+		return Viewer{}, false
+	}
+	return *v, true
+}
+
+// Len 获取名单中的用户数量
+func (r *Roster) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ttl > 0 {
+		r.expire(time.Now())
+	}
+	return len(r.viewers)
+}
+
+// Viewers 获取满足 f 的用户，按最后出现时间从近到远排列，f 为 nil 时返回全部用户
+func (r *Roster) Viewers(f func(v *Viewer) bool) []Viewer {
+	r.mu.Lock()
+	if r.ttl > 0 {
+		r.expire(time.Now())
+	}
+	list := make([]Viewer, 0, len(r.viewers))
+	for _, v := range r.viewers {
+		if f == nil || f(v) {
+			list = append(list, *v)
+		}
+	}
+	r.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].LastSeen.Equal(list[j].LastSeen) {
+			return list[i].LastSeen.After(list[j].LastSeen)
+		}
+		return list[i].Uid < list[j].Uid
+	})
+	return list
+}
+
+// Active 获取最近 d 时间内出现过的用户
+func (r *Roster) Active(d time.Duration) []Viewer {
+	since := time.Now().Add(-d)
+	return r.Viewers(func(v *Viewer) bool { return !v.LastSeen.Before(since) })
+}
+
+// Guards 获取舰队成员
+func (r *Roster) Guards() []Viewer {
+	return r.Viewers(func(v *Viewer) bool { return v.GuardLevel > 0 })
+}
+
+// WithMedal 获取佩戴 roomID 直播间粉丝勋章且等级不低于 level 的用户
+func (r *Roster) WithMedal(roomID int, level int) []Viewer {
+	return r.Viewers(func(v *Viewer) bool { return v.MedalRoomID == roomID && v.MedalLevel >= level })
+}
+
+// WriteJSON 以 JSON 数组导出全部用户
+func (r *Roster) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Viewers(nil))
+}
+
+// WriteCSV 以 CSV 导出全部用户，第一行为表头
+func (r *Roster) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"uid", "uname", "guard_level", "medal_name", "medal_level", "medal_room_id", "first_seen", "last_seen", "danmaku", "gifts", "followed"})
+	for _, v := range r.Viewers(nil) {
+		_ = cw.Write([]string{
+			strconv.Itoa(v.Uid), v.Uname, strconv.Itoa(v.GuardLevel),
+			v.MedalName, strconv.Itoa(v.MedalLevel), strconv.Itoa(v.MedalRoomID),
+			v.FirstSeen.Format(time.RFC3339), v.LastSeen.Format(time.RFC3339),
+			strconv.Itoa(v.Danmaku), strconv.Itoa(v.Gifts), strconv.FormatBool(v.Followed),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,241 @@
+package analytics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/api"
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxSessions 保留的已结束场次数量
+const maxSessions = 64
+
+// liveTimeLayout 直播间信息接口中开播时间的格式
+const liveTimeLayout = "2006-01-02 15:04:05"
+
+// Session 一场直播
+type Session struct {
+	RoomID   int       `json:"room_id"`
+	LiveKey  string    `json:"live_key"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"` // 直播中为零值
+	Danmaku  int       `json:"danmaku"`
+	Revenue  Revenue   `json:"revenue"`
+	Verified bool      `json:"verified"` // 开播、下播是否经过 HTTP 接口确认
+}
+
+// Live 该场直播是否仍在进行
+func (s *Session) Live() bool {
+	return s.End.IsZero()
+}
+
+// Duration 直播时长，直播中时为到目前为止的时长
+func (s *Session) Duration() time.Duration {
+	if s.Live() {
+		return time.Since(s.Start)
+	}
+	return s.End.Sub(s.Start)
+}
+
+// SessionTracker 根据 LIVE 与 PREPARING 划分直播场次，并统计每场的弹幕与营收
+//
+// 收到 PREPARING 时会请求直播间信息接口确认已下播，避免误判
+type SessionTracker struct {
+	mu        sync.Mutex
+	roomID    int
+	api       *api.Client
+	revenue   *RevenueTracker
+	current   *Session
+	sessions  []Session
+	onStart   []func(Session)
+	onEnd     []func(Session)
+	verifying bool
+}
+
+// NewSessionTracker 创建直播间 roomID 的场次统计
+func NewSessionTracker(roomID int) *SessionTracker {
+	return &SessionTracker{
+		roomID:  roomID,
+		api:     api.DefaultClient,
+		revenue: NewRevenueTracker(),
+	}
+}
+
+// SetAPIClient 设置确认直播状态时使用的 api.Client
+func (t *SessionTracker) SetAPIClient(a *api.Client) {
+	t.mu.Lock()
+	t.api = a
+	t.mu.Unlock()
+}
+
+// OnSessionStart 添加 开始新场次 的处理器
+func (t *SessionTracker) OnSessionStart(f func(Session)) {
+	t.mu.Lock()
+	t.onStart = append(t.onStart, f)
+	t.mu.Unlock()
+}
+
+// OnSessionEnd 添加 场次结束 的处理器
+func (t *SessionTracker) OnSessionEnd(f func(Session)) {
+	t.mu.Lock()
+	t.onEnd = append(t.onEnd, f)
+	t.mu.Unlock()
+}
+
+// Attach 统计 src 中的开播、下播、弹幕与营收事件
+func (t *SessionTracker) Attach(src client.DanmakuSource) {
+	src.OnLive(t.handleLive)
+	src.OnPreparing(t.handlePreparing)
+	src.OnDanmaku(func(*message.Danmaku) {
+		t.mu.Lock()
+		if t.current != nil {
+			t.current.Danmaku++
+		}
+		t.mu.Unlock()
+	})
+	src.OnGift(t.revenue.AddGift)
+	src.OnSuperChat(t.revenue.AddSuperChat)
+	src.OnGuardBuy(t.revenue.AddGuardBuy)
+}
+
+// Current 获取正在进行的场次，未开播时返回 nil
+func (t *SessionTracker) Current() *Session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil {
+		return nil
+	}
+	s := *t.current
+	s.Revenue = t.revenue.Snapshot()
+	return &s
+}
+
+// Sessions 获取已结束的场次，按开始时间排序
+func (t *SessionTracker) Sessions() []Session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Session(nil), t.sessions...)
+}
+
+// Verify 通过直播间信息接口同步直播状态
+//
+// 直播中但没有进行中的场次时以接口返回的开播时间开始新场次，未开播时结束进行中的场次
+func (t *SessionTracker) Verify() error {
+	t.mu.Lock()
+	a := t.api
+	t.mu.Unlock()
+	info, err := a.GetLiveRoomInfo(strconv.Itoa(t.roomID))
+	if err != nil {
+		return err
+	}
+	if info.Data.LiveStatus == 1 {
+		start, err := time.ParseInLocation(liveTimeLayout, info.Data.LiveTime, cst)
+		if err != nil {
+			start = time.Now()
+		}
+		t.start("", start, true)
+	} else {
+		t.end(time.Now(), true)
+	}
+	return nil
+}
+
+func (t *SessionTracker) handleLive(l *message.Live) {
+	start := l.ReceivedAt
+	if l.LiveTime > 0 {
+		start = time.Unix(int64(l.LiveTime), 0)
+	}
+	if start.IsZero() {
+		start = time.Now()
+	}
+	t.start(l.LiveKey, start, false)
+}
+
+func (t *SessionTracker) handlePreparing(p *message.Preparing) {
+	t.mu.Lock()
+	if t.verifying || t.current == nil {
+		t.mu.Unlock()
+		return
+	}
+	t.verifying = true
+	t.mu.Unlock()
+	end := p.ReceivedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			t.verifying = false
+			t.mu.Unlock()
+		}()
+		t.mu.Lock()
+		a := t.api
+		t.mu.Unlock()
+		info, err := a.GetLiveRoomInfo(strconv.Itoa(t.roomID))
+		if err != nil {
+			log.Warn("verify live status failed: ", err)
+			t.end(end, false)
+			return
+		}
+		if info.Data.LiveStatus == 1 {
+			return
+		}
+		t.end(end, true)
+	}()
+}
+
+// start 开始新场次，已有进行中的场次时只补充信息
+func (t *SessionTracker) start(liveKey string, at time.Time, verified bool) {
+	t.mu.Lock()
+	if t.current != nil {
+		if t.current.LiveKey == "" || liveKey == "" || t.current.LiveKey == liveKey {
+			if liveKey != "" {
+				t.current.LiveKey = liveKey
+			}
+			t.current.Verified = t.current.Verified || verified
+			t.mu.Unlock()
+			return
+		}
+		// live_key 变化说明上一场已结束但没有收到 PREPARING
+		t.mu.Unlock()
+		t.end(at, false)
+		t.mu.Lock()
+	}
+	t.current = &Session{RoomID: t.roomID, LiveKey: liveKey, Start: at, Verified: verified}
+	t.revenue.Reset()
+	s, handlers := *t.current, t.onStart
+	t.mu.Unlock()
+	for _, f := range handlers {
+		f(s)
+	}
+}
+
+// end 结束进行中的场次
+func (t *SessionTracker) end(at time.Time, verified bool) {
+	t.mu.Lock()
+	if t.current == nil {
+		t.mu.Unlock()
+		return
+	}
+	s := *t.current
+	s.End = at
+	s.Verified = s.Verified || verified
+	s.Revenue = t.revenue.Snapshot()
+	t.current = nil
+	t.sessions = append(t.sessions, s)
+	if len(t.sessions) > maxSessions {
+		t.sessions = t.sessions[len(t.sessions)-maxSessions:]
+	}
+	handlers := t.onEnd
+	t.mu.Unlock()
+	for _, f := range handlers {
+		f(s)
+	}
+}
+
+var cst = time.FixedZone("CST", 8*3600)
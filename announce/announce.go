@@ -0,0 +1,231 @@
+// Package announce 按固定间隔在直播间发送公告弹幕，默认只在直播中发送
+package announce
+
+import (
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/api"
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultPartInterval 拆分后相邻两条弹幕的发送间隔，B 站对同一用户的发言有约 1s 的冷却
+const defaultPartInterval = 1500 * time.Millisecond
+
+// Announcement 一条定时公告
+type Announcement struct {
+	Name     string        `json:"name"`
+	Message  string        `json:"message"`  // 超出长度限制时拆分为多条弹幕依次发送
+	Interval time.Duration `json:"interval"` // 发送间隔
+	Delay    time.Duration `json:"delay"`    // Start 之后第一次发送前等待的时间，为 0 时等待一个 Interval
+	Always   bool          `json:"always"`   // 未开播时也发送
+}
+
+// Scheduler 定时公告的调度器
+type Scheduler struct {
+	roomID       string
+	verify       *api.BiliVerify
+	apiClient    *api.Client
+	limit        int
+	partInterval time.Duration
+	sender       *api.Sender
+	mu           sync.Mutex
+	sendMu       sync.Mutex
+	live         bool
+	items        []*Announcement
+	sent         []func(a *Announcement, part string, err error)
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewScheduler 创建向 roomID 直播间发送公告的调度器，verify 为发送弹幕账号的身份信息
+func NewScheduler(roomID string, verify *api.BiliVerify) *Scheduler {
+	return &Scheduler{
+		roomID:       roomID,
+		verify:       verify,
+		apiClient:    api.DefaultClient,
+		limit:        api.DanmakuMaxLength,
+		partInterval: defaultPartInterval,
+	}
+}
+
+// SetAPIClient 设置发送弹幕与查询直播状态时使用的 api.Client
+func (s *Scheduler) SetAPIClient(a *api.Client) {
+	s.apiClient = a
+}
+
+// SetLengthLimit 设置单条弹幕的最大字符数，默认 api.DanmakuMaxLength，账号为 UL20 或大航海时可设为 api.DanmakuMaxLengthLong
+func (s *Scheduler) SetLengthLimit(n int) {
+	s.limit = n
+}
+
+// SetPartInterval 设置拆分后相邻两条弹幕的发送间隔，默认 1.5s
+func (s *Scheduler) SetPartInterval(d time.Duration) {
+	s.partInterval = d
+}
+
+// SetSender 通过 sender 的发送队列发送公告，与其他消息共享同一账号的发送间隔，此时使用 sender 的长度限制
+func (s *Scheduler) SetSender(sender *api.Sender) {
+	s.sender = sender
+}
+
+// Add 添加一条公告，需要在 Start 之前调用
+func (s *Scheduler) Add(a Announcement) {
+	s.mu.Lock()
+	s.items = append(s.items, &a)
+	s.mu.Unlock()
+}
+
+// OnSent 添加每条弹幕发送后调用的处理器，err 为 nil 时表示发送成功
+func (s *Scheduler) OnSent(f func(a *Announcement, part string, err error)) {
+	s.mu.Lock()
+	s.sent = append(s.sent, f)
+	s.mu.Unlock()
+}
+
+// Attach 根据 src 中的开播、下播事件更新直播状态
+func (s *Scheduler) Attach(src client.DanmakuSource) {
+	src.OnLive(func(*message.Live) { s.SetLive(true) })
+	src.OnPreparing(func(*message.Preparing) { s.SetLive(false) })
+}
+
+// SetLive 设置直播状态
+func (s *Scheduler) SetLive(live bool) {
+	s.mu.Lock()
+	s.live = live
+	s.mu.Unlock()
+}
+
+// Live 获取当前的直播状态
+func (s *Scheduler) Live() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.live
+}
+
+// RefreshLiveStatus 通过接口查询直播状态，轮播中视为未开播
+func (s *Scheduler) RefreshLiveStatus() error {
+	info, err := s.apiClient.GetLiveRoomInfo(s.roomID)
+	if err != nil {
+		return err
+	}
+	s.SetLive(info.Data.LiveStatus == 1)
+	return nil
+}
+
+// Start 查询一次直播状态并开始定时发送
+func (s *Scheduler) Start() {
+	if err := s.RefreshLiveStatus(); err != nil {
+		log.Error("get live status failed: ", err)
+	}
+	stop := make(chan struct{})
+	s.mu.Lock()
+	s.stop = stop
+	items := s.items
+	s.mu.Unlock()
+	for _, a := range items {
+		if a.Interval <= 0 {
+			log.Warnf("announcement %q has no interval, skipped", a.Name)
+			continue
+		}
+		s.wg.Add(1)
+		go s.loop(a, stop)
+	}
+}
+
+func (s *Scheduler) loop(a *Announcement, stop <-chan struct{}) {
+	defer s.wg.Done()
+	wait := a.Delay
+	if wait <= 0 {
+		wait = a.Interval
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+		if a.Always || s.Live() {
+			s.Send(a)
+		}
+		timer.Reset(a.Interval)
+	}
+}
+
+// Send 立即发送一条公告，超出长度限制时拆分后依次发送，任一条失败时不再发送剩余部分
+func (s *Scheduler) Send(a *Announcement) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.mu.Lock()
+	handlers := s.sent
+	s.mu.Unlock()
+	if s.sender != nil {
+		return s.sendQueued(a, handlers)
+	}
+	for i, part := range api.SplitDanmaku(a.Message, s.limit) {
+		if i > 0 {
+			select {
+			case <-s.stopped():
+				return nil
+			case <-time.After(s.partInterval):
+			}
+		}
+		resp, err := s.apiClient.SendDefaultDanmaku(s.roomID, part, s.verify)
+		if err == nil {
+			err = resp.Err()
+		}
+		for _, fn := range handlers {
+			fn(a, part, err)
+		}
+		if err != nil {
+			log.Errorf("send announcement %q to room %s failed: %v", a.Name, s.roomID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// sendQueued 通过发送队列发送并等待结果
+func (s *Scheduler) sendQueued(a *Announcement, handlers []func(a *Announcement, part string, err error)) error {
+	ch := make(chan *api.SendResult, 1)
+	if err := s.sender.Send(s.roomID, a.Message, func(r *api.SendResult) { ch <- r }); err != nil {
+		log.Errorf("send announcement %q to room %s failed: %v", a.Name, s.roomID, err)
+		return err
+	}
+	r := <-ch
+	for i, part := range r.Parts {
+		var err error
+		if i == r.Sent {
+			err = r.Err
+		} else if i > r.Sent {
+			break
+		}
+		for _, fn := range handlers {
+			fn(a, part, err)
+		}
+	}
+	return r.Err
+}
+
+// stopped 未启动时返回 nil，从 nil channel 接收会一直阻塞
+func (s *Scheduler) stopped() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stop
+}
+
+// Stop 停止定时发送，等待正在发送的公告结束
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop := s.stop
+	s.stop = nil
+	s.mu.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+	s.wg.Wait()
+}
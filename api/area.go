@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// AreaListCacheTTL 分区列表的缓存时间，为 0 时不缓存
+var AreaListCacheTTL = time.Hour
+
+// AreaList 全部分区
+// api https://api.live.bilibili.com/room/v1/Area/getList response
+type AreaList struct {
+	Code    int          `json:"code"`
+	Message string       `json:"message"`
+	Data    []ParentArea `json:"data"`
+}
+
+// ParentArea 父分区，如 网游、娱乐
+type ParentArea struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	List []Area `json:"list"` // 子分区
+}
+
+// Area 子分区
+type Area struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	ParentId   string `json:"parent_id"`
+	ParentName string `json:"parent_name"`
+	Pic        string `json:"pic"`
+}
+
+// GetAreaList 获取全部父分区与子分区
+func (a *Client) GetAreaList() (*AreaList, error) {
+	result := &AreaList{}
+	err := a.GetJsonCached(a.url("/room/v1/Area/getList"), result, AreaListCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func GetAreaList() (*AreaList, error) {
+	return DefaultClient.GetAreaList()
+}
+
+// AreaRoomList 分区中正在直播的房间
+// api https://api.live.bilibili.com/room/v3/area/getRoomList response
+type AreaRoomList struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Count   int        `json:"count"`
+		HasMore int        `json:"has_more"`
+		List    []AreaRoom `json:"list"`
+	} `json:"data"`
+}
+
+// AreaRoom 分区中正在直播的一个房间
+type AreaRoom struct {
+	RoomId     int    `json:"roomid"`
+	ShortId    int    `json:"short_id"`
+	Uid        int    `json:"uid"`
+	Uname      string `json:"uname"`
+	Face       string `json:"face"`
+	Title      string `json:"title"`
+	Online     int    `json:"online"` // 人气值
+	UserCover  string `json:"user_cover"`
+	AreaId     int    `json:"area_id"`
+	AreaName   string `json:"area_name"`
+	ParentId   int    `json:"parent_id"`
+	ParentName string `json:"parent_name"`
+}
+
+// GetAreaRooms 获取分区中正在直播的房间，按人气排序，areaID 为 0 时获取整个父分区，page 从 1 开始
+func (a *Client) GetAreaRooms(parentAreaID int, areaID int, page int, pageSize int) (*AreaRoomList, error) {
+	result := &AreaRoomList{}
+	err := a.GetJson(a.url(fmt.Sprintf("/room/v3/area/getRoomList?platform=web&parent_area_id=%d&area_id=%d&sort_type=online&page=%d&page_size=%d", parentAreaID, areaID, page, pageSize)), result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func GetAreaRooms(parentAreaID int, areaID int, page int, pageSize int) (*AreaRoomList, error) {
+	return DefaultClient.GetAreaRooms(parentAreaID, areaID, page, pageSize)
+}
+
+// GetAllAreaRooms 翻页获取分区中正在直播的房间，maxPages 为 0 时获取全部
+//
+// 翻页期间房间的排名会变化，结果中可能有重复或遗漏的房间，已按房间号去重
+func (a *Client) GetAllAreaRooms(parentAreaID int, areaID int, maxPages int) ([]AreaRoom, error) {
+	const pageSize = 30
+	var rooms []AreaRoom
+	seen := make(map[int]bool)
+	for page := 1; maxPages <= 0 || page <= maxPages; page++ {
+		l, err := a.GetAreaRooms(parentAreaID, areaID, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		if l.Code != 0 {
+			return nil, fmt.Errorf("area/getRoomList: %d %s", l.Code, l.Message)
+		}
+		for _, r := range l.Data.List {
+			if !seen[r.RoomId] {
+				seen[r.RoomId] = true
+				rooms = append(rooms, r)
+			}
+		}
+		if len(l.Data.List) == 0 || l.Data.HasMore == 0 {
+			break
+		}
+	}
+	return rooms, nil
+}
+
+func GetAllAreaRooms(parentAreaID int, areaID int, maxPages int) ([]AreaRoom, error) {
+	return DefaultClient.GetAllAreaRooms(parentAreaID, areaID, maxPages)
+}
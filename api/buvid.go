@@ -0,0 +1,15 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// NewBuvid 随机生成一个 buvid3 格式的设备标识
+func NewBuvid() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	h := strings.ToUpper(hex.EncodeToString(b))
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:] + "infoc"
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Cache 接口响应的缓存，key 为请求的 URL，value 为响应的原始内容
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// 各接口响应的缓存时间，为 0 时不缓存
+var (
+	RoomInfoCacheTTL   = 10 * time.Minute
+	DanmuInfoCacheTTL  = 30 * time.Second
+	GiftConfigCacheTTL = time.Hour
+)
+
+var (
+	cacheMu sync.RWMutex
+	cache   Cache = NewLRUCache(4096)
+)
+
+// SetCache 替换全局的接口响应缓存，为 nil 时禁用缓存
+func SetCache(c Cache) {
+	cacheMu.Lock()
+	cache = c
+	cacheMu.Unlock()
+}
+
+func getCache() Cache {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	return cache
+}
+
+// GetJsonCached 与 GetJson 相同，但会缓存 code 为 0 的响应 ttl 时长
+func (a *Client) GetJsonCached(url string, result interface{}, ttl time.Duration) error {
+	c := getCache()
+	if c == nil || ttl <= 0 {
+		return a.GetJson(url, result)
+	}
+	if b, ok := c.Get(url); ok {
+		return json.Unmarshal(b, result)
+	}
+	return a.fetchJsonCached(c, url, result, ttl)
+}
+
+// RefreshJsonCached 跳过缓存重新请求，code 为 0 的响应会替换缓存中原有的内容
+func (a *Client) RefreshJsonCached(url string, result interface{}, ttl time.Duration) error {
+	c := getCache()
+	if c == nil || ttl <= 0 {
+		return a.GetJson(url, result)
+	}
+	return a.fetchJsonCached(c, url, result, ttl)
+}
+
+func (a *Client) fetchJsonCached(c Cache, url string, result interface{}, ttl time.Duration) error {
+	b, err := a.get(url)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, result); err != nil {
+		return err
+	}
+	if gjson.GetBytes(b, "code").Int() == 0 {
+		c.Set(url, b, ttl)
+	}
+	return nil
+}
+
+func GetJsonCached(url string, result interface{}, ttl time.Duration) error {
+	return DefaultClient.GetJsonCached(url, result, ttl)
+}
+
+// LRUCache 带过期时间的内存 LRU 缓存
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key    string
+	value  []byte
+	expire time.Time
+}
+
+// NewLRUCache 创建最多保存 capacity 条记录的 LRU 缓存
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (l *LRUCache) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expire) {
+		l.ll.Remove(el)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.ll.MoveToFront(el)
+	return e.value, true
+}
+
+func (l *LRUCache) Set(key string, value []byte, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	expire := time.Now().Add(ttl)
+	if el, ok := l.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.value, e.expire = value, expire
+		l.ll.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, value: value, expire: expire})
+	for l.capacity > 0 && l.ll.Len() > l.capacity {
+		el := l.ll.Back()
+		l.ll.Remove(el)
+		delete(l.items, el.Value.(*lruEntry).key)
+	}
+}
+
+// Delete 删除一条缓存
+func (l *LRUCache) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.ll.Remove(el)
+		delete(l.items, key)
+	}
+}
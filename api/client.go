@@ -0,0 +1,108 @@
+package api
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBaseURL api.live.bilibili.com 接口的默认地址
+const DefaultBaseURL = "https://api.live.bilibili.com"
+
+// DefaultMainURL api.bilibili.com 接口的默认地址，用于搜索等非直播接口
+const DefaultMainURL = "https://api.bilibili.com"
+
+// Client 调用 B 站接口的客户端，可以为不同的弹幕 client 指定不同的接口地址
+//
+// 包级别的函数都使用 DefaultClient
+type Client struct {
+	// BaseURL 接口地址，如 https://api.live.bilibili.com ，为空时使用 SetBaseURL 设置的全局地址
+	BaseURL string
+	// MainURL 非直播接口的地址，如 https://api.bilibili.com ，为空时使用 SetMainURL 设置的全局地址
+	MainURL string
+	// UserAgent 每次请求时调用以获取 User-Agent，为 nil 或返回空字符串时使用 SetUserAgents 设置的全局列表
+	UserAgent func() string
+	// Buvid 每次请求时调用以获取请求附带的 buvid3 Cookie，为 nil 或返回空字符串时不附带
+	Buvid func() string
+}
+
+// DefaultClient 包级别函数使用的客户端
+var DefaultClient = &Client{}
+
+var (
+	baseURLMu sync.RWMutex
+	baseURL   = DefaultBaseURL
+	mainURL   = DefaultMainURL
+)
+
+// SetBaseURL 设置全局的接口地址，用于反向代理、镜像或测试
+func SetBaseURL(u string) {
+	baseURLMu.Lock()
+	baseURL = strings.TrimRight(u, "/")
+	baseURLMu.Unlock()
+}
+
+// SetMainURL 设置全局的非直播接口地址
+func SetMainURL(u string) {
+	baseURLMu.Lock()
+	mainURL = strings.TrimRight(u, "/")
+	baseURLMu.Unlock()
+}
+
+var globalUserAgent func() string
+
+// SetUserAgents 设置全局请求使用的 User-Agent 列表，每次请求轮换使用下一个
+func SetUserAgents(uas ...string) {
+	baseURLMu.Lock()
+	globalUserAgent = RotateUserAgents(uas...)
+	baseURLMu.Unlock()
+}
+
+// RotateUserAgents 返回一个每次调用都按顺序轮换返回 uas 中下一个 User-Agent 的函数
+func RotateUserAgents(uas ...string) func() string {
+	if len(uas) == 0 {
+		return nil
+	}
+	list := append([]string(nil), uas...)
+	var n uint64
+	return func() string {
+		i := atomic.AddUint64(&n, 1) - 1
+		return list[i%uint64(len(list))]
+	}
+}
+
+// userAgent 获取本次请求使用的 User-Agent
+func (a *Client) userAgent() string {
+	if a != nil && a.UserAgent != nil {
+		if ua := a.UserAgent(); ua != "" {
+			return ua
+		}
+	}
+	baseURLMu.RLock()
+	f := globalUserAgent
+	baseURLMu.RUnlock()
+	if f == nil {
+		return ""
+	}
+	return f()
+}
+
+// url 拼接接口地址与路径
+func (a *Client) url(path string) string {
+	if a != nil && a.BaseURL != "" {
+		return strings.TrimRight(a.BaseURL, "/") + path
+	}
+	baseURLMu.RLock()
+	defer baseURLMu.RUnlock()
+	return baseURL + path
+}
+
+// mainURL 拼接非直播接口地址与路径
+func (a *Client) mainURL(path string) string {
+	if a != nil && a.MainURL != "" {
+		return strings.TrimRight(a.MainURL, "/") + path
+	}
+	baseURLMu.RLock()
+	defer baseURLMu.RUnlock()
+	return mainURL + path
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FollowingLiveList 关注的主播中正在直播的房间，需要登录
+// api https://api.live.bilibili.com/xlive/web-ucenter/v1/xfetter/GetWebList response
+type FollowingLiveList struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Count int                 `json:"count"`
+		Rooms []FollowingLiveRoom `json:"rooms"`
+	} `json:"data"`
+}
+
+// FollowingLiveRoom 关注的主播中正在直播的一个房间
+type FollowingLiveRoom struct {
+	RoomId         int    `json:"room_id"`
+	ShortId        int    `json:"short_id"`
+	Uid            int    `json:"uid"`
+	Uname          string `json:"uname"`
+	Face           string `json:"face"`
+	Title          string `json:"title"`
+	Online         int    `json:"online"` // 人气值
+	LiveTime       int64  `json:"live_time"`
+	LiveStatus     int    `json:"live_status"`
+	Cover          string `json:"cover_from_user"`
+	Keyframe       string `json:"keyframe"`
+	AreaId         int    `json:"area_v2_id"`
+	AreaName       string `json:"area_v2_name"`
+	ParentAreaId   int    `json:"area_v2_parent_id"`
+	ParentAreaName string `json:"area_v2_parent_name"`
+}
+
+// GetFollowingLive 获取 v 对应账号关注的主播中正在直播的房间，page 从 1 开始
+func (a *Client) GetFollowingLive(page int, pageSize int, v *BiliVerify) (*FollowingLiveList, error) {
+	req, err := http.NewRequest("GET", a.url(fmt.Sprintf("/xlive/web-ucenter/v1/xfetter/GetWebList?page=%d&page_size=%d", page, pageSize)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Cookie", fmt.Sprintf("bili_jct=%s;SESSDATA=%s", v.Csrf, v.SessData))
+	b, err := a.do(req)
+	if err != nil {
+		return nil, err
+	}
+	result := &FollowingLiveList{}
+	if err = json.Unmarshal(b, result); err != nil {
+		return nil, err
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("xfetter/GetWebList: %d %s", result.Code, result.Message)
+	}
+	return result, nil
+}
+
+func GetFollowingLive(page int, pageSize int, v *BiliVerify) (*FollowingLiveList, error) {
+	return DefaultClient.GetFollowingLive(page, pageSize, v)
+}
+
+// GetAllFollowingLive 翻页获取关注的主播中全部正在直播的房间，已按房间号去重
+//
+// 可以将结果中的房间号交给 RoomManager.AddRooms，定时调用以自动订阅开播的关注主播
+func (a *Client) GetAllFollowingLive(v *BiliVerify) ([]FollowingLiveRoom, error) {
+	const pageSize = 10
+	var rooms []FollowingLiveRoom
+	seen := make(map[int]bool)
+	for page := 1; ; page++ {
+		l, err := a.GetFollowingLive(page, pageSize, v)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range l.Data.Rooms {
+			if !seen[r.RoomId] {
+				seen[r.RoomId] = true
+				rooms = append(rooms, r)
+			}
+		}
+		if len(l.Data.Rooms) < pageSize || len(rooms) >= l.Data.Count {
+			break
+		}
+	}
+	return rooms, nil
+}
+
+func GetAllFollowingLive(v *BiliVerify) ([]FollowingLiveRoom, error) {
+	return DefaultClient.GetAllFollowingLive(v)
+}
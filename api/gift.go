@@ -0,0 +1,43 @@
+package api
+
+import (
+	"fmt"
+)
+
+// GiftConfig
+// api https://api.live.bilibili.com/xlive/web-room/v1/giftPanel/giftConfig?platform=pc&room_id={} response
+type GiftConfig struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		List []GiftInfo `json:"list"`
+	} `json:"data"`
+}
+
+type GiftInfo struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	Price     int    `json:"price"`
+	Type      int    `json:"type"`
+	CoinType  string `json:"coin_type"`
+	Effect    int    `json:"effect"`
+	Img       string `json:"img_basic"`
+	Gif       string `json:"gif"`
+	Webp      string `json:"webp"`
+	Desc      string `json:"desc"`
+	Rights    string `json:"rights"`
+	Privilege int    `json:"privilege_required"`
+}
+
+func (a *Client) GetGiftConfig(roomID string) (*GiftConfig, error) {
+	result := &GiftConfig{}
+	err := a.GetJsonCached(a.url(fmt.Sprintf("/xlive/web-room/v1/giftPanel/giftConfig?platform=pc&room_id=%s", roomID)), result, GiftConfigCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func GetGiftConfig(roomID string) (*GiftConfig, error) {
+	return DefaultClient.GetGiftConfig(roomID)
+}
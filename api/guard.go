@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+)
+
+// GuardTopList 直播间的大航海列表
+// api https://api.live.bilibili.com/xlive/app-room/v2/guardTab/topList response
+type GuardTopList struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Info struct {
+			Num              int `json:"num"` // 舰队总人数
+			Page             int `json:"page"`
+			Now              int `json:"now"`
+			AchievementLevel int `json:"achievement_level"`
+		} `json:"info"`
+		List []GuardMember `json:"list"`
+		Top3 []GuardMember `json:"top3"` // 只在第一页返回
+	} `json:"data"`
+}
+
+// GuardMember 大航海列表中的一名成员，接口不返回到期时间
+type GuardMember struct {
+	Uid           int    `json:"uid"`
+	Ruid          int    `json:"ruid"`
+	Rank          int    `json:"rank"`
+	Username      string `json:"username"`
+	Face          string `json:"face"`
+	IsAlive       int    `json:"is_alive"`
+	GuardLevel    int    `json:"guard_level"` // 1:总督 2:提督 3:舰长
+	GuardSubLevel int    `json:"guard_sub_level"`
+	MedalInfo     struct {
+		MedalName        string `json:"medal_name"`
+		MedalLevel       int    `json:"medal_level"`
+		MedalColorStart  int    `json:"medal_color_start"`
+		MedalColorEnd    int    `json:"medal_color_end"`
+		MedalColorBorder int    `json:"medal_color_border"`
+	} `json:"medal_info"`
+}
+
+// GetGuardTopList 获取直播间的大航海列表，ruid 为主播 uid，page 从 1 开始
+func (a *Client) GetGuardTopList(roomID string, ruid int, page int, pageSize int) (*GuardTopList, error) {
+	result := &GuardTopList{}
+	err := a.GetJson(a.url(fmt.Sprintf("/xlive/app-room/v2/guardTab/topList?roomid=%s&ruid=%d&page=%d&page_size=%d", roomID, ruid, page, pageSize)), result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func GetGuardTopList(roomID string, ruid int, page int, pageSize int) (*GuardTopList, error) {
+	return DefaultClient.GetGuardTopList(roomID, ruid, page, pageSize)
+}
+
+// GetAllGuards 翻页获取直播间的全部舰队成员
+func (a *Client) GetAllGuards(roomID string, ruid int) ([]GuardMember, error) {
+	const pageSize = 30
+	var members []GuardMember
+	for page := 1; ; page++ {
+		l, err := a.GetGuardTopList(roomID, ruid, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		if l.Code != 0 {
+			return nil, fmt.Errorf("guardTab/topList: %d %s", l.Code, l.Message)
+		}
+		if page == 1 {
+			members = append(members, l.Data.Top3...)
+		}
+		members = append(members, l.Data.List...)
+		if len(l.Data.List) == 0 || len(members) >= l.Data.Info.Num || page >= l.Data.Info.Page {
+			return members, nil
+		}
+	}
+}
+
+func GetAllGuards(roomID string, ruid int) ([]GuardMember, error) {
+	return DefaultClient.GetAllGuards(roomID, ruid)
+}
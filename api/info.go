@@ -1,7 +1,9 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 )
 
@@ -53,28 +55,190 @@ type DanmuInfo struct {
 	} `json:"data"`
 }
 
+func (a *Client) GetDanmuInfo(roomID string) (*DanmuInfo, error) {
+	result := &DanmuInfo{}
+	err := a.GetJsonCached(a.url(fmt.Sprintf("/xlive/web-room/v1/index/getDanmuInfo?id=%s&type=0", roomID)), result, DanmuInfoCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func GetDanmuInfo(roomID string) (*DanmuInfo, error) {
+	return DefaultClient.GetDanmuInfo(roomID)
+}
+
+// RefreshDanmuInfo 跳过缓存重新获取 getDanmuInfo，缓存中的 token 被服务器拒绝后使用
+func (a *Client) RefreshDanmuInfo(roomID string) (*DanmuInfo, error) {
 	result := &DanmuInfo{}
-	err := GetJson(fmt.Sprintf("https://api.live.bilibili.com/xlive/web-room/v1/index/getDanmuInfo?id=%s&type=0", roomID), result)
+	err := a.RefreshJsonCached(a.url(fmt.Sprintf("/xlive/web-room/v1/index/getDanmuInfo?id=%s&type=0", roomID)), result, DanmuInfoCacheTTL)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func GetRoomInfo(roomID string) (*RoomInfo, error) {
+func (a *Client) GetRoomInfo(roomID string) (*RoomInfo, error) {
 	result := &RoomInfo{}
-	err := GetJson(fmt.Sprintf("https://api.live.bilibili.com/room/v1/Room/room_init?id=%s", roomID), result)
+	err := a.GetJsonCached(a.url(fmt.Sprintf("/room/v1/Room/room_init?id=%s", roomID)), result, RoomInfoCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func GetRoomInfo(roomID string) (*RoomInfo, error) {
+	return DefaultClient.GetRoomInfo(roomID)
+}
+
+// DanmakuHistory
+// api https://api.live.bilibili.com/xlive/web-room/v1/dM/gethistory?roomid={} response
+type DanmakuHistory struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Admin []HistoryDanmaku `json:"admin"`
+		Room  []HistoryDanmaku `json:"room"`
+	} `json:"data"`
+}
+
+type HistoryDanmaku struct {
+	Text       string          `json:"text"`
+	DmType     int             `json:"dm_type"`
+	Uid        int             `json:"uid"`
+	Nickname   string          `json:"nickname"`
+	UnameColor string          `json:"uname_color"`
+	Timeline   string          `json:"timeline"`
+	IsAdmin    int             `json:"isadmin"`
+	Vip        int             `json:"vip"`
+	Svip       int             `json:"svip"`
+	Medal      json.RawMessage `json:"medal"`      // [等级, 勋章名, 主播名, 房间号, 颜色, ...]
+	UserLevel  json.RawMessage `json:"user_level"` // [等级, ...]
+	Rank       int             `json:"rank"`
+	Rnd        string          `json:"rnd"`
+	GuardLevel int             `json:"guard_level"`
+	Bubble     int             `json:"bubble"`
+	CheckInfo  struct {
+		Ts int64  `json:"ts"`
+		Ct string `json:"ct"`
+	} `json:"check_info"`
+	Emoticon struct {
+		Id             int    `json:"id"`
+		EmoticonUnique string `json:"emoticon_unique"`
+		Text           string `json:"text"`
+		Url            string `json:"url"`
+		Width          int    `json:"width"`
+		Height         int    `json:"height"`
+	} `json:"emoticon"`
+}
+
+func (a *Client) GetDanmakuHistory(roomID string) (*DanmakuHistory, error) {
+	result := &DanmakuHistory{}
+	err := a.GetJson(a.url(fmt.Sprintf("/xlive/web-room/v1/dM/gethistory?roomid=%s", roomID)), result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func GetRoomRealID(roomID string) (string, error) {
-	res, err := GetRoomInfo(roomID)
+func GetDanmakuHistory(roomID string) (*DanmakuHistory, error) {
+	return DefaultClient.GetDanmakuHistory(roomID)
+}
+
+// LiveRoomInfo
+// api https://api.live.bilibili.com/room/v1/Room/get_info?room_id={} response
+type LiveRoomInfo struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	Message string `json:"message"`
+	Data    struct {
+		Uid            int    `json:"uid"`
+		RoomId         int    `json:"room_id"`
+		ShortId        int    `json:"short_id"`
+		Attention      int    `json:"attention"`
+		Online         int    `json:"online"`
+		IsPortrait     bool   `json:"is_portrait"`
+		Description    string `json:"description"`
+		LiveStatus     int    `json:"live_status"` // 0:未开播 1:直播中 2:轮播中
+		AreaId         int    `json:"area_id"`
+		ParentAreaId   int    `json:"parent_area_id"`
+		ParentAreaName string `json:"parent_area_name"`
+		AreaName       string `json:"area_name"`
+		Title          string `json:"title"`
+		UserCover      string `json:"user_cover"`
+		Keyframe       string `json:"keyframe"`
+		LiveTime       string `json:"live_time"` // 开播时间，未开播时为 0000-00-00 00:00:00
+		Tags           string `json:"tags"`
+	} `json:"data"`
+}
+
+func (a *Client) GetLiveRoomInfo(roomID string) (*LiveRoomInfo, error) {
+	result := &LiveRoomInfo{}
+	err := a.GetJson(a.url(fmt.Sprintf("/room/v1/Room/get_info?room_id=%s", roomID)), result)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return result, nil
+}
+
+func GetLiveRoomInfo(roomID string) (*LiveRoomInfo, error) {
+	return DefaultClient.GetLiveRoomInfo(roomID)
+}
+
+// RoomBaseInfo 批量获取的房间信息
+type RoomBaseInfo struct {
+	RoomId         int    `json:"room_id"`
+	ShortId        int    `json:"short_id"`
+	Uid            int    `json:"uid"`
+	Uname          string `json:"uname"`
+	LiveStatus     int    `json:"live_status"` // 0:未开播 1:直播中 2:轮播中
+	LiveTime       string `json:"live_time"`
+	Title          string `json:"title"`
+	Cover          string `json:"cover"`
+	AreaId         int    `json:"area_id"`
+	AreaName       string `json:"area_name"`
+	ParentAreaId   int    `json:"parent_area_id"`
+	ParentAreaName string `json:"parent_area_name"`
+	Online         int    `json:"online"`
+	Attention      int    `json:"attention"`
+}
+
+// RoomBaseInfoBatchSize 每次请求 getRoomBaseInfo 的房间数量上限
+const RoomBaseInfoBatchSize = 50
+
+// GetRoomBaseInfo 批量获取房间信息，返回真实房间号到房间信息的映射，房间数量超过 RoomBaseInfoBatchSize 时分多次请求
+// api https://api.live.bilibili.com/xlive/web-room/v1/index/getRoomBaseInfo?room_ids={}&room_ids={}&req_biz=web_room_componet
+func (a *Client) GetRoomBaseInfo(roomIDs ...int) (map[int]*RoomBaseInfo, error) {
+	rooms := make(map[int]*RoomBaseInfo, len(roomIDs))
+	for start := 0; start < len(roomIDs); start += RoomBaseInfoBatchSize {
+		end := start + RoomBaseInfoBatchSize
+		if end > len(roomIDs) {
+			end = len(roomIDs)
+		}
+		q := url.Values{"req_biz": {"web_room_componet"}}
+		for _, id := range roomIDs[start:end] {
+			q.Add("room_ids", strconv.Itoa(id))
+		}
+		var result struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    struct {
+				ByRoomIds map[string]*RoomBaseInfo `json:"by_room_ids"`
+			} `json:"data"`
+		}
+		if err := a.GetJson(a.url("/xlive/web-room/v1/index/getRoomBaseInfo?"+q.Encode()), &result); err != nil {
+			return nil, err
+		}
+		if result.Code != 0 {
+			return nil, fmt.Errorf("getRoomBaseInfo: %d %s", result.Code, result.Message)
+		}
+		for _, r := range result.Data.ByRoomIds {
+			rooms[r.RoomId] = r
+		}
 	}
-	return strconv.Itoa(res.Data.RoomId), nil
+	return rooms, nil
+}
+
+func GetRoomBaseInfo(roomIDs ...int) (map[int]*RoomBaseInfo, error) {
+	return DefaultClient.GetRoomBaseInfo(roomIDs...)
 }
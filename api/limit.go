@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited 请求被风控拦截(HTTP 412、code -412 或 -352)，具体的错误为 *RiskControlError
+var ErrRateLimited = errors.New("api request rejected by risk control (412)")
+
+// RiskControlError 请求被风控拦截，errors.Is(err, ErrRateLimited) 为 true
+type RiskControlError struct {
+	URL    string
+	Status int // HTTP 状态码
+	Code   int // 响应中的 code
+}
+
+func (e *RiskControlError) Error() string {
+	return fmt.Sprintf("api request %s rejected by risk control: status %d, code %d", e.URL, e.Status, e.Code)
+}
+
+func (e *RiskControlError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// isRiskControl 响应是否为风控拦截
+func isRiskControl(status int, code int64) bool {
+	return status == 412 || code == -412 || code == -352
+}
+
+// 被风控拦截后的退避时间，每次连续拦截翻倍直至 maxBackoff
+const (
+	minBackoff = 10 * time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// limiter 所有 api 请求共享的令牌桶限流器
+type limiter struct {
+	mu           sync.Mutex
+	rate         float64 // 每秒生成的令牌数，<= 0 时不限流
+	burst        float64
+	tokens       float64
+	last         time.Time
+	backoff      time.Duration
+	blockedUntil time.Time
+	handlers     []func(url string, backoff time.Duration)
+}
+
+var globalLimiter = &limiter{rate: 10, burst: 20, tokens: 20}
+
+// SetRateLimit 设置所有 api 请求共享的速率限制，rate 为每秒请求数，burst 为允许的突发请求数
+//
+// rate <= 0 时不限制请求速率，但仍会在被风控拦截后退避；burst 小于 1 时按 1 处理，否则令牌永远不足一个
+func SetRateLimit(rate float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	l := globalLimiter
+	l.mu.Lock()
+	l.rate = rate
+	l.burst = float64(burst)
+	l.tokens = l.burst
+	l.mu.Unlock()
+}
+
+// OnRateLimited 添加 请求被风控拦截 的处理器，参数为被拦截的 URL 与之后的退避时间
+func OnRateLimited(f func(url string, backoff time.Duration)) {
+	l := globalLimiter
+	l.mu.Lock()
+	l.handlers = append(l.handlers, f)
+	l.mu.Unlock()
+}
+
+// wait 等待直至可以发出请求
+func (l *limiter) wait() {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		var d time.Duration
+		if now.Before(l.blockedUntil) {
+			d = l.blockedUntil.Sub(now)
+		} else if l.rate > 0 {
+			l.tokens += now.Sub(l.last).Seconds() * l.rate
+			if l.tokens > l.burst {
+				l.tokens = l.burst
+			}
+			l.last = now
+			if l.tokens >= 1 {
+				l.tokens--
+			} else {
+				d = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+			}
+		}
+		l.mu.Unlock()
+		if d <= 0 {
+			return
+		}
+		time.Sleep(d)
+	}
+}
+
+// penalize 请求被风控拦截，之后的请求在退避时间内都会等待
+func (l *limiter) penalize(url string) {
+	l.mu.Lock()
+	if l.backoff == 0 {
+		l.backoff = minBackoff
+	} else if l.backoff < maxBackoff {
+		l.backoff *= 2
+		if l.backoff > maxBackoff {
+			l.backoff = maxBackoff
+		}
+	}
+	backoff := l.backoff
+	l.blockedUntil = time.Now().Add(backoff)
+	handlers := l.handlers
+	l.mu.Unlock()
+	for _, fn := range handlers {
+		fn(url, backoff)
+	}
+}
+
+// success 请求成功，重置退避时间
+func (l *limiter) success() {
+	l.mu.Lock()
+	l.backoff = 0
+	l.mu.Unlock()
+}
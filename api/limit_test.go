@@ -0,0 +1,32 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetRateLimitZeroBurst(t *testing.T) {
+	l := globalLimiter
+	l.mu.Lock()
+	rate, burst, tokens := l.rate, l.burst, l.tokens
+	l.mu.Unlock()
+	t.Cleanup(func() {
+		l.mu.Lock()
+		l.rate, l.burst, l.tokens = rate, burst, tokens
+		l.mu.Unlock()
+	})
+	for _, b := range []int{0, -1} {
+		SetRateLimit(100, b)
+		done := make(chan struct{})
+		go func() {
+			l.wait()
+			l.wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("burst %d: wait blocked forever", b)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 以下接口需要主播或房管账号的身份信息，BiliVerify.Csrf 为 Cookie 中的 bili_jct
+
+// ManageResp 直播间管理接口的通用返回
+type ManageResp struct {
+	Code    int             `json:"code"`
+	Msg     string          `json:"msg"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// ManageError 直播间管理接口返回的错误，如没有权限或房管已满
+type ManageError struct {
+	Code    int
+	Message string
+}
+
+func (e *ManageError) Error() string {
+	return fmt.Sprintf("room manage failed: %d %s", e.Code, e.Message)
+}
+
+// Err 接口返回失败时返回 *ManageError，否则返回 nil
+func (r *ManageResp) Err() error {
+	if r.Code == 0 {
+		return nil
+	}
+	msg := r.Message
+	if msg == "" {
+		msg = r.Msg
+	}
+	return &ManageError{Code: r.Code, Message: msg}
+}
+
+// postForm 带上身份信息与 csrf 发出表单 POST 请求，接口返回失败时返回 *ManageError
+func (a *Client) postForm(path string, form url.Values, v *BiliVerify) (*ManageResp, error) {
+	form.Set("csrf", v.Csrf)
+	form.Set("csrf_token", v.Csrf)
+	req, err := http.NewRequest("POST", a.url(path), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Cookie", fmt.Sprintf("bili_jct=%s;SESSDATA=%s", v.Csrf, v.SessData))
+	b, err := a.do(req)
+	if err != nil {
+		return nil, err
+	}
+	result := &ManageResp{}
+	if err = json.Unmarshal(b, result); err != nil {
+		return nil, err
+	}
+	return result, result.Err()
+}
+
+// UpdateRoomTitle 修改直播间标题 https://api.live.bilibili.com/room/v1/Room/update
+func (a *Client) UpdateRoomTitle(roomID string, title string, v *BiliVerify) (*ManageResp, error) {
+	return a.postForm("/room/v1/Room/update", url.Values{"room_id": {roomID}, "title": {title}}, v)
+}
+
+func UpdateRoomTitle(roomID string, title string, v *BiliVerify) (*ManageResp, error) {
+	return DefaultClient.UpdateRoomTitle(roomID, title, v)
+}
+
+// UpdateRoomArea 修改直播间分区，areaID 为子分区 ID https://api.live.bilibili.com/room/v1/Room/update
+func (a *Client) UpdateRoomArea(roomID string, areaID int, v *BiliVerify) (*ManageResp, error) {
+	return a.postForm("/room/v1/Room/update", url.Values{"room_id": {roomID}, "area_id": {strconv.Itoa(areaID)}}, v)
+}
+
+func UpdateRoomArea(roomID string, areaID int, v *BiliVerify) (*ManageResp, error) {
+	return DefaultClient.UpdateRoomArea(roomID, areaID, v)
+}
+
+// AddRoomAdmin 任命房管，只能在自己的直播间使用 https://api.live.bilibili.com/xlive/web-ucenter/v1/roomAdmin/appoint
+func (a *Client) AddRoomAdmin(uid int, v *BiliVerify) (*ManageResp, error) {
+	return a.postForm("/xlive/web-ucenter/v1/roomAdmin/appoint", url.Values{"admin": {strconv.Itoa(uid)}}, v)
+}
+
+func AddRoomAdmin(uid int, v *BiliVerify) (*ManageResp, error) {
+	return DefaultClient.AddRoomAdmin(uid, v)
+}
+
+// RemoveRoomAdmin 撤销房管 https://api.live.bilibili.com/xlive/web-ucenter/v1/roomAdmin/dismiss
+func (a *Client) RemoveRoomAdmin(uid int, v *BiliVerify) (*ManageResp, error) {
+	return a.postForm("/xlive/web-ucenter/v1/roomAdmin/dismiss", url.Values{"uid": {strconv.Itoa(uid)}}, v)
+}
+
+func RemoveRoomAdmin(uid int, v *BiliVerify) (*ManageResp, error) {
+	return DefaultClient.RemoveRoomAdmin(uid, v)
+}
+
+// 禁言时长
+const (
+	BanPermanent   = -1 // 永久
+	BanCurrentLive = 0  // 本场直播
+)
+
+// BanUser 禁言用户，hour 为禁言的小时数，也可以为 BanPermanent 或 BanCurrentLive
+// https://api.live.bilibili.com/xlive/web-ucenter/v1/banned/AddSilentUser
+func (a *Client) BanUser(roomID string, uid int, hour int, v *BiliVerify) (*ManageResp, error) {
+	return a.postForm("/xlive/web-ucenter/v1/banned/AddSilentUser", url.Values{
+		"room_id":    {roomID},
+		"tuid":       {strconv.Itoa(uid)},
+		"hour":       {strconv.Itoa(hour)},
+		"mobile_app": {"web"},
+	}, v)
+}
+
+func BanUser(roomID string, uid int, hour int, v *BiliVerify) (*ManageResp, error) {
+	return DefaultClient.BanUser(roomID, uid, hour, v)
+}
+
+// UnbanUser 解除禁言 https://api.live.bilibili.com/xlive/web-ucenter/v1/banned/DelSilentUser
+func (a *Client) UnbanUser(roomID string, uid int, v *BiliVerify) (*ManageResp, error) {
+	return a.postForm("/xlive/web-ucenter/v1/banned/DelSilentUser", url.Values{
+		"room_id": {roomID},
+		"tuid":    {strconv.Itoa(uid)},
+	}, v)
+}
+
+func UnbanUser(roomID string, uid int, v *BiliVerify) (*ManageResp, error) {
+	return DefaultClient.UnbanUser(roomID, uid, v)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultSendCooldown 同一账号相邻两条弹幕的默认发送间隔
+	DefaultSendCooldown = 1500 * time.Millisecond
+	// sendTooFast 发送弹幕过快时接口返回的错误码
+	sendTooFast      = 10030
+	defaultOutboxLen = 64
+	maxSendRetries   = 3
+)
+
+var (
+	// ErrOutboxFull 发送队列已满
+	ErrOutboxFull = errors.New("send queue full")
+	// ErrOutboxClosed 发送队列已关闭
+	ErrOutboxClosed = errors.New("send queue closed")
+)
+
+// SendResult 一条消息的发送结果
+type SendResult struct {
+	RoomID  string
+	Message string
+	Parts   []string           // 按长度限制拆分后的弹幕
+	Sent    int                // 发送成功的条数
+	Resps   []*SendDanmakuResp // 每条已发送弹幕的接口返回
+	Err     error              // 第一条发送失败的弹幕的错误，之后的部分不再发送
+}
+
+type outgoing struct {
+	req  DanmakuRequest
+	done func(*SendResult)
+}
+
+// Sender 一个账号的弹幕发送队列，自动拆分超长消息并按冷却时间依次发送
+type Sender struct {
+	verify    *BiliVerify
+	client    *Client
+	limit     int
+	cooldown  time.Duration
+	queue     chan *outgoing
+	mu        sync.Mutex
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSender 创建使用 verify 账号发送弹幕的队列，默认每条不超过 DanmakuMaxLength 个字符，间隔 DefaultSendCooldown
+func NewSender(verify *BiliVerify) *Sender {
+	s := &Sender{
+		verify:   verify,
+		client:   DefaultClient,
+		limit:    DanmakuMaxLength,
+		cooldown: DefaultSendCooldown,
+		queue:    make(chan *outgoing, defaultOutboxLen),
+		done:     make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+// SetClient 设置发送时使用的 Client
+func (s *Sender) SetClient(c *Client) {
+	s.mu.Lock()
+	s.client = c
+	s.mu.Unlock()
+}
+
+// SetLengthLimit 设置单条弹幕的最大字符数，账号为 UL20 或大航海时可设为 DanmakuMaxLengthLong
+func (s *Sender) SetLengthLimit(n int) {
+	s.mu.Lock()
+	s.limit = n
+	s.mu.Unlock()
+}
+
+// SetCooldown 设置相邻两条弹幕的发送间隔
+func (s *Sender) SetCooldown(d time.Duration) {
+	s.mu.Lock()
+	s.cooldown = d
+	s.mu.Unlock()
+}
+
+// Send 将消息加入发送队列，done 在消息全部发送或失败后调用，可以为 nil
+func (s *Sender) Send(roomID string, msg string, done func(*SendResult)) error {
+	return s.SendRequest(&DanmakuRequest{
+		Msg:      msg,
+		RoomID:   roomID,
+		Bubble:   "0",
+		Color:    "16777215",
+		FontSize: "25",
+		Mode:     "1",
+		DmType:   "1",
+	}, done)
+}
+
+// SendRequest 将弹幕请求加入发送队列，Msg 超出长度限制时拆分为多条，其余字段保持不变
+func (s *Sender) SendRequest(d *DanmakuRequest, done func(*SendResult)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrOutboxClosed
+	}
+	select {
+	case s.queue <- &outgoing{req: *d, done: done}:
+		return nil
+	default:
+		return ErrOutboxFull
+	}
+}
+
+// Pending 获取等待发送的消息数量
+func (s *Sender) Pending() int {
+	return len(s.queue)
+}
+
+func (s *Sender) loop() {
+	defer close(s.done)
+	var last time.Time
+	for o := range s.queue {
+		s.mu.Lock()
+		client, limit, cooldown := s.client, s.limit, s.cooldown
+		s.mu.Unlock()
+		r := &SendResult{RoomID: o.req.RoomID, Message: o.req.Msg, Parts: SplitDanmaku(o.req.Msg, limit)}
+		for _, part := range r.Parts {
+			req := o.req
+			req.Msg = part
+			var resp *SendDanmakuResp
+			for attempt := 0; ; attempt++ {
+				if wait := cooldown - time.Since(last); wait > 0 {
+					time.Sleep(wait)
+				}
+				resp, r.Err = client.SendDanmaku(&req, s.verify)
+				last = time.Now()
+				if r.Err == nil {
+					r.Err = resp.Err()
+				}
+				var se *SendError
+				if !errors.As(r.Err, &se) || se.Code != sendTooFast || attempt >= maxSendRetries {
+					break
+				}
+				// 发送过快时加倍等待后重试
+				time.Sleep(cooldown << uint(attempt))
+			}
+			if r.Err != nil {
+				log.Errorf("send danmaku to room %s failed: %v", req.RoomID, r.Err)
+				break
+			}
+			r.Sent++
+			r.Resps = append(r.Resps, resp)
+		}
+		if o.done != nil {
+			o.done(r)
+		}
+	}
+}
+
+// Close 停止接收新的消息，等待队列中的消息发送完成
+func (s *Sender) Close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		close(s.queue)
+		s.mu.Unlock()
+		<-s.done
+	})
+}
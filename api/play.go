@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// 直播流画质 qn
+const (
+	QualityDolby    = 30000 // 杜比
+	Quality4K       = 20000
+	QualityOriginal = 10000 // 原画
+	QualityBluRay   = 400   // 蓝光
+	QualitySuperHD  = 250   // 超清
+	QualityHD       = 150   // 高清
+	QualitySmooth   = 80    // 流畅
+)
+
+// ErrNotLive 直播间未开播，没有可用的直播流
+var ErrNotLive = errors.New("room is not live")
+
+// RoomPlayInfo
+// api https://api.live.bilibili.com/xlive/web-room/v2/index/getRoomPlayInfo response
+type RoomPlayInfo struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		RoomId      int  `json:"room_id"`
+		ShortId     int  `json:"short_id"`
+		Uid         int  `json:"uid"`
+		IsHidden    bool `json:"is_hidden"`
+		IsLocked    bool `json:"is_locked"`
+		IsPortrait  bool `json:"is_portrait"`
+		LiveStatus  int  `json:"live_status"` // 0:未开播 1:直播中 2:轮播中
+		Encrypted   bool `json:"encrypted"`
+		PwdVerified bool `json:"pwd_verified"`
+		LiveTime    int  `json:"live_time"`
+		PlayurlInfo *struct {
+			ConfJson string `json:"conf_json"`
+			Playurl  struct {
+				Cid     int `json:"cid"`
+				GQnDesc []struct {
+					Qn   int    `json:"qn"`
+					Desc string `json:"desc"`
+				} `json:"g_qn_desc"`
+				Stream []struct {
+					ProtocolName string `json:"protocol_name"` // http_stream 或 http_hls
+					Format       []struct {
+						FormatName string `json:"format_name"` // flv、ts 或 fmp4
+						Codec      []struct {
+							CodecName string `json:"codec_name"` // avc 或 hevc
+							CurrentQn int    `json:"current_qn"`
+							AcceptQn  []int  `json:"accept_qn"`
+							BaseUrl   string `json:"base_url"`
+							UrlInfo   []struct {
+								Host      string `json:"host"`
+								Extra     string `json:"extra"`
+								StreamTtl int    `json:"stream_ttl"`
+							} `json:"url_info"`
+						} `json:"codec"`
+					} `json:"format"`
+				} `json:"stream"`
+			} `json:"playurl"`
+		} `json:"playurl_info"`
+	} `json:"data"`
+}
+
+// GetRoomPlayInfo 获取直播流信息，qn 为画质，如 QualityOriginal，返回的画质可能低于请求的画质
+func (a *Client) GetRoomPlayInfo(roomID string, qn int) (*RoomPlayInfo, error) {
+	result := &RoomPlayInfo{}
+	err := a.GetJson(a.url(fmt.Sprintf("/xlive/web-room/v2/index/getRoomPlayInfo?room_id=%s&protocol=0,1&format=0,1,2&codec=0,1&qn=%d&platform=web&ptype=8", roomID, qn)), result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func GetRoomPlayInfo(roomID string, qn int) (*RoomPlayInfo, error) {
+	return DefaultClient.GetRoomPlayInfo(roomID, qn)
+}
+
+// PlayURLOptions GetPlayURL 的选项，字段为空时不限制
+type PlayURLOptions struct {
+	Quality int    // 画质，默认 QualityOriginal
+	Format  string // flv、ts 或 fmp4，ts 与 fmp4 为 HLS 流
+	Codec   string // avc 或 hevc
+}
+
+// StreamURL 一个可用的直播流地址
+type StreamURL struct {
+	URL         string `json:"url"`
+	Protocol    string `json:"protocol"` // http_stream 或 http_hls
+	Format      string `json:"format"`
+	Codec       string `json:"codec"`
+	Quality     int    `json:"quality"`
+	QualityDesc string `json:"quality_desc"`
+	TTL         int    `json:"ttl"` // 地址的有效期，单位为秒
+}
+
+// GetPlayURL 获取直播流地址，按 flv、fmp4、ts 与 avc、hevc 的顺序排列，同一种流的多个 CDN 依次排列
+//
+// 未开播时返回 ErrNotLive
+func (a *Client) GetPlayURL(roomID string, opts *PlayURLOptions) ([]StreamURL, error) {
+	var o PlayURLOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Quality == 0 {
+		o.Quality = QualityOriginal
+	}
+	info, err := a.GetRoomPlayInfo(roomID, o.Quality)
+	if err != nil {
+		return nil, err
+	}
+	if info.Code != 0 {
+		return nil, fmt.Errorf("getRoomPlayInfo: %d %s", info.Code, info.Message)
+	}
+	if info.Data.LiveStatus != 1 || info.Data.PlayurlInfo == nil {
+		return nil, ErrNotLive
+	}
+	desc := make(map[int]string)
+	for _, d := range info.Data.PlayurlInfo.Playurl.GQnDesc {
+		desc[d.Qn] = d.Desc
+	}
+	var urls []StreamURL
+	for _, s := range info.Data.PlayurlInfo.Playurl.Stream {
+		for _, f := range s.Format {
+			if o.Format != "" && f.FormatName != o.Format {
+				continue
+			}
+			for _, c := range f.Codec {
+				if o.Codec != "" && c.CodecName != o.Codec {
+					continue
+				}
+				for _, u := range c.UrlInfo {
+					urls = append(urls, StreamURL{
+						URL:         u.Host + c.BaseUrl + u.Extra,
+						Protocol:    s.ProtocolName,
+						Format:      f.FormatName,
+						Codec:       c.CodecName,
+						Quality:     c.CurrentQn,
+						QualityDesc: desc[c.CurrentQn],
+						TTL:         u.StreamTtl,
+					})
+				}
+			}
+		}
+	}
+	rank := map[string]int{"flv": 0, "fmp4": 1, "ts": 2, "avc": 0, "hevc": 1}
+	sort.SliceStable(urls, func(i, j int) bool {
+		if urls[i].Format != urls[j].Format {
+			return rank[urls[i].Format] < rank[urls[j].Format]
+		}
+		return rank[urls[i].Codec] < rank[urls[j].Codec]
+	})
+	return urls, nil
+}
+
+func GetPlayURL(roomID string, opts *PlayURLOptions) ([]StreamURL, error) {
+	return DefaultClient.GetPlayURL(roomID, opts)
+}
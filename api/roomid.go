@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	// ErrRoomNotFound room_init 返回直播间不存在
+	ErrRoomNotFound = errors.New("room not found")
+	// ErrRoomBanned room_init 返回直播间已被封禁
+	ErrRoomBanned = errors.New("room banned")
+)
+
+// 短号转换结果的缓存时间，短号与真实房间号的对应关系基本不会变化；
+// 直播间不存在的结果也会缓存 RoomIDNegativeTTL，避免已删除的房间每次都请求接口，为 0 时不缓存
+var (
+	RoomIDCacheTTL    = 24 * time.Hour
+	RoomIDNegativeTTL = 5 * time.Minute
+)
+
+// codeRoomNotFound room_init 中直播间不存在的 code
+const codeRoomNotFound = 60004
+
+// roomIDCacheSize 缓存的短号数量上限，超过时先清理过期的记录
+const roomIDCacheSize = 4096
+
+type roomIDEntry struct {
+	realID string
+	err    error
+	expire time.Time
+}
+
+var (
+	roomIDMu    sync.Mutex
+	roomIDCache = make(map[string]roomIDEntry)
+)
+
+// ResetRoomIDCache 清空短号转换结果的缓存
+func ResetRoomIDCache() {
+	roomIDMu.Lock()
+	roomIDCache = make(map[string]roomIDEntry)
+	roomIDMu.Unlock()
+}
+
+func loadRoomID(key string) (roomIDEntry, bool) {
+	roomIDMu.Lock()
+	defer roomIDMu.Unlock()
+	e, ok := roomIDCache[key]
+	if ok && time.Now().After(e.expire) {
+		delete(roomIDCache, key)
+		return e, false
+	}
+	return e, ok
+}
+
+func storeRoomID(key string, e roomIDEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	e.expire = time.Now().Add(ttl)
+	roomIDMu.Lock()
+	defer roomIDMu.Unlock()
+	if len(roomIDCache) >= roomIDCacheSize {
+		now := time.Now()
+		for k, v := range roomIDCache {
+			if now.After(v.expire) {
+				delete(roomIDCache, k)
+			}
+		}
+		for k := range roomIDCache {
+			if len(roomIDCache) < roomIDCacheSize {
+				break
+			}
+			delete(roomIDCache, k)
+		}
+	}
+	roomIDCache[key] = e
+}
+
+// GetRoomRealID 获取短号对应的真实房间号，直播间不存在时返回 ErrRoomNotFound，被封禁时返回 ErrRoomBanned
+//
+// 结果会缓存 RoomIDCacheTTL，直播间不存在与被封禁的结果缓存 RoomIDNegativeTTL，网络错误与其他 code 的错误不会缓存
+func (a *Client) GetRoomRealID(roomID string) (string, error) {
+	key := a.url("/room/v1/Room/room_init?id=" + roomID)
+	if e, ok := loadRoomID(key); ok {
+		return e.realID, e.err
+	}
+	// 直播间不存在时 data 为 []，无法解析为 RoomInfo
+	b, err := a.get(key)
+	if err != nil {
+		return "", err
+	}
+	res := gjson.ParseBytes(b)
+	rid := res.Get("data.room_id").Int()
+	if code := res.Get("code").Int(); code != 0 || rid == 0 {
+		if !res.Get("code").Exists() {
+			return "", fmt.Errorf("invalid room_init response: %.128s", b)
+		}
+		// 限流等临时错误不能当作直播间不存在
+		if code != 0 && code != codeRoomNotFound {
+			return "", fmt.Errorf("room_init failed: %s (code %d)", res.Get("message").String(), code)
+		}
+		err = fmt.Errorf("%w: %s (code %d)", ErrRoomNotFound, res.Get("message").String(), code)
+		storeRoomID(key, roomIDEntry{err: err}, RoomIDNegativeTTL)
+		return "", err
+	}
+	if res.Get("data.is_locked").Bool() {
+		ttl := RoomIDNegativeTTL
+		err = ErrRoomBanned
+		if till := res.Get("data.lock_till").Int(); till > 0 {
+			until := time.Unix(till, 0)
+			err = fmt.Errorf("%w until %s", ErrRoomBanned, until.Format("2006-01-02 15:04:05"))
+			if d := time.Until(until); d < ttl {
+				ttl = d
+			}
+		}
+		storeRoomID(key, roomIDEntry{err: err}, ttl)
+		return "", err
+	}
+	realID := strconv.FormatInt(rid, 10)
+	storeRoomID(key, roomIDEntry{realID: realID}, RoomIDCacheTTL)
+	return realID, nil
+}
+
+func GetRoomRealID(roomID string) (string, error) {
+	return DefaultClient.GetRoomRealID(roomID)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// LiveRoomSearch 直播间搜索结果
+// api https://api.bilibili.com/x/web-interface/search/type?search_type=live_room response
+type LiveRoomSearch struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Page       int              `json:"page"`
+		PageSize   int              `json:"pagesize"`
+		NumResults int              `json:"numResults"`
+		NumPages   int              `json:"numPages"`
+		Result     []LiveRoomResult `json:"result"`
+	} `json:"data"`
+}
+
+// LiveRoomResult 搜索到的一个直播间，Title 与 Uname 中高亮关键字的标签已去除
+type LiveRoomResult struct {
+	RoomId     int    `json:"roomid"`
+	ShortId    int    `json:"short_id"`
+	Uid        int    `json:"uid"`
+	Uname      string `json:"uname"`
+	Uface      string `json:"uface"`
+	Title      string `json:"title"`
+	Online     int    `json:"online"` // 人气值
+	LiveStatus int    `json:"live_status"`
+	LiveTime   string `json:"live_time"`
+	UserCover  string `json:"user_cover"`
+	Cover      string `json:"cover"`
+	Tags       string `json:"tags"`
+	AreaName   string `json:"cate_name"`
+}
+
+// highlightTag 搜索结果中高亮关键字的标签，如 <em class="keyword">
+var highlightTag = regexp.MustCompile(`</?em[^>]*>`)
+
+func stripHighlight(s string) string {
+	return html.UnescapeString(highlightTag.ReplaceAllString(s, ""))
+}
+
+var (
+	buvidOnce sync.Once
+	buvid     string
+)
+
+// searchBuvid 搜索接口没有 buvid3 Cookie 时会返回 -412，生成一个进程内固定的 buvid3
+func searchBuvid() string {
+	buvidOnce.Do(func() {
+		buvid = NewBuvid()
+	})
+	return buvid
+}
+
+// SearchLiveRoomsPage 按关键字搜索直播间，关键字会匹配标题与主播名，page 从 1 开始
+func (a *Client) SearchLiveRoomsPage(keyword string, page int) (*LiveRoomSearch, error) {
+	q := url.Values{}
+	q.Set("search_type", "live_room")
+	q.Set("keyword", keyword)
+	q.Set("page", fmt.Sprint(page))
+	req, err := http.NewRequest("GET", a.mainURL("/x/web-interface/search/type?"+q.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Cookie", "buvid3="+searchBuvid())
+	req.Header.Set("Referer", "https://search.bilibili.com/")
+	b, err := a.do(req)
+	if err != nil {
+		return nil, err
+	}
+	result := &LiveRoomSearch{}
+	if err = json.Unmarshal(b, result); err != nil {
+		return nil, err
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("search/type: %d %s", result.Code, result.Message)
+	}
+	for i := range result.Data.Result {
+		r := &result.Data.Result[i]
+		r.Title = stripHighlight(r.Title)
+		r.Uname = stripHighlight(r.Uname)
+	}
+	return result, nil
+}
+
+func SearchLiveRoomsPage(keyword string, page int) (*LiveRoomSearch, error) {
+	return DefaultClient.SearchLiveRoomsPage(keyword, page)
+}
+
+// SearchLiveRooms 按关键字搜索直播间，返回第一页的结果，可用于将主播名解析为房间号
+func (a *Client) SearchLiveRooms(keyword string) ([]LiveRoomResult, error) {
+	result, err := a.SearchLiveRoomsPage(keyword, 1)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data.Result, nil
+}
+
+func SearchLiveRooms(keyword string) ([]LiveRoomResult, error) {
+	return DefaultClient.SearchLiveRooms(keyword)
+}
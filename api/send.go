@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"unicode"
 )
 
 type DanmakuRequest struct {
@@ -37,8 +38,7 @@ type BiliVerify struct {
 }
 
 // SendDanmaku https://api.live.bilibili.com/msg/send
-func SendDanmaku(d *DanmakuRequest, v *BiliVerify) (*SendDanmakuResp, error) {
-	client := &http.Client{}
+func (a *Client) SendDanmaku(d *DanmakuRequest, v *BiliVerify) (*SendDanmakuResp, error) {
 	result := &SendDanmakuResp{}
 	form := url.Values{
 		"bubble":     {d.Bubble},
@@ -55,22 +55,27 @@ func SendDanmaku(d *DanmakuRequest, v *BiliVerify) (*SendDanmakuResp, error) {
 	if d.DmType != "" {
 		form.Add("dm_type", d.DmType)
 	}
-	req, err := http.NewRequest("POST", "https://api.live.bilibili.com/msg/send", strings.NewReader(form.Encode()))
+	req, err := http.NewRequest("POST", a.url("/msg/send"), strings.NewReader(form.Encode()))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Cookie", fmt.Sprintf("bili_jct=%s;SESSDATA=%s", v.Csrf, v.SessData))
-	resp, err := client.Do(req)
-	defer resp.Body.Close()
-
-	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+	b, err := a.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, result); err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func SendDefaultDanmaku(roomID string, message string, verify *BiliVerify) (*SendDanmakuResp, error) {
+func SendDanmaku(d *DanmakuRequest, v *BiliVerify) (*SendDanmakuResp, error) {
+	return DefaultClient.SendDanmaku(d, v)
+}
+
+func (a *Client) SendDefaultDanmaku(roomID string, message string, verify *BiliVerify) (*SendDanmakuResp, error) {
 	req := &DanmakuRequest{
 		Msg:      message,
 		RoomID:   roomID,
@@ -80,5 +85,71 @@ func SendDefaultDanmaku(roomID string, message string, verify *BiliVerify) (*Sen
 		Mode:     "1",
 		DmType:   "1",
 	}
-	return SendDanmaku(req, verify)
+	return a.SendDanmaku(req, verify)
+}
+
+func SendDefaultDanmaku(roomID string, message string, verify *BiliVerify) (*SendDanmakuResp, error) {
+	return DefaultClient.SendDefaultDanmaku(roomID, message, verify)
+}
+
+// 单条弹幕的最大字符数
+const (
+	DanmakuMaxLength     = 20 // 普通用户
+	DanmakuMaxLengthLong = 40 // 用户等级达到 UL20 或开通大航海的用户
+)
+
+// SendError 发送弹幕接口返回的错误，如发送过快、被禁言或包含屏蔽词
+type SendError struct {
+	Code    int
+	Message string
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("send danmaku failed: %d %s", e.Code, e.Message)
+}
+
+// Err 接口返回失败时返回 *SendError，否则返回 nil
+func (r *SendDanmakuResp) Err() error {
+	if r.Code == 0 {
+		return nil
+	}
+	msg := r.Message
+	if msg == "" {
+		msg = r.Msg
+	}
+	return &SendError{Code: r.Code, Message: msg}
+}
+
+// SplitDanmaku 将 msg 拆分为每条不超过 limit 个字符的多条弹幕，尽量在空白与标点之后断开
+func SplitDanmaku(msg string, limit int) []string {
+	rs := []rune(strings.TrimSpace(msg))
+	if limit <= 0 || len(rs) <= limit {
+		if len(rs) == 0 {
+			return nil
+		}
+		return []string{string(rs)}
+	}
+	var parts []string
+	for len(rs) > limit {
+		cut := limit
+		// 在后半段寻找最后一个可以断开的位置
+		for i := limit; i > limit/2; i-- {
+			if isBreak(rs[i-1]) {
+				cut = i
+				break
+			}
+		}
+		if p := strings.TrimSpace(string(rs[:cut])); p != "" {
+			parts = append(parts, p)
+		}
+		rs = []rune(strings.TrimSpace(string(rs[cut:])))
+	}
+	if len(rs) > 0 {
+		parts = append(parts, string(rs))
+	}
+	return parts
+}
+
+func isBreak(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r)
 }
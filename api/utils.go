@@ -2,17 +2,73 @@ package api
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
 )
 
-func GetJson(url string, result interface{}) error {
-	resp, err := http.Get(url)
+// do 发出请求并读取响应，所有请求都会经过全局限流器
+func (a *Client) do(req *http.Request) ([]byte, error) {
+	if ua := a.userAgent(); ua != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	if a != nil && a.Buvid != nil {
+		if b := a.Buvid(); b != "" {
+			addCookie(req, "buvid3", b)
+		}
+	}
+	globalLimiter.wait()
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if code := gjson.GetBytes(b, "code").Int(); isRiskControl(resp.StatusCode, code) {
+		globalLimiter.penalize(req.URL.String())
+		return nil, &RiskControlError{URL: req.URL.String(), Status: resp.StatusCode, Code: int(code)}
+	}
+	globalLimiter.success()
+	return b, nil
+}
+
+// addCookie 在请求的 Cookie 中添加 name，已有 name 时不修改
+func addCookie(req *http.Request, name, value string) {
+	cookie := req.Header.Get("Cookie")
+	if cookie == "" {
+		req.Header.Set("Cookie", name+"="+value)
+		return
+	}
+	for _, kv := range strings.Split(cookie, ";") {
+		if strings.HasPrefix(strings.TrimSpace(kv), name+"=") {
+			return
+		}
+	}
+	req.Header.Set("Cookie", cookie+";"+name+"="+value)
+}
+
+// get 发出 GET 请求并读取响应
+func (a *Client) get(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return a.do(req)
+}
+
+func (a *Client) GetJson(url string, result interface{}) error {
+	b, err := a.get(url)
+	if err != nil {
 		return err
 	}
-	return nil
+	return json.Unmarshal(b, result)
+}
+
+func GetJson(url string, result interface{}) error {
+	return DefaultClient.GetJson(url, result)
 }
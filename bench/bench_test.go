@@ -0,0 +1,188 @@
+package bench
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// capturePath 录制的直播间消息，由 record 写入
+const capturePath = "testdata/capture.rec.gz"
+
+var corpora struct {
+	sync.Mutex
+	m map[uint16]*Corpus
+}
+
+// loadCorpus 读取录制的语料，每种压缩方式只读取一次
+func loadCorpus(b *testing.B, protover uint16) *Corpus {
+	corpora.Lock()
+	defer corpora.Unlock()
+	if c, ok := corpora.m[protover]; ok {
+		return c
+	}
+	c, err := LoadCorpus(capturePath, protover, DefaultBatch)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if corpora.m == nil {
+		corpora.m = make(map[uint16]*Corpus)
+	}
+	corpora.m[protover] = c
+	return c
+}
+
+func BenchmarkDecode(b *testing.B) {
+	b.Run("Brotli", func(b *testing.B) { benchmarkDecode(b, loadCorpus(b, packet.Brotli)) })
+	b.Run("Zlib", func(b *testing.B) { benchmarkDecode(b, loadCorpus(b, packet.Zlib)) })
+}
+
+func benchmarkDecode(b *testing.B, c *Corpus) {
+	b.ReportAllocs()
+	b.SetBytes(c.Bytes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, f := range c.Frames {
+			if _, err := packet.DecodePacket(f).Decode(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(c.Messages), "msgs/op")
+}
+
+func BenchmarkParseDanmaku(b *testing.B) {
+	bodies := danmakuBodies(loadCorpus(b, packet.Brotli))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, body := range bodies {
+			d := new(message.Danmaku)
+			d.Parse(body)
+		}
+	}
+	b.ReportMetric(float64(len(bodies)), "msgs/op")
+}
+
+func BenchmarkDispatchAsync(b *testing.B) {
+	benchmarkDispatch(b, client.DispatchAsync, nil)
+}
+
+// BenchmarkDispatchAsyncLegacy 开启了 EnableLegacyDispatch，每个事件一个 goroutine
+func BenchmarkDispatchAsyncLegacy(b *testing.B) {
+	benchmarkDispatch(b, client.DispatchAsync, (*client.Client).EnableLegacyDispatch)
+}
+
+func BenchmarkDispatchOrdered(b *testing.B) {
+	benchmarkDispatch(b, client.DispatchOrdered, nil)
+}
+
+func BenchmarkDispatchSharded(b *testing.B) {
+	benchmarkDispatch(b, client.DispatchSharded, nil)
+}
+
+func BenchmarkDispatchSequenced(b *testing.B) {
+	benchmarkDispatch(b, client.DispatchSequenced, nil)
+}
+
+// BenchmarkDispatchOrderedPooled 开启了事件对象池
+func BenchmarkDispatchOrderedPooled(b *testing.B) {
+	benchmarkDispatch(b, client.DispatchOrdered, (*client.Client).EnablePooling)
+}
+
+// benchmarkDispatch 解包并通过 client 分发全部消息
+//
+// 除每条消息的平均耗时外，会报告 Handle 开始到弹幕处理器执行的平均延迟 latency-ns/msg
+func benchmarkDispatch(b *testing.B, mode int, setup func(*client.Client)) {
+	c := loadCorpus(b, packet.Brotli)
+	cl := client.NewClient("0", "0", "", "", "")
+	cl.SetDispatchMode(mode)
+	if setup != nil {
+		setup(cl)
+	}
+	defer cl.Stop()
+	var wg sync.WaitGroup
+	var latency, count int64
+	cl.OnDanmaku(func(d *message.Danmaku) {
+		atomic.AddInt64(&latency, int64(time.Since(d.ReceivedAt)))
+		atomic.AddInt64(&count, 1)
+		wg.Done()
+	})
+	b.ReportAllocs()
+	b.SetBytes(c.Bytes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, f := range c.Frames {
+			pkts, err := packet.DecodePacket(f).Decode()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, p := range pkts {
+				if isDanmaku(p.Body) {
+					wg.Add(1)
+				}
+				p.ReceivedAt = time.Now()
+				cl.Handle(p)
+			}
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(c.Messages), "msgs/op")
+	if count > 0 {
+		b.ReportMetric(float64(latency)/float64(count), "latency-ns/msg")
+	}
+}
+
+// BenchmarkDispatchGifts 只注册礼物处理器时分发全部消息，其余事件不需要解析
+func BenchmarkDispatchGifts(b *testing.B) {
+	c := loadCorpus(b, packet.Brotli)
+	cl := client.NewClient("0", "0", "", "", "")
+	cl.SetDispatchMode(client.DispatchOrdered)
+	defer cl.Stop()
+	var wg sync.WaitGroup
+	cl.OnGift(func(*message.Gift) { wg.Done() })
+	b.ReportAllocs()
+	b.SetBytes(c.Bytes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, f := range c.Frames {
+			pkts, err := packet.DecodePacket(f).Decode()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, p := range pkts {
+				if bytes.HasPrefix(p.Body, []byte(`{"cmd":"SEND_GIFT"`)) {
+					wg.Add(1)
+				}
+				cl.Handle(p)
+			}
+		}
+		wg.Wait()
+	}
+	b.ReportMetric(float64(c.Messages), "msgs/op")
+}
+
+func isDanmaku(body []byte) bool {
+	return len(body) > 17 && string(body[:17]) == `{"cmd":"DANMU_MSG`
+}
+
+// danmakuBodies 解包后全部弹幕的报文
+func danmakuBodies(c *Corpus) [][]byte {
+	var bodies [][]byte
+	for _, f := range c.Frames {
+		pkts, _ := packet.DecodePacket(f).Decode()
+		for _, p := range pkts {
+			if isDanmaku(p.Body) {
+				bodies = append(bodies, p.Body)
+			}
+		}
+	}
+	return bodies
+}
@@ -0,0 +1,118 @@
+// Package bench 提供解包、解析与事件分发的基准测试，用于发现编解码与分发器的性能回退
+//
+// 基准测试使用 testdata 中录制的语料，通过 go test -run '^$' -bench . -benchmem ./bench 运行，
+// 输出可以交给 example/bench 与保存的基线比较
+package bench
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultBatch 每个压缩帧中默认包含的消息数量，与高峰时弹幕服务器下发的帧相近
+const DefaultBatch = 20
+
+// Corpus 一组 websocket 二进制帧，即弹幕服务器推送的原始数据
+type Corpus struct {
+	Frames   [][]byte
+	Messages int   // 帧中 Notification 消息的总数
+	Bytes    int64 // 帧的总字节数
+	Danmaku  int   // 其中 DANMU_MSG 的数量
+}
+
+// LoadCorpus 读取 record 录制的文件作为语料，按 batch 条消息一帧重新压缩
+func LoadCorpus(path string, protover uint16, batch int) (*Corpus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := record.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var msgs [][]byte
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, e.Data)
+	}
+	return Build(msgs, protover, batch)
+}
+
+// Build 将报文打包为帧，压缩时每 batch 条消息放入同一帧
+func Build(msgs [][]byte, protover uint16, batch int) (*Corpus, error) {
+	if batch <= 0 {
+		batch = DefaultBatch
+	}
+	c := &Corpus{Messages: len(msgs)}
+	for _, m := range msgs {
+		if bytes.HasPrefix(m, []byte(`{"cmd":"DANMU_MSG"`)) {
+			c.Danmaku++
+		}
+	}
+	if protover == packet.Plain {
+		for _, m := range msgs {
+			pkt := packet.NewPlainPacket(packet.Notification, m)
+			c.add(pkt.Build())
+		}
+		return c, nil
+	}
+	for i := 0; i < len(msgs); i += batch {
+		end := i + batch
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+		var raw []byte
+		for _, m := range msgs[i:end] {
+			pkt := packet.NewPlainPacket(packet.Notification, m)
+			raw = append(raw, pkt.Build()...)
+		}
+		body, err := compress(raw, protover)
+		if err != nil {
+			return nil, err
+		}
+		pkt := packet.NewPacket(protover, packet.Notification, body)
+		c.add(pkt.Build())
+	}
+	return c, nil
+}
+
+func (c *Corpus) add(frame []byte) {
+	c.Frames = append(c.Frames, frame)
+	c.Bytes += int64(len(frame))
+}
+
+// compress 按协议版本压缩拼接后的包
+func compress(b []byte, protover uint16) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch protover {
+	case packet.Zlib:
+		w = zlib.NewWriter(&buf)
+	case packet.Brotli:
+		w = brotli.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("unknown protocolVersion %d", protover)
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
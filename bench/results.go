@@ -0,0 +1,140 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Result 一项基准测试的结果，按每条消息计算
+type Result struct {
+	Name         string  `json:"name"`
+	N            int     `json:"n"`
+	NsPerMsg     float64 `json:"ns_per_msg"`
+	AllocsPerMsg float64 `json:"allocs_per_msg"`
+	BytesPerMsg  float64 `json:"bytes_per_msg"` // 每条消息分配的内存字节数
+	MBPerSec     float64 `json:"mb_per_sec"`    // 原始帧的吞吐量
+	LatencyNs    float64 `json:"latency_ns,omitempty"`
+}
+
+// String 格式化为一行
+func (r Result) String() string {
+	s := fmt.Sprintf("%-24s %10.0f ns/msg %8.1f allocs/msg %10.0f B/msg", r.Name, r.NsPerMsg, r.AllocsPerMsg, r.BytesPerMsg)
+	if r.MBPerSec > 0 {
+		s += fmt.Sprintf(" %8.2f MB/s", r.MBPerSec)
+	}
+	if r.LatencyNs > 0 {
+		s += fmt.Sprintf(" %10.0f latency-ns/msg", r.LatencyNs)
+	}
+	return s
+}
+
+// ParseResults 解析 go test -bench -benchmem 的输出，忽略基准测试结果以外的行
+//
+// 名称去掉 Benchmark 前缀与 GOMAXPROCS 后缀，如 BenchmarkDecode-8 为 Decode，
+// 各项指标按基准测试报告的 msgs/op 换算为每条消息的值
+func ParseResults(r io.Reader) ([]Result, error) {
+	var results []Result
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		name := strings.TrimPrefix(fields[0], "Benchmark")
+		if i := strings.LastIndexByte(name, '-'); i > 0 {
+			if _, err := strconv.Atoi(name[i+1:]); err == nil {
+				name = name[:i]
+			}
+		}
+		r := Result{Name: name, N: n}
+		msgs := 1.0
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", fields[0], err)
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				r.NsPerMsg = v
+			case "allocs/op":
+				r.AllocsPerMsg = v
+			case "B/op":
+				r.BytesPerMsg = v
+			case "MB/s":
+				r.MBPerSec = v
+			case "latency-ns/msg":
+				r.LatencyNs = v
+			case "msgs/op":
+				msgs = v
+			}
+		}
+		if msgs > 0 {
+			r.NsPerMsg /= msgs
+			r.AllocsPerMsg /= msgs
+			r.BytesPerMsg /= msgs
+		}
+		results = append(results, r)
+	}
+	return results, sc.Err()
+}
+
+// Regression 相比基线变慢或分配更多的一项结果
+type Regression struct {
+	Name     string
+	Metric   string
+	Baseline float64
+	Current  float64
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s %s: %.1f -> %.1f (%+.1f%%)", r.Name, r.Metric, r.Baseline, r.Current, (r.Current/r.Baseline-1)*100)
+}
+
+// Compare 与基线比较，耗时或分配次数超过基线 threshold 比例（如 0.1 表示 10%）的结果视为回退
+func Compare(baseline, current []Result, threshold float64) []Regression {
+	base := make(map[string]Result, len(baseline))
+	for _, r := range baseline {
+		base[r.Name] = r
+	}
+	var regs []Regression
+	for _, r := range current {
+		b, ok := base[r.Name]
+		if !ok {
+			continue
+		}
+		if b.NsPerMsg > 0 && r.NsPerMsg > b.NsPerMsg*(1+threshold) {
+			regs = append(regs, Regression{Name: r.Name, Metric: "ns/msg", Baseline: b.NsPerMsg, Current: r.NsPerMsg})
+		}
+		// 分配次数基本是确定的，每条消息多出半次以上即视为回退
+		if r.AllocsPerMsg > b.AllocsPerMsg+0.5 {
+			regs = append(regs, Regression{Name: r.Name, Metric: "allocs/msg", Baseline: b.AllocsPerMsg, Current: r.AllocsPerMsg})
+		}
+	}
+	return regs
+}
+
+// WriteResults 将结果写为 JSON，可作为之后比较的基线
+func WriteResults(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// LoadResults 读取 WriteResults 保存的结果
+func LoadResults(path string) ([]Result, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	return results, json.Unmarshal(b, &results)
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/api"
+	"github.com/RemKeeper/blivedm-go/message"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// EnableBackfill 启动时通过历史弹幕接口获取房间最近的弹幕，
+// 在连接弹幕服务器之前交给弹幕处理器，这些弹幕的 Backfilled 为 true
+func (c *Client) EnableBackfill() {
+	c.backfill = true
+}
+
+// backfillHistory 获取并分发历史弹幕，失败时只记录日志
+func (c *Client) backfillHistory() {
+	history, err := c.apiClient.GetDanmakuHistory(c.roomID)
+	if err != nil {
+		log.Warn("fetch danmaku history failed: ", err)
+		c.reportError(&APIError{API: "gethistory", Err: err})
+		return
+	}
+	for i := range history.Data.Room {
+		d := historyToDanmaku(&history.Data.Room[i])
+		d.RoomID, _ = strconv.Atoi(c.roomID)
+		d.ReceivedAt = time.Now()
+		for _, fn := range c.eventHandlers.danmakuHandlers {
+			c.cover(func() { fn(d) })
+		}
+	}
+}
+
+// historyToDanmaku 将历史弹幕转换为 message.Danmaku
+func historyToDanmaku(h *api.HistoryDanmaku) *message.Danmaku {
+	medal := gjson.ParseBytes(h.Medal)
+	d := &message.Danmaku{
+		Content:    h.Text,
+		Type:       h.DmType,
+		Timestamp:  h.CheckInfo.Ts * 1000,
+		Backfilled: true,
+		Sender: &message.User{
+			Uid:        h.Uid,
+			Uname:      h.Nickname,
+			Admin:      h.IsAdmin == 1,
+			Urank:      h.Rank,
+			GuardLevel: h.GuardLevel,
+			Medal: &message.Medal{
+				Level:    int(medal.Get("0").Int()),
+				Name:     medal.Get("1").String(),
+				UpName:   medal.Get("2").String(),
+				UpRoomId: int(medal.Get("3").Int()),
+				Color:    int(medal.Get("4").Int()),
+				UpUid:    int(medal.Get("12").Int()),
+			},
+		},
+		Extra: &message.Extra{Content: h.Text, DmType: h.DmType, EmoticonUnique: h.Emoticon.EmoticonUnique},
+		Emoticon: &message.Emoticon{
+			EmoticonUnique: h.Emoticon.EmoticonUnique,
+			Url:            h.Emoticon.Url,
+			Width:          h.Emoticon.Width,
+			Height:         h.Emoticon.Height,
+		},
+	}
+	return d
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultAddConcurrency AddRooms 默认的并发数
+const defaultAddConcurrency = 5
+
+type addRoomsConfig struct {
+	concurrency int
+	stagger     time.Duration
+	progress    func(done, total int, roomID string, err error)
+}
+
+// AddRoomsOption AddRooms 的选项
+type AddRoomsOption func(*addRoomsConfig)
+
+// WithConcurrency 同时进行初始化与握手的房间数量
+func WithConcurrency(n int) AddRoomsOption {
+	return func(c *addRoomsConfig) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithStagger 每个 worker 启动相邻两个房间之间的间隔
+func WithStagger(d time.Duration) AddRoomsOption {
+	return func(c *addRoomsConfig) {
+		c.stagger = d
+	}
+}
+
+// WithProgress 每个房间添加完成(成功或失败)后调用，done 为已完成的数量
+//
+// 多个 worker 的调用是串行的，done 依次递增，f 执行期间其他 worker 的结果需要等待
+func WithProgress(f func(done, total int, roomID string, err error)) AddRoomsOption {
+	return func(c *addRoomsConfig) {
+		c.progress = f
+	}
+}
+
+// AddRooms 以有限的并发批量添加房间，避免同时发起大量接口请求与握手
+//
+// 返回添加失败的房间与对应的错误，全部成功时返回 nil
+func (m *RoomManager) AddRooms(roomIDs []string, opts ...AddRoomsOption) map[string]error {
+	cfg := &addRoomsConfig{concurrency: defaultAddConcurrency}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		done   int
+		failed map[string]error
+	)
+	ids := make(chan string)
+	for i := 0; i < cfg.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			first := true
+			for id := range ids {
+				if !first && cfg.stagger > 0 {
+					time.Sleep(cfg.stagger)
+				}
+				first = false
+				_, err := m.AddRoom(id)
+				mu.Lock()
+				done++
+				if err != nil {
+					if failed == nil {
+						failed = make(map[string]error)
+					}
+					failed[id] = err
+				}
+				if cfg.progress != nil {
+					cfg.progress(done, len(roomIDs), id, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range roomIDs {
+		ids <- id
+	}
+	close(ids)
+	wg.Wait()
+	return failed
+}
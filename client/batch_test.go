@@ -0,0 +1,32 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddRoomsProgressSerialized(t *testing.T) {
+	quietLogs(t)
+	api := httptest.NewServer(http.NotFoundHandler())
+	defer api.Close()
+	m := NewRoomManager("0", "", "", "")
+	defer m.Stop()
+	ids := make([]string, 20)
+	for i := range ids {
+		ids[i] = string(rune('a' + i))
+		m.SetRoomOptions(ids[i], &RoomOptions{APIBaseURL: api.URL})
+	}
+	var calls, last int
+	failed := m.AddRooms(ids, WithConcurrency(8), WithProgress(func(done, total int, roomID string, err error) {
+		// 并发调用时 -race 会报告对 calls 的竞争
+		calls++
+		if done != last+1 {
+			t.Errorf("done = %d after %d, want increasing by one", done, last)
+		}
+		last = done
+	}))
+	if calls != len(ids) || len(failed) != len(ids) {
+		t.Errorf("%d progress calls and %d failures, want %d", calls, len(failed), len(ids))
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// Capabilities 与弹幕服务器协商得到的协议特性
+type Capabilities struct {
+	Protover      int    // 进房时请求的协议版本，见 SetProtover
+	Compression   string // 实际收到的压缩方式 zlib 或 brotli，还没有收到压缩包时为空
+	Transport     string // websocket、tcp，使用其他自定义 Dialer 时为 custom
+	Host          string // 当前连接的弹幕服务器
+	Authenticated bool   // 是否以登录用户进房，即 uid 不为 0 且带有 getDanmuInfo 返回的 token
+	// MaskedUsers 弹幕中的用户信息是否被隐藏，匿名连接时 uid 为 0、用户名被打码
+	//
+	// 收到弹幕后以实际的弹幕为准，之前根据 Authenticated 推测
+	MaskedUsers bool
+}
+
+// capabilityState 运行中观察到的协议特性
+type capabilityState struct {
+	protover int
+	observed int32 // 0 未收到弹幕，1 用户信息完整，2 用户信息被隐藏
+}
+
+// SetProtover 设置进房时请求的协议版本，packet.ProtoverZlib（默认）或 packet.ProtoverBrotli
+//
+// brotli 压缩率更高，可以减少带宽占用，需要在 Start 之前调用
+func (c *Client) SetProtover(v int) {
+	c.caps.protover = v
+}
+
+// protover 进房时使用的协议版本
+func (c *Client) protover() int {
+	if c.caps.protover == 0 {
+		return packet.ProtoverZlib
+	}
+	return c.caps.protover
+}
+
+// Capabilities 获取当前连接的协议特性
+func (c *Client) Capabilities() Capabilities {
+	c.credMu.Lock()
+	uid, _ := strconv.Atoi(c.enterUID)
+	c.credMu.Unlock()
+	caps := Capabilities{
+		Protover:      c.protover(),
+		Host:          c.host,
+		Authenticated: uid != 0 && c.token != "",
+	}
+	switch {
+	case atomic.LoadUint64(&c.stats.brotliPackets) > 0:
+		caps.Compression = "brotli"
+	case atomic.LoadUint64(&c.stats.zlibPackets) > 0:
+		caps.Compression = "zlib"
+	}
+	switch c.customDialer.(type) {
+	case nil:
+		caps.Transport = "websocket"
+	case *TCPDialer:
+		caps.Transport = "tcp"
+	default:
+		caps.Transport = "custom"
+	}
+	switch atomic.LoadInt32(&c.caps.observed) {
+	case 0:
+		caps.MaskedUsers = !caps.Authenticated
+	case 2:
+		caps.MaskedUsers = true
+	}
+	return caps
+}
+
+// observeSender 记录弹幕发送者的 uid 是否被隐藏
+func (c *Client) observeSender(uid int) {
+	v := int32(1)
+	if uid == 0 {
+		v = 2
+	}
+	atomic.StoreInt32(&c.caps.observed, v)
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// replayEvent 可以回放的消息
+type replayEvent interface {
+	parser
+	metaHolder
+}
+
+// lateHandler 通过 CatchUp 注册的处理器
+type lateHandler struct {
+	cmds     []string
+	newEvent func() replayEvent
+	call     func(v replayEvent)
+}
+
+// pendingEvent 回放期间收到的实时事件
+type pendingEvent struct {
+	cmd string
+	p   packet.Packet
+}
+
+func (h *lateHandler) match(cmd string) bool {
+	for _, c := range h.cmds {
+		if c == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+// replayBuffer 最近事件的环形缓冲与 CatchUp 注册的处理器
+type replayBuffer struct {
+	active   int32
+	mu       sync.Mutex
+	size     int
+	ring     []packet.Packet
+	cmds     []string
+	next     int
+	handlers []*lateHandler
+	// catching 正在回放的处理器与回放期间缓存的实时事件
+	catching map[*lateHandler][]pendingEvent
+}
+
+// EnableReplayBuffer 保留最近 n 条事件，通过 CatchUp 注册的处理器会先收到这些事件再接收实时事件，需要在 Start 之前调用
+//
+// 用于 Start 之后才连接的界面组件立即显示最近的内容
+func (c *Client) EnableReplayBuffer(n int) {
+	if n <= 0 {
+		return
+	}
+	r := &c.replay
+	r.mu.Lock()
+	r.size = n
+	r.mu.Unlock()
+	atomic.StoreInt32(&r.active, 1)
+}
+
+// CatchUp 注册处理器，先将回放缓冲中的对应事件交给处理器，再接收实时事件，回放的事件 Replayed 为 true
+//
+// handler 为 OnDanmaku、OnGift 等方法接受的处理器，可以在 Start 之后调用，也可以在处理器中调用，
+// 回放在当前 goroutine 中执行，期间新的事件先缓存，回放结束后再交给处理器，不会重复也不会遗漏
+func (c *Client) CatchUp(handler interface{}) error {
+	h := newLateHandler(handler)
+	if h == nil {
+		return fmt.Errorf("unsupported handler type %T", handler)
+	}
+	r := &c.replay
+	r.mu.Lock()
+	n := len(r.ring)
+	var history []packet.Packet
+	for i := 0; i < n; i++ {
+		j := (r.next + i) % n
+		if h.match(r.cmds[j]) {
+			history = append(history, r.ring[j])
+		}
+	}
+	if r.catching == nil {
+		r.catching = make(map[*lateHandler][]pendingEvent)
+	}
+	r.catching[h] = nil
+	r.handlers = append(r.handlers, h)
+	atomic.StoreInt32(&r.active, 1)
+	r.mu.Unlock()
+
+	for _, p := range history {
+		v := h.newEvent()
+		if v.Parse(p.Body) != nil {
+			continue
+		}
+		c.fillMeta(p, v)
+		v.EventMeta().Replayed = true
+		c.cover(func() { h.call(v) })
+	}
+	// 交付回放期间缓存的事件，直到没有新的缓存才转为直接接收实时事件
+	for {
+		r.mu.Lock()
+		pending := r.catching[h]
+		if len(pending) == 0 {
+			delete(r.catching, h)
+			r.mu.Unlock()
+			return nil
+		}
+		r.catching[h] = nil
+		r.mu.Unlock()
+		for _, e := range pending {
+			c.deliverLate(h, e.cmd, e.p)
+		}
+	}
+}
+
+// recordReplay 将事件写入回放缓冲并交给 CatchUp 注册的处理器，正在回放的处理器先缓存该事件
+func (c *Client) recordReplay(cmd string, p packet.Packet) {
+	r := &c.replay
+	if atomic.LoadInt32(&r.active) == 0 {
+		return
+	}
+	var live []*lateHandler
+	r.mu.Lock()
+	copied := false
+	if r.size > 0 {
+		p.Body = append([]byte(nil), p.Body...)
+		copied = true
+		if len(r.ring) < r.size {
+			r.ring = append(r.ring, p)
+			r.cmds = append(r.cmds, cmd)
+		} else {
+			r.ring[r.next] = p
+			r.cmds[r.next] = cmd
+			r.next = (r.next + 1) % r.size
+		}
+	}
+	for _, h := range r.handlers {
+		if !h.match(cmd) {
+			continue
+		}
+		if pending, ok := r.catching[h]; ok {
+			if !copied {
+				p.Body = append([]byte(nil), p.Body...)
+				copied = true
+			}
+			r.catching[h] = append(pending, pendingEvent{cmd, p})
+			continue
+		}
+		live = append(live, h)
+	}
+	r.mu.Unlock()
+	for _, h := range live {
+		c.deliverLate(h, cmd, p)
+	}
+}
+
+// deliverLate 将实时事件交给 CatchUp 注册的处理器
+func (c *Client) deliverLate(h *lateHandler, cmd string, p packet.Packet) {
+	c.dispatch(cmd, p, nil, func() {
+		v := h.newEvent()
+		if c.parse(cmd, p, v) {
+			h.call(v)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+func danmakuPacket(content string) packet.Packet {
+	body := fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,1697371200077,1697371200,0,"",0,0,0,"",0,"{}","{}",{"extra":"{}"}],%q,[1,"u"],[],[],[],0,0]}`, content)
+	return packet.NewPlainPacket(packet.Notification, []byte(body))
+}
+
+func TestCatchUpFromHandler(t *testing.T) {
+	quietLogs(t)
+	c := NewClient("8792912", "0", "", "", "")
+	defer c.Stop()
+	c.EnableReplayBuffer(10)
+	for _, s := range []string{"1", "2", "3"} {
+		c.Handle(danmakuPacket(s))
+	}
+	events := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.CatchUp(func(d *message.Danmaku) {
+			events <- fmt.Sprintf("%s:%v", d.Content, d.Replayed)
+			if d.Content == "1" {
+				// 回放期间收到的实时事件与再次调用 CatchUp 都不能阻塞
+				c.Handle(danmakuPacket("4"))
+				if err := c.CatchUp(func(*message.Gift) {}); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("CatchUp deadlocked")
+	}
+	c.Handle(danmakuPacket("5"))
+	var got []string
+	for len(got) < 5 {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %v, want 5 events", got)
+		}
+	}
+	want := []string{"1:true", "2:true", "3:true", "4:false", "5:false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -2,10 +2,12 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"net/http"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/RemKeeper/blivedm-go/api"
@@ -15,20 +17,59 @@ import (
 )
 
 type Client struct {
-	conn                *websocket.Conn
+	conn                Conn
+	connMu              sync.Mutex
+	customDialer        Dialer
 	roomID              string
 	tempID              string
+	credMu              sync.Mutex
 	enterUID            string
 	buvid               string
 	userAgent           string
+	userAgentFunc       func() string
+	identity            identity
 	referer             string
 	token               string
 	host                string
 	hostList            []string
+	hostStart           int
+	hostFilter          hostFilter
+	tlsConfig           *tls.Config
+	backfill            bool
+	errors              chan error
+	parseMode           int
+	pooling             bool
+	caps                capabilityState
+	dispatcher          dispatcher
+	batcher             danmakuBatcher
+	plugins             pluginState
+	replay              replayBuffer
+	risk                riskState
+	apiClient           *api.Client
+	onRoomInfo          func(*api.LiveRoomInfo)
+	resolve             resolveState
 	eventHandlers       *eventHandlers
 	customEventHandlers *customEventHandlers
+	routes              routeState
+	filters             []EventFilter
+	subscription        subscription
+	reconnect           ReconnectPolicy
+	packetHandlers      []func(packet.Packet)
+	stats               *stats
+	skew                skewState
+	state               roomStateTracker
+	live                liveCheck
+	pause               pauseState
+	memory              memoryGuard
+	logs                logSampler
+	lifecycle           lifecycle
+	ctx                 context.Context
 	cancel              context.CancelFunc
 	done                <-chan struct{}
+	loops               sync.WaitGroup
+	draining            int32
+	drops               dropCounter
+	sequencer           sequencer
 }
 
 // NewClient 创建一个新的弹幕 client
@@ -42,6 +83,10 @@ func NewClient(roomID string, enterUID string, buvid string, userAgent string, r
 		referer:             referer,
 		eventHandlers:       &eventHandlers{},
 		customEventHandlers: &customEventHandlers{},
+		stats:               &stats{},
+		errors:              make(chan error, errorsBufferSize),
+		apiClient:           api.DefaultClient,
+		ctx:                 ctx,
 		done:                ctx.Done(),
 		cancel:              cancel,
 	}
@@ -49,107 +94,282 @@ func NewClient(roomID string, enterUID string, buvid string, userAgent string, r
 
 // init 初始化 获取真实 roomID 和 弹幕服务器 host
 func (c *Client) init() error {
-	rid, _ := strconv.Atoi(c.tempID)
-	// 处理 shortID
-	if rid <= 1000 && c.roomID == "" {
-		realID, err := api.GetRoomRealID(c.tempID)
-		if err != nil {
-			return err
-		}
-		c.roomID = realID
-	} else {
-		c.roomID = c.tempID
+	c.bindAPISession()
+	if _, err := c.realRoomID(); err != nil {
+		return err
 	}
 	if c.host == "" {
-		info, err := api.GetDanmuInfo(c.roomID)
+		info, err := c.apiClient.GetDanmuInfo(c.roomID)
 		if err != nil {
+			c.reportError(&APIError{API: "getDanmuInfo", Err: err})
+			c.checkAPIRisk(err)
 			c.hostList = []string{"broadcastlv.chat.bilibili.com"}
 		} else {
 			for _, h := range info.Data.HostList {
 				c.hostList = append(c.hostList, h.Host)
 			}
+			c.token = info.Data.Token
+		}
+		hosts, err := c.hostFilter.filterHosts(c.hostList)
+		if err != nil {
+			return err
 		}
-		c.token = info.Data.Token
+		c.hostList = hosts
+	} else if len(c.hostList) == 0 {
+		// SetHost 指定了 host 时不获取服务器列表，只连接该 host
+		c.hostList = []string{c.host}
 	}
 	return nil
 }
 
-func (c *Client) getHeader() http.Header {
-	if c.userAgent == "" && c.referer == "" {
-		return nil
+// realRoomID 获取真实房间号，短号通过接口转换
+//
+// 直播间不存在或被封禁时返回 ErrRoomNotFound 或 ErrRoomBanned，
+// 长号调用接口失败时不影响连接，直接使用长号
+func (c *Client) realRoomID() (string, error) {
+	if c.roomID != "" {
+		return c.roomID, nil
 	}
+	realID, err := c.apiClient.GetRoomRealID(c.tempID)
+	if err != nil {
+		c.reportError(&APIError{API: "room_init", Err: err})
+		c.checkAPIRisk(err)
+		rid, _ := strconv.Atoi(c.tempID)
+		// 处理 shortID
+		if rid <= 1000 || errors.Is(err, ErrRoomNotFound) || errors.Is(err, ErrRoomBanned) {
+			return "", err
+		}
+		realID = c.tempID
+	}
+	c.roomID = realID
+	return c.roomID, nil
+}
 
-	header := http.Header{}
-
-	if c.userAgent != "" {
-		header.Set("User-Agent", c.userAgent)
+// dialer 根据 client 的配置构造 websocket.Dialer
+func (c *Client) dialer() *websocket.Dialer {
+	d := *websocket.DefaultDialer
+	if c.tlsConfig != nil {
+		d.TLSClientConfig = c.tlsConfig.Clone()
 	}
-	if c.referer != "" {
-		header.Set("Referer", c.referer)
+	if c.customResolve() {
+		d.NetDialContext = c.dialContext
 	}
-	return header
+	return &d
 }
 
 func (c *Client) connect() error {
 	retryCount := 0
+	// 随着重连会自动切换弹幕服务器，从上一次连接成功的服务器开始
+	index := c.hostStart
 retry:
-	// 随着重连会自动切换弹幕服务器
-	c.host = c.hostList[retryCount%len(c.hostList)]
+	if c.stopped() {
+		return errStopped
+	}
+	c.rotateSession()
+	if err := c.waitRisk(); err != nil {
+		return err
+	}
+	index %= len(c.hostList)
+	c.host = c.hostList[index]
 	retryCount++
 	header := c.getHeader()
-	conn, res, err := websocket.DefaultDialer.Dial(fmt.Sprintf("wss://%s/sub", c.host), header)
+	conn, err := c.getDialer().DialContext(c.ctx, fmt.Sprintf("wss://%s/sub", c.host), header)
 	if err != nil {
-		log.Errorf("connect dial failed, retry %d times", retryCount)
-		time.Sleep(2 * time.Second)
+		c.logs.logf(log.ErrorLevel, "connect dial failed", "connect dial failed, retry %d times", retryCount)
+		c.reportError(&ReconnectError{Host: c.host, Attempt: retryCount, Err: err})
+		index++
+		// 被风控拦截时在下一次连接前等待退避结束
+		if c.checkHandshakeRisk(err) {
+			if c.reconnect.exhausted(retryCount) {
+				return ErrReconnectExhausted
+			}
+			goto retry
+		}
+		if err = c.waitRetry(retryCount); err != nil {
+			return err
+		}
 		goto retry
 	}
-	c.conn = conn
-	res.Body.Close()
+	c.setConn(conn)
+	// 连接建立期间 client 已被停止，Stop 关闭的是之前的连接
+	if c.stopped() {
+		c.setConn(nil)
+		return errStopped
+	}
 	if err = c.sendEnterPacket(); err != nil {
-		log.Errorf("failed to send enter packet, retry %d times", retryCount)
+		c.logs.logf(log.ErrorLevel, "send enter packet failed", "failed to send enter packet, retry %d times", retryCount)
+		c.reportError(&ReconnectError{Host: c.host, Attempt: retryCount, Err: err})
+		if err = c.waitRetry(retryCount); err != nil {
+			return err
+		}
+		index++
 		goto retry
 	}
-	if _, _, err = c.conn.ReadMessage(); fmt.Sprintf("%+v", err) == "websocket: close 1006 (abnormal closure): unexpected EOF" {
-		log.Info("request server busy, retrying other server")
+	_, data, err := conn.ReadMessage()
+	if c.stopped() {
+		return errStopped
+	}
+	var pkts []packet.Packet
+	if err == nil {
+		pkts, err = c.checkEnterReply(data)
+	}
+	if err != nil {
+		class := classifyClose(err, true)
+		c.reportError(&ReconnectError{Host: c.host, Attempt: retryCount, Err: err, CloseCode: CloseCode(err), Class: class})
+		switch c.reconnect.closeAction(err, class) {
+		case ActionGiveUp:
+			return fmt.Errorf("%w: %v", ErrReconnectAborted, err)
+		case ActionRotate:
+			index++
+		case ActionRefreshToken:
+			c.logs.logf(log.WarnLevel, "enter room rejected", "enter room rejected by %s: %v, refreshing token", c.host, err)
+			c.refreshToken()
+		}
+		// 服务器繁忙时不等待，直接重试
+		if class == CloseServerBusy {
+			c.logs.logf(log.InfoLevel, "server busy", "request server busy, retrying")
+			if c.reconnect.exhausted(retryCount) {
+				return ErrReconnectExhausted
+			}
+			goto retry
+		}
+		if err = c.waitRetry(retryCount); err != nil {
+			return err
+		}
 		goto retry
 	}
+	c.hostStart = index
+	c.riskSucceeded()
+	c.connected(retryCount)
+	for _, pkt := range pkts {
+		c.receive(pkt)
+	}
 	return nil
 }
 
+// stopped client 是否已被停止
+func (c *Client) stopped() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
 func (c *Client) wsLoop() {
+	session := atomic.LoadInt32(&c.live.session)
 	for {
 		select {
 		case <-c.done:
 			log.Debug("current client closed")
 			return
 		default:
-			msgType, data, err := c.conn.ReadMessage()
+			conn := c.getConn()
+			if conn == nil {
+				return
+			}
+			msgType, data, err := conn.ReadMessage()
 			if err != nil {
-				log.Info("reconnect")
-				time.Sleep(time.Duration(3) * time.Millisecond)
-				_ = c.connect()
+				if c.sessionEnded(session) || c.stopped() {
+					return
+				}
+				c.logs.logf(log.InfoLevel, "reconnect", "reconnect")
+				class := classifyClose(err, false)
+				c.disconnected(err, class)
+				c.reportError(&ReconnectError{Host: c.host, Err: err, CloseCode: CloseCode(err), Class: class})
+				if action := c.reconnect.closeAction(err, class); action == ActionGiveUp {
+					err = fmt.Errorf("%w: %v", ErrReconnectAborted, err)
+				} else {
+					if action == ActionRotate {
+						c.hostStart++
+					} else if action == ActionRefreshToken {
+						c.refreshToken()
+					}
+					time.Sleep(time.Duration(3) * time.Millisecond)
+					err = c.connect()
+				}
+				if err != nil {
+					if err != errStopped {
+						log.Error("give up reconnecting: ", err)
+						c.reportError(&ReconnectError{Host: c.host, Err: err})
+						// 放弃重连后停止心跳与分发 worker，避免 goroutine 泄漏
+						c.Stop()
+					}
+					return
+				}
 				continue
 			}
 			if msgType != websocket.BinaryMessage {
-				log.Error("packet not binary")
+				c.logs.logf(log.ErrorLevel, "packet not binary", "packet not binary")
 				continue
 			}
-			for _, pkt := range packet.DecodePacket(data).Parse() {
-				go c.Handle(pkt)
+			frame := packet.DecodePacket(data)
+			pkts, err := frame.Decode()
+			if err != nil {
+				c.logs.logf(log.ErrorLevel, "decode frame failed", "decode frame failed: %v", err)
+				c.reportError(&DecodeError{Raw: data, Err: err})
+			}
+			c.stats.countFrame(len(data), frame, pkts)
+			now := time.Now()
+			for _, pkt := range pkts {
+				pkt.ReceivedAt = now
+				c.receive(pkt)
 			}
 		}
 	}
 }
 
+// receive 处理一个解包后的包，依次调用原始包处理器、订阅检查、内存限制、暂停逻辑和事件分发
+func (c *Client) receive(pkt packet.Packet) {
+	if c.isDraining() {
+		c.dropped(DropShutdown, pkt)
+		return
+	}
+	c.nextSeq(&pkt)
+	for _, fn := range c.packetHandlers {
+		c.cover(func() { fn(pkt) })
+	}
+	if c.unsubscribed(pkt) || c.shed(pkt) {
+		return
+	}
+	if c.hold(pkt) {
+		return
+	}
+	c.deliver(pkt)
+}
+
+// deliver 按分发模式分发一个包，暂停期间缓存的包在 Resume 时也经过这里
+func (c *Client) deliver(pkt packet.Packet) {
+	if c.dispatcher.mode == DispatchSequenced && pkt.Operation == packet.Notification {
+		c.handleSequenced(pkt)
+		return
+	}
+	if !c.dispatcher.legacyDispatch() {
+		c.Handle(pkt)
+		return
+	}
+	atomic.AddInt64(&c.dispatcher.pending, 1)
+	go func() {
+		defer atomic.AddInt64(&c.dispatcher.pending, -1)
+		c.Handle(pkt)
+	}()
+}
+
 func (c *Client) heartBeatLoop() {
 	pkt := packet.NewHeartBeatPacket()
+	session := atomic.LoadInt32(&c.live.session)
 	for {
 		select {
 		case <-c.done:
 			return
 		case <-time.After(30 * time.Second):
-			if err := c.conn.WriteMessage(websocket.BinaryMessage, pkt); err != nil {
-				log.Error(err)
+			if c.sessionEnded(session) {
+				return
+			}
+			c.skew.sentHeartbeat(time.Now())
+			if err := c.write(pkt); err != nil {
+				c.logs.logf(log.ErrorLevel, "send heartbeat failed", "send heartbeat failed: %v", err)
 			}
 			log.Debug("send: HeartBeat")
 		}
@@ -161,23 +381,93 @@ func (c *Client) Start() error {
 	if err := c.init(); err != nil {
 		return err
 	}
+	if err := c.startPlugins(); err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&c.state.enabled) == 1 {
+		c.seedRoomState()
+	}
+	if c.deferUntilLive() {
+		return nil
+	}
+	if err := c.serve(); err != nil {
+		c.stopPlugins()
+		return err
+	}
+	return nil
+}
+
+// serve 连接 ws 并启动读取与心跳循环
+func (c *Client) serve() error {
+	if c.backfill {
+		c.backfillHistory()
+	}
 	if err := c.connect(); err != nil {
 		return err
 	}
-	go c.wsLoop()
-	go c.heartBeatLoop()
+	c.goLoop("wsLoop", c.wsLoop)
+	c.goLoop("heartBeatLoop", c.heartBeatLoop)
+	c.startMemoryGuard()
+	c.startDropReport()
 	return nil
 }
 
 // Stop 停止弹幕 Client
 func (c *Client) Stop() {
 	c.cancel()
+	c.disconnected(nil, CloseNormal)
+	c.setConn(nil)
+	c.stopPlugins()
 }
 
+// RoomID 获取真实房间号，Start 之前返回 0
+func (c *Client) RoomID() int {
+	rid, _ := strconv.Atoi(c.roomID)
+	return rid
+}
+
+// SetHost 指定弹幕服务器 host，不再从 getDanmuInfo 获取服务器列表
 func (c *Client) SetHost(host string) {
 	c.host = host
 }
 
+// SetAPIBaseURL 设置该 client 调用 api.live.bilibili.com 接口时使用的地址，用于反向代理、镜像或测试
+//
+// 全局的接口地址可以通过 api.SetBaseURL 设置
+func (c *Client) SetAPIBaseURL(u string) {
+	c.ownAPIClient().BaseURL = u
+}
+
+// SetUserAgents 设置 User-Agent 列表，每次重连时轮换使用下一个，同一次连接的接口请求与握手使用相同的 User-Agent
+func (c *Client) SetUserAgents(uas ...string) {
+	c.SetUserAgentFunc(api.RotateUserAgents(uas...))
+}
+
+// SetUserAgentFunc 设置生成 User-Agent 的函数，每次重连时调用一次，同一次连接的接口请求与握手使用相同的 User-Agent
+func (c *Client) SetUserAgentFunc(f func() string) {
+	c.credMu.Lock()
+	c.userAgentFunc = f
+	c.identity.userAgent = ""
+	c.credMu.Unlock()
+	c.bindAPISession()
+}
+
+// ownAPIClient 获取该 client 独占的 api.Client，仍在使用 api.DefaultClient 时复制一份
+func (c *Client) ownAPIClient() *api.Client {
+	if c.apiClient == api.DefaultClient {
+		cp := *api.DefaultClient
+		c.apiClient = &cp
+	}
+	return c.apiClient
+}
+
+// SetTLSConfig 设置连接弹幕服务器时使用的 TLS 配置
+//
+// 可用于自定义 RootCAs、测试环境下的 InsecureSkipVerify 或通过 ServerName 覆盖 SNI
+func (c *Client) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+}
+
 // UseDefaultHost 使用默认 host broadcastlv.chat.bilibili.com
 func (c *Client) UseDefaultHost() {
 	c.hostList = []string{"broadcastlv.chat.bilibili.com"}
@@ -188,12 +478,15 @@ func (c *Client) sendEnterPacket() error {
 	if err != nil {
 		return errors.New("error roomID")
 	}
-	uid, err := strconv.Atoi(c.enterUID)
+	c.credMu.Lock()
+	enterUID, buvid := c.enterUID, c.buvidLocked()
+	c.credMu.Unlock()
+	uid, err := strconv.Atoi(enterUID)
 	if err != nil {
 		return errors.New("error enterUID")
 	}
-	pkt := packet.NewEnterPacket(uid, c.buvid, rid, c.token)
-	if err = c.conn.WriteMessage(websocket.BinaryMessage, pkt); err != nil {
+	pkt := packet.NewEnterPacketWithProtover(uid, buvid, rid, c.token, c.protover())
+	if err = c.write(pkt); err != nil {
 		return err
 	}
 	log.Debugf("send: EnterPacket")
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+// CloseClass 连接关闭原因的分类，不同的分类使用不同的重连策略，可通过 ReconnectPolicy.Classes 修改
+type CloseClass int
+
+const (
+	// CloseUnknown 网络错误等其他原因，默认切换到下一个服务器重连
+	CloseUnknown CloseClass = iota
+	// CloseNormal 服务器正常关闭连接，默认重连同一服务器
+	CloseNormal
+	// CloseServerBusy 服务器繁忙或重启，默认切换到下一个服务器，进房时繁忙不等待直接重试
+	CloseServerBusy
+	// CloseAuthRejected 进房被拒绝，默认重新获取 token 后重连
+	CloseAuthRejected
+)
+
+var closeClassNames = []string{"unknown", "normal", "server_busy", "auth_rejected"}
+
+func (c CloseClass) String() string {
+	if c >= 0 && int(c) < len(closeClassNames) {
+		return closeClassNames[c]
+	}
+	return fmt.Sprintf("CloseClass(%d)", int(c))
+}
+
+// MarshalText 以名称保存到配置文件，如 "server_busy"
+func (c CloseClass) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *CloseClass) UnmarshalText(b []byte) error {
+	for i, name := range closeClassNames {
+		if string(b) == name {
+			*c = CloseClass(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown close class %q", b)
+}
+
+// CloseCode 获取 err 中 websocket 关闭帧的状态码，如 1006，err 不是 websocket 关闭错误时返回 0
+func CloseCode(err error) int {
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) {
+		return ce.Code
+	}
+	return 0
+}
+
+// classifyClose 对连接关闭的原因分类，entering 为 true 表示发送进房包后读取响应时断开
+//
+// 服务器繁忙时会在进房后直接断开而不发送关闭帧，此时为 1006，
+// 连接建立之后的 1006 通常是网络中断
+func classifyClose(err error, entering bool) CloseClass {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return CloseAuthRejected
+	}
+	switch CloseCode(err) {
+	case websocket.CloseNormalClosure, websocket.CloseGoingAway:
+		return CloseNormal
+	case websocket.CloseServiceRestart, websocket.CloseTryAgainLater, websocket.CloseInternalServerErr:
+		return CloseServerBusy
+	case websocket.ClosePolicyViolation:
+		return CloseAuthRejected
+	case websocket.CloseAbnormalClosure:
+		if entering {
+			return CloseServerBusy
+		}
+	}
+	return CloseUnknown
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// Conn 弹幕服务器连接，*websocket.Conn (gorilla/websocket) 直接实现了该接口
+//
+// messageType 使用 RFC 6455 中的定义，弹幕协议的包都是 BinaryMessage(2)
+type Conn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	Close() error
+}
+
+// Dialer 建立弹幕服务器连接，可以替换为其他 websocket 实现
+type Dialer interface {
+	// DialContext 连接 url，header 为握手时附带的请求头，可能为 nil
+	DialContext(ctx context.Context, url string, header http.Header) (Conn, error)
+}
+
+// DialerFunc 将函数转换为 Dialer
+type DialerFunc func(ctx context.Context, url string, header http.Header) (Conn, error)
+
+func (f DialerFunc) DialContext(ctx context.Context, url string, header http.Header) (Conn, error) {
+	return f(ctx, url, header)
+}
+
+// SetDialer 设置建立弹幕服务器连接使用的 Dialer，默认使用 gorilla/websocket
+//
+// 使用自定义 Dialer 时，SetTLSConfig、SetResolver 等连接相关的配置需要由 Dialer 自行处理
+func (c *Client) SetDialer(d Dialer) {
+	c.customDialer = d
+}
+
+// gorillaDialer 使用 gorilla/websocket 的默认 Dialer
+type gorillaDialer struct {
+	d *websocket.Dialer
+}
+
+func (g gorillaDialer) DialContext(ctx context.Context, url string, header http.Header) (Conn, error) {
+	conn, res, err := g.d.DialContext(ctx, url, header)
+	if res != nil && res.Body != nil {
+		res.Body.Close()
+	}
+	if err == websocket.ErrBadHandshake && res != nil {
+		return nil, &HandshakeError{Status: res.StatusCode, Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// getDialer 获取当前使用的 Dialer
+func (c *Client) getDialer() Dialer {
+	if c.customDialer != nil {
+		return c.customDialer
+	}
+	return gorillaDialer{d: c.dialer()}
+}
+
+// write 写入一个二进制包，websocket 连接不支持并发写入
+func (c *Client) write(pkt []byte) error {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.conn == nil {
+		return errNotConnected
+	}
+	return c.conn.WriteMessage(websocket.BinaryMessage, pkt)
+}
+
+// setConn 替换当前连接，并关闭之前的连接
+func (c *Client) setConn(conn Conn) {
+	c.connMu.Lock()
+	old := c.conn
+	c.conn = conn
+	c.connMu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+}
+
+// getConn 获取当前连接
+func (c *Client) getConn() Conn {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.conn
+}
@@ -0,0 +1,31 @@
+package client
+
+// SetCredentials 修改身份信息，参数含义与 NewClient 相同
+//
+// 不会断开当前连接，之后的重连使用新的身份信息
+func (c *Client) SetCredentials(enterUID string, buvid string, userAgent string, referer string) {
+	c.credMu.Lock()
+	c.enterUID = enterUID
+	c.buvid = buvid
+	c.userAgent = userAgent
+	c.referer = referer
+	c.identity.userAgent = ""
+	c.credMu.Unlock()
+}
+
+// SetCredentials 修改之后创建的 client 使用的身份信息，并同步到已添加的全部 client
+func (m *RoomManager) SetCredentials(enterUID string, buvid string, userAgent string, referer string) {
+	m.mu.Lock()
+	m.enterUID = enterUID
+	m.buvid = buvid
+	m.userAgent = userAgent
+	m.referer = referer
+	clients := make([]*Client, 0, len(m.rooms))
+	for _, c := range m.rooms {
+		clients = append(clients, c)
+	}
+	m.mu.Unlock()
+	for _, c := range clients {
+		c.SetCredentials(enterUID, buvid, userAgent, referer)
+	}
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"sort"
+	"sync"
+)
+
+// CustomHandle 自定义事件处理器的句柄，用于之后移除或替换该处理器
+//
+// 同一个事件的处理器被重新注册后，之前的句柄失效
+type CustomHandle struct {
+	cmd string
+	id  uint64
+}
+
+// Cmd 获取句柄对应的事件名
+func (h CustomHandle) Cmd() string {
+	return h.cmd
+}
+
+type customHandler struct {
+	id uint64
+	f  func(s string)
+}
+
+// customEventHandlers 自定义事件的处理器，每个事件只有一个处理器，运行中也可以修改
+type customEventHandlers struct {
+	mu       sync.RWMutex
+	next     uint64
+	handlers map[string]customHandler
+}
+
+func (h *customEventHandlers) get(cmd string) (func(s string), bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	e, ok := h.handlers[cmd]
+	return e.f, ok
+}
+
+func (h *customEventHandlers) set(cmd string, f func(s string)) CustomHandle {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.handlers == nil {
+		h.handlers = make(map[string]customHandler)
+	}
+	h.next++
+	h.handlers[cmd] = customHandler{id: h.next, f: f}
+	return CustomHandle{cmd: cmd, id: h.next}
+}
+
+// SetCustomEventHandler 注册 自定义事件 的处理器并返回句柄，与 RegisterCustomEventHandler 相同，会替换该事件已有的处理器
+//
+// 可以在运行中调用，用于插件式的应用加载与卸载功能
+func (c *Client) SetCustomEventHandler(cmd string, handler func(s string)) CustomHandle {
+	return c.customEventHandlers.set(cmd, handler)
+}
+
+// RemoveCustomEventHandler 移除 cmd 事件的自定义处理器，没有处理器时返回 false
+func (c *Client) RemoveCustomEventHandler(cmd string) bool {
+	h := c.customEventHandlers
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.handlers[cmd]; !ok {
+		return false
+	}
+	delete(h.handlers, cmd)
+	return true
+}
+
+// RemoveCustomHandle 移除 handle 对应的处理器，处理器已被移除或替换时返回 false，不影响之后注册的处理器
+func (c *Client) RemoveCustomHandle(handle CustomHandle) bool {
+	h := c.customEventHandlers
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if e, ok := h.handlers[handle.cmd]; !ok || e.id != handle.id {
+		return false
+	}
+	delete(h.handlers, handle.cmd)
+	return true
+}
+
+// ReplaceCustomHandle 将 handle 对应的处理器替换为 handler，句柄保持有效，处理器已被移除或替换时返回 false
+func (c *Client) ReplaceCustomHandle(handle CustomHandle, handler func(s string)) bool {
+	h := c.customEventHandlers
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, ok := h.handlers[handle.cmd]
+	if !ok || e.id != handle.id {
+		return false
+	}
+	h.handlers[handle.cmd] = customHandler{id: e.id, f: handler}
+	return true
+}
+
+// CustomEventCmds 获取已注册自定义处理器的事件名，按字典序排列
+func (c *Client) CustomEventCmds() []string {
+	h := c.customEventHandlers
+	h.mu.RLock()
+	cmds := make([]string, 0, len(h.handlers))
+	for cmd := range h.handlers {
+		cmds = append(cmds, cmd)
+	}
+	h.mu.RUnlock()
+	sort.Strings(cmds)
+	return cmds
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+const (
+	// DefaultBatchSize 批量分发时每批最多包含的弹幕数量
+	DefaultBatchSize = 100
+	// DefaultBatchWindow 批量分发时一批弹幕最长的等待时间
+	DefaultBatchWindow = 50 * time.Millisecond
+)
+
+// danmakuBatcher 将弹幕聚合为小批次交给批量处理器
+type danmakuBatcher struct {
+	handlers []func([]*message.Danmaku)
+	size     int
+	window   time.Duration
+	once     sync.Once
+	ch       chan batchItem
+}
+
+// batchItem 等待批量分发的一条弹幕，refs 为该弹幕与逐条处理器共享的计数
+type batchItem struct {
+	d    *message.Danmaku
+	refs *refCount
+}
+
+// OnDanmakuBatch 添加 批量弹幕 的处理器，弹幕达到 SetBatch 设置的数量或等待时间后一起交给处理器，
+// 适合批量写入数据库或消息队列，需要在 Start 之前调用
+//
+// 多个批量处理器共享同一个切片，处理器不应修改切片，开启对象池时处理器返回后弹幕会被复用，
+// Stop 时缓冲中的弹幕会立即交给处理器
+func (c *Client) OnDanmakuBatch(f func([]*message.Danmaku)) {
+	c.batcher.handlers = append(c.batcher.handlers, f)
+}
+
+// SetBatch 设置批量分发每批最多的弹幕数量与最长的等待时间，默认为 DefaultBatchSize 与 DefaultBatchWindow，需要在 Start 之前调用
+func (c *Client) SetBatch(size int, window time.Duration) {
+	c.batcher.size = size
+	c.batcher.window = window
+}
+
+// batchDanmaku 将一条弹幕加入当前批次
+func (c *Client) batchDanmaku(d *message.Danmaku, refs *refCount) {
+	b := &c.batcher
+	b.once.Do(func() {
+		if b.size <= 0 {
+			b.size = DefaultBatchSize
+		}
+		if b.window <= 0 {
+			b.window = DefaultBatchWindow
+		}
+		b.ch = make(chan batchItem, b.size)
+		c.goLoop("batchLoop", c.batchLoop)
+	})
+	select {
+	case b.ch <- batchItem{d: d, refs: refs}:
+	case <-c.done:
+	}
+}
+
+func (c *Client) batchLoop() {
+	b := &c.batcher
+	items := make([]batchItem, 0, b.size)
+	var (
+		timer  *time.Timer
+		expire <-chan time.Time
+	)
+	flush := func(direct bool) {
+		if timer != nil {
+			timer.Stop()
+			timer, expire = nil, nil
+		}
+		if len(items) > 0 {
+			c.flushBatch(items, direct)
+			items = make([]batchItem, 0, b.size)
+		}
+	}
+	for {
+		select {
+		case <-c.done:
+		drain:
+			for {
+				select {
+				case it := <-b.ch:
+					items = append(items, it)
+				default:
+					break drain
+				}
+			}
+			flush(true)
+			return
+		case it := <-b.ch:
+			items = append(items, it)
+			if len(items) >= b.size {
+				flush(false)
+			} else if timer == nil {
+				timer = time.NewTimer(b.window)
+				expire = timer.C
+			}
+		case <-expire:
+			timer, expire = nil, nil
+			flush(false)
+		}
+	}
+}
+
+// flushBatch 将一批弹幕交给全部批量处理器，direct 为 true 时在当前 goroutine 中执行
+func (c *Client) flushBatch(items []batchItem, direct bool) {
+	ds := make([]*message.Danmaku, len(items))
+	for i, it := range items {
+		ds[i] = it.d
+	}
+	handlers := c.batcher.handlers
+	refs := c.refs(len(handlers))
+	for _, fn := range handlers {
+		fn := fn
+		f := func() {
+			fn(ds)
+			if refs.done() {
+				for _, it := range items {
+					if it.refs.done() {
+						c.releaseDanmaku(it.d)
+					}
+				}
+			}
+		}
+		if direct {
+			c.run("DANMU_MSG", nil, f)
+		} else {
+			c.dispatch("DANMU_MSG", packet.Packet{}, nil, f)
+		}
+	}
+}
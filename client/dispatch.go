@@ -0,0 +1,231 @@
+package client
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/packet"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// DispatchAsync 处理器由固定数量的 worker 并发执行，不保证顺序，默认模式，worker 数量见 SetWorkers
+	//
+	// 开启 EnableLegacyDispatch 时每个包与每个处理器都在独立的 goroutine 中执行
+	DispatchAsync = iota
+	// DispatchOrdered 所有处理器按收到消息的顺序在同一个 goroutine 中依次执行
+	DispatchOrdered
+	// DispatchSharded 按发送者 UID 将事件分配到多个 worker，同一用户的事件按顺序执行
+	DispatchSharded
+	// DispatchSequenced 由 worker 并发解析事件，处理器在同一个 goroutine 中按 Meta.Seq 的顺序依次执行
+	DispatchSequenced
+)
+
+// defaultShards DispatchSharded 模式下默认的 worker 数量
+const defaultShards = 8
+
+// defaultWorkers DispatchAsync 模式下默认的 worker 数量
+const defaultWorkers = 64
+
+// orderedQueueSize 顺序分发模式下队列的长度，队列写满时读取循环会等待
+const orderedQueueSize = 1024
+
+// SlowHandler 执行超时的处理器信息
+type SlowHandler struct {
+	Cmd       string
+	Body      []byte        // 事件的原始报文
+	Duration  time.Duration // 处理器执行耗时，被放弃时为放弃前等待的时间
+	Abandoned bool          // 是否已放弃等待该处理器
+}
+
+type dispatcher struct {
+	mode         int
+	legacy       bool
+	workers      int
+	timeout      time.Duration
+	abandon      bool
+	slowHandlers []func(*SlowHandler)
+	shards       int
+	queues       []chan func()
+	once         sync.Once
+	pending      int64 // 等待执行与执行中的处理器数量
+	capacity     int64 // 全部队列的容量
+}
+
+// SetDispatchMode 设置事件分发模式，DispatchAsync、DispatchOrdered、DispatchSharded 或 DispatchSequenced，需要在 Start 之前调用
+//
+// 各模式下处理器看到的事件顺序见 Ordering
+func (c *Client) SetDispatchMode(mode int) {
+	c.dispatcher.mode = mode
+}
+
+// SetShards 设置 DispatchSharded 模式下的 worker 数量，需要在 Start 之前调用
+func (c *Client) SetShards(n int) {
+	c.dispatcher.shards = n
+}
+
+// SetWorkers 设置 DispatchAsync 模式下并发执行处理器、DispatchSequenced 模式下并发解析事件的 worker 数量，默认为 64，需要在 Start 之前调用
+//
+// 全部 worker 都在执行时新的事件在队列中等待，队列写满时读取循环会等待
+func (c *Client) SetWorkers(n int) {
+	c.dispatcher.workers = n
+}
+
+// EnableLegacyDispatch DispatchAsync 模式下恢复旧版本的行为，每个包与每个处理器都在独立的 goroutine 中执行，需要在 Start 之前调用
+//
+// 并发数量没有上限，热门直播间中处理器较慢时 goroutine 会持续增长，仅用于依赖旧行为的代码逐步迁移
+func (c *Client) EnableLegacyDispatch() {
+	c.dispatcher.legacy = true
+}
+
+// legacyDispatch 是否为每个包与处理器创建 goroutine
+func (d *dispatcher) legacyDispatch() bool {
+	return d.mode == DispatchAsync && d.legacy
+}
+
+// SetHandlerTimeout 设置单个处理器的执行超时时间，超时的处理器会通过 OnSlowHandler 报告，为 0 时不检测
+//
+// abandon 为 true 时超时后不再等待该处理器，顺序分发模式下后续事件可以继续执行，
+// 被放弃的处理器所在的 goroutine 会继续运行直至返回
+func (c *Client) SetHandlerTimeout(timeout time.Duration, abandon bool) {
+	c.dispatcher.timeout = timeout
+	c.dispatcher.abandon = abandon
+}
+
+// OnSlowHandler 添加 处理器执行超时 的处理器
+func (c *Client) OnSlowHandler(f func(*SlowHandler)) {
+	c.dispatcher.slowHandlers = append(c.dispatcher.slowHandlers, f)
+}
+
+// dispatch 按分发模式执行一个处理器，p 为事件所在的包，v 为解析后的消息，用于在 DispatchSharded 模式下确定 worker
+func (c *Client) dispatch(cmd string, p packet.Packet, v interface{}, f func()) {
+	d := &c.dispatcher
+	body := p.Body
+	if d.legacyDispatch() {
+		atomic.AddInt64(&d.pending, 1)
+		go func() {
+			defer atomic.AddInt64(&d.pending, -1)
+			c.run(cmd, body, f)
+		}()
+		return
+	}
+	if d.mode == DispatchSequenced {
+		c.sequence(cmd, p, f)
+		return
+	}
+	c.startWorkers()
+	q := d.queues[0]
+	if len(d.queues) > 1 {
+		q = d.queues[uint(senderUID(body, v))%uint(len(d.queues))]
+	}
+	atomic.AddInt64(&d.pending, 1)
+	select {
+	case q <- func() {
+		defer atomic.AddInt64(&d.pending, -1)
+		c.run(cmd, body, f)
+	}:
+	case <-c.done:
+		atomic.AddInt64(&d.pending, -1)
+	}
+}
+
+// startWorkers 第一次分发时按分发模式创建队列并启动 worker
+func (c *Client) startWorkers() {
+	d := &c.dispatcher
+	d.once.Do(func() {
+		// DispatchSharded 每个 worker 一个队列，DispatchAsync 与 DispatchSequenced 全部 worker 共用一个队列
+		n, workers := 1, 1
+		switch d.mode {
+		case DispatchSharded:
+			n = d.shards
+			if n <= 0 {
+				n = defaultShards
+			}
+		case DispatchAsync, DispatchSequenced:
+			workers = d.workers
+			if workers <= 0 {
+				workers = defaultWorkers
+			}
+		}
+		d.queues = make([]chan func(), n)
+		atomic.StoreInt64(&d.capacity, int64(n*orderedQueueSize))
+		for i := range d.queues {
+			d.queues[i] = make(chan func(), orderedQueueSize)
+			queue := d.queues[i]
+			for j := 0; j < workers; j++ {
+				c.goLoop("workerLoop", func() { c.workerLoop(queue) })
+			}
+		}
+	})
+}
+
+func (c *Client) workerLoop(queue chan func()) {
+	for {
+		select {
+		case <-c.done:
+			return
+		case f := <-queue:
+			f()
+		}
+	}
+}
+
+// senderUID 获取事件发送者的 UID，无法确定时返回 0
+func senderUID(body []byte, v interface{}) int {
+	switch m := v.(type) {
+	case *message.Danmaku:
+		return m.Sender.Uid
+	case *message.SuperChat:
+		return m.Uid
+	case *message.Gift:
+		return m.Uid
+	case *message.GuardBuy:
+		return m.Uid
+	case *message.UserToast:
+		return m.Uid
+	case *message.MedalGain:
+		return m.Uid
+	case *message.MedalChange:
+		return m.Uid
+	}
+	if uid := gjson.GetBytes(body, "data.uid"); uid.Exists() {
+		return int(uid.Int())
+	}
+	return int(gjson.GetBytes(body, "info.2.0").Int())
+}
+
+// run 执行一个处理器，恢复 panic 并检测执行超时
+func (c *Client) run(cmd string, body []byte, f func()) {
+	d := &c.dispatcher
+	if d.timeout <= 0 {
+		c.cover(f)
+		return
+	}
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.cover(f)
+	}()
+	timer := time.NewTimer(d.timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+	if d.abandon {
+		c.reportSlow(&SlowHandler{Cmd: cmd, Body: body, Duration: time.Since(start), Abandoned: true})
+		return
+	}
+	<-done
+	c.reportSlow(&SlowHandler{Cmd: cmd, Body: body, Duration: time.Since(start)})
+}
+
+func (c *Client) reportSlow(s *SlowHandler) {
+	for _, fn := range c.dispatcher.slowHandlers {
+		c.cover(func() { fn(s) })
+	}
+}
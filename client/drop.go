@@ -0,0 +1,148 @@
+package client
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// 事件被丢弃的原因
+const (
+	DropMemory   = "memory"   // 内存紧张，见 SetMemoryLimit
+	DropPaused   = "paused"   // 暂停期间未开启缓存或缓存已满，见 SetPauseBufferSize
+	DropShutdown = "shutdown" // 调用 Shutdown 之后收到，或暂停期间缓存的包在 Shutdown 时未分发
+)
+
+// defaultDropReportInterval OnDropReport 默认的报告间隔
+const defaultDropReportInterval = time.Minute
+
+// DropReport 上一次报告以来被丢弃的事件，没有丢弃时 Total 为 0
+type DropReport struct {
+	RoomID  int
+	Since   time.Time
+	Until   time.Time
+	Total   uint64
+	Cmds    map[string]uint64 // cmd 到丢弃数量
+	Reasons map[string]uint64 // 丢弃原因到丢弃数量
+}
+
+// dropCounter 按 cmd 统计被丢弃的事件
+type dropCounter struct {
+	mu       sync.Mutex
+	total    uint64
+	cmds     map[string]uint64 // 累计的数量
+	report   DropReport        // 上一次报告以来的数量
+	interval time.Duration
+	handlers []func(*DropReport)
+	once     sync.Once
+}
+
+// OnDropReport 添加 丢弃事件报告 的处理器，每隔 interval 调用一次，<= 0 时为每分钟，需要在 Start 之前调用
+//
+// 即使没有丢弃也会报告，可用于确认统计数据是否完整；interval 以最后一次调用为准
+func (c *Client) OnDropReport(interval time.Duration, f func(*DropReport)) {
+	if interval <= 0 {
+		interval = defaultDropReportInterval
+	}
+	d := &c.drops
+	d.mu.Lock()
+	d.interval = interval
+	d.handlers = append(d.handlers, f)
+	d.mu.Unlock()
+}
+
+// dropped 记录一个被丢弃的包
+func (c *Client) dropped(reason string, pkt packet.Packet) {
+	if pkt.Operation != packet.Notification {
+		return
+	}
+	// 带参数的 cmd 归入基本的 cmd，如 DANMU_MSG:4:0:2:2:2:0 计为 DANMU_MSG
+	cmd := baseCmd(parseCmd(pkt.Body))
+	d := &c.drops
+	d.mu.Lock()
+	d.total++
+	if d.cmds == nil {
+		d.cmds = make(map[string]uint64)
+	}
+	d.cmds[cmd]++
+	r := &d.report
+	if r.Cmds == nil {
+		r.Cmds = make(map[string]uint64)
+		r.Reasons = make(map[string]uint64)
+	}
+	r.Total++
+	r.Cmds[cmd]++
+	r.Reasons[reason]++
+	d.mu.Unlock()
+}
+
+// droppedStats 获取累计丢弃的数量与每个 cmd 的数量
+func (c *Client) droppedStats() (uint64, map[string]uint64) {
+	d := &c.drops
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var cmds map[string]uint64
+	if len(d.cmds) > 0 {
+		cmds = make(map[string]uint64, len(d.cmds))
+		for cmd, n := range d.cmds {
+			cmds[cmd] = n
+		}
+	}
+	return d.total, cmds
+}
+
+// startDropReport 添加了报告处理器时启动报告循环
+func (c *Client) startDropReport() {
+	d := &c.drops
+	d.mu.Lock()
+	n := len(d.handlers)
+	d.mu.Unlock()
+	if n == 0 {
+		return
+	}
+	d.once.Do(func() { c.goLoop("dropReportLoop", c.dropReportLoop) })
+}
+
+func (c *Client) dropReportLoop() {
+	d := &c.drops
+	d.mu.Lock()
+	d.report.Since = time.Now()
+	d.mu.Unlock()
+	for {
+		d.mu.Lock()
+		interval := d.interval
+		d.mu.Unlock()
+		select {
+		case <-c.done:
+			return
+		case <-time.After(interval):
+			c.reportDrops()
+		}
+	}
+}
+
+// reportDrops 将上一次报告以来的数量交给处理器并重新计数
+func (c *Client) reportDrops() {
+	d := &c.drops
+	now := time.Now()
+	d.mu.Lock()
+	r := d.report
+	d.report = DropReport{Since: now}
+	handlers := d.handlers
+	d.mu.Unlock()
+	r.RoomID, _ = strconv.Atoi(c.roomID)
+	r.Until = now
+	for _, fn := range handlers {
+		fn := fn
+		c.cover(func() { fn(&r) })
+	}
+}
+
+// OnDropReport 为之后添加的全部房间添加 丢弃事件报告 的处理器，roomID 为 AddRoom 时使用的房间号
+func (m *RoomManager) OnDropReport(interval time.Duration, f func(roomID string, r *DropReport)) {
+	m.OnClient(func(roomID string, c *Client) {
+		c.OnDropReport(interval, func(r *DropReport) { f(roomID, r) })
+	})
+}
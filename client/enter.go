@@ -0,0 +1,42 @@
+package client
+
+import (
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+	"github.com/tidwall/gjson"
+)
+
+// checkEnterReply 检查进房包的响应，服务器拒绝时返回 *AuthError，同一帧中的其他包会被返回
+func (c *Client) checkEnterReply(data []byte) ([]packet.Packet, error) {
+	pkts, err := packet.DecodePacket(data).Decode()
+	if err != nil {
+		return nil, err
+	}
+	rest := pkts[:0]
+	for _, p := range pkts {
+		if p.Operation != packet.RoomEnterResponse {
+			rest = append(rest, p)
+			continue
+		}
+		if code := gjson.GetBytes(p.Body, "code"); code.Exists() && code.Int() != 0 {
+			return nil, &AuthError{Host: c.host, Code: int(code.Int()), Body: p.Body}
+		}
+	}
+	now := time.Now()
+	for i := range rest {
+		rest[i].ReceivedAt = now
+	}
+	return rest, nil
+}
+
+// refreshToken 进房被拒绝后重新获取进房使用的 token，不使用缓存中已被拒绝的 token
+func (c *Client) refreshToken() {
+	info, err := c.apiClient.RefreshDanmuInfo(c.roomID)
+	if err != nil {
+		c.reportError(&APIError{API: "getDanmuInfo", Err: err})
+		c.checkAPIRisk(err)
+		return
+	}
+	c.token = info.Data.Token
+}
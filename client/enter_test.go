@@ -0,0 +1,85 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+	"github.com/gorilla/websocket"
+	"github.com/tidwall/gjson"
+)
+
+func TestRefreshTokenAfterAuthRejected(t *testing.T) {
+	quietLogs(t)
+	var fetches int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "room_init") {
+			w.Write([]byte(`{"code":0,"data":{"room_id":8792912,"uid":1}}`))
+			return
+		}
+		n := atomic.AddInt32(&fetches, 1)
+		fmt.Fprintf(w, `{"code":0,"data":{"token":"t%d","host_list":[]}}`, n)
+	}))
+	var mu sync.Mutex
+	var keys []string
+	entered := make(chan struct{})
+	upgrader := websocket.Upgrader{}
+	ws := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		pkts, _ := packet.DecodePacket(data).Decode()
+		if len(pkts) == 0 {
+			return
+		}
+		mu.Lock()
+		keys = append(keys, gjson.GetBytes(pkts[0].Body, "key").String())
+		first := len(keys) == 1
+		mu.Unlock()
+		if first {
+			// 第一次进房使用的 token 被拒绝
+			reject := packet.NewPlainPacket(packet.RoomEnterResponse, []byte(`{"code":-101}`))
+			conn.WriteMessage(websocket.BinaryMessage, reject.Build())
+			return
+		}
+		conn.WriteMessage(websocket.BinaryMessage, enterReply)
+		close(entered)
+		conn.ReadMessage()
+	}))
+	c := NewClient("8792912", "0", "", "", "")
+	useServers(c, api, ws)
+	// SetHost 后 init 不再请求 getDanmuInfo，这里与 init 一样获取 token，响应会进入缓存
+	info, err := c.apiClient.GetDanmuInfo("8792912")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.token = info.Data.Token
+	if err = c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	<-entered
+	c.Stop()
+	c.Wait()
+	api.Close()
+	ws.Close()
+
+	if n := atomic.LoadInt32(&fetches); n < 2 {
+		t.Fatalf("getDanmuInfo fetched %d times, want the rejected token to be refreshed", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) < 2 || keys[0] != "t1" || keys[1] != "t2" {
+		t.Fatalf("enter tokens %v, want [t1 t2]", keys)
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/RemKeeper/blivedm-go/api"
+)
+
+var errNotConnected = errors.New("not connected")
+
+// Start 时 room_init 返回的直播间状态错误，与 api 包中的错误相同，可以使用 errors.Is 判断
+var (
+	ErrRoomNotFound = api.ErrRoomNotFound
+	ErrRoomBanned   = api.ErrRoomBanned
+)
+
+// errorsBufferSize Errors 通道的缓冲大小，通道写满时新的错误会被丢弃
+const errorsBufferSize = 64
+
+// DecodeError 解包或解析消息失败
+type DecodeError struct {
+	Cmd string // 解析消息失败时为消息的 cmd，解包失败时为空
+	Raw []byte // 原始数据
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Cmd == "" {
+		return fmt.Sprintf("decode packet failed: %v", e.Err)
+	}
+	return fmt.Sprintf("parse %s failed: %v", e.Cmd, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// HandlerPanicError 事件处理器发生 panic
+type HandlerPanicError struct {
+	Value interface{} // recover 得到的值
+	Stack []byte
+}
+
+func (e *HandlerPanicError) Error() string {
+	return fmt.Sprintf("event handler panic: %v", e.Value)
+}
+
+// ReconnectError 连接弹幕服务器失败，client 会继续重试
+type ReconnectError struct {
+	Host      string
+	Attempt   int
+	Err       error
+	CloseCode int        // websocket 关闭帧的状态码，见 CloseCode
+	Class     CloseClass // 断开原因的分类
+}
+
+func (e *ReconnectError) Error() string {
+	return fmt.Sprintf("connect %s failed (attempt %d): %v", e.Host, e.Attempt, e.Err)
+}
+
+func (e *ReconnectError) Unwrap() error {
+	return e.Err
+}
+
+// AuthError 进房包被服务器拒绝，如 token 失效，client 会重新获取 token 后重连
+type AuthError struct {
+	Host string
+	Code int    // 响应中的 code
+	Body []byte // 响应的原始内容
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("enter room rejected by %s: code %d", e.Host, e.Code)
+}
+
+// HandshakeError websocket 握手被服务器拒绝，状态码为 412 时为风控拦截
+type HandshakeError struct {
+	Status int // 握手响应的 HTTP 状态码
+	Err    error
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("%v (status %d)", e.Err, e.Status)
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// APIError 调用 HTTP 接口失败
+type APIError struct {
+	API string
+	Err error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api %s failed: %v", e.API, e.Err)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Errors 返回接收错误的通道，错误类型为 *DecodeError、*HandlerPanicError、*ReconnectError 或 *APIError，
+// 进房被拒绝时为 Err 为 *AuthError 的 *ReconnectError
+//
+// 通道写满时新的错误会被丢弃，不会阻塞 client
+func (c *Client) Errors() <-chan error {
+	return c.errors
+}
+
+// reportError 将错误发送到 Errors 通道
+func (c *Client) reportError(err error) {
+	select {
+	case c.errors <- err:
+	default:
+	}
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ExpvarStats 通过 expvar 发布的统计信息
+type ExpvarStats struct {
+	RoomID            int               `json:"room_id"`
+	Connected         bool              `json:"connected"`
+	ConnectedAt       time.Time         `json:"connected_at"`
+	LastMessageAt     time.Time         `json:"last_message_at"`
+	Reconnects        uint64            `json:"reconnects"`
+	Messages          uint64            `json:"messages"`
+	MessagesPerSecond float64           `json:"messages_per_second"` // 最近两次读取之间的平均速率
+	ReceivedBytes     uint64            `json:"received_bytes"`
+	CompressionRatio  float64           `json:"compression_ratio"`
+	AvgLatencyMs      float64           `json:"avg_latency_ms"`
+	MaxLatencyMs      float64           `json:"max_latency_ms"`
+	Unsubscribed      uint64            `json:"unsubscribed"`
+	Shed              uint64            `json:"shed"`
+	Dropped           uint64            `json:"dropped"`
+	DroppedCmds       map[string]uint64 `json:"dropped_cmds,omitempty"`
+}
+
+// minRateInterval 计算消息速率的最短间隔，间隔过短的读取沿用上一次的速率
+const minRateInterval = time.Second
+
+// rateMeter 根据相邻两次读取的消息数量计算速率
+type rateMeter struct {
+	mu    sync.Mutex
+	at    time.Time
+	count uint64
+	rate  float64
+}
+
+func (r *rateMeter) update(count uint64, now time.Time) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.at.IsZero() {
+		r.at, r.count = now, count
+		return 0
+	}
+	if elapsed := now.Sub(r.at); elapsed >= minRateInterval {
+		if count >= r.count {
+			r.rate = float64(count-r.count) / elapsed.Seconds()
+		}
+		r.at, r.count = now, count
+	}
+	return r.rate
+}
+
+func expvarStats(s Stats, rate float64) *ExpvarStats {
+	return &ExpvarStats{
+		RoomID:            s.RoomID,
+		Connected:         s.Connected,
+		ConnectedAt:       s.ConnectedAt,
+		LastMessageAt:     s.LastMessageAt,
+		Reconnects:        s.Reconnects,
+		Messages:          s.Messages,
+		MessagesPerSecond: rate,
+		ReceivedBytes:     s.ReceivedBytes,
+		CompressionRatio:  s.CompressionRatio(),
+		AvgLatencyMs:      float64(s.AvgLatency) / float64(time.Millisecond),
+		MaxLatencyMs:      float64(s.MaxLatency) / float64(time.Millisecond),
+		Unsubscribed:      s.Unsubscribed,
+		Shed:              s.Shed,
+		Dropped:           s.Dropped,
+		DroppedCmds:       s.DroppedCmds,
+	}
+}
+
+// publishExpvar 发布 expvar 变量，name 已被使用时返回错误而不是像 expvar.Publish 一样 panic
+func publishExpvar(name string, f func() interface{}) error {
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %q already published", name)
+	}
+	expvar.Publish(name, expvar.Func(f))
+	return nil
+}
+
+// PublishExpvar 以 name 为变量名通过 expvar 发布该 client 的统计信息，可在 /debug/vars 中读取
+//
+// expvar 的变量无法删除，name 已被使用时返回错误
+func (c *Client) PublishExpvar(name string) error {
+	var meter rateMeter
+	return publishExpvar(name, func() interface{} {
+		s := c.Stats()
+		return expvarStats(s, meter.update(s.Messages, time.Now()))
+	})
+}
+
+// PublishExpvar 以 prefix 为变量名通过 expvar 发布全部房间的统计信息，值为 AddRoom 时使用的房间号到统计信息的映射
+//
+// 变量在每次读取时计算，之后添加的房间与移除的房间会自动体现
+func (m *RoomManager) PublishExpvar(prefix string) error {
+	var (
+		mu     sync.Mutex
+		meters = make(map[string]*rateMeter)
+	)
+	return publishExpvar(prefix, func() interface{} {
+		m.mu.Lock()
+		rooms := make(map[string]*Client, len(m.rooms))
+		for id, c := range m.rooms {
+			rooms[id] = c
+		}
+		m.mu.Unlock()
+		now := time.Now()
+		out := make(map[string]*ExpvarStats, len(rooms))
+		mu.Lock()
+		defer mu.Unlock()
+		for id, c := range rooms {
+			meter := meters[id]
+			if meter == nil {
+				meter = &rateMeter{}
+				meters[id] = meter
+			}
+			s := c.Stats()
+			out[id] = expvarStats(s, meter.update(s.Messages, now))
+		}
+		for id := range meters {
+			if rooms[id] == nil {
+				delete(meters, id)
+			}
+		}
+		return out
+	})
+}
@@ -0,0 +1,19 @@
+package client
+
+// EventFilter 事件过滤器，cmd 为事件的 CMD，body 为原始报文，返回 false 时事件不会分发给任何处理器
+type EventFilter func(cmd string, body []byte) bool
+
+// AddEventFilter 添加事件过滤器，对全部事件处理器与自定义事件处理器生效，需要在 Start 之前调用
+func (c *Client) AddEventFilter(f EventFilter) {
+	c.filters = append(c.filters, f)
+}
+
+// filter 依次执行过滤器，任一过滤器返回 false 时返回 false
+func (c *Client) filter(cmd string, body []byte) bool {
+	for _, f := range c.filters {
+		if !f(cmd, body) {
+			return false
+		}
+	}
+	return true
+}
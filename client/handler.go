@@ -1,7 +1,6 @@
 package client
 
 import (
-	"github.com/RemKeeper/blivedm-go/message"
 	"github.com/RemKeeper/blivedm-go/packet"
 	"github.com/RemKeeper/blivedm-go/utils"
 	log "github.com/sirupsen/logrus"
@@ -14,17 +13,6 @@ var (
 	knownCMDMap map[string]int
 )
 
-type eventHandlers struct {
-	danmakuMessageHandlers []func(*message.Danmaku)
-	superChatHandlers      []func(*message.SuperChat)
-	giftHandlers           []func(*message.Gift)
-	guardBuyHandlers       []func(*message.GuardBuy)
-	liveHandlers           []func(*message.Live)
-	userToastHandlers      []func(*message.UserToast)
-}
-
-type customEventHandlers map[string]func(s string)
-
 func init() {
 	knownCMDMap = make(map[string]int)
 	for _, c := range knownCMD {
@@ -36,99 +24,42 @@ func init() {
 //
 // 需要提供事件名，可参考 knownCMD
 func (c *Client) RegisterCustomEventHandler(cmd string, handler func(s string)) {
-	(*c.customEventHandlers)[cmd] = handler
-}
-
-// OnDanmaku 添加 弹幕事件 的处理器
-func (c *Client) OnDanmaku(f func(*message.Danmaku)) {
-	c.eventHandlers.danmakuMessageHandlers = append(c.eventHandlers.danmakuMessageHandlers, f)
-}
-
-// OnSuperChat 添加 醒目留言事件 的处理器
-func (c *Client) OnSuperChat(f func(*message.SuperChat)) {
-	c.eventHandlers.superChatHandlers = append(c.eventHandlers.superChatHandlers, f)
-}
-
-// OnGift 添加 礼物事件 的处理器
-func (c *Client) OnGift(f func(gift *message.Gift)) {
-	c.eventHandlers.giftHandlers = append(c.eventHandlers.giftHandlers, f)
+	c.customEventHandlers.set(cmd, handler)
 }
 
-// OnGuardBuy 添加 开通大航海事件 的处理器
-func (c *Client) OnGuardBuy(f func(*message.GuardBuy)) {
-	c.eventHandlers.guardBuyHandlers = append(c.eventHandlers.guardBuyHandlers, f)
-}
-
-// OnLive 添加 开播事件 的处理器
-func (c *Client) OnLive(f func(*message.Live)) {
-	c.eventHandlers.liveHandlers = append(c.eventHandlers.liveHandlers, f)
-}
-
-// OnUserToast 添加 UserToast 的处理器
-func (c *Client) OnUserToast(f func(*message.UserToast)) {
-	c.eventHandlers.userToastHandlers = append(c.eventHandlers.userToastHandlers, f)
+// baseCmd 去掉 cmd 中的参数，新的弹幕 cmd 可能带参数，如 DANMU_MSG:4:0:2:2:2:0
+func baseCmd(cmd string) string {
+	if ind := strings.Index(cmd, ":"); ind >= 0 {
+		return cmd[:ind]
+	}
+	return cmd
 }
 
 // Handle 处理一个包
 func (c *Client) Handle(p packet.Packet) {
 	switch p.Operation {
 	case packet.Notification:
-		cmd := parseCmd(p.Body)
+		cmd := baseCmd(parseCmd(p.Body))
 		sb := utils.BytesToString(p.Body)
-		// 新的弹幕 cmd 可能带参数
-		if ind := strings.Index(cmd, ":"); ind >= 0 {
-			cmd = cmd[:ind]
-		}
 		// 优先执行自定义 eventHandler ，会覆盖库内自带的 handler
-		f, ok := (*c.customEventHandlers)[cmd]
-		if ok {
-			go cover(func() { f(sb) })
+		f, cmd := c.route(cmd)
+		if !c.filter(cmd, p.Body) {
 			return
 		}
-		switch cmd {
-		case "DANMU_MSG":
-			d := new(message.Danmaku)
-			d.Parse(p.Body)
-			for _, fn := range c.eventHandlers.danmakuMessageHandlers {
-				go cover(func() { fn(d) })
-			}
-		case "SUPER_CHAT_MESSAGE":
-			s := new(message.SuperChat)
-			s.Parse(p.Body)
-			for _, fn := range c.eventHandlers.superChatHandlers {
-				go cover(func() { fn(s) })
-			}
-		case "SEND_GIFT":
-			g := new(message.Gift)
-			g.Parse(p.Body)
-			for _, fn := range c.eventHandlers.giftHandlers {
-				go cover(func() { fn(g) })
-			}
-		case "GUARD_BUY":
-			g := new(message.GuardBuy)
-			g.Parse(p.Body)
-			for _, fn := range c.eventHandlers.guardBuyHandlers {
-				go cover(func() { fn(g) })
-			}
-		case "LIVE":
-			l := new(message.Live)
-			l.Parse(p.Body)
-			for _, fn := range c.eventHandlers.liveHandlers {
-				go cover(func() { fn(l) })
-			}
-		case "USER_TOAST_MSG":
-			u := new(message.UserToast)
-			u.Parse(p.Body)
-			for _, fn := range c.eventHandlers.userToastHandlers {
-				go cover(func() { fn(u) })
-			}
-		default:
-			if _, ok := knownCMDMap[cmd]; ok {
-				return
-			}
-			log.Debugf("unknown cmd(%s), body: %s", cmd, p.Body)
+		c.recordReplay(cmd, p)
+		if f != nil {
+			c.dispatch(cmd, p, nil, func() { f(sb) })
+			return
+		}
+		if c.handleEvent(cmd, p) {
+			return
+		}
+		if _, ok := knownCMDMap[cmd]; ok {
+			return
 		}
+		log.Debugf("unknown cmd(%s), body: %s", cmd, p.Body)
 	case packet.HeartBeatResponse:
+		c.skew.heartbeatReply(p.ReceivedAt)
 	case packet.RoomEnterResponse:
 	default:
 		log.WithField("protover", p.ProtocolVersion).
@@ -137,6 +68,40 @@ func (c *Client) Handle(p packet.Packet) {
 	}
 }
 
+// handleDanmaku 解析并分发弹幕，弹幕同时交给批量弹幕处理器，开启对象池时全部处理器返回后回收
+func (c *Client) handleDanmaku(cmd string, p packet.Packet) {
+	handlers := c.eventHandlers.danmakuHandlers
+	n := len(handlers)
+	if len(c.batcher.handlers) > 0 {
+		n++
+	}
+	if n == 0 && c.parseMode != ParseStrict {
+		return
+	}
+	d := c.newDanmaku()
+	if !c.parse(cmd, p, d) {
+		c.releaseDanmaku(d)
+		return
+	}
+	c.observeSender(d.Sender.Uid)
+	refs := c.refs(n)
+	for _, fn := range handlers {
+		fn := fn
+		c.dispatch(cmd, p, d, func() {
+			fn(d)
+			if refs.done() {
+				c.releaseDanmaku(d)
+			}
+		})
+	}
+	if len(c.batcher.handlers) > 0 {
+		c.batchDanmaku(d, refs)
+	}
+	if n == 0 {
+		c.releaseDanmaku(d)
+	}
+}
+
 // parseCmd 获取 JSON 报文的 CMD
 func parseCmd(d []byte) string {
 	// {"cmd":"DANMU_MSG", ...
@@ -152,10 +117,12 @@ func parseCmd(d []byte) string {
 	return utils.BytesToString(d[8:pos])
 }
 
-func cover(f func()) {
+func (c *Client) cover(f func()) {
 	defer func() {
 		if pan := recover(); pan != nil {
-			log.Errorf("event error: %v\n%s", pan, debug.Stack())
+			stack := debug.Stack()
+			log.Errorf("event error: %v\n%s", pan, stack)
+			c.reportError(&HandlerPanicError{Value: pan, Stack: stack})
 		}
 	}()
 	f()
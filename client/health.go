@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// RoomHealth 单个房间的健康状态
+type RoomHealth struct {
+	RoomID        string    `json:"room_id"`
+	Healthy       bool      `json:"healthy"`
+	Connected     bool      `json:"connected"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	Reconnects    uint64    `json:"reconnects"`
+}
+
+// HealthReport 健康检查的结果
+type HealthReport struct {
+	Healthy bool          `json:"healthy"`
+	Rooms   []*RoomHealth `json:"rooms"`
+}
+
+// roomHealth 根据统计信息判断房间是否健康，maxSilence 为 0 时不检查最近收到消息的时间
+func roomHealth(roomID string, s Stats, maxSilence time.Duration) *RoomHealth {
+	h := &RoomHealth{
+		RoomID:        roomID,
+		Connected:     s.Connected,
+		ConnectedAt:   s.ConnectedAt,
+		LastMessageAt: s.LastMessageAt,
+		Reconnects:    s.Reconnects,
+	}
+	h.Healthy = s.Connected
+	if h.Healthy && maxSilence > 0 {
+		last := s.LastMessageAt
+		if last.IsZero() {
+			last = s.ConnectedAt
+		}
+		h.Healthy = time.Since(last) <= maxSilence
+	}
+	return h
+}
+
+// writeHealth 输出健康检查结果，不健康时返回 503
+func writeHealth(w http.ResponseWriter, report *HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// HealthHandler 返回报告该 client 健康状态的 http.Handler，可用于 Kubernetes 存活、就绪探针
+//
+// 未连接或超过 maxSilence 没有收到消息时返回 503，maxSilence 为 0 时不检查
+func (c *Client) HealthHandler(maxSilence time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := roomHealth(c.tempID, c.Stats(), maxSilence)
+		writeHealth(w, &HealthReport{Healthy: h.Healthy, Rooms: []*RoomHealth{h}})
+	})
+}
+
+// HealthHandler 返回报告全部房间健康状态的 http.Handler，任一房间不健康时返回 503
+func (m *RoomManager) HealthHandler(maxSilence time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		rooms := make(map[string]*Client, len(m.rooms))
+		for id, c := range m.rooms {
+			rooms[id] = c
+		}
+		m.mu.Unlock()
+		report := &HealthReport{Healthy: true, Rooms: make([]*RoomHealth, 0, len(rooms))}
+		for id, c := range rooms {
+			h := roomHealth(id, c.Stats(), maxSilence)
+			report.Healthy = report.Healthy && h.Healthy
+			report.Rooms = append(report.Rooms, h)
+		}
+		sort.Slice(report.Rooms, func(i, j int) bool { return report.Rooms[i].RoomID < report.Rooms[j].RoomID })
+		writeHealth(w, report)
+	})
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/api"
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+// maxRoomHistory 每个房间最多保留的标题、分区变化记录数
+const maxRoomHistory = 64
+
+// RoomInfoChange 直播间标题、分区的一次变化
+type RoomInfoChange struct {
+	Time           time.Time
+	Title          string
+	AreaId         int
+	AreaName       string
+	ParentAreaId   int
+	ParentAreaName string
+}
+
+type roomHistory struct {
+	sync.Mutex
+	rooms map[string][]RoomInfoChange
+}
+
+func newRoomHistory() *roomHistory {
+	return &roomHistory{rooms: make(map[string][]RoomInfoChange)}
+}
+
+func (h *roomHistory) add(roomID string, change RoomInfoChange) {
+	h.Lock()
+	defer h.Unlock()
+	list := h.rooms[roomID]
+	if n := len(list); n > 0 {
+		last := list[n-1]
+		if last.Title == change.Title && last.AreaId == change.AreaId {
+			return
+		}
+	}
+	list = append(list, change)
+	if len(list) > maxRoomHistory {
+		list = list[len(list)-maxRoomHistory:]
+	}
+	h.rooms[roomID] = list
+}
+
+// empty roomID 是否还没有任何记录
+func (h *roomHistory) empty(roomID string) bool {
+	h.Lock()
+	defer h.Unlock()
+	return len(h.rooms[roomID]) == 0
+}
+
+func (h *roomHistory) remove(roomID string) {
+	h.Lock()
+	delete(h.rooms, roomID)
+	h.Unlock()
+}
+
+func (h *roomHistory) reset() {
+	h.Lock()
+	h.rooms = make(map[string][]RoomInfoChange)
+	h.Unlock()
+}
+
+// trackHistory 记录 client 通过 get_info 接口获取的标题与分区，并在 ROOM_CHANGE 时追加记录
+//
+// 开启开播检查或直播间状态跟踪时以其获取的信息作为初始记录，否则由 seedHistory 在房间启动后请求一次
+func (m *RoomManager) trackHistory(roomID string, c *Client) {
+	c.onRoomInfo = func(info *api.LiveRoomInfo) {
+		d := info.Data
+		m.history.add(roomID, RoomInfoChange{
+			Time:           time.Now(),
+			Title:          d.Title,
+			AreaId:         d.AreaId,
+			AreaName:       d.AreaName,
+			ParentAreaId:   d.ParentAreaId,
+			ParentAreaName: d.ParentAreaName,
+		})
+	}
+	c.OnRoomChange(func(r *message.RoomChange) {
+		m.history.add(roomID, RoomInfoChange{
+			Time:           r.ReceivedAt,
+			Title:          r.Title,
+			AreaId:         r.AreaId,
+			AreaName:       r.AreaName,
+			ParentAreaId:   r.ParentAreaId,
+			ParentAreaName: r.ParentAreaName,
+		})
+	})
+}
+
+// seedHistory 房间启动后还没有记录时请求一次 get_info 作为初始记录，使 RoomInfoAt 在第一次 ROOM_CHANGE 之前也有结果
+func (m *RoomManager) seedHistory(roomID string, c *Client) {
+	if !m.history.empty(roomID) {
+		return
+	}
+	if _, err := c.fetchRoomInfo(); err != nil {
+		c.reportError(&APIError{API: "get_info", Err: err})
+	}
+}
+
+// fetchRoomInfo 通过 get_info 接口获取直播间信息，成功时交给 trackHistory 记录
+func (c *Client) fetchRoomInfo() (*api.LiveRoomInfo, error) {
+	info, err := c.apiClient.GetLiveRoomInfo(c.roomID)
+	if err == nil && c.onRoomInfo != nil {
+		c.onRoomInfo(info)
+	}
+	return info, err
+}
+
+// RoomHistory 获取房间的标题、分区变化记录，按时间从早到晚排列
+func (m *RoomManager) RoomHistory(roomID string) []RoomInfoChange {
+	m.mu.Lock()
+	roomID = m.key(roomID)
+	m.mu.Unlock()
+	m.history.Lock()
+	defer m.history.Unlock()
+	return append([]RoomInfoChange(nil), m.history.rooms[roomID]...)
+}
+
+// RoomInfoAt 获取房间在 t 时刻的标题与分区，没有记录时返回 nil
+func (m *RoomManager) RoomInfoAt(roomID string, t time.Time) *RoomInfoChange {
+	m.mu.Lock()
+	roomID = m.key(roomID)
+	m.mu.Unlock()
+	m.history.Lock()
+	defer m.history.Unlock()
+	var found *RoomInfoChange
+	for i, ch := range m.history.rooms[roomID] {
+		if ch.Time.After(t) {
+			break
+		}
+		found = &m.history.rooms[roomID][i]
+	}
+	if found == nil {
+		return nil
+	}
+	ch := *found
+	return &ch
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoomHistorySeededOnAdd(t *testing.T) {
+	quietLogs(t)
+	var conns int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "room_init"):
+			w.Write([]byte(`{"code":0,"data":{"room_id":8792912,"uid":1}}`))
+		case strings.Contains(r.URL.Path, "get_info"):
+			w.Write([]byte(`{"code":0,"data":{"room_id":8792912,"title":"标题","area_id":86,"area_name":"英雄联盟"}}`))
+		default:
+			w.Write([]byte(`{"code":0,"data":{"token":"t","host_list":[]}}`))
+		}
+	}))
+	ws := newFlakyServer(&conns)
+	defer ws.Close()
+	defer api.Close()
+
+	m := NewRoomManager("0", "", "", "")
+	defer m.Stop()
+	m.SetDuplicatePolicy(DuplicateShare)
+	m.OnClient(func(roomID string, c *Client) { useServers(c, api, ws) })
+	if _, err := m.AddRoom("8792912"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.AddRoomWithOptions("510", &RoomOptions{APIBaseURL: api.URL}); err != nil {
+		t.Fatal(err)
+	}
+	waitConns(t, &conns, 1)
+
+	if h := m.RoomHistory("8792912"); len(h) != 1 || h[0].Title != "标题" || h[0].AreaId != 86 {
+		t.Fatalf("RoomHistory = %+v, want one record from get_info", h)
+	}
+	info := m.RoomInfoAt("510", time.Now())
+	if info == nil || info.AreaName != "英雄联盟" {
+		t.Errorf("RoomInfoAt via alias = %+v, want the seeded record", info)
+	}
+}
+
+func TestRoomManagerForgetsFailedAndStoppedRooms(t *testing.T) {
+	quietLogs(t)
+	var conns int32
+	api, ws := newAPIServer(), newFlakyServer(&conns)
+	defer ws.Close()
+	defer api.Close()
+
+	m := NewRoomManager("0", "", "", "")
+	if _, err := m.AddRoomWithOptions("8792912", &RoomOptions{APIBaseURL: api.URL, AllowHosts: []string{"none"}}); err == nil {
+		t.Fatal("AddRoom with no allowed host succeeded")
+	}
+	if opts, ok := m.options["8792912"]; ok {
+		t.Errorf("options of the failed room kept: %+v", opts)
+	}
+
+	m.OnClient(func(roomID string, c *Client) { useServers(c, api, ws) })
+	if _, err := m.AddRoom("8792912"); err != nil {
+		t.Fatal(err)
+	}
+	m.history.add("8792912", RoomInfoChange{Time: time.Now(), Title: "标题"})
+	m.Stop()
+	if h := m.RoomHistory("8792912"); len(h) != 0 {
+		t.Errorf("RoomHistory after Stop = %+v, want none", h)
+	}
+	if len(m.adding) != 0 {
+		t.Errorf("adding after Stop = %v, want none", m.adding)
+	}
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNoAllowedHost 弹幕服务器列表经过 AllowHosts 与 DenyHosts 过滤后为空
+var ErrNoAllowedHost = errors.New("no allowed danmaku host")
+
+// hostFilter 弹幕服务器的允许与排除列表
+type hostFilter struct {
+	allow []string
+	deny  []string
+}
+
+// AllowHosts 只连接匹配的弹幕服务器，支持 path.Match 的通配符，如 *.chat.bilibili.com，需要在 Start 之前调用
+//
+// 过滤作用于 getDanmuInfo 返回的服务器列表，SetHost 指定的服务器不受影响
+func (c *Client) AllowHosts(patterns ...string) {
+	c.hostFilter.allow = append(c.hostFilter.allow, patterns...)
+}
+
+// DenyHosts 不连接匹配的弹幕服务器，如所在地区无法访问的节点，优先于 AllowHosts，需要在 Start 之前调用
+func (c *Client) DenyHosts(patterns ...string) {
+	c.hostFilter.deny = append(c.hostFilter.deny, patterns...)
+}
+
+// filterHosts 过滤服务器列表，没有设置过滤时原样返回
+func (f *hostFilter) filterHosts(hosts []string) ([]string, error) {
+	if len(f.allow) == 0 && len(f.deny) == 0 {
+		return hosts, nil
+	}
+	kept := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		if matchHost(f.deny, h) {
+			continue
+		}
+		if len(f.allow) > 0 && !matchHost(f.allow, h) {
+			continue
+		}
+		kept = append(kept, h)
+	}
+	if len(kept) == 0 {
+		log.Warnf("all danmaku hosts filtered out: %s", strings.Join(hosts, ", "))
+		return nil, ErrNoAllowedHost
+	}
+	return kept, nil
+}
+
+// matchHost host 是否匹配任一模式，忽略大小写
+func matchHost(patterns []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if p == host {
+			return true
+		}
+		if ok, _ := path.Match(p, host); ok {
+			return true
+		}
+	}
+	return false
+}
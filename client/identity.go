@@ -0,0 +1,117 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/RemKeeper/blivedm-go/api"
+)
+
+// sessionOrigin 开启 EnableSessionHeaders 时握手使用的 Origin
+const sessionOrigin = "https://live.bilibili.com"
+
+// identity 一次连接使用的身份信息，同一次连接的接口请求与 websocket 握手使用相同的 User-Agent 与 buvid
+type identity struct {
+	userAgent string // 当前连接使用的 User-Agent，为空时重新选择
+	used      bool   // 是否已用于握手，用过之后的下一次连接会重新选择 User-Agent
+	headers   bool   // 握手是否附带与接口请求相同的 Cookie、Referer 与 Origin
+}
+
+// EnableSessionHeaders 握手时附带与接口请求相同的 buvid3 Cookie，并补充 Referer 与 Origin，减少与浏览器指纹不一致导致的风控
+//
+// 未设置 buvid 时生成一个供接口请求、握手与进房共同使用，未设置 referer 时使用直播间的地址
+func (c *Client) EnableSessionHeaders() {
+	c.credMu.Lock()
+	c.identity.headers = true
+	c.credMu.Unlock()
+}
+
+// sessionUserAgent 获取当前连接使用的 User-Agent，设置了 SetUserAgentFunc 时每次连接只调用一次
+func (c *Client) sessionUserAgent() string {
+	c.credMu.Lock()
+	defer c.credMu.Unlock()
+	return c.sessionUserAgentLocked()
+}
+
+func (c *Client) sessionUserAgentLocked() string {
+	if c.userAgentFunc == nil {
+		return c.userAgent
+	}
+	if c.identity.userAgent == "" {
+		c.identity.userAgent = c.userAgentFunc()
+	}
+	return c.identity.userAgent
+}
+
+// sessionBuvid 获取接口请求与进房使用的 buvid
+func (c *Client) sessionBuvid() string {
+	c.credMu.Lock()
+	defer c.credMu.Unlock()
+	return c.buvidLocked()
+}
+
+func (c *Client) buvidLocked() string {
+	if c.buvid == "" && c.identity.headers {
+		c.buvid = api.NewBuvid()
+	}
+	return c.buvid
+}
+
+// rotateSession 开始一次新的连接，之前的 User-Agent 已用于握手时重新选择
+func (c *Client) rotateSession() {
+	c.credMu.Lock()
+	if c.identity.used {
+		c.identity.userAgent = ""
+		c.identity.used = false
+	}
+	c.credMu.Unlock()
+}
+
+// bindAPISession 使该 client 的接口请求使用与握手相同的 User-Agent 与 buvid
+func (c *Client) bindAPISession() {
+	a := c.ownAPIClient()
+	prev := a.UserAgent
+	if prev != nil && a.Buvid != nil {
+		// 已经绑定过
+		return
+	}
+	a.UserAgent = func() string {
+		if ua := c.sessionUserAgent(); ua != "" {
+			return ua
+		}
+		if prev != nil {
+			return prev()
+		}
+		return ""
+	}
+	a.Buvid = c.sessionBuvid
+}
+
+func (c *Client) getHeader() http.Header {
+	c.credMu.Lock()
+	userAgent, referer, buvid := c.sessionUserAgentLocked(), c.referer, c.buvidLocked()
+	c.identity.used = true
+	headers := c.identity.headers
+	c.credMu.Unlock()
+	if headers && referer == "" && c.roomID != "" {
+		referer = sessionOrigin + "/" + c.roomID
+	}
+	if userAgent == "" && referer == "" && !headers {
+		return nil
+	}
+
+	header := http.Header{}
+
+	if userAgent != "" {
+		header.Set("User-Agent", userAgent)
+	}
+	if referer != "" {
+		header.Set("Referer", referer)
+	}
+	if headers {
+		header.Set("Origin", sessionOrigin)
+		if buvid != "" {
+			header.Set("Cookie", "buvid3="+buvid)
+		}
+	}
+	return header
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// goLoop 在带有 pprof 标签的 goroutine 中运行 client 的后台循环
+//
+// 标签为 room_id 与 loop，其中创建的 goroutine（如异步分发的处理器）会继承这些标签，
+// 在 goroutine profile 中可以按房间区分，Wait 会等待这些循环退出
+func (c *Client) goLoop(name string, f func()) {
+	if c.stopped() {
+		return
+	}
+	roomID := c.roomID
+	if roomID == "" {
+		roomID = c.tempID
+	}
+	c.loops.Add(1)
+	go pprof.Do(context.Background(), pprof.Labels("room_id", roomID, "loop", name), func(context.Context) {
+		defer c.loops.Done()
+		f()
+	})
+}
+
+// Wait 等待 Stop 之后 client 的全部后台循环退出，可用于检查 goroutine 泄漏
+//
+// 异步分发中仍在执行的处理器不会等待
+func (c *Client) Wait() {
+	c.loops.Wait()
+}
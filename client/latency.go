@@ -0,0 +1,85 @@
+package client
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// metaHolder 带有元信息的消息
+type metaHolder interface {
+	EventMeta() *message.Meta
+}
+
+// stamp 填充消息的元信息，并统计消息延迟
+func (c *Client) stamp(p packet.Packet, v interface{}) {
+	if m, timed := c.fillMeta(p, v); timed {
+		c.stats.countLatency(m.Latency)
+	}
+}
+
+// fillMeta 填充消息的元信息，消息带有服务端时间时 timed 为 true
+func (c *Client) fillMeta(p packet.Packet, v interface{}) (m *message.Meta, timed bool) {
+	h, ok := v.(metaHolder)
+	if !ok {
+		return nil, false
+	}
+	m = h.EventMeta()
+	m.RoomID, _ = strconv.Atoi(c.roomID)
+	m.ReceivedAt = p.ReceivedAt
+	m.Seq = p.Seq
+	if m.ReceivedAt.IsZero() {
+		m.ReceivedAt = time.Now()
+	}
+	st, precise := serverTime(v)
+	if st.IsZero() {
+		return m, false
+	}
+	m.ReceivedAt = c.skew.correct(m.ReceivedAt, st, precise)
+	m.Latency = m.ReceivedAt.Sub(st)
+	return m, true
+}
+
+// serverTime 消息中携带的服务端时间，不带时间戳时返回零值，precise 表示时间戳精确到毫秒
+func serverTime(v interface{}) (t time.Time, precise bool) {
+	var sec, ms int64
+	switch m := v.(type) {
+	case *message.Danmaku:
+		ms = m.Timestamp
+	case *message.Gift:
+		sec = int64(m.Timestamp)
+	case *message.SuperChat:
+		sec = int64(m.StartTime)
+	case *message.GuardBuy:
+		sec = int64(m.StartTime)
+	case *message.UserToast:
+		sec = int64(m.StartTime)
+	case *message.WidgetBanner:
+		sec = int64(m.Timestamp)
+	case *message.InteractWord:
+		sec = int64(m.Timestamp)
+	}
+	if ms > 0 {
+		return time.Unix(0, ms*int64(time.Millisecond)), true
+	}
+	if sec > 0 {
+		return time.Unix(sec, 0), false
+	}
+	return time.Time{}, false
+}
+
+// countLatency 统计一次消息延迟
+func (s *stats) countLatency(d time.Duration) {
+	atomic.AddUint64(&s.latencySamples, 1)
+	atomic.AddInt64(&s.latencySum, int64(d))
+	atomic.StoreInt64(&s.lastLatency, int64(d))
+	for {
+		max := atomic.LoadInt64(&s.maxLatency)
+		if int64(d) <= max || atomic.CompareAndSwapInt64(&s.maxLatency, max, int64(d)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+// enterReply 进房成功的回复
+var enterReply = []byte{0, 0, 0, 16, 0, 16, 0, 1, 0, 0, 0, 8, 0, 0, 0, 1}
+
+// newAPIServer 模拟 room_init 与 getDanmuInfo 接口
+func newAPIServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "room_init") {
+			w.Write([]byte(`{"code":0,"data":{"room_id":8792912,"uid":1}}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"data":{"token":"t","host_list":[]}}`))
+	}))
+}
+
+// newFlakyServer 模拟不断断开的弹幕服务器：奇数次连接进房成功后立即断开，偶数次连接直接拒绝握手
+func newFlakyServer(conns *int32) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(conns, 1)%2 == 0 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, _, err = conn.ReadMessage(); err != nil {
+			return
+		}
+		conn.WriteMessage(websocket.BinaryMessage, enterReply)
+	}))
+}
+
+func useServers(c *Client, api, ws *httptest.Server) {
+	c.SetAPIBaseURL(api.URL)
+	c.SetHost(strings.TrimPrefix(ws.URL, "https://"))
+	c.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	c.SetReconnectPolicy(ReconnectPolicy{Delay: 10 * time.Millisecond})
+}
+
+// waitConns 等待弹幕服务器收到至少 n 次连接
+func waitConns(t *testing.T, conns *int32, n int32) {
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(conns) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d connections, want at least %d", atomic.LoadInt32(conns), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// checkGoroutines 关闭测试服务器后等待 goroutine 数量回到 base，超时时输出仍在运行的 goroutine
+func checkGoroutines(t *testing.T, base int, servers ...*httptest.Server) {
+	for _, s := range servers {
+		s.CloseClientConnections()
+		s.Close()
+	}
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > base {
+		if time.Now().After(deadline) {
+			var buf bytes.Buffer
+			pprof.Lookup("goroutine").WriteTo(&buf, 1)
+			t.Fatalf("%d goroutines left behind, want %d:\n%s", runtime.NumGoroutine(), base, buf.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func quietLogs(t *testing.T) {
+	level := log.GetLevel()
+	log.SetLevel(log.PanicLevel)
+	t.Cleanup(func() { log.SetLevel(level) })
+}
+
+func TestClientStopAfterFailedReconnects(t *testing.T) {
+	quietLogs(t)
+	base := runtime.NumGoroutine()
+	var conns int32
+	api, ws := newAPIServer(), newFlakyServer(&conns)
+	c := NewClient("8792912", "0", "", "", "")
+	useServers(c, api, ws)
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitConns(t, &conns, 8)
+	c.Stop()
+	c.Wait()
+	checkGoroutines(t, base, api, ws)
+}
+
+func TestRoomManagerAddRemoveRoom(t *testing.T) {
+	quietLogs(t)
+	base := runtime.NumGoroutine()
+	var conns int32
+	api, ws := newAPIServer(), newFlakyServer(&conns)
+	m := NewRoomManager("0", "", "", "")
+	m.OnClient(func(roomID string, c *Client) { useServers(c, api, ws) })
+	for i := 0; i < 3; i++ {
+		c, err := m.AddRoom("8792912")
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitConns(t, &conns, atomic.LoadInt32(&conns)+4)
+		m.RemoveRoom("8792912")
+		c.Wait()
+	}
+	m.Stop()
+	checkGoroutines(t, base, api, ws)
+}
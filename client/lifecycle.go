@@ -0,0 +1,69 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ConnectEvent 连接建立的信息
+type ConnectEvent struct {
+	Host    string
+	Attempt int // 本次连接尝试的次数，首次尝试即成功时为 1
+}
+
+// DisconnectEvent 连接断开的信息
+type DisconnectEvent struct {
+	Host      string
+	Err       error         // 断开的原因，调用 Stop 或因下播断开时为 nil
+	CloseCode int           // websocket 关闭帧的状态码，见 CloseCode
+	Class     CloseClass    // 断开原因的分类，调用 Stop 或因下播断开时为 CloseNormal
+	Duration  time.Duration // 本次连接持续的时间
+}
+
+// lifecycle 连接生命周期的处理器
+type lifecycle struct {
+	connectHandlers    []func(*ConnectEvent)
+	disconnectHandlers []func(*DisconnectEvent)
+}
+
+// OnConnect 添加 连接建立 的处理器，在进房包得到服务器响应后调用，每次重连成功都会调用
+//
+// 处理器在连接所在的 goroutine 中执行，不应阻塞
+func (c *Client) OnConnect(f func(*ConnectEvent)) {
+	c.lifecycle.connectHandlers = append(c.lifecycle.connectHandlers, f)
+}
+
+// OnDisconnect 添加 连接断开 的处理器，只在之前处于连接状态时调用，之后 client 会自动重连
+//
+// 处理器在连接所在的 goroutine 中执行，不应阻塞
+func (c *Client) OnDisconnect(f func(*DisconnectEvent)) {
+	c.lifecycle.disconnectHandlers = append(c.lifecycle.disconnectHandlers, f)
+}
+
+// connected 记录连接建立并调用 OnConnect 的处理器
+func (c *Client) connected(attempt int) {
+	c.stats.setConnected(true)
+	e := &ConnectEvent{Host: c.host, Attempt: attempt}
+	for _, fn := range c.lifecycle.connectHandlers {
+		fn := fn
+		c.cover(func() { fn(e) })
+	}
+}
+
+// disconnected 记录连接断开，之前处于连接状态时调用 OnDisconnect 的处理器，err 不为 nil 时计入重连次数
+func (c *Client) disconnected(err error, class CloseClass) {
+	if atomic.SwapInt32(&c.stats.connected, 0) != 1 {
+		return
+	}
+	if err != nil {
+		atomic.AddUint64(&c.stats.reconnects, 1)
+	}
+	e := &DisconnectEvent{Host: c.host, Err: err, CloseCode: CloseCode(err), Class: class}
+	if at := atomic.LoadInt64(&c.stats.connectedAt); at > 0 {
+		e.Duration = time.Since(time.Unix(0, at))
+	}
+	for _, fn := range c.lifecycle.disconnectHandlers {
+		fn := fn
+		c.cover(func() { fn(e) })
+	}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	LiveCheckOff    = iota // 不检查开播状态，直接连接，默认
+	LiveCheckDefer         // Start 时未开播则推迟连接，轮询到开播或调用 NotifyLive 后再连接，下播后断开并重新等待
+	LiveCheckNotify        // 与 LiveCheckDefer 相同，但不自行轮询，只等待 NotifyLive，用于由外部统一检查开播状态
+)
+
+// defaultLiveCheckInterval 推迟连接时轮询开播状态的默认间隔
+const defaultLiveCheckInterval = time.Minute
+
+// liveCheck 推迟连接的配置与状态
+type liveCheck struct {
+	mode     int
+	interval time.Duration
+	waiting  int32
+	session  int32 // 每次下播断开时加一，旧连接的读取与心跳循环据此退出
+	notify   chan struct{}
+	hooked   bool
+}
+
+// SetLiveCheck 设置 Start 时是否检查开播状态，interval 为推迟连接时轮询开播状态的间隔，为 0 时使用 1 分钟
+//
+// 使用 LiveCheckDefer 时未开播的房间 Start 直接返回 nil 而不连接弹幕服务器，收到 PREPARING 下播后断开连接并重新等待开播，
+// 监控大量大多未开播的房间时可以节省连接，需要在 Start 之前调用
+func (c *Client) SetLiveCheck(mode int, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultLiveCheckInterval
+	}
+	c.live.mode = mode
+	c.live.interval = interval
+	if c.live.notify == nil {
+		c.live.notify = make(chan struct{}, 1)
+	}
+	if mode != LiveCheckOff && !c.live.hooked {
+		c.live.hooked = true
+		c.OnPacket(func(p packet.Packet) {
+			if p.Operation == packet.Notification && len(p.Body) > 8 && parseCmd(p.Body) == "PREPARING" {
+				c.suspend()
+			}
+		})
+	}
+}
+
+// NotifyLive 通知房间已开播，推迟连接的 client 立即连接，可用于接入外部的开播通知，没有推迟连接时不做任何事
+func (c *Client) NotifyLive() {
+	if c.live.notify == nil || !c.WaitingForLive() {
+		return
+	}
+	select {
+	case c.live.notify <- struct{}{}:
+	default:
+	}
+}
+
+// WaitingForLive client 是否因未开播而推迟了连接
+func (c *Client) WaitingForLive() bool {
+	return atomic.LoadInt32(&c.live.waiting) == 1
+}
+
+// isLive 通过接口检查是否正在直播，调用失败时视为正在直播，避免错过弹幕
+func (c *Client) isLive() bool {
+	info, err := c.fetchRoomInfo()
+	if err != nil {
+		c.reportError(&APIError{API: "get_info", Err: err})
+		return true
+	}
+	return info.Data.LiveStatus == 1
+}
+
+// deferUntilLive 未开播时开始等待开播并返回 true
+func (c *Client) deferUntilLive() bool {
+	if c.live.mode == LiveCheckOff || c.isLive() {
+		return false
+	}
+	log.Debugf("room %s is offline, defer connecting", c.roomID)
+	atomic.StoreInt32(&c.live.waiting, 1)
+	c.goLoop("liveWaitLoop", c.liveWaitLoop)
+	return true
+}
+
+// suspend 下播后断开连接并重新等待开播
+func (c *Client) suspend() {
+	if !atomic.CompareAndSwapInt32(&c.live.waiting, 0, 1) {
+		return
+	}
+	log.Debugf("room %s went offline, disconnecting", c.roomID)
+	atomic.AddInt32(&c.live.session, 1)
+	c.disconnected(nil, CloseNormal)
+	c.setConn(nil)
+	c.goLoop("liveWaitLoop", c.liveWaitLoop)
+}
+
+// sessionEnded 开始于 session 的连接是否已因下播断开
+func (c *Client) sessionEnded(session int32) bool {
+	return atomic.LoadInt32(&c.live.session) != session
+}
+
+// liveWaitLoop 轮询开播状态，开播后连接弹幕服务器
+func (c *Client) liveWaitLoop() {
+	for {
+		var poll <-chan time.Time
+		if c.live.mode != LiveCheckNotify {
+			poll = time.After(c.live.interval)
+		}
+		select {
+		case <-c.done:
+			return
+		case <-c.live.notify:
+		case <-poll:
+			info, err := c.fetchRoomInfo()
+			if err != nil {
+				c.reportError(&APIError{API: "get_info", Err: err})
+				continue
+			}
+			if info.Data.LiveStatus != 1 {
+				continue
+			}
+		}
+		log.Debugf("room %s went live, connecting", c.roomID)
+		atomic.StoreInt32(&c.live.waiting, 0)
+		if err := c.serve(); err != nil && err != errStopped {
+			log.Error("connect after going live failed: ", err)
+			c.Stop()
+		}
+		return
+	}
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultLogSampleWindow 默认的重复日志统计窗口
+	DefaultLogSampleWindow = time.Minute
+	// DefaultLogSampleBurst 默认每个窗口内同一条日志最多输出的次数
+	DefaultLogSampleBurst = 5
+)
+
+// logSampler 抑制重连风暴等场景中大量重复的日志，窗口结束时输出一条被抑制次数的汇总
+//
+// 日志按 key 区分，零值使用默认的窗口与次数
+type logSampler struct {
+	mu       sync.Mutex
+	window   time.Duration
+	burst    int
+	disabled bool
+	sites    map[string]*logSite
+}
+
+// logSite 一种日志在当前窗口内的计数
+type logSite struct {
+	start      time.Time
+	count      int
+	suppressed int
+	level      log.Level
+}
+
+// SetLogSampling 设置重复日志的采样，同一条日志在 window 内最多输出 burst 次，
+// 之后的被抑制并在窗口结束时汇总为一条，如 "connect dial failed 1433 times in last 60s"
+//
+// 默认为 DefaultLogSampleWindow 内 DefaultLogSampleBurst 次，window 为 0 时不采样
+func (c *Client) SetLogSampling(window time.Duration, burst int) {
+	s := &c.logs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disabled = window <= 0
+	s.window = window
+	if burst < 1 {
+		burst = 1
+	}
+	s.burst = burst
+}
+
+// logf 以 level 输出一条日志，key 相同的日志共享计数
+func (s *logSampler) logf(level log.Level, key string, format string, args ...interface{}) {
+	if !s.allow(level, key) {
+		return
+	}
+	log.StandardLogger().Logf(level, format, args...)
+}
+
+func (s *logSampler) allow(level log.Level, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disabled {
+		return true
+	}
+	window, burst := s.window, s.burst
+	if window == 0 {
+		window, burst = DefaultLogSampleWindow, DefaultLogSampleBurst
+	}
+	now := time.Now()
+	site := s.sites[key]
+	if site == nil {
+		if s.sites == nil {
+			s.sites = make(map[string]*logSite)
+		}
+		site = &logSite{start: now}
+		s.sites[key] = site
+	}
+	// 窗口内有被抑制的日志时由定时器汇总并开始新的窗口
+	if site.suppressed == 0 && now.Sub(site.start) >= window {
+		site.start, site.count = now, 0
+	}
+	site.count++
+	if site.count <= burst {
+		return true
+	}
+	site.suppressed++
+	if site.suppressed == 1 {
+		site.level = level
+		time.AfterFunc(site.start.Add(window).Sub(now), func() { s.summarize(key, window) })
+	}
+	return false
+}
+
+// summarize 输出 key 在窗口内的汇总并开始新的窗口
+func (s *logSampler) summarize(key string, window time.Duration) {
+	s.mu.Lock()
+	site := s.sites[key]
+	if site == nil || site.suppressed == 0 {
+		s.mu.Unlock()
+		return
+	}
+	count, suppressed, level := site.count, site.suppressed, site.level
+	delete(s.sites, key)
+	s.mu.Unlock()
+	log.StandardLogger().Logf(level, "%s %d times in last %s (%d suppressed)", key, count, formatWindow(window), suppressed)
+}
+
+// formatWindow 整秒的窗口格式化为 60s 的形式
+func formatWindow(d time.Duration) string {
+	if d%time.Second == 0 {
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	return d.String()
+}
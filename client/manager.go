@@ -0,0 +1,325 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrRoomExists 房间已经在 RoomManager 中
+var ErrRoomExists = errors.New("room already added")
+
+const (
+	DuplicateReject = iota // 重复添加同一个房间时返回 ErrRoomExists，默认
+	DuplicateShare         // 重复添加同一个房间时返回已有的 client，并增加引用计数
+)
+
+// RoomManager 管理多个直播间的弹幕 client
+type RoomManager struct {
+	mu        sync.Mutex
+	rooms     map[string]*Client
+	realRooms map[string]string         // 真实房间号到 rooms 中的房间号
+	aliases   map[string]string         // 指向同一个真实房间的其他房间号到 rooms 中的房间号
+	refs      map[string]map[string]int // rooms 中的房间号到每个房间号添加的次数
+	adding    map[string]*pendingAdd    // 正在确定真实房间号与启动的房间
+	duplicate int
+	options   map[string]*RoomOptions
+	manifest  string
+	saver     manifestWriter
+	setups    []func(roomID string, c *Client)
+	roomSetup map[string][]func(c *Client)
+	enterUID  string
+	buvid     string
+	userAgent string
+	referer   string
+	history   *roomHistory
+	live      liveScheduler
+	shutdown  []func(ctx context.Context) error
+}
+
+// NewRoomManager 创建一个 RoomManager，参数会用于创建每个房间的 client，含义与 NewClient 相同
+func NewRoomManager(enterUID string, buvid string, userAgent string, referer string) *RoomManager {
+	return &RoomManager{
+		rooms:     make(map[string]*Client),
+		realRooms: make(map[string]string),
+		aliases:   make(map[string]string),
+		refs:      make(map[string]map[string]int),
+		adding:    make(map[string]*pendingAdd),
+		options:   make(map[string]*RoomOptions),
+		roomSetup: make(map[string][]func(c *Client)),
+		enterUID:  enterUID,
+		buvid:     buvid,
+		userAgent: userAgent,
+		referer:   referer,
+		history:   newRoomHistory(),
+	}
+}
+
+// OnClient 添加 client 创建后、启动前调用的处理器，可用于注册事件处理器与修改配置
+func (m *RoomManager) OnClient(f func(roomID string, c *Client)) {
+	m.mu.Lock()
+	m.setups = append(m.setups, f)
+	m.mu.Unlock()
+}
+
+// OnRoom 添加只对 roomID 房间生效的处理器，在该房间的 client 创建后、启动前调用，需要在 AddRoom 之前调用
+//
+// 全部房间都需要的处理器使用 OnClient 注册，事件中的 Meta.RoomID 为事件所属的真实房间号
+func (m *RoomManager) OnRoom(roomID string, f func(c *Client)) {
+	m.mu.Lock()
+	m.roomSetup[roomID] = append(m.roomSetup[roomID], f)
+	m.mu.Unlock()
+}
+
+// RegisterCustomEventHandler 为全部房间注册 自定义事件 的处理器，roomID 为 AddRoom 时使用的房间号
+func (m *RoomManager) RegisterCustomEventHandler(cmd string, handler func(roomID string, s string)) {
+	m.OnClient(func(roomID string, c *Client) {
+		c.RegisterCustomEventHandler(cmd, func(s string) { handler(roomID, s) })
+	})
+}
+
+// AddRoom 添加并启动一个直播间的 client
+func (m *RoomManager) AddRoom(roomID string) (*Client, error) {
+	m.mu.Lock()
+	opts := m.options[roomID]
+	m.mu.Unlock()
+	return m.AddRoomWithOptions(roomID, opts)
+}
+
+// SetDuplicatePolicy 设置重复添加同一个房间时的处理方式，DuplicateReject 或 DuplicateShare
+//
+// 短号与长号指向同一个真实房间时也视为重复添加，使用 DuplicateShare 时 RemoveRoom 的次数与添加的次数相同才会断开连接
+func (m *RoomManager) SetDuplicatePolicy(policy int) {
+	m.mu.Lock()
+	m.duplicate = policy
+	m.mu.Unlock()
+}
+
+// key 获取房间号在 rooms 中对应的房间号，调用时需要持有 m.mu
+func (m *RoomManager) key(roomID string) string {
+	if key, ok := m.aliases[roomID]; ok {
+		return key
+	}
+	return roomID
+}
+
+// share 重复添加 key 房间，返回已有的 client，调用时需要持有 m.mu
+func (m *RoomManager) share(roomID string, key string) (*Client, error) {
+	if m.duplicate != DuplicateShare {
+		return nil, ErrRoomExists
+	}
+	if roomID != key {
+		m.aliases[roomID] = key
+	}
+	m.refs[key][roomID]++
+	return m.rooms[key], nil
+}
+
+// pendingAdd 正在添加的房间，重复添加的调用等待其完成后共享结果
+type pendingAdd struct {
+	done chan struct{}
+	err  error
+}
+
+// join 将 roomID 作为 key 房间的重复添加，key 正在添加时等待添加完成，key 不存在时 done 为 false
+//
+// 调用时需要持有 m.mu，等待期间会释放
+func (m *RoomManager) join(roomID string, key string) (c *Client, done bool, err error) {
+	for {
+		if p := m.adding[key]; p != nil {
+			if m.duplicate != DuplicateShare {
+				return nil, true, ErrRoomExists
+			}
+			m.mu.Unlock()
+			<-p.done
+			m.mu.Lock()
+			if p.err != nil {
+				return nil, true, p.err
+			}
+			// key 可能指向了另一个房间号的 client
+			if k := m.key(roomID); k != roomID {
+				key = k
+			}
+			continue
+		}
+		if m.rooms[key] != nil {
+			c, err = m.share(roomID, key)
+			return c, true, err
+		}
+		return nil, false, nil
+	}
+}
+
+// AddRoomWithOptions 使用指定的配置添加并启动一个直播间的 client，配置会保存到清单文件中
+//
+// 同一个房间正在添加时，使用 DuplicateShare 的重复添加会等待其完成，成功时共享同一个 client，失败时返回相同的错误
+func (m *RoomManager) AddRoomWithOptions(roomID string, opts *RoomOptions) (*Client, error) {
+	m.mu.Lock()
+	if c, done, err := m.join(roomID, m.key(roomID)); done {
+		m.mu.Unlock()
+		return c, err
+	}
+	p := &pendingAdd{done: make(chan struct{})}
+	m.adding[roomID] = p
+	m.mu.Unlock()
+
+	c, err := m.startRoom(roomID, opts)
+	m.mu.Lock()
+	// Stop 期间可能已经清空 adding，之后的 AddRoom 会放入新的 pendingAdd
+	if m.adding[roomID] == p {
+		delete(m.adding, roomID)
+	}
+	m.mu.Unlock()
+	p.err = err
+	close(p.done)
+	return c, err
+}
+
+// startRoom 创建并启动 roomID 房间的 client，启动成功后才加入 rooms
+func (m *RoomManager) startRoom(roomID string, opts *RoomOptions) (*Client, error) {
+	c := NewClient(roomID, m.enterUID, m.buvid, m.userAgent, m.referer)
+	m.mu.Lock()
+	setups := m.setups
+	roomSetup := m.roomSetup[roomID]
+	liveInterval := m.live.interval
+	m.mu.Unlock()
+
+	opts.Apply(c)
+	if liveInterval > 0 {
+		c.SetLiveCheck(LiveCheckNotify, liveInterval)
+	}
+	// 先确定真实房间号，短号与长号指向同一个房间时视为重复添加
+	realID, err := c.realRoomID()
+	if err != nil {
+		c.Stop()
+		return nil, err
+	}
+	m.mu.Lock()
+	if key, ok := m.realRooms[realID]; ok {
+		if existing, done, err := m.join(roomID, key); done {
+			m.mu.Unlock()
+			c.Stop()
+			return existing, err
+		}
+	}
+	m.realRooms[realID] = roomID
+	m.mu.Unlock()
+
+	m.trackHistory(roomID, c)
+	for _, fn := range setups {
+		fn(roomID, c)
+	}
+	for _, fn := range roomSetup {
+		fn(c)
+	}
+	if err := c.Start(); err != nil {
+		m.mu.Lock()
+		m.forget(roomID)
+		m.mu.Unlock()
+		c.Stop()
+		return nil, err
+	}
+	m.seedHistory(roomID, c)
+	m.mu.Lock()
+	m.rooms[roomID] = c
+	m.refs[roomID] = map[string]int{roomID: 1}
+	m.options[roomID] = opts
+	m.saveManifest()
+	m.mu.Unlock()
+	return c, nil
+}
+
+// forget 移除 key 房间以及指向它的房间号，调用时需要持有 m.mu
+func (m *RoomManager) forget(key string) {
+	for realID, k := range m.realRooms {
+		if k == key {
+			delete(m.realRooms, realID)
+		}
+	}
+	for id := range m.refs[key] {
+		delete(m.aliases, id)
+	}
+	delete(m.refs, key)
+	delete(m.rooms, key)
+	m.history.remove(key)
+}
+
+// RemoveRoom 停止并移除一个直播间的 client
+//
+// 使用 DuplicateShare 重复添加的房间只减少引用计数，最后一次添加对应的 RemoveRoom 才会断开连接
+func (m *RoomManager) RemoveRoom(roomID string) {
+	m.mu.Lock()
+	key := m.key(roomID)
+	c, ok := m.rooms[key]
+	if ok {
+		refs := m.refs[key]
+		if refs[roomID] > 0 {
+			if refs[roomID]--; refs[roomID] == 0 {
+				delete(refs, roomID)
+				if roomID != key {
+					delete(m.aliases, roomID)
+				}
+			}
+		}
+		if len(refs) > 0 {
+			m.mu.Unlock()
+			return
+		}
+		m.forget(key)
+		delete(m.options, key)
+		m.saveManifest()
+	}
+	m.mu.Unlock()
+	if ok {
+		c.Stop()
+	}
+}
+
+// RefCount 获取房间被添加的次数，不存在时返回 0
+func (m *RoomManager) RefCount(roomID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int
+	for _, count := range m.refs[m.key(roomID)] {
+		n += count
+	}
+	return n
+}
+
+// Room 获取直播间的 client，指向同一个房间的短号与长号返回同一个 client，不存在时返回 nil
+func (m *RoomManager) Room(roomID string) *Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rooms[m.key(roomID)]
+}
+
+// Rooms 获取已添加的全部房间号
+func (m *RoomManager) Rooms() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, 0, len(m.rooms))
+	for id := range m.rooms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Stop 停止全部直播间的 client
+func (m *RoomManager) Stop() {
+	m.mu.Lock()
+	rooms := m.rooms
+	m.rooms = make(map[string]*Client)
+	m.realRooms = make(map[string]string)
+	m.aliases = make(map[string]string)
+	m.refs = make(map[string]map[string]int)
+	m.adding = make(map[string]*pendingAdd)
+	m.stopLiveScheduler()
+	m.mu.Unlock()
+	m.flushManifest()
+	for _, c := range rooms {
+		c.Stop()
+	}
+	m.history.reset()
+}
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// manifestDelay 添加、移除房间后写入清单文件前等待的时间，期间的多次修改合并为一次写入
+const manifestDelay = 500 * time.Millisecond
+
+// RoomOptions 单个房间 client 的配置，可以保存到清单文件中
+type RoomOptions struct {
+	ParseMode    int              `json:"parse_mode,omitempty"`
+	DispatchMode int              `json:"dispatch_mode,omitempty"`
+	Shards       int              `json:"shards,omitempty"`
+	Workers      int              `json:"workers,omitempty"`
+	Legacy       bool             `json:"legacy_dispatch,omitempty"`
+	Backfill     bool             `json:"backfill,omitempty"`
+	TCP          bool             `json:"tcp,omitempty"`
+	Host         string           `json:"host,omitempty"`
+	APIBaseURL   string           `json:"api_base_url,omitempty"`
+	Reconnect    *ReconnectPolicy `json:"reconnect,omitempty"`
+	ClockSkew    bool             `json:"clock_skew,omitempty"`    // 开启时钟偏差校正
+	Subscribe    []string         `json:"subscribe,omitempty"`     // 只订阅的 cmd，见 Client.Subscribe
+	MemoryLimit  uint64           `json:"memory_limit,omitempty"`  // 内存上限（字节），见 Client.SetMemoryLimit
+	Protover     int              `json:"protover,omitempty"`      // 进房时请求的协议版本，见 Client.SetProtover
+	AllowHosts   []string         `json:"allow_hosts,omitempty"`   // 只连接的弹幕服务器，见 Client.AllowHosts
+	DenyHosts    []string         `json:"deny_hosts,omitempty"`    // 不连接的弹幕服务器，见 Client.DenyHosts
+	RoomState    bool             `json:"room_state,omitempty"`    // 跟踪直播间状态，见 Client.RoomState
+	DeferOffline bool             `json:"defer_offline,omitempty"` // 未开播时推迟连接，见 Client.SetLiveCheck
+}
+
+// Apply 将配置应用到 client
+func (o *RoomOptions) Apply(c *Client) {
+	if o == nil {
+		return
+	}
+	c.SetParseMode(o.ParseMode)
+	c.SetDispatchMode(o.DispatchMode)
+	c.SetShards(o.Shards)
+	c.SetWorkers(o.Workers)
+	if o.Legacy {
+		c.EnableLegacyDispatch()
+	}
+	if o.Backfill {
+		c.EnableBackfill()
+	}
+	if o.Host != "" {
+		c.SetHost(o.Host)
+	}
+	if o.APIBaseURL != "" {
+		c.SetAPIBaseURL(o.APIBaseURL)
+	}
+	if o.TCP {
+		c.UseTCP()
+	}
+	if o.Reconnect != nil {
+		c.SetReconnectPolicy(*o.Reconnect)
+	}
+	if o.ClockSkew {
+		c.EnableClockSkewCorrection()
+	}
+	if len(o.Subscribe) > 0 {
+		c.Subscribe(o.Subscribe...)
+	}
+	if o.MemoryLimit > 0 {
+		c.SetMemoryLimit(o.MemoryLimit)
+	}
+	if o.Protover > 0 {
+		c.SetProtover(o.Protover)
+	}
+	c.AllowHosts(o.AllowHosts...)
+	c.DenyHosts(o.DenyHosts...)
+	if o.RoomState {
+		c.EnableRoomState()
+	}
+	if o.DeferOffline {
+		c.SetLiveCheck(LiveCheckDefer, 0)
+	}
+}
+
+// manifestRoom 清单文件中的一个房间
+type manifestRoom struct {
+	RoomID  string       `json:"room_id"`
+	Options *RoomOptions `json:"options,omitempty"`
+}
+
+type manifestFile struct {
+	Rooms []manifestRoom `json:"rooms"`
+}
+
+// manifestWriter 在 RoomManager 的锁之外合并写入清单文件
+type manifestWriter struct {
+	mu      sync.Mutex
+	write   sync.Mutex // 同时只有一次写入
+	path    string
+	pending *manifestFile
+	timer   *time.Timer
+}
+
+// SetManifest 设置保存已订阅房间的清单文件，之后每次添加、移除房间都会写入该文件
+//
+// 短时间内的多次修改会合并为一次写入，Stop 与 Shutdown 时写入尚未写入的修改
+//
+// 进程重启后调用 Restore 可以恢复清单中的全部房间
+func (m *RoomManager) SetManifest(path string) {
+	m.mu.Lock()
+	m.manifest = path
+	m.mu.Unlock()
+}
+
+// SetRoomOptions 设置 roomID 房间之后 AddRoom 时使用的配置，不会启动该房间
+func (m *RoomManager) SetRoomOptions(roomID string, opts *RoomOptions) {
+	m.mu.Lock()
+	m.options[roomID] = opts
+	m.mu.Unlock()
+}
+
+// Restore 读取清单文件并添加其中的房间，返回值与 AddRooms 相同，清单文件不存在时返回 nil
+func (m *RoomManager) Restore(opts ...AddRoomsOption) (map[string]error, error) {
+	m.mu.Lock()
+	path := m.manifest
+	m.mu.Unlock()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var mf manifestFile
+	if err = json.Unmarshal(b, &mf); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(mf.Rooms))
+	m.mu.Lock()
+	for _, r := range mf.Rooms {
+		ids = append(ids, r.RoomID)
+		m.options[r.RoomID] = r.Options
+	}
+	m.mu.Unlock()
+	return m.AddRooms(ids, opts...), nil
+}
+
+// saveManifest 记录当前的房间与配置，manifestDelay 后写入清单文件，调用时需要持有 m.mu
+func (m *RoomManager) saveManifest() {
+	if m.manifest == "" {
+		return
+	}
+	mf := &manifestFile{Rooms: make([]manifestRoom, 0, len(m.rooms))}
+	for id := range m.rooms {
+		mf.Rooms = append(mf.Rooms, manifestRoom{RoomID: id, Options: m.options[id]})
+	}
+	w := &m.saver
+	w.mu.Lock()
+	w.path = m.manifest
+	w.pending = mf
+	if w.timer == nil {
+		w.timer = time.AfterFunc(manifestDelay, w.flush)
+	}
+	w.mu.Unlock()
+}
+
+// flushManifest 立即写入尚未写入的清单文件修改
+func (m *RoomManager) flushManifest() {
+	m.saver.flush()
+}
+
+func (w *manifestWriter) flush() {
+	w.write.Lock()
+	defer w.write.Unlock()
+	w.mu.Lock()
+	path, mf := w.path, w.pending
+	w.pending = nil
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.mu.Unlock()
+	if mf == nil {
+		return
+	}
+	if err := writeManifest(path, mf); err != nil {
+		log.Error("save room manifest failed: ", err)
+	}
+}
+
+// writeManifest 写入清单文件
+func writeManifest(path string, mf *manifestFile) error {
+	sort.Slice(mf.Rooms, func(i, j int) bool { return mf.Rooms[i].RoomID < mf.Rooms[j].RoomID })
+	b, err := json.MarshalIndent(mf, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 先写入临时文件再重命名，避免进程退出时清单文件损坏
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".manifest-*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+	log "github.com/sirupsen/logrus"
+)
+
+// 事件优先级，内存紧张时从低到高依次丢弃
+const (
+	PriorityLow      = iota // 进房、在线人数等高频且可以丢失的事件
+	PriorityNormal          // 弹幕
+	PriorityHigh            // 礼物、醒目留言、上舰等与收入相关的事件
+	PriorityCritical        // 开播、下播与直播间被封禁等状态变化，不会被丢弃
+)
+
+// 内存使用率达到各级阈值时开始丢弃对应优先级的事件
+var shedThresholds = [...]float64{0.7, 0.85, 0.95}
+
+// memoryCheckInterval 检查内存使用的间隔
+const memoryCheckInterval = time.Second
+
+// memoryGuard 内存上限与当前的丢弃级别
+type memoryGuard struct {
+	ceiling  uint64
+	priority func(cmd string) int
+	level    int32 // 低于该优先级的事件会被丢弃，0 表示不丢弃
+	once     sync.Once
+}
+
+// SetMemoryLimit 设置内存上限（字节），为 0 时不限制，需要在 Start 之前调用
+//
+// 进程堆内存或分发队列接近上限时按优先级逐步丢弃事件，并缩小暂停缓存，
+// 使低内存的机器在热门直播中不会因内存耗尽被杀死，丢弃的数量见 Stats.Shed
+func (c *Client) SetMemoryLimit(ceiling uint64) {
+	c.memory.ceiling = ceiling
+}
+
+// SetEventPriority 设置事件的优先级函数，返回 PriorityLow 等值，默认见 DefaultEventPriority
+func (c *Client) SetEventPriority(f func(cmd string) int) {
+	c.memory.priority = f
+}
+
+// DefaultEventPriority 默认的事件优先级
+func DefaultEventPriority(cmd string) int {
+	switch cmd {
+	case "LIVE", "PREPARING", "ROOM_LOCK", "CUT_OFF", "WARNING", "ROOM_LIMIT", "ROOM_CHANGE":
+		return PriorityCritical
+	case "SEND_GIFT", "SUPER_CHAT_MESSAGE", "SUPER_CHAT_MESSAGE_JPN", "GUARD_BUY", "USER_TOAST_MSG", "SPECIAL_GIFT":
+		return PriorityHigh
+	case "DANMU_MSG":
+		return PriorityNormal
+	}
+	return PriorityLow
+}
+
+// ShedLevel 当前的丢弃级别，0 表示不丢弃，为 n 时优先级低于 n 的事件会被丢弃
+func (c *Client) ShedLevel() int {
+	return int(atomic.LoadInt32(&c.memory.level))
+}
+
+// shed 内存紧张时判断是否丢弃该包，返回 true 时已丢弃
+func (c *Client) shed(pkt packet.Packet) bool {
+	level := atomic.LoadInt32(&c.memory.level)
+	if level == 0 || pkt.Operation != packet.Notification {
+		return false
+	}
+	priority := c.memory.priority
+	if priority == nil {
+		priority = DefaultEventPriority
+	}
+	if priority(baseCmd(parseCmd(pkt.Body))) >= int(level) {
+		return false
+	}
+	atomic.AddUint64(&c.stats.shed, 1)
+	c.dropped(DropMemory, pkt)
+	return true
+}
+
+// startMemoryGuard 设置了内存上限时启动检查循环
+func (c *Client) startMemoryGuard() {
+	if c.memory.ceiling == 0 {
+		return
+	}
+	c.memory.once.Do(func() { c.goLoop("memoryLoop", c.memoryLoop) })
+}
+
+func (c *Client) memoryLoop() {
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.checkMemory()
+		}
+	}
+}
+
+// checkMemory 根据堆内存与队列使用率更新丢弃级别
+func (c *Client) checkMemory() {
+	usage := float64(heapInuse()) / float64(c.memory.ceiling)
+	if q := c.queueUsage(); q > usage {
+		usage = q
+	}
+	level := int32(0)
+	for i, t := range shedThresholds {
+		if usage >= t {
+			level = int32(i + 1)
+		}
+	}
+	old := atomic.SwapInt32(&c.memory.level, level)
+	if level != old {
+		log.Warnf("memory usage %.0f%%, shed level %d -> %d", usage*100, old, level)
+	}
+	if level >= 2 {
+		c.shrinkPauseBuffer(int(level))
+	} else if old >= 2 {
+		c.restorePauseBuffer()
+	}
+}
+
+// asyncCapacity EnableLegacyDispatch 时视为队列已满的执行中处理器数量
+const asyncCapacity = 10000
+
+// queueUsage 等待执行与执行中的处理器占分发队列容量的比例
+func (c *Client) queueUsage() float64 {
+	d := &c.dispatcher
+	capacity := atomic.LoadInt64(&d.capacity)
+	if d.legacyDispatch() {
+		capacity = asyncCapacity
+	}
+	if capacity == 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&d.pending)) / float64(capacity)
+}
+
+// shrinkPauseBuffer 丢弃暂停缓存中的低优先级包，并将缓存上限缩小到原来的 1/2^(level-1)，直到内存恢复
+func (c *Client) shrinkPauseBuffer(level int) {
+	priority := c.memory.priority
+	if priority == nil {
+		priority = DefaultEventPriority
+	}
+	c.pause.Lock()
+	defer c.pause.Unlock()
+	limit := c.pause.bufferSize >> uint(level-1)
+	c.pause.shrunk, c.pause.limit = true, limit
+	if len(c.pause.buffer) == 0 {
+		return
+	}
+	kept := make([]packet.Packet, 0, len(c.pause.buffer))
+	for _, pkt := range c.pause.buffer {
+		if pkt.Operation == packet.Notification && priority(baseCmd(parseCmd(pkt.Body))) < level {
+			c.dropped(DropMemory, pkt)
+			continue
+		}
+		kept = append(kept, pkt)
+	}
+	if len(kept) > limit {
+		for _, pkt := range kept[:len(kept)-limit] {
+			c.dropped(DropMemory, pkt)
+		}
+		kept = kept[len(kept)-limit:]
+	}
+	shed := len(c.pause.buffer) - len(kept)
+	atomic.AddUint64(&c.stats.shed, uint64(shed))
+	// 复制到新的切片，释放原缓存占用的内存
+	c.pause.buffer = append([]packet.Packet(nil), kept...)
+}
+
+// restorePauseBuffer 内存恢复后取消 shrinkPauseBuffer 对缓存上限的限制
+func (c *Client) restorePauseBuffer() {
+	c.pause.Lock()
+	c.pause.shrunk = false
+	c.pause.Unlock()
+}
+
+// 多个 client 共享的堆内存采样，避免每个 client 都调用 runtime.ReadMemStats
+var heapSample struct {
+	sync.Mutex
+	at    time.Time
+	bytes uint64
+}
+
+// heapInuse 进程当前的堆内存占用，一个检查间隔内只采样一次
+func heapInuse() uint64 {
+	heapSample.Lock()
+	defer heapSample.Unlock()
+	if time.Since(heapSample.at) < memoryCheckInterval/2 {
+		return heapSample.bytes
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	heapSample.at = time.Now()
+	heapSample.bytes = m.HeapInuse
+	return heapSample.bytes
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// pauseState 暂停消费时的状态
+type pauseState struct {
+	sync.Mutex
+	paused     bool
+	resuming   bool // Resume 正在分发缓存的包
+	repause    bool // Resume 分发缓存期间调用了 Pause，分发完当前的包后保持暂停
+	bufferSize int
+	shrunk     bool // 内存紧张时缓存上限被缩小为 limit，见 shrinkPauseBuffer
+	limit      int
+	buffer     []packet.Packet
+	drain      sync.Mutex // 同时只有一个 Resume 分发缓存的包
+}
+
+// Pause 暂停分发事件，连接和心跳会继续保持，心跳回复不受暂停影响
+//
+// 暂停期间收到的包会缓存最近的 N 条（见 SetPauseBufferSize），默认不缓存
+func (c *Client) Pause() {
+	c.pause.Lock()
+	c.pause.paused = true
+	if c.pause.resuming {
+		c.pause.repause = true
+	}
+	c.pause.Unlock()
+}
+
+// Resume 恢复分发事件，暂停期间缓存的包会按收到的顺序先行分发
+//
+// 分发缓存的包期间仍处于暂停状态，新收到的包继续加入缓存并在之后分发，缓存清空后才恢复实时分发；
+// 期间调用了 Pause 时分发完已取出的包后保持暂停，其余的包留在缓存中
+func (c *Client) Resume() {
+	c.pause.drain.Lock()
+	defer c.pause.drain.Unlock()
+	for {
+		c.pause.Lock()
+		if c.pause.repause {
+			c.pause.repause = false
+			c.pause.resuming = false
+			c.pause.Unlock()
+			return
+		}
+		buffered := c.pause.buffer
+		c.pause.buffer = nil
+		if len(buffered) == 0 {
+			c.pause.paused = false
+			c.pause.resuming = false
+			c.pause.Unlock()
+			return
+		}
+		c.pause.resuming = true
+		c.pause.Unlock()
+		for _, pkt := range buffered {
+			c.deliver(pkt)
+		}
+	}
+}
+
+// Paused 是否处于暂停状态
+func (c *Client) Paused() bool {
+	c.pause.Lock()
+	defer c.pause.Unlock()
+	return c.pause.paused
+}
+
+// SetPauseBufferSize 设置暂停期间最多缓存的包数量，超出时丢弃最早的包，为 0 时不缓存
+func (c *Client) SetPauseBufferSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	c.pause.Lock()
+	c.pause.bufferSize = n
+	if len(c.pause.buffer) > n {
+		c.pause.buffer = c.pause.buffer[len(c.pause.buffer)-n:]
+	}
+	c.pause.Unlock()
+}
+
+// hold 暂停时缓存包，返回 true 表示包已被暂停逻辑接管，不需要再分发
+//
+// 心跳回复等非 Notification 包直接分发，避免人气值与往返时间的统计被暂停推迟
+func (c *Client) hold(pkt packet.Packet) bool {
+	if pkt.Operation != packet.Notification {
+		return false
+	}
+	c.pause.Lock()
+	defer c.pause.Unlock()
+	if !c.pause.paused {
+		return false
+	}
+	size := c.pause.capacity()
+	if size <= 0 {
+		c.dropped(DropPaused, pkt)
+		return true
+	}
+	if len(c.pause.buffer) >= size {
+		c.dropped(DropPaused, c.pause.buffer[0])
+		c.pause.buffer = c.pause.buffer[1:]
+	}
+	c.pause.buffer = append(c.pause.buffer, pkt)
+	return true
+}
+
+// capacity 当前最多缓存的包数量
+func (p *pauseState) capacity() int {
+	if p.shrunk && p.limit < p.bufferSize {
+		return p.limit
+	}
+	return p.bufferSize
+}
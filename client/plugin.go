@@ -0,0 +1,135 @@
+package client
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Plugin 可复用的功能插件，如营收统计、TTS 与 webhook，由 client 管理生命周期
+//
+// Init 在 UsePlugin 时调用，用于注册事件处理器与修改配置；Start 在 client 启动时调用；
+// Stop 在 client 停止或插件被移除时调用，之后插件注册的处理器仍会被调用，需要插件自行忽略
+//
+// Start 与 Stop 中不能调用 client 的 UsePlugin、RemovePlugin 与 Plugins
+type Plugin interface {
+	Init(c *Client) error
+	Start() error
+	Stop() error
+}
+
+type pluginEntry struct {
+	p       Plugin
+	started bool
+}
+
+type pluginState struct {
+	mu      sync.Mutex
+	entries []*pluginEntry
+	running bool
+}
+
+// UsePlugin 添加并初始化插件，client 已经启动时会立即启动插件
+func (c *Client) UsePlugin(p Plugin) error {
+	if err := p.Init(c); err != nil {
+		return err
+	}
+	s := &c.plugins
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := &pluginEntry{p: p}
+	if s.running {
+		if err := p.Start(); err != nil {
+			return err
+		}
+		e.started = true
+	}
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+// RemovePlugin 停止并移除插件，插件不存在时返回 false
+func (c *Client) RemovePlugin(p Plugin) bool {
+	s := &c.plugins
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, e := range s.entries {
+		if e.p != p {
+			continue
+		}
+		s.entries = append(s.entries[:i:i], s.entries[i+1:]...)
+		stopPlugin(e)
+		return true
+	}
+	return false
+}
+
+// Plugins 获取已添加的插件，按添加顺序排列
+func (c *Client) Plugins() []Plugin {
+	s := &c.plugins
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ps := make([]Plugin, len(s.entries))
+	for i, e := range s.entries {
+		ps[i] = e.p
+	}
+	return ps
+}
+
+// startPlugins 按添加顺序启动插件，失败时停止已启动的插件
+func (c *Client) startPlugins() error {
+	s := &c.plugins
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = true
+	for _, e := range s.entries {
+		if e.started {
+			continue
+		}
+		if err := e.p.Start(); err != nil {
+			s.running = false
+			c.stopPluginsLocked()
+			return err
+		}
+		e.started = true
+	}
+	return nil
+}
+
+// stopPlugins 按添加顺序的逆序停止插件
+func (c *Client) stopPlugins() {
+	s := &c.plugins
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	c.stopPluginsLocked()
+}
+
+func (c *Client) stopPluginsLocked() {
+	entries := c.plugins.entries
+	for i := len(entries) - 1; i >= 0; i-- {
+		stopPlugin(entries[i])
+	}
+}
+
+func stopPlugin(e *pluginEntry) {
+	if !e.started {
+		return
+	}
+	e.started = false
+	if err := e.p.Stop(); err != nil {
+		log.Warnf("stop plugin %T failed: %v", e.p, err)
+	}
+}
+
+// UsePlugin 为每个房间添加插件，newPlugin 为每个房间创建一个插件，需要在 AddRoom 之前调用
+//
+// 插件随房间的 client 启动与停止，添加失败时记录日志，不影响房间的添加
+func (m *RoomManager) UsePlugin(newPlugin func(roomID string) Plugin) {
+	m.OnClient(func(roomID string, c *Client) {
+		p := newPlugin(roomID)
+		if err := c.UsePlugin(p); err != nil {
+			log.Errorf("use plugin %T for room %s failed: %v", p, roomID, err)
+		}
+	})
+}
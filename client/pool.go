@@ -0,0 +1,59 @@
+package client
+
+import (
+	"sync/atomic"
+
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+// EnablePooling 开启弹幕与礼物事件的对象池，全部处理器返回后事件会被清空并复用，需要在 Start 之前调用
+//
+// 长时间高吞吐采集时可以减少内存分配与 GC 停顿，
+// 处理器返回后仍需使用事件时（如放入队列或交给其他 goroutine）需要在返回前调用事件的 Retain
+func (c *Client) EnablePooling() {
+	c.pooling = true
+}
+
+func (c *Client) newDanmaku() *message.Danmaku {
+	if c.pooling {
+		return message.AcquireDanmaku()
+	}
+	return new(message.Danmaku)
+}
+
+func (c *Client) releaseDanmaku(d *message.Danmaku) {
+	if c.pooling {
+		message.ReleaseDanmaku(d)
+	}
+}
+
+func (c *Client) newGift() *message.Gift {
+	if c.pooling {
+		return message.AcquireGift()
+	}
+	return new(message.Gift)
+}
+
+func (c *Client) releaseGift(g *message.Gift) {
+	if c.pooling {
+		message.ReleaseGift(g)
+	}
+}
+
+// refCount 多个处理器共享同一个事件时尚未返回的处理器数量
+type refCount struct {
+	n int32
+}
+
+// refs 创建 n 个处理器的计数，未开启对象池或只有一个处理器时返回 nil，不需要额外分配
+func (c *Client) refs(n int) *refCount {
+	if !c.pooling || n <= 1 {
+		return nil
+	}
+	return &refCount{n: int32(n)}
+}
+
+// done 一个处理器已返回，全部返回时返回 true，处理器 panic 时事件不会放回对象池
+func (r *refCount) done() bool {
+	return r == nil || atomic.AddInt32(&r.n, -1) == 0
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultReconnectDelay 默认的重连间隔
+const defaultReconnectDelay = 2 * time.Second
+
+// ErrReconnectExhausted 连续重连失败次数达到 ReconnectPolicy.MaxAttempts
+var ErrReconnectExhausted = errors.New("reconnect attempts exhausted")
+
+// ErrReconnectAborted 连接关闭的原因对应的处理方式为 ActionGiveUp
+var ErrReconnectAborted = errors.New("reconnect aborted by close policy")
+
+// errStopped 重连等待期间 client 被停止
+var errStopped = errors.New("client stopped")
+
+// ReconnectPolicy 连接弹幕服务器失败时的重试策略
+type ReconnectPolicy struct {
+	MaxAttempts int           `json:"max_attempts,omitempty"` // 连续失败的最大次数，0 表示不限制
+	Delay       time.Duration `json:"delay,omitempty"`        // 首次重试前的等待，默认 2s
+	MaxDelay    time.Duration `json:"max_delay,omitempty"`    // 大于 Delay 时每次失败等待时间翻倍，直到 MaxDelay
+	// CloseCodes 按 websocket 关闭帧的状态码指定连接关闭后的处理方式，优先于 Classes，
+	// 进房被拒绝时按响应中的 code 匹配，如因违规被切断的房间可设置为 ActionGiveUp
+	CloseCodes map[int]CloseAction `json:"close_codes,omitempty"`
+	// Classes 按关闭原因的分类指定处理方式，未指定的分类使用默认的处理方式，见 CloseClass
+	Classes map[CloseClass]CloseAction `json:"classes,omitempty"`
+}
+
+// CloseAction 连接关闭后的处理方式
+type CloseAction int
+
+const (
+	// ActionDefault 使用关闭原因分类的默认处理方式
+	ActionDefault CloseAction = iota
+	// ActionRetry 重连同一服务器
+	ActionRetry
+	// ActionRotate 切换到下一个服务器重连
+	ActionRotate
+	// ActionRefreshToken 重新获取 token 后重连同一服务器
+	ActionRefreshToken
+	// ActionGiveUp 放弃重连并停止 client，Start 或 Errors 中返回 ErrReconnectAborted
+	ActionGiveUp
+)
+
+var closeActionNames = []string{"default", "retry", "rotate", "refresh_token", "give_up"}
+
+func (a CloseAction) String() string {
+	if a >= 0 && int(a) < len(closeActionNames) {
+		return closeActionNames[a]
+	}
+	return fmt.Sprintf("CloseAction(%d)", int(a))
+}
+
+// MarshalText 以名称保存到配置文件，如 "give_up"
+func (a CloseAction) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *CloseAction) UnmarshalText(b []byte) error {
+	for i, name := range closeActionNames {
+		if string(b) == name {
+			*a = CloseAction(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown close action %q", b)
+}
+
+// defaultCloseActions 各分类默认的处理方式
+var defaultCloseActions = map[CloseClass]CloseAction{
+	CloseUnknown:      ActionRotate,
+	CloseNormal:       ActionRetry,
+	CloseServerBusy:   ActionRotate,
+	CloseAuthRejected: ActionRefreshToken,
+}
+
+// closeAction 获取连接因 err 关闭后的处理方式
+func (p ReconnectPolicy) closeAction(err error, class CloseClass) CloseAction {
+	code := CloseCode(err)
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		code = authErr.Code
+	}
+	if a := p.CloseCodes[code]; code != 0 && a != ActionDefault {
+		return a
+	}
+	if a := p.Classes[class]; a != ActionDefault {
+		return a
+	}
+	return defaultCloseActions[class]
+}
+
+// SetReconnectPolicy 设置重连策略，默认每 2s 重试一次且不限次数，连接关闭后按 CloseClass 的默认方式处理
+func (c *Client) SetReconnectPolicy(p ReconnectPolicy) {
+	c.reconnect = p
+}
+
+// delay 第 attempt 次失败后的等待时间
+func (p ReconnectPolicy) delay(attempt int) time.Duration {
+	d := p.Delay
+	if d <= 0 {
+		d = defaultReconnectDelay
+	}
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		if d *= 2; d > p.MaxDelay {
+			d = p.MaxDelay
+		}
+	}
+	return d
+}
+
+// exhausted 第 attempt 次失败后是否应该放弃
+func (p ReconnectPolicy) exhausted(attempt int) bool {
+	return p.MaxAttempts > 0 && attempt >= p.MaxAttempts
+}
+
+// waitRetry 等待下一次重连，client 停止时返回 errStopped
+func (c *Client) waitRetry(attempt int) error {
+	if c.reconnect.exhausted(attempt) {
+		return ErrReconnectExhausted
+	}
+	select {
+	case <-c.done:
+		return errStopped
+	case <-time.After(c.reconnect.delay(attempt)):
+		return nil
+	}
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+	"github.com/RemKeeper/blivedm-go/record"
+	log "github.com/sirupsen/logrus"
+)
+
+// OnPacket 添加 原始包 的处理器
+//
+// 处理器在读取循环中按收到的顺序同步调用，请勿在其中阻塞
+func (c *Client) OnPacket(f func(packet.Packet)) {
+	c.packetHandlers = append(c.packetHandlers, f)
+}
+
+// Record 将收到的 Notification 包写入录制文件，可用 ReplaySource 回放
+func (c *Client) Record(w *record.Writer) {
+	c.OnPacket(func(p packet.Packet) {
+		if p.Operation != packet.Notification {
+			return
+		}
+		rid, _ := strconv.Atoi(c.roomID)
+		e := &record.Entry{
+			Time:   time.Now().UnixNano() / int64(time.Millisecond),
+			RoomID: rid,
+			Data:   append([]byte(nil), p.Body...),
+		}
+		if err := w.Write(e); err != nil {
+			log.Error("write record failed: ", err)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+	"github.com/RemKeeper/blivedm-go/record"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReplaySource 从录制文件回放事件的 DanmakuSource
+//
+// 处理器的注册方式与 Client 完全相同
+type ReplaySource struct {
+	*Client
+	path  string
+	speed float64
+	start int64
+}
+
+// NewReplaySource 创建一个回放 path 录制文件的事件来源，默认按原速回放
+func NewReplaySource(path string) *ReplaySource {
+	return &ReplaySource{
+		Client: NewClient("", "0", "", "", ""),
+		path:   path,
+		speed:  1,
+	}
+}
+
+// SetSpeed 设置回放倍速，为 0 时不等待，尽快回放全部记录
+func (r *ReplaySource) SetSpeed(speed float64) {
+	r.speed = speed
+}
+
+// SetStartTime 设置回放的起始时间，存在索引时会直接跳转
+func (r *ReplaySource) SetStartTime(t time.Time) {
+	r.start = t.UnixNano() / int64(time.Millisecond)
+}
+
+// Start 打开录制文件并开始回放，支持 gzip 压缩的录制文件
+func (r *ReplaySource) Start() error {
+	rd, err := record.OpenAt(r.path, r.start)
+	if err != nil {
+		return err
+	}
+	// 在回放开始前读取第一条记录确定房间号，回放期间 roomID 不再修改
+	first, err := rd.Next()
+	if err == nil && r.roomID == "" {
+		r.roomID = strconv.Itoa(first.RoomID)
+	}
+	r.goLoop("replayLoop", func() { r.replayLoop(rd, first, err) })
+	return nil
+}
+
+func (r *ReplaySource) replayLoop(rd *record.Reader, e *record.Entry, err error) {
+	defer rd.Close()
+	var last int64
+	for ; ; e, err = rd.Next() {
+		if err != nil {
+			if err != io.EOF {
+				log.Error("read record failed: ", err)
+			}
+			log.Debug("replay finished")
+			return
+		}
+		if last != 0 && r.speed > 0 && e.Time > last {
+			wait := time.Duration(float64(e.Time-last)/r.speed) * time.Millisecond
+			select {
+			case <-r.done:
+				return
+			case <-time.After(wait):
+			}
+		}
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+		last = e.Time
+		pkt := packet.NewPlainPacket(packet.Notification, e.Data)
+		pkt.ReceivedAt = time.Unix(0, e.Time*int64(time.Millisecond))
+		r.receive(pkt)
+	}
+}
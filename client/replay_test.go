@@ -0,0 +1,50 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/record"
+)
+
+func TestReplaySourceRoomID(t *testing.T) {
+	quietLogs(t)
+	path := filepath.Join(t.TempDir(), "replay.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := record.NewWriter(f)
+	for i, s := range []string{"1", "2", "3"} {
+		if err := w.Write(&record.Entry{Time: int64(i + 1), RoomID: 732, Data: danmakuPacket(s).Body}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+	f.Close()
+
+	r := NewReplaySource(path)
+	defer r.Stop()
+	r.SetSpeed(0)
+	got := make(chan int, 3)
+	r.OnDanmaku(func(*message.Danmaku) { got <- r.RoomID() })
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if id := r.RoomID(); id != 732 {
+		t.Errorf("RoomID after Start = %d, want 732", id)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case id := <-got:
+			if id != 732 {
+				t.Errorf("RoomID in handler = %d, want 732", id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d danmaku, want 3", i)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// dialTimeout 连接单个 IP 的超时时间
+const dialTimeout = 10 * time.Second
+
+// fallbackDelay happy-eyeballs 中发起下一个连接尝试之前等待的时间
+const fallbackDelay = 300 * time.Millisecond
+
+const (
+	// IPDefault 按解析结果的顺序连接
+	IPDefault = iota
+	// PreferIPv4 优先连接 IPv4 地址
+	PreferIPv4
+	// PreferIPv6 优先连接 IPv6 地址
+	PreferIPv6
+	// IPv4Only 只连接 IPv4 地址
+	IPv4Only
+	// IPv6Only 只连接 IPv6 地址
+	IPv6Only
+)
+
+// resolveState 自定义 DNS 解析的配置与解析结果缓存
+type resolveState struct {
+	sync.Mutex
+	resolver *net.Resolver
+	static   map[string][]net.IP
+	lastGood map[string][]net.IP
+	pref     int
+}
+
+// SetResolver 设置解析弹幕服务器域名时使用的 DNS 解析器，可用于 DoH 等自定义解析
+//
+// 自定义解析器失败时会回退到系统解析器，再失败时使用上一次成功解析的结果
+func (c *Client) SetResolver(r *net.Resolver) {
+	c.resolve.Lock()
+	c.resolve.resolver = r
+	c.resolve.Unlock()
+}
+
+// SetIPPreference 设置连接弹幕服务器时的 IP 协议偏好，如 PreferIPv4
+//
+// 多个地址会以 happy-eyeballs 的方式错开发起连接，使用最先建立的连接
+func (c *Client) SetIPPreference(pref int) {
+	c.resolve.Lock()
+	c.resolve.pref = pref
+	c.resolve.Unlock()
+}
+
+// SetHostIPs 为弹幕服务器 host 指定预先解析好的 IP，连接时不再进行 DNS 查询
+func (c *Client) SetHostIPs(host string, ips ...string) {
+	c.resolve.Lock()
+	defer c.resolve.Unlock()
+	if c.resolve.static == nil {
+		c.resolve.static = make(map[string][]net.IP)
+	}
+	var parsed []net.IP
+	for _, s := range ips {
+		if ip := net.ParseIP(s); ip != nil {
+			parsed = append(parsed, ip)
+		}
+	}
+	c.resolve.static[host] = parsed
+}
+
+// customResolve 是否设置了自定义解析
+func (c *Client) customResolve() bool {
+	c.resolve.Lock()
+	defer c.resolve.Unlock()
+	return c.resolve.resolver != nil || len(c.resolve.static) > 0 || c.resolve.pref != IPDefault
+}
+
+// lookup 解析 host，依次尝试预设 IP、自定义解析器、系统解析器与上一次成功的结果
+func (c *Client) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	rs := &c.resolve
+	rs.Lock()
+	static, resolver, lastGood := rs.static[host], rs.resolver, rs.lastGood[host]
+	rs.Unlock()
+	if len(static) > 0 {
+		return static, nil
+	}
+	var err error
+	for _, r := range []*net.Resolver{resolver, net.DefaultResolver} {
+		if r == nil {
+			continue
+		}
+		var addrs []net.IPAddr
+		addrs, err = r.LookupIPAddr(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			c.logs.logf(log.WarnLevel, "resolve "+host+" failed", "resolve %s failed: %v", host, err)
+			continue
+		}
+		ips := make([]net.IP, 0, len(addrs))
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+		rs.Lock()
+		if rs.lastGood == nil {
+			rs.lastGood = make(map[string][]net.IP)
+		}
+		rs.lastGood[host] = ips
+		rs.Unlock()
+		return ips, nil
+	}
+	if len(lastGood) > 0 {
+		c.logs.logf(log.WarnLevel, "using last resolved addresses of "+host, "using last resolved addresses of %s", host)
+		return lastGood, nil
+	}
+	if err == nil {
+		err = errors.New("no address found for " + host)
+	}
+	return nil, err
+}
+
+// dialContext 使用自定义解析结果建立 TCP 连接
+func (c *Client) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	c.resolve.Lock()
+	pref := c.resolve.pref
+	c.resolve.Unlock()
+	ips = sortIPs(ips, pref)
+	if len(ips) == 0 {
+		return nil, errors.New("no address of preferred family for " + host)
+	}
+	return dialParallel(ctx, network, port, ips)
+}
+
+// sortIPs 按偏好排序地址，偏好的协议在前并与另一种协议交替排列
+func sortIPs(ips []net.IP, pref int) []net.IP {
+	if pref == IPDefault {
+		return ips
+	}
+	var v4, v6 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+	switch pref {
+	case IPv4Only:
+		return v4
+	case IPv6Only:
+		return v6
+	}
+	first, second := v4, v6
+	if pref == PreferIPv6 {
+		first, second = v6, v4
+	}
+	sorted := make([]net.IP, 0, len(ips))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			sorted = append(sorted, first[i])
+		}
+		if i < len(second) {
+			sorted = append(sorted, second[i])
+		}
+	}
+	return sorted
+}
+
+// dialParallel 按顺序每隔 fallbackDelay 发起一个连接，返回最先成功的连接
+func dialParallel(ctx context.Context, network, port string, ips []net.IP) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, len(ips))
+	d := &net.Dialer{Timeout: dialTimeout}
+	next := 0
+	pending := 0
+	start := func() {
+		ip := ips[next]
+		next++
+		pending++
+		go func() {
+			conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			results <- result{conn, err}
+		}()
+	}
+	start()
+	var lastErr error
+	timer := time.NewTimer(fallbackDelay)
+	defer timer.Stop()
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// 关闭之后才建立成功的连接
+				go func(n int) {
+					for ; n > 0; n-- {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+			lastErr = r.err
+			if next < len(ips) {
+				start()
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(fallbackDelay)
+			}
+		case <-timer.C:
+			if next < len(ips) {
+				start()
+				timer.Reset(fallbackDelay)
+			}
+		}
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// RiskControlEvent 的来源
+const (
+	RiskSourceAPI       = "api"       // 调用 HTTP 接口
+	RiskSourceWebsocket = "websocket" // 弹幕服务器 websocket 握手
+)
+
+// 被风控拦截后连接前默认的等待时间，每次连续拦截翻倍
+const (
+	defaultRiskMinBackoff = 30 * time.Second
+	defaultRiskMaxBackoff = 10 * time.Minute
+)
+
+// RiskControlPolicy 被风控拦截(HTTP 412、code -412 或 -352)后的处理方式
+type RiskControlPolicy struct {
+	MinBackoff time.Duration `json:"min_backoff,omitempty"` // 第一次被拦截后连接前等待的时间，默认 30s
+	MaxBackoff time.Duration `json:"max_backoff,omitempty"` // 连续被拦截时等待时间翻倍直至 MaxBackoff，默认 10m
+	// UserAgents 被拦截后依次切换使用的 User-Agent，使用 SetUserAgents 时每次连接都会切换，不需要设置
+	UserAgents []string `json:"user_agents,omitempty"`
+	// KeepBuvid 被拦截后不重新生成进房使用的 buvid，使用登录账号的 buvid 时需要开启
+	KeepBuvid bool `json:"keep_buvid,omitempty"`
+}
+
+// RiskControlEvent 请求被风控拦截
+type RiskControlEvent struct {
+	Source  string        // RiskSourceAPI 或 RiskSourceWebsocket
+	Target  string        // 接口 URL 或弹幕服务器 host
+	Status  int           // HTTP 状态码
+	Code    int           // 接口响应中的 code，websocket 握手时为 0
+	Count   int           // 连续被拦截的次数
+	Backoff time.Duration // 下一次连接前等待的时间
+}
+
+type riskState struct {
+	mu       sync.Mutex
+	policy   RiskControlPolicy
+	count    int
+	until    time.Time
+	ua       int
+	handlers []func(*RiskControlEvent)
+}
+
+// SetRiskControlPolicy 设置被风控拦截后的处理方式
+func (c *Client) SetRiskControlPolicy(p RiskControlPolicy) {
+	c.risk.mu.Lock()
+	c.risk.policy = p
+	c.risk.mu.Unlock()
+}
+
+// OnRiskControl 添加 请求被风控拦截 的处理器
+//
+// 被拦截后 client 会延迟之后的连接，并按 RiskControlPolicy 切换 User-Agent 与 buvid，避免持续请求导致封禁升级
+func (c *Client) OnRiskControl(f func(*RiskControlEvent)) {
+	c.risk.mu.Lock()
+	c.risk.handlers = append(c.risk.handlers, f)
+	c.risk.mu.Unlock()
+}
+
+// checkAPIRisk 接口错误为风控拦截时记录并调整之后的行为
+func (c *Client) checkAPIRisk(err error) {
+	var e *api.RiskControlError
+	if errors.As(err, &e) {
+		c.riskControlled(RiskSourceAPI, e.URL, e.Status, e.Code)
+	}
+}
+
+// checkHandshakeRisk 握手错误为风控拦截时记录并调整之后的行为，返回是否为风控拦截
+func (c *Client) checkHandshakeRisk(err error) bool {
+	var e *HandshakeError
+	if errors.As(err, &e) && e.Status == http.StatusPreconditionFailed {
+		c.riskControlled(RiskSourceWebsocket, c.host, e.Status, 0)
+		return true
+	}
+	return false
+}
+
+// riskControlled 记录一次风控拦截，延迟之后的连接并切换 User-Agent 与 buvid
+func (c *Client) riskControlled(source, target string, status, code int) {
+	r := &c.risk
+	r.mu.Lock()
+	r.count++
+	p := r.policy
+	backoff := p.MinBackoff
+	if backoff <= 0 {
+		backoff = defaultRiskMinBackoff
+	}
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = defaultRiskMaxBackoff
+	}
+	for i := 1; i < r.count && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	r.until = time.Now().Add(backoff)
+	var ua string
+	if len(p.UserAgents) > 0 {
+		ua = p.UserAgents[r.ua%len(p.UserAgents)]
+		r.ua++
+	}
+	e := &RiskControlEvent{Source: source, Target: target, Status: status, Code: code, Count: r.count, Backoff: backoff}
+	handlers := r.handlers
+	r.mu.Unlock()
+
+	c.credMu.Lock()
+	if ua != "" {
+		c.userAgent = ua
+	}
+	if !p.KeepBuvid {
+		c.buvid = api.NewBuvid()
+	}
+	c.credMu.Unlock()
+	c.logs.logf(log.WarnLevel, "risk control", "%s request to %s rejected by risk control (status %d, code %d), backing off %s", source, target, status, code, backoff)
+	for _, fn := range handlers {
+		fn := fn
+		c.cover(func() { fn(e) })
+	}
+}
+
+// riskSucceeded 连接成功，重置连续被拦截的次数
+func (c *Client) riskSucceeded() {
+	c.risk.mu.Lock()
+	c.risk.count = 0
+	c.risk.mu.Unlock()
+}
+
+// waitRisk 被风控拦截后等待至退避结束
+func (c *Client) waitRisk() error {
+	c.risk.mu.Lock()
+	d := time.Until(c.risk.until)
+	c.risk.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-c.done:
+		return errStopped
+	case <-time.After(d):
+		return nil
+	}
+}
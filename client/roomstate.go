@@ -0,0 +1,210 @@
+package client
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+	"github.com/tidwall/gjson"
+)
+
+// RoomState 直播间当前状态的快照
+type RoomState struct {
+	RoomID         int
+	LiveStatus     int       // 0:未开播 1:直播中 2:轮播中
+	LiveTime       time.Time // 本次开播时间，未开播时为零值
+	Title          string
+	AreaId         int
+	AreaName       string
+	ParentAreaId   int
+	ParentAreaName string
+	Popularity     int // 心跳回复中的人气值
+	Watched        int // 看过的人数
+	OnlineRank     int // 高能用户数量
+	OnlineCount    int // 在线人数
+	// SuperChats 仍在展示中的醒目留言，按收到的顺序排列
+	SuperChats []ActiveSuperChat
+	UpdatedAt  time.Time // 最后一次更新的时间
+}
+
+// ActiveSuperChat 仍在展示中的醒目留言
+type ActiveSuperChat struct {
+	Id        int
+	Uid       int
+	Uname     string
+	Message   string
+	Price     int // 价格（人民币）
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// roomStateTracker 根据事件维护的直播间状态，每次更新后发布一份新的快照
+type roomStateTracker struct {
+	enabled int32
+	mu      sync.Mutex
+	state   RoomState
+	snap    atomic.Value // *RoomState
+}
+
+// EnableRoomState 开启直播间状态跟踪，之后可以通过 RoomState 获取当前状态
+//
+// Start 时会先通过接口获取标题、分区与开播状态，之后根据开播、下播、房间信息变更、人气、
+// 看过人数、高能用户与醒目留言等事件更新，不依赖是否注册了对应的事件处理器
+func (c *Client) EnableRoomState() {
+	if !atomic.CompareAndSwapInt32(&c.state.enabled, 0, 1) {
+		return
+	}
+	c.OnPacket(c.trackRoomState)
+}
+
+// RoomState 获取直播间当前状态的快照，未开启 EnableRoomState 时返回零值
+//
+// 可以在任意 goroutine 中调用，不会阻塞事件处理，已结束的醒目留言不会出现在返回值中
+func (c *Client) RoomState() RoomState {
+	s, _ := c.state.snap.Load().(*RoomState)
+	if s == nil {
+		return RoomState{}
+	}
+	state := *s
+	state.RoomID = c.RoomID()
+	now := time.Now()
+	state.SuperChats = make([]ActiveSuperChat, 0, len(s.SuperChats))
+	for _, sc := range s.SuperChats {
+		if sc.EndTime.IsZero() || sc.EndTime.After(now) {
+			state.SuperChats = append(state.SuperChats, sc)
+		}
+	}
+	return state
+}
+
+// update 修改状态并发布新的快照
+func (t *roomStateTracker) update(f func(s *RoomState)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f(&t.state)
+	t.state.UpdatedAt = time.Now()
+	s := t.state
+	s.SuperChats = append([]ActiveSuperChat(nil), t.state.SuperChats...)
+	t.snap.Store(&s)
+}
+
+// seedRoomState 通过接口获取直播间的初始状态
+func (c *Client) seedRoomState() {
+	info, err := c.fetchRoomInfo()
+	if err != nil {
+		c.reportError(&APIError{API: "get_info", Err: err})
+		return
+	}
+	d := info.Data
+	c.state.update(func(s *RoomState) {
+		s.LiveStatus = d.LiveStatus
+		s.Title = d.Title
+		s.AreaId, s.AreaName = d.AreaId, d.AreaName
+		s.ParentAreaId, s.ParentAreaName = d.ParentAreaId, d.ParentAreaName
+		s.Popularity = d.Online
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", d.LiveTime, time.Local); err == nil && d.LiveStatus == 1 {
+			s.LiveTime = t
+		}
+	})
+}
+
+// trackRoomState 根据收到的包更新直播间状态
+func (c *Client) trackRoomState(p packet.Packet) {
+	switch p.Operation {
+	case packet.HeartBeatResponse:
+		if len(p.Body) < 4 {
+			return
+		}
+		popularity := int(binary.BigEndian.Uint32(p.Body))
+		c.state.update(func(s *RoomState) { s.Popularity = popularity })
+		return
+	case packet.Notification:
+	default:
+		return
+	}
+	if len(p.Body) < 8 {
+		return
+	}
+	switch parseCmd(p.Body) {
+	case "LIVE":
+		liveTime := gjson.GetBytes(p.Body, "live_time").Int()
+		c.state.update(func(s *RoomState) {
+			s.LiveStatus = 1
+			s.LiveTime = time.Now()
+			if liveTime > 0 {
+				s.LiveTime = time.Unix(liveTime, 0)
+			}
+		})
+	case "PREPARING":
+		round := gjson.GetBytes(p.Body, "round").Int()
+		c.state.update(func(s *RoomState) {
+			s.LiveStatus = 0
+			if round == 1 {
+				s.LiveStatus = 2
+			}
+			s.LiveTime = time.Time{}
+			s.SuperChats = nil
+		})
+	case "ROOM_CHANGE":
+		d := gjson.GetBytes(p.Body, "data")
+		c.state.update(func(s *RoomState) {
+			s.Title = d.Get("title").String()
+			s.AreaId = int(d.Get("area_id").Int())
+			s.AreaName = d.Get("area_name").String()
+			s.ParentAreaId = int(d.Get("parent_area_id").Int())
+			s.ParentAreaName = d.Get("parent_area_name").String()
+		})
+	case "WATCHED_CHANGE":
+		num := int(gjson.GetBytes(p.Body, "data.num").Int())
+		c.state.update(func(s *RoomState) { s.Watched = num })
+	case "ONLINE_RANK_COUNT":
+		d := gjson.GetBytes(p.Body, "data")
+		c.state.update(func(s *RoomState) {
+			s.OnlineRank = int(d.Get("count").Int())
+			if n := d.Get("online_count"); n.Exists() {
+				s.OnlineCount = int(n.Int())
+			}
+		})
+	case "SUPER_CHAT_MESSAGE":
+		d := gjson.GetBytes(p.Body, "data")
+		sc := ActiveSuperChat{
+			Id:      int(d.Get("id").Int()),
+			Uid:     int(d.Get("uid").Int()),
+			Uname:   d.Get("user_info.uname").String(),
+			Message: d.Get("message").String(),
+			Price:   int(d.Get("price").Int()),
+		}
+		if start := d.Get("start_time").Int(); start > 0 {
+			sc.StartTime = time.Unix(start, 0)
+		}
+		if end := d.Get("end_time").Int(); end > 0 {
+			sc.EndTime = time.Unix(end, 0)
+		}
+		now := time.Now()
+		c.state.update(func(s *RoomState) {
+			scs := s.SuperChats[:0]
+			for _, old := range s.SuperChats {
+				if old.Id != sc.Id && (old.EndTime.IsZero() || old.EndTime.After(now)) {
+					scs = append(scs, old)
+				}
+			}
+			s.SuperChats = append(scs, sc)
+		})
+	case "SUPER_CHAT_MESSAGE_DELETE":
+		ids := make(map[int]bool)
+		for _, id := range gjson.GetBytes(p.Body, "data.ids").Array() {
+			ids[int(id.Int())] = true
+		}
+		c.state.update(func(s *RoomState) {
+			scs := s.SuperChats[:0]
+			for _, sc := range s.SuperChats {
+				if !ids[sc.Id] {
+					scs = append(scs, sc)
+				}
+			}
+			s.SuperChats = scs
+		})
+	}
+}
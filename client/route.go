@@ -0,0 +1,96 @@
+package client
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CmdMatcher 匹配事件 cmd
+type CmdMatcher interface {
+	Match(cmd string) bool
+}
+
+// CmdMatcherFunc 将函数转为 CmdMatcher
+type CmdMatcherFunc func(cmd string) bool
+
+func (f CmdMatcherFunc) Match(cmd string) bool {
+	return f(cmd)
+}
+
+// ExactCmd 匹配与 cmd 完全相同的事件
+func ExactCmd(cmd string) CmdMatcher {
+	return CmdMatcherFunc(func(s string) bool { return s == cmd })
+}
+
+// PrefixCmd 匹配以 prefix 开头的事件，如 PrefixCmd("DANMU_MSG") 可匹配 DANMU_MSG_V2
+func PrefixCmd(prefix string) CmdMatcher {
+	return CmdMatcherFunc(func(s string) bool { return strings.HasPrefix(s, prefix) })
+}
+
+// RegexpCmd 匹配满足正则表达式 expr 的事件
+func RegexpCmd(expr string) (CmdMatcher, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return CmdMatcherFunc(re.MatchString), nil
+}
+
+type cmdRoute struct {
+	matcher CmdMatcher
+	handler func(s string)
+	target  string
+}
+
+type routeState struct {
+	normalize bool
+	routes    []cmdRoute
+}
+
+// EnableCmdNormalization 匹配前将事件 cmd 去除首尾空白并转为大写
+//
+// 开启后内置事件与 RouteCmd、RegisterCmdMatcher 都使用规范化后的 cmd 匹配
+func (c *Client) EnableCmdNormalization() {
+	c.routes.normalize = true
+}
+
+// RegisterCmdMatcher 注册 匹配 m 的自定义事件 的处理器
+//
+// 在 RegisterCustomEventHandler 的精确匹配之后按注册顺序尝试，只执行第一个匹配的处理器
+func (c *Client) RegisterCmdMatcher(m CmdMatcher, handler func(s string)) {
+	c.routes.routes = append(c.routes.routes, cmdRoute{matcher: m, handler: handler})
+}
+
+// RouteCmd 将匹配 m 的事件当作内置事件 cmd 处理
+//
+// 用于 B站 修改大小写或追加后缀后仍然触发内置的处理器，如 RouteCmd(PrefixCmd("DANMU_MSG"), "DANMU_MSG")
+func (c *Client) RouteCmd(m CmdMatcher, cmd string) {
+	c.routes.routes = append(c.routes.routes, cmdRoute{matcher: m, target: cmd})
+}
+
+// route 查找事件对应的处理器，返回自定义处理器或内置事件名
+func (c *Client) route(cmd string) (func(s string), string) {
+	if f, ok := c.customEventHandlers.get(cmd); ok {
+		return f, cmd
+	}
+	if c.routes.normalize {
+		cmd = normalizeCmd(cmd)
+		if f, ok := c.customEventHandlers.get(cmd); ok {
+			return f, cmd
+		}
+	}
+	for _, r := range c.routes.routes {
+		if !r.matcher.Match(cmd) {
+			continue
+		}
+		if r.handler != nil {
+			return r.handler, cmd
+		}
+		return nil, r.target
+	}
+	return nil, cmd
+}
+
+func normalizeCmd(cmd string) string {
+	return strings.ToUpper(strings.TrimSpace(cmd))
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// liveScheduler RoomManager 统一检查未开播房间的开播状态
+type liveScheduler struct {
+	interval time.Duration
+	stop     chan struct{}
+}
+
+// EnableLiveScheduler 只为正在直播的房间保持连接，需要在 AddRoom 之前调用，再次调用时修改检查间隔
+//
+// 之后添加的房间使用 LiveCheckNotify，未开播时不连接，下播后断开；RoomManager 每隔 interval
+// 通过 getRoomBaseInfo 批量检查全部等待中的房间，开播后再连接，监控成千上万个房间时可以大幅减少连接数与请求数
+func (m *RoomManager) EnableLiveScheduler(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultLiveCheckInterval
+	}
+	m.mu.Lock()
+	m.live.interval = interval
+	if m.live.stop == nil {
+		m.live.stop = make(chan struct{})
+		go m.liveSchedulerLoop(m.live.stop)
+	}
+	m.mu.Unlock()
+}
+
+// liveSchedulerLoop 定期检查等待开播的房间，直到 stop 被关闭，每次等待前重新读取检查间隔
+func (m *RoomManager) liveSchedulerLoop(stop chan struct{}) {
+	for {
+		m.mu.Lock()
+		interval := m.live.interval
+		m.mu.Unlock()
+		t := time.NewTimer(interval)
+		select {
+		case <-stop:
+			t.Stop()
+			return
+		case <-t.C:
+			m.checkWaitingRooms()
+		}
+	}
+}
+
+// checkWaitingRooms 批量获取等待中房间的开播状态，通知已开播的房间连接
+//
+// 每个 client 都有自己的 api.Client 副本，因此按接口地址分组，同一地址的房间合并为一次请求，
+// 使用组内任意一个房间的 api.Client 发出，保留 SetAPIBaseURL、User-Agent 与 buvid 等设置
+func (m *RoomManager) checkWaitingRooms() {
+	m.mu.Lock()
+	groups := make(map[string]map[int]*Client)
+	clients := make(map[string]*api.Client)
+	keys := make(map[*Client]string)
+	for key, c := range m.rooms {
+		if c.WaitingForLive() {
+			base := c.apiClient.BaseURL
+			waiting := groups[base]
+			if waiting == nil {
+				waiting = make(map[int]*Client)
+				groups[base] = waiting
+				clients[base] = c.apiClient
+			}
+			waiting[c.RoomID()] = c
+			keys[c] = key
+		}
+	}
+	m.mu.Unlock()
+	for base, waiting := range groups {
+		m.checkLive(clients[base], waiting, keys)
+	}
+}
+
+// checkLive 通过 ac 批量获取 waiting 中房间的开播状态，keys 为房间在 m.rooms 中的 key
+func (m *RoomManager) checkLive(ac *api.Client, waiting map[int]*Client, keys map[*Client]string) {
+	ids := make([]int, 0, len(waiting))
+	for id := range waiting {
+		ids = append(ids, id)
+	}
+	rooms, err := ac.GetRoomBaseInfo(ids...)
+	if err != nil {
+		log.Warn("check live status failed: ", err)
+		return
+	}
+	for id, r := range rooms {
+		c := waiting[id]
+		if c == nil {
+			continue
+		}
+		key := keys[c]
+		// 批量接口同样带有标题与分区，作为变化记录，不需要再单独请求，期间已移除的房间不再记录
+		m.mu.Lock()
+		if m.rooms[key] == c {
+			m.history.add(key, RoomInfoChange{
+				Time:           time.Now(),
+				Title:          r.Title,
+				AreaId:         r.AreaId,
+				AreaName:       r.AreaName,
+				ParentAreaId:   r.ParentAreaId,
+				ParentAreaName: r.ParentAreaName,
+			})
+		}
+		m.mu.Unlock()
+		if r.LiveStatus == 1 {
+			c.NotifyLive()
+		}
+	}
+}
+
+// stopLiveScheduler 停止检查开播状态，调用时需要持有 m.mu
+func (m *RoomManager) stopLiveScheduler() {
+	if m.live.stop != nil {
+		close(m.live.stop)
+		m.live.stop = nil
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCheckWaitingRoomsBatches(t *testing.T) {
+	quietLogs(t)
+	var mu sync.Mutex
+	var requests [][]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "getRoomBaseInfo") {
+			http.NotFound(w, r)
+			return
+		}
+		ids := r.URL.Query()["room_ids"]
+		mu.Lock()
+		requests = append(requests, ids)
+		mu.Unlock()
+		var rooms []string
+		for _, id := range ids {
+			rooms = append(rooms, fmt.Sprintf(`"%s":{"room_id":%s,"live_status":0}`, id, id))
+		}
+		fmt.Fprintf(w, `{"code":0,"data":{"by_room_ids":{%s}}}`, strings.Join(rooms, ","))
+	}))
+	defer api.Close()
+
+	m := NewRoomManager("0", "", "", "")
+	for _, id := range []string{"1001", "1002", "1003"} {
+		c := NewClient(id, "0", "", "", "")
+		c.roomID = id
+		c.SetAPIBaseURL(api.URL)
+		c.bindAPISession()
+		c.live.waiting = 1
+		m.rooms[id] = c
+	}
+	m.checkWaitingRooms()
+	if len(requests) != 1 {
+		t.Fatalf("%d getRoomBaseInfo requests, want 1: %v", len(requests), requests)
+	}
+	got := requests[0]
+	sort.Strings(got)
+	if strings.Join(got, ",") != "1001,1002,1003" {
+		t.Errorf("room_ids = %v, want all waiting rooms", got)
+	}
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// 处理器看到的事件顺序，见 Client.Ordering
+const (
+	OrderNone     = iota // 不保证顺序
+	OrderSender          // 同一发送者的事件按 Meta.Seq 的顺序执行
+	OrderSequence        // 全部事件按 Meta.Seq 的顺序执行，前一个事件的处理器返回后才执行下一个
+)
+
+// sequencer 分配事件序号，并在 DispatchSequenced 模式下按序号执行处理器
+type sequencer struct {
+	last   uint64 // 最近分配的序号
+	mu     sync.Mutex
+	once   sync.Once
+	order  chan *seqGroup // 按收到的顺序等待执行的事件
+	groups map[uint64]*seqGroup
+}
+
+// seqGroup 一个事件的全部处理器，解析完成后 done 关闭
+type seqGroup struct {
+	seq   uint64
+	done  chan struct{}
+	calls []seqCall
+}
+
+type seqCall struct {
+	cmd  string
+	body []byte
+	f    func()
+}
+
+// Ordering 当前配置下处理器看到的事件顺序，OrderNone、OrderSender 或 OrderSequence
+//
+// DispatchOrdered 与 DispatchSequenced 为 OrderSequence，DispatchSharded 为 OrderSender，DispatchAsync 为 OrderNone；
+// SetHandlerTimeout 开启 abandon 时被放弃的处理器可能与之后的事件同时执行，均为 OrderNone。
+// 批量弹幕处理器(OnDanmakuBatch)不受分发模式影响
+func (c *Client) Ordering() int {
+	d := &c.dispatcher
+	if d.timeout > 0 && d.abandon {
+		return OrderNone
+	}
+	switch d.mode {
+	case DispatchOrdered, DispatchSequenced:
+		return OrderSequence
+	case DispatchSharded:
+		return OrderSender
+	}
+	return OrderNone
+}
+
+// nextSeq 为 Notification 包分配房间内单调递增的序号，序号不连续表示中间的包被丢弃或过滤，只在 receive 中调用
+func (c *Client) nextSeq(pkt *packet.Packet) {
+	if pkt.Operation == packet.Notification {
+		pkt.Seq = atomic.AddUint64(&c.sequencer.last, 1)
+	}
+}
+
+// handleSequenced DispatchSequenced 模式下将包交给 worker 解析，处理器由 sequenceLoop 按收到的顺序执行
+func (c *Client) handleSequenced(pkt packet.Packet) {
+	s := &c.sequencer
+	d := &c.dispatcher
+	s.once.Do(func() {
+		s.order = make(chan *seqGroup, orderedQueueSize)
+		c.goLoop("sequenceLoop", c.sequenceLoop)
+	})
+	c.startWorkers()
+	g := &seqGroup{seq: pkt.Seq, done: make(chan struct{})}
+	s.mu.Lock()
+	if s.groups == nil {
+		s.groups = make(map[uint64]*seqGroup)
+	}
+	s.groups[g.seq] = g
+	s.mu.Unlock()
+	atomic.AddInt64(&d.pending, 1)
+	select {
+	case s.order <- g:
+	case <-c.done:
+		atomic.AddInt64(&d.pending, -1)
+		return
+	}
+	select {
+	case d.queues[0] <- func() {
+		defer close(g.done)
+		c.cover(func() { c.Handle(pkt) })
+	}:
+	case <-c.done:
+	}
+}
+
+// sequence DispatchSequenced 模式下记录事件的处理器，未经过 handleSequenced 的包(如直接调用 Handle)立即执行
+func (c *Client) sequence(cmd string, p packet.Packet, f func()) {
+	s := &c.sequencer
+	s.mu.Lock()
+	g := s.groups[p.Seq]
+	if g != nil {
+		g.calls = append(g.calls, seqCall{cmd, p.Body, f})
+	}
+	s.mu.Unlock()
+	if g == nil {
+		c.run(cmd, p.Body, f)
+	}
+}
+
+func (c *Client) sequenceLoop() {
+	s := &c.sequencer
+	for {
+		var g *seqGroup
+		select {
+		case <-c.done:
+			return
+		case g = <-s.order:
+		}
+		select {
+		case <-c.done:
+			return
+		case <-g.done:
+		}
+		s.mu.Lock()
+		delete(s.groups, g.seq)
+		calls := g.calls
+		s.mu.Unlock()
+		for _, call := range calls {
+			c.run(call.cmd, call.body, call.f)
+		}
+		atomic.AddInt64(&c.dispatcher.pending, -1)
+	}
+}
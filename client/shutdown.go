@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shutdownPollInterval Shutdown 检查处理器是否执行完毕的间隔
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Shutdown 停止接收新的事件，等待已收到的事件处理完毕(包括待发送的弹幕批次)后停止 client
+//
+// 暂停期间缓存的包不会再分发，按 DropShutdown 计为丢弃；
+// ctx 结束时不再等待，直接停止并返回包含未处理数量的错误，错误可以用 errors.Is 与 ctx.Err() 比较
+func (c *Client) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&c.draining, 1)
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&c.dispatcher.pending) > 0 {
+		select {
+		case <-ctx.Done():
+			n := atomic.LoadInt64(&c.dispatcher.pending)
+			c.Stop()
+			c.dropPauseBuffer()
+			return fmt.Errorf("%d handlers pending: %w", n, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	c.Stop()
+	// 弹幕批次在 Stop 之后由批处理循环发送
+	stopped := make(chan struct{})
+	go func() {
+		c.Wait()
+		c.dropPauseBuffer()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait for loops: %w", ctx.Err())
+	}
+}
+
+// dropPauseBuffer 将暂停期间缓存、尚未分发的包计为 DropShutdown 丢弃
+func (c *Client) dropPauseBuffer() {
+	c.pause.Lock()
+	defer c.pause.Unlock()
+	for _, pkt := range c.pause.buffer {
+		c.dropped(DropShutdown, pkt)
+	}
+	c.pause.buffer = nil
+}
+
+// isDraining 是否已调用 Shutdown，之后收到的包会被丢弃
+func (c *Client) isDraining() bool {
+	return atomic.LoadInt32(&c.draining) == 1
+}
+
+// OnShutdown 添加 Shutdown 时在全部房间停止后调用的处理器，用于刷新并关闭 Sink、通知等外部输出，按添加的顺序执行
+func (m *RoomManager) OnShutdown(f func(ctx context.Context) error) {
+	m.mu.Lock()
+	m.shutdown = append(m.shutdown, f)
+	m.mu.Unlock()
+}
+
+// Shutdown 停止全部房间，每个房间处理完已收到的事件后断开，再依次调用 OnShutdown 添加的处理器
+//
+// 返回每个房间的结果，nil 表示已处理完全部事件，以及第一个 OnShutdown 处理器返回的错误；
+// ctx 结束时未完成的房间直接停止，剩余的处理器仍会以结束的 ctx 调用，以便尽快关闭输出
+func (m *RoomManager) Shutdown(ctx context.Context) (map[string]error, error) {
+	m.mu.Lock()
+	rooms := m.rooms
+	m.rooms = make(map[string]*Client)
+	m.realRooms = make(map[string]string)
+	m.aliases = make(map[string]string)
+	m.refs = make(map[string]map[string]int)
+	m.adding = make(map[string]*pendingAdd)
+	m.stopLiveScheduler()
+	hooks := m.shutdown
+	m.mu.Unlock()
+	m.flushManifest()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		status = make(map[string]error, len(rooms))
+	)
+	for id, c := range rooms {
+		id, c := id, c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.Shutdown(ctx)
+			mu.Lock()
+			status[id] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	m.history.reset()
+
+	var first error
+	for _, fn := range hooks {
+		if err := fn(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return status, first
+}
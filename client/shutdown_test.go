@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"testing"
+)
+
+func TestShutdownDropsPauseBuffer(t *testing.T) {
+	quietLogs(t)
+	c := NewClient("8792912", "0", "", "", "")
+	c.SetPauseBufferSize(10)
+	c.Pause()
+	for _, s := range []string{"1", "2", "3"} {
+		c.receive(danmakuPacket(s))
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c.drops.mu.Lock()
+	n := c.drops.report.Reasons[DropShutdown]
+	c.drops.mu.Unlock()
+	if n != 3 {
+		t.Errorf("%d buffered events dropped on Shutdown, want 3", n)
+	}
+	if len(c.pause.buffer) != 0 {
+		t.Errorf("%d events left in the pause buffer", len(c.pause.buffer))
+	}
+}
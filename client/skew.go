@@ -0,0 +1,116 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// skewWindow 估算时钟偏差时保留的最近样本数
+const skewWindow = 64
+
+// skewState 本地时钟与服务端时钟的偏差估算
+//
+// 每条带毫秒服务端时间的消息给出 本地收到时间-服务端时间 = 偏差+单程延迟，
+// 取最近样本中的最小值作为延迟最小的一次，再减去心跳往返时间的一半即为偏差；
+// 礼物、醒目留言等只精确到秒的时间戳有最多 1s 的截断误差，不作为样本
+type skewState struct {
+	mu        sync.Mutex
+	enabled   bool
+	fixed     bool
+	offset    time.Duration
+	samples   [skewWindow]time.Duration
+	n         int
+	heartbeat time.Time
+	rtt       time.Duration
+}
+
+// EnableClockSkewCorrection 开启时钟偏差校正
+//
+// 开启后事件 Meta.ReceivedAt 为校正到服务端时钟的收到时间，Meta.Latency 为估算的单程延迟，
+// 多台机器上的 client 统计的时间可以直接比较
+func (c *Client) EnableClockSkewCorrection() {
+	c.skew.mu.Lock()
+	c.skew.enabled = true
+	c.skew.mu.Unlock()
+}
+
+// SetClockSkew 指定本地时钟比服务端时钟快的时长并开启校正，不再自动估算，如使用 NTP 测得的偏差
+func (c *Client) SetClockSkew(d time.Duration) {
+	c.skew.mu.Lock()
+	c.skew.enabled = true
+	c.skew.fixed = true
+	c.skew.offset = d
+	c.skew.mu.Unlock()
+}
+
+// ClockSkew 获取本地时钟比服务端时钟快的时长，未开启校正或没有样本时返回 0
+func (c *Client) ClockSkew() time.Duration {
+	c.skew.mu.Lock()
+	defer c.skew.mu.Unlock()
+	return c.skew.offset
+}
+
+// sentHeartbeat 记录心跳包的发送时间
+func (s *skewState) sentHeartbeat(t time.Time) {
+	s.mu.Lock()
+	s.heartbeat = t
+	s.mu.Unlock()
+}
+
+// heartbeatReply 收到心跳回复，更新往返时间
+func (s *skewState) heartbeatReply(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.heartbeat.IsZero() || t.Before(s.heartbeat) {
+		return
+	}
+	rtt := t.Sub(s.heartbeat)
+	s.heartbeat = time.Time{}
+	if s.rtt == 0 {
+		s.rtt = rtt
+	} else {
+		// 平滑往返时间，避免单次抖动改变偏差
+		s.rtt = (s.rtt*7 + rtt) / 8
+	}
+	s.estimate()
+}
+
+// correct 开启校正时返回校正后的本地收到时间，precise 为 true 时同时记录一个样本
+func (s *skewState) correct(local, server time.Time, precise bool) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return local
+	}
+	if !s.fixed && precise {
+		s.samples[s.n%skewWindow] = local.Sub(server)
+		s.n++
+		s.estimate()
+	}
+	return local.Add(-s.offset)
+}
+
+// estimate 根据样本与往返时间重新估算偏差，调用时需要持有 s.mu
+func (s *skewState) estimate() {
+	if s.fixed || s.n == 0 {
+		return
+	}
+	n := s.n
+	if n > skewWindow {
+		n = skewWindow
+	}
+	low := s.samples[0]
+	for _, d := range s.samples[1:n] {
+		if d < low {
+			low = d
+		}
+	}
+	s.offset = low - s.rtt/2
+}
+
+// HeartbeatRTT 获取平滑后的心跳往返时间，还没有收到心跳回复时返回 0
+func (c *Client) HeartbeatRTT() time.Duration {
+	c.skew.mu.Lock()
+	defer c.skew.mu.Unlock()
+	return c.skew.rtt
+}
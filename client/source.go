@@ -0,0 +1,16 @@
+package client
+
+// DanmakuSource 弹幕事件来源，Client 和 ReplaySource 都实现了该接口
+//
+// 应用只依赖该接口时，切换真实直播间与录制文件回放只需要修改构造方法
+type DanmakuSource interface {
+	eventSource
+	RegisterCustomEventHandler(cmd string, handler func(s string))
+	Start() error
+	Stop()
+}
+
+var (
+	_ DanmakuSource = (*Client)(nil)
+	_ DanmakuSource = (*ReplaySource)(nil)
+)
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// Stats client 的统计信息快照
+type Stats struct {
+	RoomID            int
+	Connected         bool          // 当前是否已连接到弹幕服务器
+	ConnectedAt       time.Time     // 最近一次连接成功的时间
+	LastMessageAt     time.Time     // 最近一次收到消息的时间
+	Reconnects        uint64        // 连接断开后重连的次数
+	ReceivedBytes     uint64        // 从 websocket 收到的原始字节数
+	CompressedBytes   uint64        // 其中压缩包体的字节数
+	DecompressedBytes uint64        // 压缩包体解压后的字节数
+	PlainPackets      uint64        // 未压缩的包数量
+	ZlibPackets       uint64        // zlib 压缩的包数量
+	BrotliPackets     uint64        // brotli 压缩的包数量
+	LatencySamples    uint64        // 统计了延迟的消息数量，只统计注册了处理器的事件
+	AvgLatency        time.Duration // 消息中的服务端时间到收到时的平均延迟
+	MaxLatency        time.Duration
+	LastLatency       time.Duration
+	ClockSkew         time.Duration     // 估算的本地时钟比服务端时钟快的时长
+	HeartbeatRTT      time.Duration     // 平滑后的心跳往返时间
+	Unsubscribed      uint64            // 因未订阅而丢弃的消息数量
+	Shed              uint64            // 因内存紧张而丢弃的消息数量，见 SetMemoryLimit
+	Messages          uint64            // 收到的 Notification 消息数量
+	Dropped           uint64            // 因内存紧张、暂停或 Shutdown 而丢弃的消息数量，见 OnDropReport
+	DroppedCmds       map[string]uint64 // 每个 cmd 丢弃的数量
+}
+
+// CompressionRatio 压缩率，即解压后字节数与压缩字节数之比，没有收到压缩包时返回 0
+func (s Stats) CompressionRatio() float64 {
+	if s.CompressedBytes == 0 {
+		return 0
+	}
+	return float64(s.DecompressedBytes) / float64(s.CompressedBytes)
+}
+
+// stats 内部使用的计数器，字段全部使用 atomic 操作
+type stats struct {
+	connected         int32
+	connectedAt       int64
+	lastMessageAt     int64
+	reconnects        uint64
+	receivedBytes     uint64
+	compressedBytes   uint64
+	decompressedBytes uint64
+	plainPackets      uint64
+	zlibPackets       uint64
+	brotliPackets     uint64
+	latencySamples    uint64
+	latencySum        int64
+	maxLatency        int64
+	lastLatency       int64
+	unsubscribed      uint64
+	shed              uint64
+	messages          uint64
+}
+
+// setConnected 记录连接状态的变化
+func (s *stats) setConnected(connected bool) {
+	if connected {
+		atomic.StoreInt64(&s.connectedAt, time.Now().UnixNano())
+		atomic.StoreInt32(&s.connected, 1)
+		return
+	}
+	if atomic.SwapInt32(&s.connected, 0) == 1 {
+		atomic.AddUint64(&s.reconnects, 1)
+	}
+}
+
+// countFrame 统计一个 websocket 帧以及它解包后的结果
+func (s *stats) countFrame(frameLen int, pkt packet.Packet, pkts []packet.Packet) {
+	atomic.StoreInt64(&s.lastMessageAt, time.Now().UnixNano())
+	atomic.AddUint64(&s.receivedBytes, uint64(frameLen))
+	var messages uint64
+	for _, p := range pkts {
+		if p.Operation == packet.Notification {
+			messages++
+		}
+	}
+	atomic.AddUint64(&s.messages, messages)
+	switch pkt.ProtocolVersion {
+	case packet.Zlib, packet.Brotli:
+		if pkt.ProtocolVersion == packet.Zlib {
+			atomic.AddUint64(&s.zlibPackets, 1)
+		} else {
+			atomic.AddUint64(&s.brotliPackets, 1)
+		}
+		atomic.AddUint64(&s.compressedBytes, uint64(len(pkt.Body)))
+		var n int
+		for _, p := range pkts {
+			n += 16 + len(p.Body)
+		}
+		atomic.AddUint64(&s.decompressedBytes, uint64(n))
+	default:
+		atomic.AddUint64(&s.plainPackets, 1)
+	}
+}
+
+// Stats 获取 client 的统计信息
+func (c *Client) Stats() Stats {
+	s := c.stats
+	rid, _ := strconv.Atoi(c.roomID)
+	samples := atomic.LoadUint64(&s.latencySamples)
+	var avg time.Duration
+	if samples > 0 {
+		avg = time.Duration(atomic.LoadInt64(&s.latencySum) / int64(samples))
+	}
+	dropped, droppedCmds := c.droppedStats()
+	return Stats{
+		RoomID:            rid,
+		Connected:         atomic.LoadInt32(&s.connected) == 1,
+		ConnectedAt:       unixNano(atomic.LoadInt64(&s.connectedAt)),
+		LastMessageAt:     unixNano(atomic.LoadInt64(&s.lastMessageAt)),
+		Reconnects:        atomic.LoadUint64(&s.reconnects),
+		ReceivedBytes:     atomic.LoadUint64(&s.receivedBytes),
+		CompressedBytes:   atomic.LoadUint64(&s.compressedBytes),
+		DecompressedBytes: atomic.LoadUint64(&s.decompressedBytes),
+		PlainPackets:      atomic.LoadUint64(&s.plainPackets),
+		ZlibPackets:       atomic.LoadUint64(&s.zlibPackets),
+		BrotliPackets:     atomic.LoadUint64(&s.brotliPackets),
+		LatencySamples:    samples,
+		AvgLatency:        avg,
+		MaxLatency:        time.Duration(atomic.LoadInt64(&s.maxLatency)),
+		LastLatency:       time.Duration(atomic.LoadInt64(&s.lastLatency)),
+		ClockSkew:         c.ClockSkew(),
+		HeartbeatRTT:      c.HeartbeatRTT(),
+		Unsubscribed:      atomic.LoadUint64(&s.unsubscribed),
+		Shed:              atomic.LoadUint64(&s.shed),
+		Messages:          atomic.LoadUint64(&s.messages),
+		Dropped:           dropped,
+		DroppedCmds:       droppedCmds,
+	}
+}
+
+// unixNano 将纳秒时间戳转换为 time.Time，0 转换为零值
+func unixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
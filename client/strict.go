@@ -0,0 +1,130 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/packet"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// ParseLenient 尽力解析，缺失的字段保持零值，解析失败时仍会分发事件，默认模式
+	ParseLenient = iota
+	// ParseStrict 严格解析，出现未知字段时通过 Errors 报告，解析失败的事件不会分发
+	ParseStrict
+)
+
+// parser 可以从原始报文解析的消息
+type parser interface {
+	Parse(data []byte) error
+}
+
+// SetParseMode 设置消息的解析模式，ParseLenient 或 ParseStrict
+//
+// ParseLenient 模式下没有注册处理器的事件不会解析，ParseStrict 模式下总是解析并检查字段
+func (c *Client) SetParseMode(mode int) {
+	c.parseMode = mode
+}
+
+// parse 解析消息并报告错误，返回 false 时不应分发该消息
+func (c *Client) parse(cmd string, p packet.Packet, v parser) bool {
+	body := p.Body
+	if err := v.Parse(body); err != nil {
+		c.reportError(&DecodeError{Cmd: cmd, Raw: body, Err: err})
+		return c.parseMode != ParseStrict
+	}
+	if c.parseMode == ParseStrict {
+		if err := checkUnknownFields(body, v); err != nil {
+			c.reportError(&DecodeError{Cmd: cmd, Raw: body, Err: err})
+		}
+	}
+	c.stamp(p, v)
+	return true
+}
+
+// danmakuExtraKeys DANMU_MSG 的 info.0.15.extra 中已知的字段
+var danmakuExtraKeys = []string{
+	"send_from_me", "mode", "color", "dm_type", "font_size", "player_mode", "show_player_type",
+	"content", "user_hash", "emoticon_unique", "bulge_display", "recommend_score",
+	"main_state_dm_color", "objective_state_dm_color", "direction", "pk_direction",
+	"quartet_direction", "anniversary_crowd", "yeah_space_type", "yeah_space_url", "jump_to_url",
+	"space_type", "space_url", "animation", "emots", "is_audited", "id_str", "icon", "show_reply",
+	"reply_mid", "reply_uname", "reply_uname_color", "reply_is_mystery", "hit_combo",
+}
+
+// checkUnknownFields 检查报文中是否存在消息结构体中未定义的字段
+func checkUnknownFields(body []byte, v interface{}) error {
+	var (
+		raw    string
+		target interface{}
+	)
+	switch v.(type) {
+	case *message.Danmaku:
+		// extra 中的大部分字段 message.Extra 没有解析，按已知字段列表检查
+		extra := gjson.GetBytes(body, "info.0.15.extra").String()
+		if extra == "" {
+			return nil
+		}
+		return checkKeys(gjson.Parse(extra), danmakuExtraKeys...)
+	case *message.SpecialGift:
+		// data 以礼物 ID 为 key，如 {"39":{"action":"start",...}}
+		var err error
+		gjson.GetBytes(body, "data").ForEach(func(_, g gjson.Result) bool {
+			err = checkKeys(g, "id", "action", "content", "time", "num", "hadJoin", "storm_gif")
+			return err == nil
+		})
+		return err
+	case *message.WidgetBanner:
+		d := gjson.GetBytes(body, "data")
+		if err := checkKeys(d, "timestamp", "widget_list"); err != nil {
+			return err
+		}
+		// widget_list 的 key 不固定，逐个按 message.Widget 检查，值为 null 的挂件已被移除
+		var err error
+		d.Get("widget_list").ForEach(func(_, w gjson.Result) bool {
+			if w.Type != gjson.Null {
+				err = decodeStrict(w.Raw, new(message.Widget))
+			}
+			return err == nil
+		})
+		return err
+	case *message.RoomPunish:
+		return checkKeys(gjson.ParseBytes(body), "cmd", "roomid", "msg", "type", "expire")
+	case *message.Live, *message.Preparing:
+		raw = string(body)
+		target = reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	default:
+		raw = gjson.GetBytes(body, "data").Raw
+		target = reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	}
+	if raw == "" {
+		return nil
+	}
+	return decodeStrict(raw, target)
+}
+
+// decodeStrict 解析 raw，出现 target 中未定义的字段时返回错误
+func decodeStrict(raw string, target interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	dec.DisallowUnknownFields()
+	return dec.Decode(target)
+}
+
+// checkKeys 检查 gjson 解析的对象中是否存在 known 以外的字段，用于没有 json tag 的消息结构体
+func checkKeys(obj gjson.Result, known ...string) error {
+	var err error
+	obj.ForEach(func(k, _ gjson.Result) bool {
+		for _, name := range known {
+			if k.Str == name {
+				return true
+			}
+		}
+		err = fmt.Errorf("json: unknown field %q", k.Str)
+		return false
+	})
+	return err
+}
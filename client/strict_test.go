@@ -0,0 +1,47 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+func TestStrictDanmakuCorpus(t *testing.T) {
+	quietLogs(t)
+	f, err := os.Open("../message/testdata/danmu_msg.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c := NewClient("8792912", "0", "", "", "")
+	c.SetParseMode(ParseStrict)
+	n := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		n++
+		p := packet.NewPlainPacket(packet.Notification, append([]byte(nil), line...))
+		if !c.parse("DANMU_MSG", p, new(message.Danmaku)) {
+			t.Fatalf("line %d: danmaku dropped in strict mode", n)
+		}
+		select {
+		case err := <-c.Errors():
+			t.Fatalf("line %d: %v", n, err)
+		default:
+		}
+	}
+	if err = sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("no DANMU_MSG in corpus")
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"sync/atomic"
+
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// subscription 订阅的 cmd，cmds 为 nil 时表示订阅全部
+type subscription struct {
+	cmds     map[string]struct{}
+	handlers []func(cmd string, body []byte)
+}
+
+// Subscribe 声明只关心的 cmd，可以多次调用追加，需要在 Start 之前调用
+//
+// 其余消息读出 cmd 后立即丢弃，不会解析、过滤或分发，只会交给 OnPacket 与 OnUnsubscribed 的处理器，
+// 适合同时连接大量房间时降低 CPU 占用，自定义事件处理器与路由使用的 cmd 也需要订阅
+func (c *Client) Subscribe(cmds ...string) {
+	if c.subscription.cmds == nil {
+		c.subscription.cmds = make(map[string]struct{}, len(cmds))
+	}
+	for _, cmd := range cmds {
+		c.subscription.cmds[cmd] = struct{}{}
+	}
+}
+
+// OnUnsubscribed 添加 未订阅的消息 的处理器，cmd 为读出的 CMD，body 为原始报文
+//
+// 处理器在读取循环中同步调用，请勿在其中阻塞
+func (c *Client) OnUnsubscribed(f func(cmd string, body []byte)) {
+	c.subscription.handlers = append(c.subscription.handlers, f)
+}
+
+// Subscribed 是否订阅了 cmd，没有调用过 Subscribe 时总是返回 true
+func (c *Client) Subscribed(cmd string) bool {
+	if c.subscription.cmds == nil {
+		return true
+	}
+	_, ok := c.subscription.cmds[baseCmd(cmd)]
+	return ok
+}
+
+// unsubscribed 处理未订阅的消息，返回 true 时该消息已丢弃
+func (c *Client) unsubscribed(pkt packet.Packet) bool {
+	if c.subscription.cmds == nil || pkt.Operation != packet.Notification {
+		return false
+	}
+	cmd := parseCmd(pkt.Body)
+	if c.Subscribed(cmd) {
+		return false
+	}
+	atomic.AddUint64(&c.stats.unsubscribed, 1)
+	for _, fn := range c.subscription.handlers {
+		c.cover(func() { fn(cmd, pkt.Body) })
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultTCPPort 弹幕服务器 TCP 协议的端口
+const DefaultTCPPort = 2243
+
+// maxTCPPacketLength TCP 协议中单个包的最大长度
+const maxTCPPacketLength = 16 << 20
+
+// TCPDialer 使用 TCP 协议连接弹幕服务器，包格式与 websocket 相同，只是没有 websocket 帧
+type TCPDialer struct {
+	Port           int // 为 0 时使用 DefaultTCPPort
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// UseTCP 使用 TCP 协议代替 websocket 连接弹幕服务器，适用于 websocket 升级被干扰的网络环境
+func (c *Client) UseTCP() {
+	c.SetDialer(&TCPDialer{NetDialContext: c.tcpDialContext})
+}
+
+// tcpDialContext 连接时才检查是否设置了自定义解析，UseTCP 之后调用的 SetResolver、SetHostIPs 等同样生效
+func (c *Client) tcpDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.customResolve() {
+		return c.dialContext(ctx, network, addr)
+	}
+	return (&net.Dialer{Timeout: dialTimeout}).DialContext(ctx, network, addr)
+}
+
+func (t *TCPDialer) DialContext(ctx context.Context, rawURL string, _ http.Header) (Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	port := t.Port
+	if port == 0 {
+		port = DefaultTCPPort
+	}
+	dial := t.NetDialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: dialTimeout}).DialContext
+	}
+	conn, err := dial(ctx, "tcp", net.JoinHostPort(u.Hostname(), strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	return &tcpConn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// tcpConn 将 TCP 连接上的包流按包长度拆分为消息
+type tcpConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	mu   sync.Mutex
+}
+
+func (t *tcpConn) ReadMessage() (int, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(t.r, header); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(header)
+	if n < 16 || n > maxTCPPacketLength {
+		return 0, nil, errors.New("invalid tcp packet length " + strconv.Itoa(int(n)))
+	}
+	data := make([]byte, n)
+	copy(data, header)
+	if _, err := io.ReadFull(t.r, data[4:]); err != nil {
+		return 0, nil, err
+	}
+	return websocket.BinaryMessage, data, nil
+}
+
+func (t *tcpConn) WriteMessage(_ int, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.conn.Write(data)
+	return err
+}
+
+func (t *tcpConn) Close() error {
+	return t.conn.Close()
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestUseTCPResolvesAtDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	c := NewClient("8792912", "0", "", "", "")
+	c.UseTCP()
+	// UseTCP 之后才设置的解析同样生效
+	c.SetHostIPs("danmaku.invalid", "127.0.0.1")
+	d := c.customDialer.(*TCPDialer)
+	d.Port = ln.Addr().(*net.TCPAddr).Port
+	conn, err := d.DialContext(context.Background(), "wss://danmaku.invalid/sub", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,281 @@
+// Package command 将以前缀开头的弹幕解析为命令，按权限与冷却时间分发到注册的处理器
+package command
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+// Permission 执行命令需要的权限
+type Permission int
+
+const (
+	Everyone Permission = iota
+	Captain             // 舰长及以上
+	Admiral             // 提督及以上
+	Governor            // 总督
+	Admin               // 房管，可以执行需要舰队等级的命令
+	Owner               // 主播，见 Router.SetOwner
+)
+
+var (
+	// ErrPermission 用户没有执行命令的权限
+	ErrPermission = errors.New("command: permission denied")
+	// ErrCooldown 命令仍在冷却中
+	ErrCooldown = errors.New("command: cooling down")
+)
+
+// Command 一次解析后的命令
+type Command struct {
+	Name    string   // 注册时的命令名，使用别名时也为注册名
+	Alias   string   // 弹幕中实际使用的名称，已转为小写
+	Args    []string // 以空白分隔的参数
+	RawArgs string   // 命令名之后的原始文本
+	RoomID  int
+	Sender  *message.User
+	Danmaku *message.Danmaku
+}
+
+// Arg 获取第 i 个参数，不存在时返回空字符串
+func (c *Command) Arg(i int) string {
+	if i < 0 || i >= len(c.Args) {
+		return ""
+	}
+	return c.Args[i]
+}
+
+// Handler 命令处理器
+type Handler func(c *Command)
+
+// Option 注册命令时的选项
+type Option func(*route)
+
+// WithAliases 命令的别名
+func WithAliases(aliases ...string) Option {
+	return func(r *route) {
+		r.aliases = append(r.aliases, aliases...)
+	}
+}
+
+// WithPermission 执行命令需要的权限，默认 Everyone
+func WithPermission(p Permission) Option {
+	return func(r *route) {
+		r.perm = p
+	}
+}
+
+// WithCooldown 同一用户两次执行命令的最小间隔，房管与主播不受限制
+func WithCooldown(d time.Duration) Option {
+	return func(r *route) {
+		r.cooldown = d
+	}
+}
+
+// WithGlobalCooldown 全部用户两次执行命令的最小间隔，房管与主播不受限制
+func WithGlobalCooldown(d time.Duration) Option {
+	return func(r *route) {
+		r.globalCooldown = d
+	}
+}
+
+type route struct {
+	name           string
+	aliases        []string
+	handler        Handler
+	perm           Permission
+	cooldown       time.Duration
+	globalCooldown time.Duration
+	last           map[roomUser]time.Time
+	lastGlobal     map[int]time.Time
+}
+
+type roomUser struct {
+	room int
+	uid  int
+}
+
+// Router 命令路由
+type Router struct {
+	mu       sync.Mutex
+	prefixes []string
+	routes   map[string]*route
+	owners   map[int]bool
+	reject   func(c *Command, err error)
+	unknown  func(c *Command)
+}
+
+// NewRouter 创建命令路由，prefixes 为命令前缀，默认为 "!" 与全角的 "！"
+func NewRouter(prefixes ...string) *Router {
+	if len(prefixes) == 0 {
+		prefixes = []string{"!", "！"}
+	}
+	return &Router{
+		prefixes: prefixes,
+		routes:   make(map[string]*route),
+		owners:   make(map[int]bool),
+	}
+}
+
+// Handle 注册命令处理器，命令名不区分大小写，重复注册时覆盖之前的处理器
+func (r *Router) Handle(name string, h Handler, opts ...Option) {
+	rt := &route{
+		name:       strings.ToLower(name),
+		handler:    h,
+		last:       make(map[roomUser]time.Time),
+		lastGlobal: make(map[int]time.Time),
+	}
+	for _, opt := range opts {
+		opt(rt)
+	}
+	r.mu.Lock()
+	r.routes[rt.name] = rt
+	for _, a := range rt.aliases {
+		r.routes[strings.ToLower(a)] = rt
+	}
+	r.mu.Unlock()
+}
+
+// SetOwner 设置拥有 Owner 权限的用户，通常为主播的 uid
+func (r *Router) SetOwner(uids ...int) {
+	r.mu.Lock()
+	for _, uid := range uids {
+		r.owners[uid] = true
+	}
+	r.mu.Unlock()
+}
+
+// OnReject 设置命令因权限或冷却被拒绝时调用的函数，err 为 ErrPermission 或 ErrCooldown
+func (r *Router) OnReject(f func(c *Command, err error)) {
+	r.mu.Lock()
+	r.reject = f
+	r.mu.Unlock()
+}
+
+// OnUnknown 设置收到未注册的命令时调用的函数
+func (r *Router) OnUnknown(f func(c *Command)) {
+	r.mu.Lock()
+	r.unknown = f
+	r.mu.Unlock()
+}
+
+// Attach 处理 src 中的弹幕
+func (r *Router) Attach(src client.DanmakuSource) {
+	src.OnDanmaku(func(d *message.Danmaku) {
+		if !d.Backfilled {
+			r.Dispatch(d)
+		}
+	})
+}
+
+// Parse 解析以前缀开头的文本，返回小写的命令名、参数与命令名之后的原始文本
+func (r *Router) Parse(text string) (name string, args []string, rawArgs string, ok bool) {
+	text = strings.TrimSpace(text)
+	for _, p := range r.prefixes {
+		if strings.HasPrefix(text, p) {
+			text = text[len(p):]
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return "", nil, "", false
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", nil, "", false
+	}
+	name = strings.ToLower(fields[0])
+	rawArgs = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), fields[0]))
+	return name, fields[1:], rawArgs, true
+}
+
+// Dispatch 解析弹幕并调用对应的处理器，弹幕不是命令时返回 false
+func (r *Router) Dispatch(d *message.Danmaku) bool {
+	if d.Type != message.TextDanmaku || d.Sender == nil {
+		return false
+	}
+	alias, args, raw, ok := r.Parse(d.Content)
+	if !ok {
+		return false
+	}
+	c := &Command{Name: alias, Alias: alias, Args: args, RawArgs: raw, RoomID: d.RoomID, Sender: d.Sender, Danmaku: d}
+	r.mu.Lock()
+	rt := r.routes[alias]
+	if rt == nil {
+		unknown := r.unknown
+		r.mu.Unlock()
+		if unknown != nil {
+			unknown(c)
+		}
+		return true
+	}
+	c.Name = rt.name
+	err := r.check(rt, d)
+	reject := r.reject
+	r.mu.Unlock()
+	if err != nil {
+		if reject != nil {
+			reject(c, err)
+		}
+		return true
+	}
+	rt.handler(c)
+	return true
+}
+
+// check 检查权限与冷却时间，通过时记录执行时间，调用时需要持有 r.mu
+func (r *Router) check(rt *route, d *message.Danmaku) error {
+	level := r.level(d.Sender)
+	if level < rt.perm {
+		return ErrPermission
+	}
+	if level >= Admin {
+		return nil
+	}
+	now := time.Now()
+	key := roomUser{room: d.RoomID, uid: d.Sender.Uid}
+	if rt.globalCooldown > 0 && now.Sub(rt.lastGlobal[d.RoomID]) < rt.globalCooldown {
+		return ErrCooldown
+	}
+	if rt.cooldown > 0 && now.Sub(rt.last[key]) < rt.cooldown {
+		return ErrCooldown
+	}
+	if rt.globalCooldown > 0 {
+		rt.lastGlobal[d.RoomID] = now
+	}
+	if rt.cooldown > 0 {
+		rt.last[key] = now
+		if len(rt.last) > 1024 {
+			for k, t := range rt.last {
+				if now.Sub(t) >= rt.cooldown {
+					delete(rt.last, k)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// level 用户拥有的最高权限，调用时需要持有 r.mu
+func (r *Router) level(u *message.User) Permission {
+	switch {
+	case r.owners[u.Uid]:
+		return Owner
+	case u.Admin:
+		return Admin
+	}
+	switch u.GuardLevel {
+	case 1:
+		return Governor
+	case 2:
+		return Admiral
+	case 3:
+		return Captain
+	}
+	return Everyone
+}
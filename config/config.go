@@ -0,0 +1,401 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/sink"
+	"github.com/RemKeeper/blivedm-go/sink/clickhouse"
+	"github.com/RemKeeper/blivedm-go/sink/redis"
+	"github.com/RemKeeper/blivedm-go/store"
+)
+
+// Config 声明式的弹幕采集配置
+//
+//	rooms:
+//	  - "8792912"
+//	  - id: "21452505"
+//	    dispatch_mode: ordered
+//	credentials:
+//	  buvid: ${BUVID}
+//	reconnect:
+//	  max_attempts: 10
+//	  delay: 2s
+//	  max_delay: 1m
+//	sinks:
+//	  - type: record
+//	    options: {path: danmaku.jsonl.gz}
+//	    filter: {cmds: [DANMU_MSG, SEND_GIFT]}
+type Config struct {
+	Rooms       []RoomConfig           `config:"rooms"`
+	Credentials Credentials            `config:"credentials"`
+	Reconnect   client.ReconnectPolicy `config:"reconnect"`
+	Sinks       []SinkConfig           `config:"sinks"`
+	Filters     FilterConfig           `config:"filters"` // 对全部 Sink 生效的过滤器
+}
+
+// Credentials 创建 client 使用的身份信息，含义与 client.NewClient 的参数相同
+type Credentials struct {
+	EnterUID   string   `config:"enter_uid"`
+	Buvid      string   `config:"buvid"`
+	UserAgent  string   `config:"user_agent"`
+	UserAgents []string `config:"user_agents"` // 设置后轮换使用，覆盖 UserAgent
+	Referer    string   `config:"referer"`
+}
+
+// RoomConfig 单个房间的配置，只需要房间号时可以直接写成字符串
+type RoomConfig struct {
+	ID           string                  `config:"id"`
+	ParseMode    string                  `config:"parse_mode"`    // lenient 或 strict
+	DispatchMode string                  `config:"dispatch_mode"` // async、ordered、sharded 或 sequenced
+	Shards       int                     `config:"shards"`
+	Workers      int                     `config:"workers"`         // async 模式下的 worker 数量
+	Legacy       bool                    `config:"legacy_dispatch"` // async 模式下每个事件一个 goroutine
+	Backfill     bool                    `config:"backfill"`
+	TCP          bool                    `config:"tcp"`
+	Host         string                  `config:"host"`
+	APIBaseURL   string                  `config:"api_base_url"`
+	Reconnect    *client.ReconnectPolicy `config:"reconnect"` // 未设置时使用全局的重连策略
+}
+
+func (r *RoomConfig) decodeScalar(s string) error {
+	r.ID = s
+	return nil
+}
+
+// SinkConfig 一个事件输出
+type SinkConfig struct {
+	Type    string       `config:"type"`
+	Options Options      `config:"options"` // 由对应类型的 SinkFactory 解码
+	Filter  FilterConfig `config:"filter"`
+}
+
+// FilterConfig 事件过滤规则，均为空时不过滤
+type FilterConfig struct {
+	Cmds          []string `config:"cmds"`         // 只保留这些事件
+	ExcludeCmds   []string `config:"exclude_cmds"` // 丢弃这些事件
+	BlockUsers    []int    `config:"block_users"`  // 丢弃这些用户的事件
+	BlockKeywords []string `config:"block_keywords"`
+}
+
+// SinkFactory 根据配置项创建 Sink
+type SinkFactory func(opts Options) (sink.Sink, error)
+
+var (
+	sinkMu        sync.Mutex
+	sinkFactories = map[string]SinkFactory{
+		"record":     newRecordSink,
+		"jsonl":      newJSONLinesSink,
+		"sqlite":     newSQLiteSink,
+		"clickhouse": newClickHouseSink,
+		"redis":      newRedisSink,
+	}
+)
+
+// RegisterSink 注册 Sink 类型，供配置中的 sinks[].type 使用
+func RegisterSink(typ string, f SinkFactory) {
+	sinkMu.Lock()
+	sinkFactories[typ] = f
+	sinkMu.Unlock()
+}
+
+func sinkFactory(typ string) SinkFactory {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	return sinkFactories[typ]
+}
+
+// LoadFile 读取并校验配置文件
+func LoadFile(path string) (*Config, error) {
+	cfg := &Config{}
+	if err := Load(path, cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 检查配置，返回的错误包含全部问题
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	seen := make(map[string]bool)
+	for i, r := range c.Rooms {
+		if _, err := strconv.Atoi(r.ID); err != nil {
+			add("rooms[%d]: invalid room id %q", i, r.ID)
+		} else if seen[r.ID] {
+			add("rooms[%d]: duplicate room %s", i, r.ID)
+		}
+		seen[r.ID] = true
+		if _, err := parseMode(r.ParseMode); err != nil {
+			add("rooms[%d]: %v", i, err)
+		}
+		if _, err := dispatchMode(r.DispatchMode); err != nil {
+			add("rooms[%d]: %v", i, err)
+		}
+		if r.Shards < 0 {
+			add("rooms[%d]: shards must not be negative", i)
+		}
+		if r.Reconnect != nil {
+			validateReconnect(fmt.Sprintf("rooms[%d].reconnect", i), *r.Reconnect, add)
+		}
+	}
+	validateReconnect("reconnect", c.Reconnect, add)
+	for i, s := range c.Sinks {
+		if sinkFactory(s.Type) == nil {
+			add("sinks[%d]: unknown sink type %q", i, s.Type)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func validateReconnect(path string, p client.ReconnectPolicy, add func(string, ...interface{})) {
+	if p.MaxAttempts < 0 || p.Delay < 0 || p.MaxDelay < 0 {
+		add("%s: values must not be negative", path)
+	}
+}
+
+func parseMode(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "", "lenient":
+		return client.ParseLenient, nil
+	case "strict":
+		return client.ParseStrict, nil
+	}
+	return 0, fmt.Errorf("unknown parse_mode %q", s)
+}
+
+func dispatchMode(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "", "async":
+		return client.DispatchAsync, nil
+	case "ordered":
+		return client.DispatchOrdered, nil
+	case "sharded":
+		return client.DispatchSharded, nil
+	case "sequenced":
+		return client.DispatchSequenced, nil
+	}
+	return 0, fmt.Errorf("unknown dispatch_mode %q", s)
+}
+
+// RoomOptions 转换为 client.RoomOptions，未设置重连策略时使用 global
+func (r *RoomConfig) RoomOptions(global client.ReconnectPolicy) *client.RoomOptions {
+	pm, _ := parseMode(r.ParseMode)
+	dm, _ := dispatchMode(r.DispatchMode)
+	reconnect := global
+	if r.Reconnect != nil {
+		reconnect = *r.Reconnect
+	}
+	return &client.RoomOptions{
+		ParseMode:    pm,
+		DispatchMode: dm,
+		Shards:       r.Shards,
+		Workers:      r.Workers,
+		Legacy:       r.Legacy,
+		Backfill:     r.Backfill,
+		TCP:          r.TCP,
+		Host:         r.Host,
+		APIBaseURL:   r.APIBaseURL,
+		Reconnect:    &reconnect,
+	}
+}
+
+// Filters 将过滤规则转为 sink.Filter
+func (f *FilterConfig) Filters() []sink.Filter {
+	var filters []sink.Filter
+	if len(f.Cmds) > 0 {
+		filters = append(filters, sink.CmdFilter(f.Cmds...))
+	}
+	if len(f.ExcludeCmds) > 0 {
+		filters = append(filters, sink.ExcludeCmdFilter(f.ExcludeCmds...))
+	}
+	if len(f.BlockUsers) > 0 {
+		filters = append(filters, sink.UserFilter(f.BlockUsers...))
+	}
+	if len(f.BlockKeywords) > 0 {
+		filters = append(filters, sink.KeywordFilter(f.BlockKeywords...))
+	}
+	return filters
+}
+
+// recordOptions record 与 jsonl 类型 Sink 的配置项
+type recordOptions struct {
+	Path string `config:"path"`
+}
+
+// newRecordSink 写入录制文件，path 以 .gz 结尾时使用 gzip 压缩
+//
+// wal 为 true 时使用预写日志，resume 为 true 时在已有的文件末尾继续追加，见 record.Resume
+func newRecordSink(opts Options) (sink.Sink, error) {
+	var o struct {
+		recordOptions
+		WAL          bool          `config:"wal"`
+		Sync         string        `config:"sync"` // block、none、always 或 interval，默认 block
+		SyncInterval time.Duration `config:"sync_interval"`
+		Resume       bool          `config:"resume"`
+	}
+	if err := opts.Decode(&o); err != nil {
+		return nil, err
+	}
+	if o.Path == "" {
+		return nil, fmt.Errorf("record sink requires path")
+	}
+	fo := record.FileOptions{WAL: o.WAL, SyncInterval: o.SyncInterval}
+	switch strings.ToLower(o.Sync) {
+	case "", "block":
+		fo.Sync = record.SyncBlock
+	case "none":
+		fo.Sync = record.SyncNone
+	case "always":
+		fo.Sync = record.SyncAlways
+	case "interval":
+		fo.Sync = record.SyncInterval
+	default:
+		return nil, fmt.Errorf("unknown sync policy %q", o.Sync)
+	}
+	open := record.CreateWithOptions
+	if o.Resume {
+		open = record.Resume
+	}
+	f, err := open(o.Path, fo)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// newJSONLinesSink 每行写入一个事件 JSON，path 为空或 - 时写入标准输出
+//
+// schema 为输出格式，entry、raw、flat 或 compact，默认 entry，见 sink.NewEncoder
+func newJSONLinesSink(opts Options) (sink.Sink, error) {
+	var o struct {
+		recordOptions
+		Schema string `config:"schema"`
+	}
+	if err := opts.Decode(&o); err != nil {
+		return nil, err
+	}
+	enc, err := sink.NewEncoder(o.Schema)
+	if err != nil {
+		return nil, err
+	}
+	if o.Path == "" || o.Path == "-" {
+		return sink.NewJSONLinesEncoder(nopCloser{os.Stdout}, enc), nil
+	}
+	f, err := os.OpenFile(o.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return sink.NewJSONLinesEncoder(f, enc), nil
+}
+
+// sqliteOptions sqlite 类型 Sink 的配置项，需要导入 driver 对应的 SQLite 驱动
+type sqliteOptions struct {
+	Driver        string        `config:"driver"` // 默认 sqlite3
+	DSN           string        `config:"dsn"`
+	BatchSize     int           `config:"batch_size"`
+	FlushInterval time.Duration `config:"flush_interval"`
+	MaxPending    int           `config:"max_pending"`
+	Tokenizer     string        `config:"tokenizer"`
+}
+
+// newSQLiteSink 写入 store.Store，Close 时同时关闭数据库
+func newSQLiteSink(opts Options) (sink.Sink, error) {
+	o := sqliteOptions{Driver: "sqlite3"}
+	if err := opts.Decode(&o); err != nil {
+		return nil, err
+	}
+	if o.DSN == "" {
+		return nil, fmt.Errorf("sqlite sink requires dsn")
+	}
+	db, err := sql.Open(o.Driver, o.DSN)
+	if err != nil {
+		return nil, err
+	}
+	st, err := store.NewWithOptions(db, store.Options{BatchSize: o.BatchSize, FlushInterval: o.FlushInterval, MaxPending: o.MaxPending, Tokenizer: o.Tokenizer})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &storeSink{st}, nil
+}
+
+type storeSink struct {
+	*store.Store
+}
+
+func (s *storeSink) Close() error {
+	err := s.Store.Close()
+	if cerr := s.DB().Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// newClickHouseSink 写入 ClickHouse，配置项见 clickhouse.Options，create_tables 为 true 时自动建表
+func newClickHouseSink(opts Options) (sink.Sink, error) {
+	var o struct {
+		clickhouse.Options
+		CreateTables bool `config:"create_tables"`
+	}
+	if err := opts.Decode(&o); err != nil {
+		return nil, err
+	}
+	s, err := clickhouse.New(o.Options)
+	if err != nil {
+		return nil, err
+	}
+	if o.CreateTables {
+		if err := s.CreateTables(context.Background()); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// newRedisSink 发布到 Redis 频道或写入 Redis Stream，配置项见 redis.Options
+func newRedisSink(opts Options) (sink.Sink, error) {
+	var o redis.Options
+	if err := opts.Decode(&o); err != nil {
+		return nil, err
+	}
+	return redis.New(o)
+}
+
+type nopCloser struct {
+	*os.File
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// SinkTypes 已注册的 Sink 类型
+func SinkTypes() []string {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	types := make([]string, 0, len(sinkFactories))
+	for t := range sinkFactories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,323 @@
+// Package config 读取 YAML、TOML、JSON 格式的配置文件
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 支持的配置格式
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatJSON = "json"
+)
+
+// scalarDecoder 可以由单个标量简写的结构体，如房间既可以写成 "123" 也可以写成 {id: "123"}
+type scalarDecoder interface {
+	decodeScalar(s string) error
+}
+
+// Options 未定义结构的配置项，由使用方解码到自己的结构体
+type Options map[string]interface{}
+
+// Decode 将配置项解码到 v，规则与 Unmarshal 相同
+func (o Options) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("config: Decode(non-pointer %T)", v)
+	}
+	if o == nil {
+		return nil
+	}
+	return decode(map[string]interface{}(o), rv.Elem(), "")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FormatOf 根据文件扩展名判断配置格式
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	case ".json":
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown config format of %s", path)
+}
+
+// Load 读取配置文件到 v，格式由扩展名决定
+func Load(path string, v interface{}) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := Unmarshal(data, format, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Unmarshal 按 format 解析配置到 v
+//
+// 结构体字段名取 config 标签，没有时取 json 标签，都没有时使用小写的字段名，匹配时忽略大小写
+// 配置中存在结构体未定义的键时返回错误，time.Duration 字段使用 "10s" 形式的字符串
+// 字符串中的 ${VAR} 与 ${VAR:-default} 会替换为环境变量
+func Unmarshal(data []byte, format string, v interface{}) error {
+	var (
+		tree interface{}
+		err  error
+	)
+	switch format {
+	case FormatYAML:
+		tree, err = parseYAML(data)
+	case FormatTOML:
+		tree, err = parseTOML(data)
+	case FormatJSON:
+		err = json.Unmarshal(data, &tree)
+	default:
+		err = fmt.Errorf("unknown config format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("config: Unmarshal(non-pointer %T)", v)
+	}
+	return decode(tree, rv.Elem(), "")
+}
+
+func decode(in interface{}, rv reflect.Value, path string) error {
+	if in == nil {
+		return nil
+	}
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decode(in, rv.Elem(), path)
+	}
+	if rv.CanAddr() {
+		if u, ok := rv.Addr().Interface().(scalarDecoder); ok {
+			if s, ok := scalarString(in); ok {
+				return u.decodeScalar(s)
+			}
+		}
+		if u, ok := rv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			s, ok := scalarString(in)
+			if !ok {
+				return typeError(path, in, rv.Type())
+			}
+			if err := u.UnmarshalText([]byte(s)); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			return nil
+		}
+	}
+	if rv.Type() == durationType {
+		s, ok := scalarString(in)
+		if !ok {
+			return typeError(path, in, rv.Type())
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		rv.SetInt(int64(d))
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(resolve(in)))
+	case reflect.String:
+		s, ok := scalarString(in)
+		if !ok {
+			return typeError(path, in, rv.Type())
+		}
+		rv.SetString(s)
+	case reflect.Bool:
+		switch b := resolve(in).(type) {
+		case bool:
+			rv.SetBool(b)
+		default:
+			return typeError(path, in, rv.Type())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s, ok := scalarString(in)
+		i, err := strconv.ParseInt(s, 10, 64)
+		if !ok || err != nil || rv.OverflowInt(i) {
+			return typeError(path, in, rv.Type())
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s, ok := scalarString(in)
+		i, err := strconv.ParseUint(s, 10, 64)
+		if !ok || err != nil || rv.OverflowUint(i) {
+			return typeError(path, in, rv.Type())
+		}
+		rv.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		s, ok := scalarString(in)
+		f, err := strconv.ParseFloat(s, 64)
+		if !ok || err != nil {
+			return typeError(path, in, rv.Type())
+		}
+		rv.SetFloat(f)
+	case reflect.Slice:
+		list, ok := in.([]interface{})
+		if !ok {
+			return typeError(path, in, rv.Type())
+		}
+		s := reflect.MakeSlice(rv.Type(), len(list), len(list))
+		for i, e := range list {
+			if err := decode(e, s.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(s)
+	case reflect.Map:
+		m, ok := in.(map[string]interface{})
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return typeError(path, in, rv.Type())
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for k, e := range m {
+			ev := reflect.New(rv.Type().Elem()).Elem()
+			if err := decode(e, ev, join(path, k)); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), ev)
+		}
+	case reflect.Struct:
+		m, ok := in.(map[string]interface{})
+		if !ok {
+			return typeError(path, in, rv.Type())
+		}
+		fields := make(map[string]reflect.Value)
+		collectFields(rv, fields)
+		for k, e := range m {
+			f, ok := fields[strings.ToLower(k)]
+			if !ok {
+				return fmt.Errorf("%s: unknown field", join(path, k))
+			}
+			if err := decode(e, f, join(path, k)); err != nil {
+				return err
+			}
+		}
+	default:
+		return typeError(path, in, rv.Type())
+	}
+	return nil
+}
+
+// collectFields 收集结构体可设置的字段，匿名嵌入的结构体字段会展开
+func collectFields(rv reflect.Value, fields map[string]reflect.Value) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		name := sf.Tag.Get("config")
+		if name == "" {
+			name = strings.Split(sf.Tag.Get("json"), ",")[0]
+		}
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			collectFields(rv.Field(i), fields)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields[strings.ToLower(name)] = rv.Field(i)
+	}
+}
+
+// scalarString 将标量转为字符串，整数形式的浮点数不带小数部分
+func scalarString(in interface{}) (string, bool) {
+	switch v := in.(type) {
+	case string:
+		return expandEnv(v), true
+	case plain:
+		return expandEnv(string(v)), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// resolve 将 YAML 未加引号的标量解释为布尔值、数字或字符串
+func resolve(in interface{}) interface{} {
+	switch v := in.(type) {
+	case string:
+		return expandEnv(v)
+	case plain:
+		s := expandEnv(string(v))
+		switch s {
+		case "true", "True", "TRUE":
+			return true
+		case "false", "False", "FALSE":
+			return false
+		}
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && isNumber(s) {
+			return f
+		}
+		return s
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = resolve(e)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = resolve(e)
+		}
+		return out
+	}
+	return in
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func typeError(path string, in interface{}, t reflect.Type) error {
+	if path == "" {
+		path = "config"
+	}
+	return fmt.Errorf("%s: cannot use %v as %s", path, resolve(in), t)
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// expandEnv 替换字符串中的 ${VAR} 与 ${VAR:-default}，$${ 表示字面量 ${
+//
+// 只识别带花括号的写法，User-Agent 等值中单独的 $ 保持不变
+func expandEnv(s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i])
+			b.WriteString("{")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		name := s[i+2 : i+end]
+		def := ""
+		if j := strings.Index(name, ":-"); j >= 0 {
+			name, def = name[:j], name[j+2:]
+		}
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			b.WriteString(v)
+		} else {
+			b.WriteString(def)
+		}
+		s = s[i+end+1:]
+	}
+}
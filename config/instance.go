@@ -0,0 +1,314 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/RemKeeper/blivedm-go/api"
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/sink"
+)
+
+// Instance 由配置创建的 RoomManager 与 Sink
+type Instance struct {
+	Manager    *client.RoomManager
+	mu         sync.Mutex
+	config     *Config
+	sinks      *fanout
+	userAgents atomic.Value // userAgentRotation
+}
+
+type userAgentRotation struct {
+	next func() string
+}
+
+// ReloadResult Reload 对房间与 Sink 做出的修改
+type ReloadResult struct {
+	Added        []string
+	Removed      []string
+	Restarted    []string         // 配置变化而重新连接的房间
+	Failed       map[string]error // 添加或重新连接失败的房间
+	SinksChanged bool
+}
+
+// Build 校验配置并创建 RoomManager 与全部 Sink，不会连接房间
+//
+// 返回后可以通过 Manager 注册事件处理器，再调用 Start
+func (c *Config) Build() (*Instance, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	cred := c.Credentials
+	inst := &Instance{
+		Manager: client.NewRoomManager(cred.EnterUID, cred.Buvid, cred.UserAgent, cred.Referer),
+		config:  c,
+		sinks:   &fanout{},
+	}
+	entries, _, err := inst.buildSinks(c, nil)
+	if err != nil {
+		return nil, err
+	}
+	inst.sinks.entries = entries
+	inst.setUserAgents(cred.UserAgents)
+	inst.Manager.OnClient(func(_ string, cl *client.Client) {
+		inst.setup(cl)
+	})
+	for i := range c.Rooms {
+		r := &c.Rooms[i]
+		inst.Manager.SetRoomOptions(r.ID, r.RoomOptions(c.Reconnect))
+	}
+	return inst, nil
+}
+
+// Config 获取当前生效的配置
+func (i *Instance) Config() *Config {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.config
+}
+
+// Sinks 获取当前的全部 Sink
+func (i *Instance) Sinks() []sink.Sink {
+	i.sinks.mu.RLock()
+	defer i.sinks.mu.RUnlock()
+	sinks := make([]sink.Sink, 0, len(i.sinks.entries))
+	for _, e := range i.sinks.entries {
+		sinks = append(sinks, e.sink)
+	}
+	return sinks
+}
+
+// setup 为 client 设置 User-Agent 并接入全部 Sink
+func (i *Instance) setup(cl *client.Client) {
+	cl.SetUserAgentFunc(i.userAgent)
+	sink.Attach(cl, i.sinks)
+}
+
+// userAgent 配置了 user_agents 时轮换使用，否则使用 user_agent
+func (i *Instance) userAgent() string {
+	if r, _ := i.userAgents.Load().(userAgentRotation); r.next != nil {
+		return r.next()
+	}
+	return i.Config().Credentials.UserAgent
+}
+
+func (i *Instance) setUserAgents(uas []string) {
+	var r userAgentRotation
+	if len(uas) > 0 {
+		r.next = api.RotateUserAgents(uas...)
+	}
+	i.userAgents.Store(r)
+}
+
+// NewClient 创建一个不由 Manager 管理的 client，使用与 Manager 中房间相同的配置与 Sink
+func (i *Instance) NewClient(room RoomConfig) *client.Client {
+	c := i.Config()
+	cred := c.Credentials
+	cl := client.NewClient(room.ID, cred.EnterUID, cred.Buvid, cred.UserAgent, cred.Referer)
+	room.RoomOptions(c.Reconnect).Apply(cl)
+	i.setup(cl)
+	return cl
+}
+
+// RoomIDs 配置中的全部房间号
+func (c *Config) RoomIDs() []string {
+	ids := make([]string, 0, len(c.Rooms))
+	for _, r := range c.Rooms {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// Start 连接配置中的全部房间，返回值与 RoomManager.AddRooms 相同
+func (i *Instance) Start(opts ...client.AddRoomsOption) map[string]error {
+	return i.Manager.AddRooms(i.Config().RoomIDs(), opts...)
+}
+
+// Stop 停止全部 client 并关闭全部 Sink
+func (i *Instance) Stop() error {
+	i.Manager.Stop()
+	return i.sinks.Close()
+}
+
+// Shutdown 等待全部房间处理完已收到的事件后停止 client，再刷新并关闭全部 Sink，返回值与 RoomManager.Shutdown 相同
+//
+// ctx 结束时不再等待 Sink 关闭
+func (i *Instance) Shutdown(ctx context.Context) (map[string]error, error) {
+	status, err := i.Manager.Shutdown(ctx)
+	closed := make(chan error, 1)
+	go func() { closed <- i.sinks.Close() }()
+	select {
+	case cerr := <-closed:
+		if err == nil {
+			err = cerr
+		}
+	case <-ctx.Done():
+		if err == nil {
+			err = fmt.Errorf("close sinks: %w", ctx.Err())
+		}
+	}
+	return status, err
+}
+
+// Reload 应用新的配置，已连接且配置未变化的房间不会断开
+//
+// 移除不再配置的房间，添加新的房间，房间配置变化时重新连接该房间；
+// 配置未变化的 Sink 继续使用，其余的重新创建；身份信息变化时已连接的房间在下次重连时使用新的身份信息
+func (i *Instance) Reload(c *Config, opts ...client.AddRoomsOption) (*ReloadResult, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	old := i.config
+	res := &ReloadResult{}
+
+	i.sinks.mu.RLock()
+	current := i.sinks.entries
+	i.sinks.mu.RUnlock()
+	entries, kept, err := i.buildSinks(c, current)
+	if err != nil {
+		return nil, err
+	}
+	res.SinksChanged = len(entries) != len(current) || len(kept) != len(current)
+	i.sinks.mu.Lock()
+	i.sinks.entries = entries
+	i.sinks.mu.Unlock()
+	for n, e := range current {
+		if !kept[n] {
+			_ = e.sink.Close()
+		}
+	}
+
+	i.config = c
+	if cred := c.Credentials; !reflect.DeepEqual(cred, old.Credentials) {
+		i.Manager.SetCredentials(cred.EnterUID, cred.Buvid, cred.UserAgent, cred.Referer)
+		i.setUserAgents(cred.UserAgents)
+	}
+
+	oldRooms := make(map[string]*client.RoomOptions)
+	for n := range old.Rooms {
+		oldRooms[old.Rooms[n].ID] = old.Rooms[n].RoomOptions(old.Reconnect)
+	}
+	var add []string
+	newRooms := make(map[string]bool)
+	for n := range c.Rooms {
+		r := &c.Rooms[n]
+		newRooms[r.ID] = true
+		opts := r.RoomOptions(c.Reconnect)
+		prev, ok := oldRooms[r.ID]
+		switch {
+		case !ok:
+			res.Added = append(res.Added, r.ID)
+		case !reflect.DeepEqual(prev, opts):
+			res.Restarted = append(res.Restarted, r.ID)
+			i.Manager.RemoveRoom(r.ID)
+		default:
+			continue
+		}
+		i.Manager.SetRoomOptions(r.ID, opts)
+		add = append(add, r.ID)
+	}
+	for id := range oldRooms {
+		if !newRooms[id] {
+			res.Removed = append(res.Removed, id)
+			i.Manager.RemoveRoom(id)
+		}
+	}
+	res.Failed = i.Manager.AddRooms(add, opts...)
+	return res, nil
+}
+
+// buildSinks 按配置创建 Sink，与 reuse 中配置相同的 Sink 直接复用，返回被复用的下标
+//
+// 出错时关闭新创建的 Sink
+func (i *Instance) buildSinks(c *Config, reuse []*sinkEntry) ([]*sinkEntry, map[int]bool, error) {
+	global := c.Filters.Filters()
+	used := make(map[int]bool)
+	var entries, created []*sinkEntry
+	for n, sc := range c.Sinks {
+		filters := append(append([]sink.Filter(nil), global...), sc.Filter.Filters()...)
+		var s sink.Sink
+		for k, e := range reuse {
+			if !used[k] && reflect.DeepEqual(e.config, sc) {
+				s = e.sink
+				used[k] = true
+				break
+			}
+		}
+		entry := &sinkEntry{config: sc, sink: s, filters: filters}
+		if s == nil {
+			var err error
+			if entry.sink, err = sinkFactory(sc.Type)(sc.Options); err != nil {
+				for _, e := range created {
+					_ = e.sink.Close()
+				}
+				return nil, nil, fmt.Errorf("sinks[%d]: %w", n, err)
+			}
+			created = append(created, entry)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, used, nil
+}
+
+type sinkEntry struct {
+	config  SinkConfig
+	sink    sink.Sink
+	filters []sink.Filter
+}
+
+// flusher 可以在关闭前刷新缓冲的 Sink
+type flusher interface {
+	Flush() error
+}
+
+// fanout 将事件写入全部 Sink，Sink 列表可以在运行中替换
+type fanout struct {
+	mu      sync.RWMutex
+	entries []*sinkEntry
+}
+
+func (f *fanout) Write(e *record.Entry) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.entries) == 0 {
+		return nil
+	}
+	cmd := sink.Cmd(e)
+	var first error
+next:
+	for _, s := range f.entries {
+		for _, filter := range s.filters {
+			if !filter(cmd, e) {
+				continue next
+			}
+		}
+		if err := s.sink.Write(e); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (f *fanout) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var first error
+	for _, e := range f.entries {
+		if fl, ok := e.sink.(flusher); ok {
+			if err := fl.Flush(); err != nil && first == nil {
+				first = err
+			}
+		}
+		if err := e.sink.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	f.entries = nil
+	return first
+}
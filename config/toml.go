@@ -0,0 +1,329 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+	root map[string]interface{}
+}
+
+// parseTOML 解析 TOML 的常用子集：表、表数组、点分键、字符串、数字、布尔值、数组与内联表
+//
+// 日期时间按字符串返回
+func parseTOML(data []byte) (interface{}, error) {
+	p := &tomlParser{s: string(data), line: 1, root: make(map[string]interface{})}
+	current := p.root
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.s) {
+			return p.root, nil
+		}
+		var err error
+		if p.s[p.pos] == '[' {
+			current, err = p.header()
+		} else {
+			err = p.keyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace 跳过空白与注释，newline 为 true 时同时跳过换行
+func (p *tomlParser) skipSpace(newline bool) {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			if !newline {
+				return
+			}
+			p.line++
+			p.pos++
+		case '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace(false)
+	if p.pos < len(p.s) && p.s[p.pos] != '\n' {
+		return p.errorf("unexpected %q", p.rest())
+	}
+	return nil
+}
+
+func (p *tomlParser) rest() string {
+	end := strings.IndexByte(p.s[p.pos:], '\n')
+	if end < 0 {
+		return p.s[p.pos:]
+	}
+	return p.s[p.pos : p.pos+end]
+}
+
+// header 解析 [table] 或 [[array]]，返回之后键值对所在的表
+func (p *tomlParser) header() (map[string]interface{}, error) {
+	array := strings.HasPrefix(p.s[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.s[p.pos:], closing) {
+		return nil, p.errorf("expected %s", closing)
+	}
+	p.pos += len(closing)
+	parent, err := p.table(p.root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	if array {
+		list, _ := parent[last].([]interface{})
+		if _, ok := parent[last]; ok && list == nil {
+			return nil, p.errorf("key %q is not an array of tables", last)
+		}
+		t := make(map[string]interface{})
+		parent[last] = append(list, t)
+		return t, nil
+	}
+	return p.table(parent, []string{last})
+}
+
+// table 沿 keys 查找或创建子表，表数组取最后一个元素
+func (p *tomlParser) table(t map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, k := range keys {
+		switch v := t[k].(type) {
+		case nil:
+			n := make(map[string]interface{})
+			t[k] = n
+			t = n
+		case map[string]interface{}:
+			t = v
+		case []interface{}:
+			last, ok := v[len(v)-1].(map[string]interface{})
+			if !ok {
+				return nil, p.errorf("key %q is not a table", k)
+			}
+			t = last
+		default:
+			return nil, p.errorf("key %q is not a table", k)
+		}
+	}
+	return t, nil
+}
+
+func (p *tomlParser) keyValue(t map[string]interface{}) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+		return p.errorf("expected '=' after key")
+	}
+	p.pos++
+	p.skipSpace(false)
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	t, err = p.table(t, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, dup := t[last]; dup {
+		return p.errorf("duplicate key %q", last)
+	}
+	t[last] = v
+	return nil
+}
+
+// key 解析可能带引号的点分键
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace(false)
+		if p.pos >= len(p.s) {
+			return nil, p.errorf("expected key")
+		}
+		switch c := p.s[p.pos]; {
+		case c == '"' || c == '\'':
+			s, n, err := readQuoted(p.rest())
+			if err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			p.pos += n
+			keys = append(keys, s)
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isBareKey(p.s[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected key, got %q", p.rest())
+			}
+			keys = append(keys, p.s[start:p.pos])
+		}
+		p.skipSpace(false)
+		if p.pos < len(p.s) && p.s[p.pos] == '.' {
+			p.pos++
+			continue
+		}
+		return keys, nil
+	}
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) value() (interface{}, error) {
+	if p.pos >= len(p.s) {
+		return nil, p.errorf("expected value")
+	}
+	switch p.s[p.pos] {
+	case '"', '\'':
+		return p.str()
+	case '[':
+		p.pos++
+		list := make([]interface{}, 0)
+		for {
+			p.skipSpace(true)
+			if p.pos < len(p.s) && p.s[p.pos] == ']' {
+				p.pos++
+				return list, nil
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			p.skipSpace(true)
+			if p.pos < len(p.s) && p.s[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.s) || p.s[p.pos] != ']' {
+				return nil, p.errorf("expected ',' or ']' in array")
+			}
+		}
+	case '{':
+		p.pos++
+		t := make(map[string]interface{})
+		for {
+			p.skipSpace(false)
+			if p.pos < len(p.s) && p.s[p.pos] == '}' {
+				p.pos++
+				return t, nil
+			}
+			if err := p.keyValue(t); err != nil {
+				return nil, err
+			}
+			p.skipSpace(false)
+			if p.pos < len(p.s) && p.s[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.s) || p.s[p.pos] != '}' {
+				return nil, p.errorf("expected ',' or '}' in inline table")
+			}
+		}
+	}
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n,]}#", p.s[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.scalar(p.s[start:p.pos])
+}
+
+// str 解析基本字符串、字面量字符串及其多行形式
+func (p *tomlParser) str() (interface{}, error) {
+	for _, q := range []string{`"""`, `'''`} {
+		if !strings.HasPrefix(p.s[p.pos:], q) {
+			continue
+		}
+		end := strings.Index(p.s[p.pos+3:], q)
+		if end < 0 {
+			return nil, p.errorf("unterminated multi-line string")
+		}
+		raw := p.s[p.pos+3 : p.pos+3+end]
+		p.line += strings.Count(raw, "\n")
+		p.pos += 3 + end + 3
+		raw = strings.TrimPrefix(strings.TrimPrefix(raw, "\r"), "\n")
+		if q == `'''` {
+			return raw, nil
+		}
+		s, err := strconv.Unquote(`"` + strings.NewReplacer("\n", `\n`, "\r", `\r`, `"`, `\"`, `\"`, `\"`).Replace(raw) + `"`)
+		if err != nil {
+			return nil, p.errorf("bad multi-line string")
+		}
+		return s, nil
+	}
+	s, n, err := readQuoted(p.rest())
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	p.pos += n
+	return s, nil
+}
+
+func (p *tomlParser) scalar(s string) (interface{}, error) {
+	switch s {
+	case "":
+		return nil, p.errorf("expected value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf", "-inf", "nan", "+nan", "-nan":
+		f, _ := strconv.ParseFloat(s, 64)
+		return f, nil
+	}
+	n := strings.Replace(s, "_", "", -1)
+	if i, err := strconv.ParseInt(n, 0, 64); err == nil && (len(n) < 2 || n[0] != '0' || strings.ContainsAny(n[1:2], "xob")) {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(n, 64); err == nil && isNumber(n) {
+		return f, nil
+	}
+	if s[0] >= '0' && s[0] <= '9' {
+		// 日期时间
+		return s, nil
+	}
+	return nil, p.errorf("bad value %q", s)
+}
+
+// isNumber s 是否只由数字、符号、小数点与指数组成
+func isNumber(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte("0123456789+-.eE", s[i]) < 0 {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,367 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// plain YAML 中未加引号的标量，解码时按目标类型解释
+type plain string
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML 解析 YAML 的常用子集：缩进表示的映射与列表、单行的 [] {} 流式写法、引号字符串与注释
+//
+// 不支持锚点、标签、多文档与多行标量
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimRight(stripComment(l), " \t\r")
+		t := strings.TrimLeft(l, " ")
+		if t == "" || t == "---" {
+			continue
+		}
+		if strings.HasPrefix(t, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(l) - len(t), text: t})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	v, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	num := 0
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	} else if len(p.lines) > 0 {
+		num = p.lines[len(p.lines)-1].num
+	}
+	return fmt.Errorf("yaml: line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func isSeqItem(t string) bool {
+	return t == "-" || strings.HasPrefix(t, "- ")
+}
+
+func (p *yamlParser) parseSeq(indent int) (interface{}, error) {
+	list := make([]interface{}, 0)
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent || (l.indent == indent && !isSeqItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("bad sequence indentation")
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			v, err := p.parseNested(indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			continue
+		}
+		if _, _, ok := splitKey(rest); ok && rest[0] != '[' && rest[0] != '{' {
+			// "- key: value" 开始一个映射，后续键与 key 对齐
+			p.lines[p.pos] = yamlLine{num: l.num, indent: l.indent + len(l.text) - len(rest), text: rest}
+			v, err := p.parseMap(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			continue
+		}
+		v, err := parseFlow(rest)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		list = append(list, v)
+		p.pos++
+	}
+	return list, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent || isSeqItem(l.text) {
+			return nil, p.errorf("bad mapping indentation")
+		}
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, p.errorf("expected key: value")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.pos++
+		if rest == "" {
+			v, err := p.parseNested(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+		if rest == "|" || rest == ">" || strings.HasPrefix(rest, "&") || strings.HasPrefix(rest, "*") {
+			p.pos--
+			return nil, p.errorf("unsupported yaml syntax %q", rest)
+		}
+		v, err := parseFlow(rest)
+		if err != nil {
+			p.pos--
+			return nil, p.errorf("%v", err)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// parseNested 解析 "key:" 或 "-" 之后缩进更深的块，允许列表与父级键对齐
+func (p *yamlParser) parseNested(indent int) (interface{}, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent {
+		return p.parseBlock(next.indent)
+	}
+	if next.indent == indent && isSeqItem(next.text) && !p.inSeq(indent) {
+		return p.parseSeq(indent)
+	}
+	return nil, nil
+}
+
+// inSeq 上一行是否为同一缩进的列表项，用于区分 "key:" 下对齐的列表与兄弟列表项
+func (p *yamlParser) inSeq(indent int) bool {
+	prev := p.lines[p.pos-1]
+	return prev.indent == indent && isSeqItem(prev.text)
+}
+
+// splitKey 拆分 "key: value"，key 可以带引号
+func splitKey(t string) (string, string, bool) {
+	if t[0] == '"' || t[0] == '\'' {
+		s, n, err := readQuoted(t)
+		if err != nil {
+			return "", "", false
+		}
+		rest := strings.TrimLeft(t[n:], " ")
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return s, strings.TrimSpace(rest[1:]), true
+	}
+	for i := 0; i < len(t); i++ {
+		if t[i] == ':' && (i == len(t)-1 || t[i+1] == ' ') {
+			return strings.TrimSpace(t[:i]), strings.TrimSpace(t[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+// stripComment 去除不在引号内的 # 注释
+func stripComment(l string) string {
+	var quote byte
+	for i := 0; i < len(l); i++ {
+		c := l[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" \t:[{,-", l[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#':
+			if i == 0 || l[i-1] == ' ' || l[i-1] == '\t' {
+				return l[:i]
+			}
+		}
+	}
+	return l
+}
+
+// readQuoted 读取开头的引号字符串，返回内容与消耗的字节数
+func readQuoted(t string) (string, int, error) {
+	q := t[0]
+	if q == '\'' {
+		var b strings.Builder
+		for i := 1; i < len(t); i++ {
+			if t[i] == '\'' {
+				if i+1 < len(t) && t[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+					continue
+				}
+				return b.String(), i + 1, nil
+			}
+			b.WriteByte(t[i])
+		}
+		return "", 0, fmt.Errorf("unterminated string %s", t)
+	}
+	for i := 1; i < len(t); i++ {
+		switch t[i] {
+		case '\\':
+			i++
+		case '"':
+			s, err := strconv.Unquote(t[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("bad string %s", t[:i+1])
+			}
+			return s, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string %s", t)
+}
+
+// parseFlow 解析一行内的标量或 [] {} 流式写法
+func parseFlow(t string) (interface{}, error) {
+	f := &flowParser{s: t}
+	v, err := f.value(false)
+	if err != nil {
+		return nil, err
+	}
+	f.skip()
+	if f.pos < len(f.s) {
+		return nil, fmt.Errorf("unexpected %q", f.s[f.pos:])
+	}
+	return v, nil
+}
+
+type flowParser struct {
+	s   string
+	pos int
+}
+
+func (f *flowParser) skip() {
+	for f.pos < len(f.s) && f.s[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *flowParser) value(inFlow bool) (interface{}, error) {
+	f.skip()
+	if f.pos >= len(f.s) {
+		return nil, nil
+	}
+	switch c := f.s[f.pos]; c {
+	case '[':
+		f.pos++
+		list := make([]interface{}, 0)
+		for {
+			f.skip()
+			if f.pos < len(f.s) && f.s[f.pos] == ']' {
+				f.pos++
+				return list, nil
+			}
+			v, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			if err := f.sep(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.pos++
+		m := make(map[string]interface{})
+		for {
+			f.skip()
+			if f.pos < len(f.s) && f.s[f.pos] == '}' {
+				f.pos++
+				return m, nil
+			}
+			k, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			f.skip()
+			if f.pos >= len(f.s) || f.s[f.pos] != ':' {
+				return nil, fmt.Errorf("expected ':' in %s", f.s)
+			}
+			f.pos++
+			v, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = v
+			if err := f.sep('}'); err != nil {
+				return nil, err
+			}
+		}
+	case '"', '\'':
+		s, n, err := readQuoted(f.s[f.pos:])
+		if err != nil {
+			return nil, err
+		}
+		f.pos += n
+		return s, nil
+	default:
+		start := f.pos
+		for f.pos < len(f.s) {
+			c := f.s[f.pos]
+			if inFlow && (c == ',' || c == ']' || c == '}' || (c == ':' && (f.pos+1 == len(f.s) || f.s[f.pos+1] == ' '))) {
+				break
+			}
+			f.pos++
+		}
+		s := strings.TrimSpace(f.s[start:f.pos])
+		if s == "" || s == "~" || s == "null" || s == "Null" || s == "NULL" {
+			return nil, nil
+		}
+		return plain(s), nil
+	}
+}
+
+// sep 读取流式写法中的 , 或结束符
+func (f *flowParser) sep(end byte) error {
+	f.skip()
+	if f.pos >= len(f.s) {
+		return fmt.Errorf("missing %q in %s", end, f.s)
+	}
+	switch f.s[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case end:
+		return nil
+	}
+	return fmt.Errorf("unexpected %q in %s", f.s[f.pos], f.s)
+}
@@ -0,0 +1,255 @@
+// Package enrich 以有限的并发异步补充事件信息，如弹幕的机器翻译，补充后的事件通过 OnEnriched 分发
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultConcurrency = 4
+	defaultTimeout     = 5 * time.Second
+	defaultQueueSize   = 1024
+)
+
+// Event 待补充信息的事件
+type Event struct {
+	Cmd     string
+	Message interface{}            // 解析后的消息，如 *message.Danmaku
+	Fields  map[string]interface{} // 补充的字段，超时的 Enricher 可能仍在写入，读取时使用 Get
+	Err     error                  // 第一个失败或超时的 Enricher 返回的错误，其余 Enricher 仍会执行
+	Elapsed time.Duration          // 全部 Enricher 的耗时
+	mu      sync.Mutex
+}
+
+// Set 设置补充的字段，可以在 Enricher 中并发调用
+func (e *Event) Set(key string, value interface{}) {
+	e.mu.Lock()
+	e.Fields[key] = value
+	e.mu.Unlock()
+}
+
+// Get 获取补充的字段
+func (e *Event) Get(key string) interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.Fields[key]
+}
+
+// String 获取字符串类型的字段
+func (e *Event) String(key string) string {
+	s, _ := e.Get(key).(string)
+	return s
+}
+
+// Danmaku 事件为弹幕时返回该弹幕，否则返回 nil
+func (e *Event) Danmaku() *message.Danmaku {
+	d, _ := e.Message.(*message.Danmaku)
+	return d
+}
+
+// SuperChat 事件为醒目留言时返回该醒目留言，否则返回 nil
+func (e *Event) SuperChat() *message.SuperChat {
+	s, _ := e.Message.(*message.SuperChat)
+	return s
+}
+
+// Text 获取弹幕或醒目留言的文本
+func (e *Event) Text() string {
+	switch m := e.Message.(type) {
+	case *message.Danmaku:
+		return m.Content
+	case *message.SuperChat:
+		return m.Message
+	}
+	return ""
+}
+
+// Enricher 补充事件信息，通过 e.Set 写入结果，ctx 在超时后取消
+type Enricher interface {
+	Enrich(ctx context.Context, e *Event) error
+}
+
+// EnricherFunc 将函数作为 Enricher 使用
+type EnricherFunc func(ctx context.Context, e *Event) error
+
+func (f EnricherFunc) Enrich(ctx context.Context, e *Event) error {
+	return f(ctx, e)
+}
+
+// Stage 异步补充事件信息的处理阶段，事件补充完成的顺序与收到的顺序可能不同
+type Stage struct {
+	enrichers   []Enricher
+	mu          sync.Mutex
+	concurrency int
+	timeout     time.Duration
+	queueSize   int
+	queue       chan *Event
+	handlers    []func(*Event)
+	dropped     uint64
+	once        sync.Once
+	closeOnce   sync.Once
+	wg          sync.WaitGroup
+}
+
+// NewStage 创建依次执行 enrichers 的处理阶段，默认并发数为 4，每个事件超时时间为 5s
+func NewStage(enrichers ...Enricher) *Stage {
+	return &Stage{
+		enrichers:   enrichers,
+		concurrency: defaultConcurrency,
+		timeout:     defaultTimeout,
+		queueSize:   defaultQueueSize,
+	}
+}
+
+// SetConcurrency 设置同时补充的事件数量，需要在收到第一个事件之前调用
+func (s *Stage) SetConcurrency(n int) {
+	if n > 0 {
+		s.concurrency = n
+	}
+}
+
+// SetTimeout 设置每个事件全部 Enricher 的超时时间，为 0 时不超时
+func (s *Stage) SetTimeout(d time.Duration) {
+	s.mu.Lock()
+	s.timeout = d
+	s.mu.Unlock()
+}
+
+// SetQueueSize 设置等待补充的事件数量上限，队列满时丢弃新的事件，需要在收到第一个事件之前调用
+func (s *Stage) SetQueueSize(n int) {
+	if n > 0 {
+		s.queueSize = n
+	}
+}
+
+// OnEnriched 添加补充完成后的事件处理器
+func (s *Stage) OnEnriched(f func(*Event)) {
+	s.mu.Lock()
+	s.handlers = append(s.handlers, f)
+	s.mu.Unlock()
+}
+
+// Attach 补充 src 中的弹幕与醒目留言
+func (s *Stage) Attach(src client.DanmakuSource) {
+	src.OnDanmaku(func(d *message.Danmaku) {
+		d.Retain()
+		s.Push("DANMU_MSG", d)
+	})
+	src.OnSuperChat(func(sc *message.SuperChat) { s.Push("SUPER_CHAT_MESSAGE", sc) })
+}
+
+// Push 将事件加入队列，队列已满或已关闭时丢弃并返回 false
+func (s *Stage) Push(cmd string, msg interface{}) bool {
+	s.once.Do(s.start)
+	e := &Event{Cmd: cmd, Message: msg, Fields: make(map[string]interface{})}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queue == nil {
+		return false
+	}
+	select {
+	case s.queue <- e:
+		return true
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+		return false
+	}
+}
+
+// Dropped 获取因队列已满被丢弃的事件数量
+func (s *Stage) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *Stage) start() {
+	s.mu.Lock()
+	s.queue = make(chan *Event, s.queueSize)
+	queue := s.queue
+	s.mu.Unlock()
+	for i := 0; i < s.concurrency; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for e := range queue {
+				s.enrich(e)
+			}
+		}()
+	}
+}
+
+func (s *Stage) enrich(e *Event) {
+	s.mu.Lock()
+	timeout, handlers := s.timeout, s.handlers
+	s.mu.Unlock()
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	start := time.Now()
+	for _, en := range s.enrichers {
+		if err := s.run(ctx, en, e); err != nil && e.Err == nil {
+			e.Err = err
+		}
+	}
+	cancel()
+	e.Elapsed = time.Since(start)
+	for _, fn := range handlers {
+		s.cover(func() { fn(e) })
+	}
+}
+
+// run 执行一个 Enricher，超时后不再等待其返回
+func (s *Stage) run(ctx context.Context, en Enricher, e *Event) (err error) {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if pan := recover(); pan != nil {
+				done <- fmt.Errorf("enricher panic: %v", pan)
+			}
+		}()
+		done <- en.Enrich(ctx, e)
+	}()
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Stage) cover(f func()) {
+	defer func() {
+		if pan := recover(); pan != nil {
+			log.Errorf("enriched handler panic: %v", pan)
+		}
+	}()
+	f()
+}
+
+// Close 停止接收事件，等待队列中的事件补充完成并分发
+func (s *Stage) Close() {
+	s.closeOnce.Do(func() {
+		s.once.Do(func() {})
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		if queue != nil {
+			close(queue)
+		}
+		s.wg.Wait()
+	})
+}
@@ -0,0 +1,175 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+// Localize 写入的字段
+const (
+	FieldGiftName  = "gift_name"  // 礼物名称的译名，没有对应译名时为原名称
+	FieldGuardName = "guard_name" // 舰队等级的译名，非舰队成员时不写入
+)
+
+// NameTable 礼物与舰队等级名称的翻译表
+type NameTable struct {
+	Gifts  map[string]string `json:"gifts"`  // 键为礼物的中文名称
+	Guards map[int]string    `json:"guards"` // 键为舰队等级，1:总督 2:提督 3:舰长
+}
+
+// guardNames 舰队等级的中文名称，翻译表中没有对应等级时使用
+var guardNames = map[int]string{1: "总督", 2: "提督", 3: "舰长"}
+
+// builtinNames 内置的翻译表，只包含舰队等级与常见的礼物
+var builtinNames = map[string]*NameTable{
+	"en": {
+		Gifts: map[string]string{
+			"辣条":    "Spicy Strip",
+			"小心心":   "Little Heart",
+			"小花花":   "Little Flower",
+			"牛哇牛哇":  "Awesome",
+			"打call": "Cheer",
+			"干杯":    "Cheers",
+			"这个好诶":  "Nice One",
+			"粉丝团灯牌": "Fan Club Light",
+			"人气票":   "Popularity Ticket",
+			"爱心专递":  "Love Delivery",
+			"告白花束":  "Confession Bouquet",
+			"醒目留言":  "Super Chat",
+			"舰长":    "Captain",
+			"提督":    "Admiral",
+			"总督":    "Governor",
+		},
+		Guards: map[int]string{1: "Governor", 2: "Admiral", 3: "Captain"},
+	},
+	"ja": {
+		Gifts: map[string]string{
+			"辣条":    "ラーティアオ",
+			"小心心":   "ハート",
+			"小花花":   "お花",
+			"牛哇牛哇":  "すごい",
+			"打call": "コール",
+			"干杯":    "乾杯",
+			"粉丝团灯牌": "ファンクラブライト",
+			"醒目留言":  "スーパーチャット",
+			"舰长":    "艦長",
+			"提督":    "提督",
+			"总督":    "総督",
+		},
+		Guards: map[int]string{1: "総督", 2: "提督", 3: "艦長"},
+	},
+}
+
+// BuiltinNames 获取内置的翻译表，locale 如 "en"、"ja-JP"，没有完全匹配时使用语言部分，不支持时返回 nil
+//
+// 返回的是副本，可以修改或通过 Merge 补充
+func BuiltinNames(locale string) *NameTable {
+	locale = strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	t, ok := builtinNames[locale]
+	if !ok {
+		if i := strings.IndexByte(locale, '-'); i >= 0 {
+			t, ok = builtinNames[locale[:i]]
+		}
+	}
+	if !ok {
+		return nil
+	}
+	return (&NameTable{}).Merge(t)
+}
+
+// LoadNames 读取 JSON 格式的翻译表，格式与 NameTable 相同，如 {"gifts":{"辣条":"Spicy Strip"},"guards":{"3":"Captain"}}
+func LoadNames(path string) (*NameTable, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := new(NameTable)
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Merge 将 o 中的名称加入 t，已有的名称被覆盖，返回 t
+func (t *NameTable) Merge(o *NameTable) *NameTable {
+	if o == nil {
+		return t
+	}
+	if t.Gifts == nil {
+		t.Gifts = make(map[string]string, len(o.Gifts))
+	}
+	for k, v := range o.Gifts {
+		t.Gifts[k] = v
+	}
+	if t.Guards == nil {
+		t.Guards = make(map[int]string, len(o.Guards))
+	}
+	for k, v := range o.Guards {
+		t.Guards[k] = v
+	}
+	return t
+}
+
+// Gift 获取礼物名称的译名，没有对应译名时返回 name
+func (t *NameTable) Gift(name string) string {
+	if s, ok := t.Gifts[name]; ok && s != "" {
+		return s
+	}
+	return name
+}
+
+// Guard 获取舰队等级的译名，没有对应译名时返回中文名称，非舰队等级返回空字符串
+func (t *NameTable) Guard(level int) string {
+	if s, ok := t.Guards[level]; ok && s != "" {
+		return s
+	}
+	return guardNames[level]
+}
+
+// Localize 按翻译表写入 FieldGiftName 与 FieldGuardName 字段，供面向非中文观众的界面直接显示
+//
+// 支持礼物、上舰、上舰提示、醒目留言与弹幕，弹幕与醒目留言的舰队等级为发送者的等级
+func Localize(t *NameTable) Enricher {
+	return EnricherFunc(func(ctx context.Context, e *Event) error {
+		var (
+			gift  string
+			guard int
+		)
+		switch m := e.Message.(type) {
+		case *message.Gift:
+			gift, guard = m.GiftName, m.GuardLevel
+		case *message.GuardBuy:
+			gift, guard = m.GiftName, m.GuardLevel
+		case *message.UserToast:
+			guard = m.GuardLevel
+		case *message.SuperChat:
+			gift, guard = m.Gift.GiftName, m.UserInfo.GuardLevel
+		case *message.Danmaku:
+			if m.Sender != nil {
+				guard = m.Sender.GuardLevel
+			}
+		}
+		if gift != "" {
+			e.Set(FieldGiftName, t.Gift(gift))
+		}
+		if s := t.Guard(guard); s != "" {
+			e.Set(FieldGuardName, s)
+		}
+		return nil
+	})
+}
+
+// AttachGifts 补充 src 中的礼物、上舰与上舰提示，与 Attach 一起使用时 Localize 可以覆盖全部支持的事件
+func (s *Stage) AttachGifts(src client.DanmakuSource) {
+	src.OnGift(func(g *message.Gift) {
+		g.Retain()
+		s.Push("SEND_GIFT", g)
+	})
+	src.OnGuardBuy(func(g *message.GuardBuy) { s.Push("GUARD_BUY", g) })
+	src.OnUserToast(func(t *message.UserToast) { s.Push("USER_TOAST_MSG", t) })
+}
@@ -0,0 +1,112 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/analytics"
+)
+
+// Revenue 写入的字段
+const (
+	FieldRevenueGold = "revenue_gold" // int64，金额，单位为金瓜子
+	FieldRevenueCNY  = "revenue_cny"  // float64，折算的人民币
+	FieldRevenue     = "revenue"      // float64，按汇率折算的 FieldCurrency 金额，目标货币为人民币时不写入
+	FieldCurrency    = "currency"     // FieldRevenue 的货币代码，如 "USD"
+)
+
+// CNY 人民币的货币代码
+const CNY = "CNY"
+
+// RateSource 汇率来源，返回 1 元人民币可兑换的 currency 数量
+type RateSource interface {
+	Rate(ctx context.Context, currency string) (float64, error)
+}
+
+// RateSourceFunc 将函数作为 RateSource 使用
+type RateSourceFunc func(ctx context.Context, currency string) (float64, error)
+
+func (f RateSourceFunc) Rate(ctx context.Context, currency string) (float64, error) {
+	return f(ctx, currency)
+}
+
+// StaticRates 固定的汇率，键为货币代码，值为 1 元人民币可兑换的数量
+type StaticRates map[string]float64
+
+func (r StaticRates) Rate(ctx context.Context, currency string) (float64, error) {
+	if v, ok := r[strings.ToUpper(currency)]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("no rate for currency %q", currency)
+}
+
+// cachedRate 缓存的一个汇率
+type cachedRate struct {
+	rate float64
+	at   time.Time
+}
+
+// rateCache 缓存汇率的 RateSource
+type rateCache struct {
+	src   RateSource
+	ttl   time.Duration
+	mu    sync.Mutex
+	rates map[string]cachedRate
+}
+
+// CacheRates 将 src 获取的汇率缓存 ttl，避免每个事件都请求汇率接口，获取失败时使用过期的缓存
+func CacheRates(src RateSource, ttl time.Duration) RateSource {
+	return &rateCache{src: src, ttl: ttl, rates: make(map[string]cachedRate)}
+}
+
+func (c *rateCache) Rate(ctx context.Context, currency string) (float64, error) {
+	currency = strings.ToUpper(currency)
+	c.mu.Lock()
+	r, ok := c.rates[currency]
+	c.mu.Unlock()
+	if ok && time.Since(r.at) < c.ttl {
+		return r.rate, nil
+	}
+	v, err := c.src.Rate(ctx, currency)
+	if err != nil {
+		if ok {
+			return r.rate, nil
+		}
+		return 0, err
+	}
+	c.mu.Lock()
+	c.rates[currency] = cachedRate{rate: v, at: time.Now()}
+	c.mu.Unlock()
+	return v, nil
+}
+
+// Revenue 写入礼物、醒目留言与上舰的金额，金瓜子与人民币按 analytics.GoldPerYuan 折算，银瓜子礼物不写入
+//
+// currency 为空或 CNY 时只写入 FieldRevenueGold 与 FieldRevenueCNY，否则通过 rates 获取汇率，
+// 写入保留两位小数的 FieldRevenue 与 FieldCurrency，获取汇率失败时仍会写入人民币金额
+func Revenue(rates RateSource, currency string) Enricher {
+	currency = strings.ToUpper(currency)
+	return EnricherFunc(func(ctx context.Context, e *Event) error {
+		gold, ok := analytics.EventGold(e.Message)
+		if !ok {
+			return nil
+		}
+		cny := analytics.GoldToYuan(gold)
+		e.Set(FieldRevenueGold, gold)
+		e.Set(FieldRevenueCNY, cny)
+		if currency == "" || currency == CNY || rates == nil {
+			return nil
+		}
+		rate, err := rates.Rate(ctx, currency)
+		if err != nil {
+			return err
+		}
+		e.Set(FieldRevenue, math.Round(cny*rate*100)/100)
+		e.Set(FieldCurrency, currency)
+		return nil
+	})
+}
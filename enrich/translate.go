@@ -0,0 +1,36 @@
+package enrich
+
+import (
+	"context"
+)
+
+// FieldTranslation Translate 写入译文的字段
+const FieldTranslation = "translation"
+
+// Translator 机器翻译接口，target 为目标语言，如 "en"、"ja"
+type Translator interface {
+	Translate(ctx context.Context, text string, target string) (string, error)
+}
+
+// TranslatorFunc 将函数作为 Translator 使用
+type TranslatorFunc func(ctx context.Context, text string, target string) (string, error)
+
+func (f TranslatorFunc) Translate(ctx context.Context, text string, target string) (string, error) {
+	return f(ctx, text, target)
+}
+
+// Translate 将弹幕与醒目留言的文本翻译为 target 语言，写入 FieldTranslation 字段
+func Translate(t Translator, target string) Enricher {
+	return EnricherFunc(func(ctx context.Context, e *Event) error {
+		text := e.Text()
+		if text == "" {
+			return nil
+		}
+		s, err := t.Translate(ctx, text, target)
+		if err != nil {
+			return err
+		}
+		e.Set(FieldTranslation, s)
+		return nil
+	})
+}
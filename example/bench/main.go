@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RemKeeper/blivedm-go/bench"
+	log "github.com/sirupsen/logrus"
+)
+
+// 读取 go test -run '^$' -bench . -benchmem ./bench 的输出，打印每条消息的结果并与基线比较
+func main() {
+	input := flag.String("input", "", "read go test -bench output from a file instead of stdin")
+	baseline := flag.String("baseline", "", "compare with a baseline result file")
+	save := flag.String("save", "", "save results as a baseline file")
+	threshold := flag.Float64("threshold", 0.1, "allowed slowdown compared with the baseline")
+	flag.Parse()
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+	results, err := bench.ParseResults(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(results) == 0 {
+		log.Fatal("no benchmark results in input")
+	}
+	for _, r := range results {
+		fmt.Println(r)
+	}
+	if *save != "" {
+		f, err := os.Create(*save)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = bench.WriteResults(f, results); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+	}
+	if *baseline != "" {
+		base, err := bench.LoadResults(*baseline)
+		if err != nil {
+			log.Fatal(err)
+		}
+		regs := bench.Compare(base, results, *threshold)
+		for _, r := range regs {
+			fmt.Println("regression:", r)
+		}
+		if len(regs) > 0 {
+			os.Exit(1)
+		}
+	}
+}
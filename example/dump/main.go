@@ -14,7 +14,7 @@ var dumps = []string{"GUARD_BUY", "USER_TOAST_MSG"}
 
 func main() {
 	log.SetLevel(log.DebugLevel)
-	c := client.NewClient(roomId, "0")
+	c := client.NewClient(roomId, "0", "", "", "")
 	for _, v := range dumps {
 		vv := v
 		c.RegisterCustomEventHandler(vv, func(s string) {
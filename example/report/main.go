@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RemKeeper/blivedm-go/analytics"
+	"github.com/RemKeeper/blivedm-go/record"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	format := flag.String("format", "json", "output format: json or csv")
+	top := flag.Int("top", 10, "number of top gifters")
+	words := flag.Int("words", 100, "number of words kept for the word cloud, 0 for all")
+	out := flag.String("o", "", "output file, default stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] record-file\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	log.SetLevel(log.FatalLevel)
+
+	r, err := record.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	report, err := analytics.BuildReport(r, *top, *words)
+	r.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	switch *format {
+	case "json":
+		err = report.WriteJSON(w)
+	case "csv":
+		err = report.WriteCSV(w)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/runner"
+)
+
+func main() {
+	runner.Main("config.yaml", func(m *client.RoomManager) {
+		m.OnClient(func(roomID string, c *client.Client) {
+			c.OnDanmaku(func(d *message.Danmaku) {
+				fmt.Printf("[%s] %s: %s\n", roomID, d.Sender.Uname, d.Content)
+			})
+		})
+	})
+}
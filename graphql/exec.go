@@ -0,0 +1,327 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/sink"
+)
+
+// Schema 网关提供的 GraphQL schema，可用于前端的代码生成与类型检查
+const Schema = `type Query {
+  rooms: [Room!]!
+  room(id: String!): Room
+}
+
+type Subscription {
+  "房间中的事件，rooms 与 cmds 为空时不过滤"
+  events(rooms: [String!], cmds: [String!]): Event!
+}
+
+type Room {
+  id: String!
+  realId: Int!
+  connected: Boolean!
+  connectedAt: String
+  lastMessageAt: String
+  reconnects: Int!
+  "以下字段需要 client 开启 EnableRoomState"
+  liveStatus: Int!
+  title: String!
+  areaName: String!
+  popularity: Int!
+  watched: Int!
+  onlineRank: Int!
+  superChats: [SuperChat!]!
+}
+
+type SuperChat {
+  id: Int!
+  uid: Int!
+  uname: String!
+  message: String!
+  price: Int!
+  endTime: String
+}
+
+type Event {
+  cmd: String!
+  roomId: Int!
+  "收到事件的毫秒时间戳"
+  time: Float!
+  uid: Int!
+  "弹幕或醒目留言的文本"
+  text: String!
+  "原始报文 JSON"
+  data: String!
+}
+`
+
+// object GraphQL 对象类型
+type object interface {
+	typeName() string
+	resolve(f *field) (interface{}, error)
+}
+
+// result 按选择集顺序输出的 JSON 对象
+type result struct {
+	keys   []string
+	values []interface{}
+}
+
+func (r *result) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		buf.Write(kb)
+		buf.WriteByte(':')
+		vb, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// complete 按选择集展开对象
+func complete(obj object, fields []*field) (*result, error) {
+	r := &result{}
+	for _, f := range fields {
+		var (
+			v   interface{}
+			err error
+		)
+		if f.name == "__typename" {
+			v = obj.typeName()
+		} else if v, err = obj.resolve(f); err != nil {
+			return nil, err
+		}
+		if v, err = completeValue(v, f); err != nil {
+			return nil, err
+		}
+		r.keys = append(r.keys, f.key())
+		r.values = append(r.values, v)
+	}
+	return r, nil
+}
+
+func completeValue(v interface{}, f *field) (interface{}, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case object:
+		if len(f.selections) == 0 {
+			return nil, fmt.Errorf("field %q of type %s must have a selection of subfields", f.name, v.typeName())
+		}
+		return complete(v, f.selections)
+	case []object:
+		list := make([]interface{}, len(v))
+		for i, o := range v {
+			r, err := completeValue(o, f)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = r
+		}
+		return list, nil
+	}
+	if len(f.selections) > 0 {
+		return nil, fmt.Errorf("field %q must not have a selection since it is a scalar", f.name)
+	}
+	return v, nil
+}
+
+func unknownField(obj object, f *field) error {
+	return fmt.Errorf("cannot query field %q on type %s", f.name, obj.typeName())
+}
+
+// stringArg 获取字符串参数
+func stringArg(f *field, name string) (string, bool, error) {
+	v, ok := f.args[name]
+	if !ok || v == nil {
+		return "", false, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", false, fmt.Errorf("argument %q of field %q must be a String", name, f.name)
+	}
+	return s, true, nil
+}
+
+// stringsArg 获取字符串列表参数，单个字符串视为只有一个元素的列表
+func stringsArg(f *field, name string) ([]string, error) {
+	v, ok := f.args[name]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	var list []interface{}
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		list = v
+	default:
+		return nil, fmt.Errorf("argument %q of field %q must be a [String!]", name, f.name)
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument %q of field %q must be a [String!]", name, f.name)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// formatTime 时间格式化为 RFC 3339，零值为 null
+func formatTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// queryObject Query 根类型
+type queryObject struct {
+	m *client.RoomManager
+}
+
+func (q queryObject) typeName() string { return "Query" }
+
+func (q queryObject) resolve(f *field) (interface{}, error) {
+	switch f.name {
+	case "rooms":
+		ids := q.m.Rooms()
+		rooms := make([]object, 0, len(ids))
+		for _, id := range ids {
+			if c := q.m.Room(id); c != nil {
+				rooms = append(rooms, newRoomObject(id, c))
+			}
+		}
+		return rooms, nil
+	case "room":
+		id, ok, err := stringArg(f, "id")
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("argument %q of field %q is required", "id", f.name)
+		}
+		c := q.m.Room(id)
+		if c == nil {
+			return nil, nil
+		}
+		return newRoomObject(id, c), nil
+	}
+	return nil, unknownField(q, f)
+}
+
+// roomObject Room 类型
+type roomObject struct {
+	id    string
+	c     *client.Client
+	stats client.Stats
+	state client.RoomState
+}
+
+func newRoomObject(id string, c *client.Client) *roomObject {
+	return &roomObject{id: id, c: c, stats: c.Stats(), state: c.RoomState()}
+}
+
+func (r *roomObject) typeName() string { return "Room" }
+
+func (r *roomObject) resolve(f *field) (interface{}, error) {
+	switch f.name {
+	case "id":
+		return r.id, nil
+	case "realId":
+		return r.c.RoomID(), nil
+	case "connected":
+		return r.stats.Connected, nil
+	case "connectedAt":
+		return formatTime(r.stats.ConnectedAt), nil
+	case "lastMessageAt":
+		return formatTime(r.stats.LastMessageAt), nil
+	case "reconnects":
+		return r.stats.Reconnects, nil
+	case "liveStatus":
+		return r.state.LiveStatus, nil
+	case "title":
+		return r.state.Title, nil
+	case "areaName":
+		return r.state.AreaName, nil
+	case "popularity":
+		return r.state.Popularity, nil
+	case "watched":
+		return r.state.Watched, nil
+	case "onlineRank":
+		return r.state.OnlineRank, nil
+	case "superChats":
+		scs := make([]object, len(r.state.SuperChats))
+		for i := range r.state.SuperChats {
+			scs[i] = superChatObject(r.state.SuperChats[i])
+		}
+		return scs, nil
+	}
+	return nil, unknownField(r, f)
+}
+
+// superChatObject SuperChat 类型
+type superChatObject client.ActiveSuperChat
+
+func (s superChatObject) typeName() string { return "SuperChat" }
+
+func (s superChatObject) resolve(f *field) (interface{}, error) {
+	switch f.name {
+	case "id":
+		return s.Id, nil
+	case "uid":
+		return s.Uid, nil
+	case "uname":
+		return s.Uname, nil
+	case "message":
+		return s.Message, nil
+	case "price":
+		return s.Price, nil
+	case "endTime":
+		return formatTime(s.EndTime), nil
+	}
+	return nil, unknownField(s, f)
+}
+
+// eventObject Event 类型
+type eventObject struct {
+	cmd string
+	e   *record.Entry
+}
+
+func (e eventObject) typeName() string { return "Event" }
+
+func (e eventObject) resolve(f *field) (interface{}, error) {
+	switch f.name {
+	case "cmd":
+		return e.cmd, nil
+	case "roomId":
+		return e.e.RoomID, nil
+	case "time":
+		return e.e.Time, nil
+	case "uid":
+		return sink.UID(e.e), nil
+	case "text":
+		return sink.Text(e.cmd, e.e), nil
+	case "data":
+		return string(e.e.Data), nil
+	}
+	return nil, unknownField(e, f)
+}
@@ -0,0 +1,389 @@
+// Package graphql 以 GraphQL 查询与订阅提供 RoomManager 中的房间与事件流
+//
+// 只实现了 GraphQL 的一个子集：字段、别名、参数与变量，不支持片段与指令，schema 见 Schema。
+// 查询通过 HTTP POST 或 GET 发送，订阅使用 graphql-transport-ws 协议的 websocket，
+// 主程序没有引用该包，不使用时不会增加依赖与二进制大小
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/sink"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBuffer = 256
+	subprotocol   = "graphql-transport-ws"
+	// initTimeout 建立 websocket 连接后等待 connection_init 的时间
+	initTimeout = 10 * time.Second
+)
+
+// Gateway GraphQL 网关，同时也是 http.Handler
+type Gateway struct {
+	m        *client.RoomManager
+	mu       sync.Mutex
+	subs     map[*subscriber]bool
+	buffer   int
+	dropped  uint64
+	upgrader websocket.Upgrader
+}
+
+// subscriber 一个订阅
+type subscriber struct {
+	rooms map[string]bool
+	cmds  map[string]bool
+	ch    chan eventObject
+}
+
+// request GraphQL 请求
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// response GraphQL 响应
+type response struct {
+	Data   interface{}     `json:"data,omitempty"`
+	Errors []responseError `json:"errors,omitempty"`
+}
+
+type responseError struct {
+	Message string `json:"message"`
+}
+
+func errorResponse(err error) *response {
+	return &response{Errors: []responseError{{Message: err.Error()}}}
+}
+
+// NewGateway 创建 m 的 GraphQL 网关，需要在 AddRoom 之前创建，之后添加的房间的事件才能被订阅
+func NewGateway(m *client.RoomManager) *Gateway {
+	g := &Gateway{
+		m:      m,
+		subs:   make(map[*subscriber]bool),
+		buffer: defaultBuffer,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{subprotocol},
+			CheckOrigin:  func(r *http.Request) bool { return true },
+		},
+	}
+	m.OnClient(func(roomID string, c *client.Client) {
+		sink.Attach(c, &roomSink{g: g, roomID: roomID})
+	})
+	return g
+}
+
+// SetBuffer 设置每个订阅缓存的事件数，缓存满时丢弃新的事件，默认为 256
+func (g *Gateway) SetBuffer(n int) {
+	if n > 0 {
+		g.mu.Lock()
+		g.buffer = n
+		g.mu.Unlock()
+	}
+}
+
+// SetCheckOrigin 设置 websocket 连接的 Origin 检查，默认允许全部来源
+func (g *Gateway) SetCheckOrigin(f func(r *http.Request) bool) {
+	g.upgrader.CheckOrigin = f
+}
+
+// Dropped 获取因订阅缓存已满被丢弃的事件数量
+func (g *Gateway) Dropped() uint64 {
+	return atomic.LoadUint64(&g.dropped)
+}
+
+// roomSink 将一个房间的事件写入网关
+type roomSink struct {
+	g      *Gateway
+	roomID string
+}
+
+func (s *roomSink) Write(e *record.Entry) error {
+	s.g.publish(s.roomID, e)
+	return nil
+}
+
+func (s *roomSink) Close() error { return nil }
+
+func (g *Gateway) publish(roomID string, e *record.Entry) {
+	ev := eventObject{cmd: sink.Cmd(e), e: e}
+	real := strconv.Itoa(e.RoomID)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for sub := range g.subs {
+		if sub.rooms != nil && !sub.rooms[roomID] && !sub.rooms[real] {
+			continue
+		}
+		if sub.cmds != nil && !sub.cmds[ev.cmd] {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			atomic.AddUint64(&g.dropped, 1)
+		}
+	}
+}
+
+// Execute 执行一个查询，订阅需要通过 websocket 发送
+func (g *Gateway) Execute(query string, operationName string, variables map[string]interface{}) (interface{}, error) {
+	op, err := parse(query, operationName, variables)
+	if err != nil {
+		return nil, err
+	}
+	if op.kind != "query" {
+		return nil, errors.New(op.kind + " operations must be sent over websocket")
+	}
+	return complete(queryObject{m: g.m}, op.selections)
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		g.serveWebsocket(w, r)
+		return
+	}
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resp := &response{}
+	data, err := g.Execute(req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		resp = errorResponse(err)
+	} else {
+		resp.Data = data
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// wsMessage graphql-transport-ws 协议的消息
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsConn 一个 websocket 连接，写入时加锁
+type wsConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+	subs map[string]func()
+}
+
+func (c *wsConn) send(id string, typ string, payload interface{}) error {
+	msg := wsMessage{ID: id, Type: typ}
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg.Payload = b
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(&msg)
+}
+
+func (g *Gateway) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != subprotocol {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(4406, "subprotocol not acceptable"), time.Now().Add(time.Second))
+		return
+	}
+	c := &wsConn{conn: conn, subs: make(map[string]func())}
+	defer func() {
+		c.mu.Lock()
+		subs := c.subs
+		c.subs = nil
+		c.mu.Unlock()
+		for _, stop := range subs {
+			stop()
+		}
+	}()
+	conn.SetReadDeadline(time.Now().Add(initTimeout))
+	initialized := false
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case "connection_init":
+			if initialized {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(4429, "too many initialisation requests"), time.Now().Add(time.Second))
+				return
+			}
+			initialized = true
+			conn.SetReadDeadline(time.Time{})
+			if err := c.send("", "connection_ack", nil); err != nil {
+				return
+			}
+		case "ping":
+			if err := c.send("", "pong", nil); err != nil {
+				return
+			}
+		case "pong":
+		case "subscribe":
+			if !initialized {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(4401, "unauthorized"), time.Now().Add(time.Second))
+				return
+			}
+			var req request
+			if err := json.Unmarshal(msg.Payload, &req); err != nil {
+				c.send(msg.ID, "error", errorResponse(err).Errors)
+				continue
+			}
+			g.subscribe(c, msg.ID, &req)
+		case "complete":
+			c.mu.Lock()
+			stop := c.subs[msg.ID]
+			delete(c.subs, msg.ID)
+			c.mu.Unlock()
+			if stop != nil {
+				stop()
+			}
+		default:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(4400, "unknown message type"), time.Now().Add(time.Second))
+			return
+		}
+	}
+}
+
+// subscribe 处理 subscribe 消息，查询立即返回结果，订阅持续发送事件直到收到 complete 或连接断开
+func (g *Gateway) subscribe(c *wsConn, id string, req *request) {
+	op, err := parse(req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		c.send(id, "error", errorResponse(err).Errors)
+		return
+	}
+	if op.kind != "subscription" {
+		data, err := g.Execute(req.Query, req.OperationName, req.Variables)
+		if err != nil {
+			c.send(id, "error", errorResponse(err).Errors)
+			return
+		}
+		c.send(id, "next", &response{Data: data})
+		c.send(id, "complete", nil)
+		return
+	}
+	if len(op.selections) != 1 || op.selections[0].name != "events" {
+		c.send(id, "error", errorResponse(errors.New("subscription must select exactly one field: events")).Errors)
+		return
+	}
+	f := op.selections[0]
+	sub, err := g.newSubscriber(f)
+	if err != nil {
+		c.send(id, "error", errorResponse(err).Errors)
+		return
+	}
+	c.mu.Lock()
+	if c.subs == nil {
+		c.mu.Unlock()
+		return
+	}
+	if _, ok := c.subs[id]; ok {
+		c.mu.Unlock()
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(4409, "subscriber for "+id+" already exists"), time.Now().Add(time.Second))
+		return
+	}
+	done := make(chan struct{})
+	var once sync.Once
+	c.subs[id] = func() { once.Do(func() { close(done) }) }
+	c.mu.Unlock()
+
+	g.mu.Lock()
+	g.subs[sub] = true
+	g.mu.Unlock()
+	go func() {
+		defer func() {
+			g.mu.Lock()
+			delete(g.subs, sub)
+			g.mu.Unlock()
+			c.mu.Lock()
+			if c.subs != nil {
+				delete(c.subs, id)
+			}
+			c.mu.Unlock()
+		}()
+		for {
+			select {
+			case <-done:
+				return
+			case ev := <-sub.ch:
+				r, err := completeValue(ev, f)
+				if err != nil {
+					c.send(id, "error", errorResponse(err).Errors)
+					return
+				}
+				data := &result{keys: []string{f.key()}, values: []interface{}{r}}
+				if err = c.send(id, "next", &response{Data: data}); err != nil {
+					log.Debug("send graphql event failed: ", err)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// newSubscriber 根据 events 字段的参数创建订阅
+func (g *Gateway) newSubscriber(f *field) (*subscriber, error) {
+	rooms, err := stringsArg(f, "rooms")
+	if err != nil {
+		return nil, err
+	}
+	cmds, err := stringsArg(f, "cmds")
+	if err != nil {
+		return nil, err
+	}
+	if len(f.selections) == 0 {
+		return nil, errors.New(`field "events" of type Event must have a selection of subfields`)
+	}
+	g.mu.Lock()
+	sub := &subscriber{ch: make(chan eventObject, g.buffer)}
+	g.mu.Unlock()
+	if len(rooms) > 0 {
+		sub.rooms = make(map[string]bool, len(rooms))
+		for _, r := range rooms {
+			sub.rooms[r] = true
+		}
+	}
+	if len(cmds) > 0 {
+		sub.cmds = make(map[string]bool, len(cmds))
+		for _, c := range cmds {
+			sub.cmds[c] = true
+		}
+	}
+	return sub, nil
+}
@@ -0,0 +1,499 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// field 选择集中的一个字段
+type field struct {
+	alias      string
+	name       string
+	args       map[string]interface{}
+	selections []*field
+}
+
+// key 字段在结果中的名称
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// operation 一个查询或订阅操作
+type operation struct {
+	kind       string // query、mutation 或 subscription
+	name       string
+	defaults   map[string]interface{}
+	varNames   []string
+	selections []*field
+}
+
+// parser 只支持字段、别名、参数与变量的 GraphQL 解析器
+type parser struct {
+	src string
+	pos int
+	tok string // 当前 token，字符串 token 带有前导的 "
+}
+
+// parse 解析查询文档并选出 operationName 对应的操作，variables 中的变量会替换到参数中
+func parse(src string, operationName string, variables map[string]interface{}) (*operation, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var ops []*operation
+	for p.tok != "" {
+		op, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	var op *operation
+	switch {
+	case len(ops) == 0:
+		return nil, errors.New("no operation in document")
+	case operationName != "":
+		for _, o := range ops {
+			if o.name == operationName {
+				op = o
+			}
+		}
+		if op == nil {
+			return nil, fmt.Errorf("unknown operation %q", operationName)
+		}
+	case len(ops) > 1:
+		return nil, errors.New("operationName is required for documents with multiple operations")
+	default:
+		op = ops[0]
+	}
+	vars := make(map[string]interface{}, len(op.varNames))
+	for _, name := range op.varNames {
+		if v, ok := variables[name]; ok {
+			vars[name] = v
+		} else if v, ok := op.defaults[name]; ok {
+			vars[name] = v
+		}
+	}
+	if err := substitute(op.selections, vars); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// variable 参数中引用的变量
+type variable string
+
+// substitute 将参数中的变量替换为变量的值
+func substitute(fields []*field, vars map[string]interface{}) error {
+	for _, f := range fields {
+		for k, v := range f.args {
+			r, err := resolveValue(v, vars)
+			if err != nil {
+				return err
+			}
+			f.args[k] = r
+		}
+		if err := substitute(f.selections, vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveValue(v interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case variable:
+		r, ok := vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", string(v))
+		}
+		return r, nil
+	case []interface{}:
+		for i := range v {
+			r, err := resolveValue(v[i], vars)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = r
+		}
+	case map[string]interface{}:
+		for k := range v {
+			r, err := resolveValue(v[k], vars)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = r
+		}
+	}
+	return v, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("syntax error at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// next 读取下一个 token，到达结尾时 tok 为空
+func (p *parser) next() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return nil
+	}
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+	case strings.IndexByte("{}()[]:!$=@", c) >= 0:
+		p.pos++
+	case c == '"':
+		s, err := p.string()
+		if err != nil {
+			return err
+		}
+		p.tok = `"` + s
+		return nil
+	case c == '-' || c >= '0' && c <= '9':
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos < len(p.src) {
+			c = p.src[p.pos]
+			if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+				break
+			}
+			p.pos++
+		}
+	default:
+		return p.errorf("unexpected character %q", c)
+	}
+	p.tok = p.src[start:p.pos]
+	return nil
+}
+
+// string 读取一个字符串字面量，不支持 """ 块字符串
+func (p *parser) string() (string, error) {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		return "", p.errorf("block strings are not supported")
+	}
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\n':
+			return "", p.errorf("unterminated string")
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				return "", p.errorf("unterminated string")
+			}
+			p.pos++
+			switch e := p.src[p.pos]; e {
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if p.pos+5 > len(p.src) {
+					return "", p.errorf("invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(p.src[p.pos+1:p.pos+5], 16, 32)
+				if err != nil {
+					return "", p.errorf("invalid unicode escape")
+				}
+				b.WriteRune(rune(r))
+				p.pos += 4
+			default:
+				return "", p.errorf("invalid escape \\%c", e)
+			}
+			p.pos++
+		default:
+			r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			b.WriteRune(r)
+			p.pos += size
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// expect 读取指定的 token
+func (p *parser) expect(tok string) error {
+	if p.tok != tok {
+		return p.errorf("expected %q, got %q", tok, p.tok)
+	}
+	return p.next()
+}
+
+func isName(tok string) bool {
+	if tok == "" {
+		return false
+	}
+	c := tok[0]
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// name 读取一个名称
+func (p *parser) name() (string, error) {
+	if !isName(p.tok) {
+		return "", p.errorf("expected name, got %q", p.tok)
+	}
+	name := p.tok
+	return name, p.next()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: "query", defaults: make(map[string]interface{})}
+	if p.tok != "{" {
+		switch p.tok {
+		case "query", "mutation", "subscription":
+			op.kind = p.tok
+		case "fragment":
+			return nil, p.errorf("fragments are not supported")
+		default:
+			return nil, p.errorf("unexpected %q", p.tok)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if isName(p.tok) {
+			op.name = p.tok
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if p.tok == "(" {
+			if err := p.variableDefinitions(op); err != nil {
+				return nil, err
+			}
+		}
+		if p.tok == "@" {
+			return nil, p.errorf("directives are not supported")
+		}
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = sel
+	return op, nil
+}
+
+func (p *parser) variableDefinitions(op *operation) error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for p.tok != ")" {
+		if err := p.expect("$"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err = p.expect(":"); err != nil {
+			return err
+		}
+		if err = p.typeRef(); err != nil {
+			return err
+		}
+		op.varNames = append(op.varNames, name)
+		if p.tok == "=" {
+			if err = p.next(); err != nil {
+				return err
+			}
+			v, err := p.value()
+			if err != nil {
+				return err
+			}
+			op.defaults[name] = v
+		}
+	}
+	return p.next()
+}
+
+// typeRef 跳过变量的类型，变量的类型在解析参数时检查
+func (p *parser) typeRef() error {
+	if p.tok == "[" {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.tok == "!" {
+		return p.next()
+	}
+	return nil
+}
+
+func (p *parser) selectionSet() ([]*field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []*field
+	for p.tok != "}" {
+		if p.tok == "" {
+			return nil, p.errorf("unexpected end of document")
+		}
+		if p.tok == "..." {
+			return nil, p.errorf("fragments are not supported")
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, p.next()
+}
+
+func (p *parser) field() (*field, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f := &field{name: name, args: make(map[string]interface{})}
+	if p.tok == ":" {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		f.alias = name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.tok == "(" {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		for p.tok != ")" {
+			arg, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(":"); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			f.args[arg] = v
+		}
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if p.tok == "@" {
+		return nil, p.errorf("directives are not supported")
+	}
+	if p.tok == "{" {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// value 读取参数值，整数为 int64，变量为 variable，枚举值为字符串
+func (p *parser) value() (interface{}, error) {
+	tok := p.tok
+	switch {
+	case tok == "$":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return variable(name), err
+	case tok == "[":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for p.tok != "]" {
+			if p.tok == "" {
+				return nil, p.errorf("unexpected end of document")
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+	case tok == "{":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		obj := make(map[string]interface{})
+		for p.tok != "}" {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.next()
+	case strings.HasPrefix(tok, `"`):
+		return tok[1:], p.next()
+	case tok == "true" || tok == "false":
+		return tok == "true", p.next()
+	case tok == "null":
+		return nil, p.next()
+	case isName(tok):
+		return tok, p.next()
+	case tok != "" && (tok[0] == '-' || tok[0] >= '0' && tok[0] <= '9'):
+		if n, err := strconv.ParseInt(tok, 10, 64); err == nil {
+			return n, p.next()
+		}
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok)
+		}
+		return f, p.next()
+	}
+	return nil, p.errorf("unexpected %q", tok)
+}
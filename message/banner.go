@@ -0,0 +1,53 @@
+package message
+
+import (
+	"github.com/RemKeeper/blivedm-go/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+type Widget struct {
+	Key            string   `json:"-"` // widget_list 中的 key
+	Id             int      `json:"id"`
+	Title          string   `json:"title"`
+	Cover          string   `json:"cover"`
+	WebCover       string   `json:"web_cover"`
+	TipText        string   `json:"tip_text"`
+	TipTextColor   string   `json:"tip_text_color"`
+	TipBottomColor string   `json:"tip_bottom_color"`
+	JumpUrl        string   `json:"jump_url"`
+	Url            string   `json:"url"`
+	StayTime       int      `json:"stay_time"` // 展示时间，秒
+	Site           int      `json:"site"`
+	PlatformIn     []string `json:"platform_in"`
+	Type           int      `json:"type"`
+	BandId         int      `json:"band_id"`
+	SubKey         string   `json:"sub_key"`
+	SubData        string   `json:"sub_data"`
+	IsAdd          bool     `json:"is_add"`
+	Countdown      int      `json:"countdown"` // 活动倒计时，秒，为 0 时没有倒计时
+	EndTime        int      `json:"-"`         // 倒计时结束的时间戳，为 WidgetBanner.Timestamp + Countdown
+}
+
+func (w *WidgetBanner) parse(sb string) error {
+	d := gjson.Get(sb, "data")
+	w.Timestamp = int(d.Get("timestamp").Int())
+	var err error
+	d.Get("widget_list").ForEach(func(key, value gjson.Result) bool {
+		if value.Type == gjson.Null {
+			w.Removed = append(w.Removed, key.String())
+			return true
+		}
+		wg := &Widget{Key: key.String()}
+		if err = utils.UnmarshalStr(value.Raw, wg); err != nil {
+			log.Error("parse WidgetBanner failed")
+			return false
+		}
+		if wg.Countdown > 0 {
+			wg.EndTime = w.Timestamp + wg.Countdown
+		}
+		w.Widgets = append(w.Widgets, wg)
+		return true
+	})
+	return err
+}
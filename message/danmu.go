@@ -1,7 +1,6 @@
 package message
 
 import (
-	"github.com/RemKeeper/blivedm-go/utils"
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 )
@@ -12,14 +11,12 @@ const (
 )
 
 type (
-	Danmaku struct {
-		Sender    *User
-		Content   string
-		Extra     *Extra
-		Emoticon  *Emoticon
-		Type      int
-		Timestamp int64
-		Raw       string
+	// danmakuStore Parse 时 Sender、Extra 等指针字段使用的存储，避免逐个分配
+	danmakuStore struct {
+		user     User
+		medal    Medal
+		extra    Extra
+		emoticon Emoticon
 	}
 
 	Extra struct {
@@ -58,41 +55,152 @@ type (
 	}
 )
 
-func (d *Danmaku) Parse(data []byte) {
-	sb := utils.BytesToString(data)
-	info := gjson.Parse(sb).Get("info")
-	ext := new(Extra)
-	emo := new(Emoticon)
-	err := utils.UnmarshalStr(info.Get("0.15.extra").String(), ext)
-	if err != nil {
-		log.Error("parse danmaku extra failed")
-	}
-	err = utils.UnmarshalStr(info.Get("0.13").String(), emo)
-	if err != nil {
-		log.Error("parse danmaku emoticon failed")
+// parse 解析 DANMU_MSG 报文
+//
+// 只遍历一次 info 数组，Sender、Extra 等字段指向 d 内部的存储，字符串直接引用 sb，
+// 一般情况下除 d 本身外没有额外的内存分配，因此 sb 在 d 使用期间不能被修改
+func (d *Danmaku) parse(sb string) error {
+	d.store = danmakuStore{}
+	d.store.user.Medal = &d.store.medal
+	d.Sender = &d.store.user
+	d.Extra = &d.store.extra
+	d.Emoticon = &d.store.emoticon
+	var err error
+	i := 0
+	gjson.Get(sb, "info").ForEach(func(_, v gjson.Result) bool {
+		switch i {
+		case 0:
+			err = d.parseInfo0(v)
+		case 1:
+			d.Content = v.String()
+		case 2:
+			u := d.Sender
+			j := 0
+			v.ForEach(func(_, f gjson.Result) bool {
+				switch j {
+				case 0:
+					u.Uid = int(f.Int())
+				case 1:
+					u.Uname = f.String()
+				case 2:
+					u.Admin = f.Bool()
+				case 5:
+					u.Urank = int(f.Int())
+				case 6:
+					u.MobileVerify = f.Bool()
+				}
+				j++
+				return j <= 6
+			})
+		case 3:
+			m := d.Sender.Medal
+			j := 0
+			v.ForEach(func(_, f gjson.Result) bool {
+				switch j {
+				case 0:
+					m.Level = int(f.Int())
+				case 1:
+					m.Name = f.String()
+				case 2:
+					m.UpName = f.String()
+				case 3:
+					m.UpRoomId = int(f.Int())
+				case 4:
+					m.Color = int(f.Int())
+				case 12:
+					m.UpUid = int(f.Int())
+				}
+				j++
+				return j <= 12
+			})
+		case 7:
+			d.Sender.GuardLevel = int(v.Int())
+		}
+		i++
+		return i <= 7
+	})
+	return err
+}
+
+// parseInfo0 解析 info[0]，其中包含时间戳、弹幕类型、表情与 extra
+//
+// 表情是转义后的 JSON 字符串，使用 gjson 遍历时会被解码并分配内存，因此直接遍历原文
+func (d *Danmaku) parseInfo0(v gjson.Result) error {
+	var err error
+	forEachRaw(v.Raw, func(j int, raw string) bool {
+		switch j {
+		case 4:
+			d.Timestamp = gjson.Parse(raw).Int()
+		case 12:
+			d.Type = atoi(raw)
+		case 13:
+			if eerr := parseObject(raw, d.Emoticon.set); eerr != nil {
+				log.Error("parse danmaku emoticon failed")
+				if err == nil {
+					err = eerr
+				}
+			}
+		case 15:
+			if eerr := parseObject(rawField(raw, "extra"), d.Extra.set); eerr != nil {
+				log.Error("parse danmaku extra failed")
+				err = eerr
+			}
+		}
+		return j < 15
+	})
+	return err
+}
+
+// set 设置 extra 中的一个字段，str 表示 val 是否为字符串
+func (e *Extra) set(key, val string, str bool) {
+	switch key {
+	case "send_from_me":
+		e.SendFromMe = val == "true"
+	case "mode":
+		e.Mode = atoi(val)
+	case "color":
+		e.Color = atoi(val)
+	case "dm_type":
+		e.DmType = atoi(val)
+	case "font_size":
+		e.FontSize = atoi(val)
+	case "player_mode":
+		e.PlayerMode = atoi(val)
+	case "show_player_type":
+		e.ShowPlayerType = atoi(val)
+	case "content":
+		e.Content = val
+	case "user_hash":
+		e.UserHash = val
+	case "emoticon_unique":
+		e.EmoticonUnique = val
+	case "direction":
+		e.Direction = atoi(val)
+	case "pk_direction":
+		e.PkDirection = atoi(val)
+	case "space_type":
+		e.SpaceType = val
+	case "space_url":
+		e.SpaceUrl = val
 	}
-	i2 := info.Get("2")
-	i3 := info.Get("3")
-	d.Content = info.Get("1").String()
-	d.Sender = &User{
-		Uid:          int(i2.Get("0").Int()),
-		Uname:        i2.Get("1").String(),
-		Admin:        i2.Get("2").Bool(),
-		Urank:        int(i2.Get("5").Int()),
-		MobileVerify: i2.Get("6").Bool(),
-		GuardLevel:   int(info.Get("7").Int()),
-		Medal: &Medal{
-			Level:    int(i3.Get("0").Int()),
-			Name:     i3.Get("1").String(),
-			UpName:   i3.Get("2").String(),
-			UpRoomId: int(i3.Get("3").Int()),
-			Color:    int(i3.Get("4").Int()),
-			UpUid:    int(i3.Get("12").Int()),
-		},
+}
+
+// set 设置表情中的一个字段，str 表示 val 是否为字符串
+func (e *Emoticon) set(key, val string, str bool) {
+	switch key {
+	case "bulge_display":
+		e.BulgeDisplay = atoi(val)
+	case "emoticon_unique":
+		e.EmoticonUnique = val
+	case "height":
+		e.Height = atoi(val)
+	case "in_player_area":
+		e.InPlayerArea = atoi(val)
+	case "is_dynamic":
+		e.IsDynamic = atoi(val)
+	case "url":
+		e.Url = val
+	case "width":
+		e.Width = atoi(val)
 	}
-	d.Extra = ext
-	d.Emoticon = emo
-	d.Type = int(info.Get("0.12").Int())
-	d.Timestamp = info.Get("0.4").Int()
-	d.Raw = sb
 }
@@ -0,0 +1,155 @@
+package message
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/RemKeeper/blivedm-go/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// danmuMsgPath 从录制的直播间消息中取出的 DANMU_MSG 报文，每行一条
+const danmuMsgPath = "testdata/danmu_msg.jsonl"
+
+// minAllocRatio Parse 相比 parseDanmakuLegacy 至少减少的分配倍数
+const minAllocRatio = 5
+
+func loadDanmuMsg(tb testing.TB) [][]byte {
+	f, err := os.Open(danmuMsgPath)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	var bodies [][]byte
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		if line := bytes.TrimSpace(sc.Bytes()); len(line) > 0 {
+			bodies = append(bodies, append([]byte(nil), line...))
+		}
+	}
+	if err = sc.Err(); err != nil {
+		tb.Fatal(err)
+	}
+	if len(bodies) == 0 {
+		tb.Fatal("no DANMU_MSG in " + danmuMsgPath)
+	}
+	return bodies
+}
+
+// parseDanmakuLegacy 原先按路径逐个查找并用 encoding/json 解析 extra 与表情的实现，作为对照
+func parseDanmakuLegacy(d *Danmaku, data []byte) error {
+	sb := utils.BytesToString(data)
+	info := gjson.Parse(sb).Get("info")
+	ext := new(Extra)
+	emo := new(Emoticon)
+	err := utils.UnmarshalStr(info.Get("0.15.extra").String(), ext)
+	if err != nil {
+		log.Error("parse danmaku extra failed")
+	}
+	if eerr := utils.UnmarshalStr(info.Get("0.13").String(), emo); eerr != nil {
+		log.Error("parse danmaku emoticon failed")
+		if err == nil {
+			err = eerr
+		}
+	}
+	i2 := info.Get("2")
+	i3 := info.Get("3")
+	d.Content = info.Get("1").String()
+	d.Sender = &User{
+		Uid:          int(i2.Get("0").Int()),
+		Uname:        i2.Get("1").String(),
+		Admin:        i2.Get("2").Bool(),
+		Urank:        int(i2.Get("5").Int()),
+		MobileVerify: i2.Get("6").Bool(),
+		GuardLevel:   int(info.Get("7").Int()),
+		Medal: &Medal{
+			Level:    int(i3.Get("0").Int()),
+			Name:     i3.Get("1").String(),
+			UpName:   i3.Get("2").String(),
+			UpRoomId: int(i3.Get("3").Int()),
+			Color:    int(i3.Get("4").Int()),
+			UpUid:    int(i3.Get("12").Int()),
+		},
+	}
+	d.Extra = ext
+	d.Emoticon = emo
+	d.Type = int(info.Get("0.12").Int())
+	d.Timestamp = info.Get("0.4").Int()
+	d.Raw = sb
+	return err
+}
+
+func TestDanmakuParseMatchesLegacy(t *testing.T) {
+	for i, body := range loadDanmuMsg(t) {
+		got, want := new(Danmaku), new(Danmaku)
+		if err := got.Parse(body); err != nil {
+			t.Fatalf("line %d: Parse: %v", i+1, err)
+		}
+		if err := parseDanmakuLegacy(want, body); err != nil {
+			t.Fatalf("line %d: legacy parse: %v", i+1, err)
+		}
+		if got.Content != want.Content || got.Type != want.Type || got.Timestamp != want.Timestamp || got.Raw != want.Raw {
+			t.Errorf("line %d: got %q type %d ts %d, want %q type %d ts %d", i+1, got.Content, got.Type, got.Timestamp, want.Content, want.Type, want.Timestamp)
+		}
+		if !reflect.DeepEqual(got.Sender, want.Sender) {
+			t.Errorf("line %d: sender %+v medal %+v, want %+v medal %+v", i+1, *got.Sender, *got.Sender.Medal, *want.Sender, *want.Sender.Medal)
+		}
+		if !reflect.DeepEqual(got.Extra, want.Extra) {
+			t.Errorf("line %d: extra %+v, want %+v", i+1, *got.Extra, *want.Extra)
+		}
+		if !reflect.DeepEqual(got.Emoticon, want.Emoticon) {
+			t.Errorf("line %d: emoticon %+v, want %+v", i+1, *got.Emoticon, *want.Emoticon)
+		}
+	}
+}
+
+func TestDanmakuParseAllocs(t *testing.T) {
+	bodies := loadDanmuMsg(t)
+	parse := testing.AllocsPerRun(20, func() {
+		for _, body := range bodies {
+			new(Danmaku).Parse(body)
+		}
+	})
+	legacy := testing.AllocsPerRun(20, func() {
+		for _, body := range bodies {
+			parseDanmakuLegacy(new(Danmaku), body)
+		}
+	})
+	n := float64(len(bodies))
+	t.Logf("allocs/msg: Parse %.2f, legacy %.2f", parse/n, legacy/n)
+	if parse == 0 {
+		return
+	}
+	if ratio := legacy / parse; ratio < minAllocRatio {
+		t.Errorf("Parse allocates %.2f/msg, legacy %.2f/msg: ratio %.1fx, want at least %dx", parse/n, legacy/n, ratio, minAllocRatio)
+	}
+}
+
+func BenchmarkDanmakuParse(b *testing.B) {
+	bodies := loadDanmuMsg(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, body := range bodies {
+			new(Danmaku).Parse(body)
+		}
+	}
+	b.ReportMetric(float64(len(bodies)), "msgs/op")
+}
+
+func BenchmarkDanmakuParseLegacy(b *testing.B) {
+	bodies := loadDanmuMsg(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, body := range bodies {
+			parseDanmakuLegacy(new(Danmaku), body)
+		}
+	}
+	b.ReportMetric(float64(len(bodies)), "msgs/op")
+}
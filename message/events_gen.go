@@ -0,0 +1,589 @@
+// Code generated by message/internal/gen from events.json; DO NOT EDIT.
+
+package message
+
+import (
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// Danmaku 弹幕，cmd 为 DANMU_MSG
+type Danmaku struct {
+	Meta
+	Sender     *User
+	Content    string
+	Extra      *Extra
+	Emoticon   *Emoticon
+	Type       int
+	Timestamp  int64
+	Backfilled bool // 为 true 时表示该弹幕是启动时通过历史弹幕接口补齐的，Raw 为空
+	store      danmakuStore
+	Raw        string `json:"-"` // 原始报文
+}
+
+func (d *Danmaku) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	d.Raw = sb
+	return d.parse(sb)
+}
+
+// SuperChat 醒目留言，cmd 为 SUPER_CHAT_MESSAGE
+//
+// message_jpn: 消息日文翻译（目前只出现在SUPER_CHAT_MESSAGE_JPN）
+// id_: str，消息ID，删除时用
+type SuperChat struct {
+	Meta
+	BackgroundBottomColor string  `json:"background_bottom_color"` // 底部背景色
+	BackgroundColor       string  `json:"background_color"`        // 背景色
+	BackgroundColorEnd    string  `json:"background_color_end"`
+	BackgroundColorStart  string  `json:"background_color_start"`
+	BackgroundIcon        string  `json:"background_icon"`        // 背景图标
+	BackgroundImage       string  `json:"background_image"`       // 背景图
+	BackgroundPriceColor  string  `json:"background_price_color"` // 背景价格颜色
+	ColorPoint            float64 `json:"color_point"`
+	Dmscore               int     `json:"dmscore"`
+	EndTime               int     `json:"end_time"` // 结束时间戳
+	Gift                  struct {
+		GiftId   int    `json:"gift_id"`   // 礼物ID
+		GiftName string `json:"gift_name"` // 礼物名
+		Num      int    `json:"num"`
+	} `json:"gift"`
+	Id          int `json:"id"`
+	IsRanked    int `json:"is_ranked"`
+	IsSendAudit int `json:"is_send_audit"`
+	MedalInfo   struct {
+		AnchorRoomid     int    `json:"anchor_roomid"`
+		AnchorUname      string `json:"anchor_uname"`
+		GuardLevel       int    `json:"guard_level"` // 舰队等级，0:非舰队，1:总督，2:提督，3:舰长
+		IconId           int    `json:"icon_id"`
+		IsLighted        int    `json:"is_lighted"`
+		MedalColor       string `json:"medal_color"`
+		MedalColorBorder int    `json:"medal_color_border"`
+		MedalColorEnd    int    `json:"medal_color_end"`
+		MedalColorStart  int    `json:"medal_color_start"`
+		MedalLevel       int    `json:"medal_level"`
+		MedalName        string `json:"medal_name"`
+		Special          string `json:"special"`
+		TargetId         int    `json:"target_id"`
+	} `json:"medal_info"`
+	Message          string `json:"message"` // 消息
+	MessageFontColor string `json:"message_font_color"`
+	MessageTrans     string `json:"message_trans"`
+	Price            int    `json:"price"` // 价格（人民币）
+	Rate             int    `json:"rate"`
+	StartTime        int    `json:"start_time"` // 开始时间戳
+	Time             int    `json:"time"`       // 剩余时间
+	Token            string `json:"token"`
+	TransMark        int    `json:"trans_mark"`
+	Ts               int    `json:"ts"`
+	Uid              int    `json:"uid"` // 用户ID
+	UserInfo         struct {
+		Face       string `json:"face"` // 用户头像URL
+		FaceFrame  string `json:"face_frame"`
+		GuardLevel int    `json:"guard_level"`
+		IsMainVip  int    `json:"is_main_vip"`
+		IsSvip     int    `json:"is_svip"`
+		IsVip      int    `json:"is_vip"`
+		LevelColor string `json:"level_color"`
+		Manager    int    `json:"manager"`
+		NameColor  string `json:"name_color"`
+		Title      string `json:"title"`
+		Uname      string `json:"uname"`      // 用户名
+		UserLevel  int    `json:"user_level"` // 用户等级
+	} `json:"user_info"`
+	Raw string `json:"-"` // 原始报文
+}
+
+func (s *SuperChat) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	s.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, s)
+	if err != nil {
+		log.Error("parse SuperChat failed")
+	}
+	return err
+}
+
+// Gift 礼物，cmd 为 SEND_GIFT
+type Gift struct {
+	Meta
+	Action            string      `json:"action"`
+	BatchComboId      string      `json:"batch_combo_id"`
+	BatchComboSend    interface{} `json:"batch_combo_send"`
+	BeatId            string      `json:"beatId"`
+	BizSource         string      `json:"biz_source"`
+	BlindGift         interface{} `json:"blind_gift"`
+	BroadcastId       int         `json:"broadcast_id"`
+	CoinType          string      `json:"coin_type"`
+	ComboResourcesId  int         `json:"combo_resources_id"`
+	ComboSend         interface{} `json:"combo_send"`
+	ComboStayTime     int         `json:"combo_stay_time"`
+	ComboTotalCoin    int         `json:"combo_total_coin"`
+	CritProb          int         `json:"crit_prob"`
+	Demarcation       int         `json:"demarcation"`
+	DiscountPrice     int         `json:"discount_price"`
+	Dmscore           int         `json:"dmscore"`
+	Draw              int         `json:"draw"`
+	Effect            int         `json:"effect"`
+	EffectBlock       int         `json:"effect_block"`
+	Face              string      `json:"face"`
+	FloatScResourceId int         `json:"float_sc_resource_id"`
+	GiftId            int         `json:"giftId"`
+	GiftName          string      `json:"giftName"`
+	GiftType          int         `json:"giftType"`
+	Gold              int         `json:"gold"`
+	GuardLevel        int         `json:"guard_level"`
+	IsFirst           bool        `json:"is_first"`
+	IsSpecialBatch    int         `json:"is_special_batch"`
+	Magnification     float64     `json:"magnification"`
+	MedalInfo         struct {
+		AnchorRoomid     int    `json:"anchor_roomid"`
+		AnchorUname      string `json:"anchor_uname"`
+		GuardLevel       int    `json:"guard_level"`
+		IconId           int    `json:"icon_id"`
+		IsLighted        int    `json:"is_lighted"`
+		MedalColor       int    `json:"medal_color"`
+		MedalColorBorder int    `json:"medal_color_border"`
+		MedalColorEnd    int    `json:"medal_color_end"`
+		MedalColorStart  int    `json:"medal_color_start"`
+		MedalLevel       int    `json:"medal_level"`
+		MedalName        string `json:"medal_name"`
+		Special          string `json:"special"`
+		TargetId         int    `json:"target_id"`
+	} `json:"medal_info"`
+	NameColor         string      `json:"name_color"`
+	Num               int         `json:"num"`
+	OriginalGiftName  string      `json:"original_gift_name"`
+	Price             int         `json:"price"`
+	Rcost             int         `json:"rcost"`
+	Remain            int         `json:"remain"`
+	Rnd               string      `json:"rnd"`
+	SendMaster        interface{} `json:"send_master"`
+	Silver            int         `json:"silver"`
+	Super             int         `json:"super"`
+	SuperBatchGiftNum int         `json:"super_batch_gift_num"`
+	SuperGiftNum      int         `json:"super_gift_num"`
+	SvgaBlock         int         `json:"svga_block"`
+	TagImage          string      `json:"tag_image"`
+	Tid               string      `json:"tid"`
+	Timestamp         int         `json:"timestamp"`
+	TopList           interface{} `json:"top_list"`
+	TotalCoin         int         `json:"total_coin"`
+	Uid               int         `json:"uid"`
+	Uname             string      `json:"uname"`
+	Raw               string      `json:"-"` // 原始报文
+}
+
+func (g *Gift) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	g.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, g)
+	if err != nil {
+		log.Error("parse Gift failed")
+	}
+	return err
+}
+
+// GuardBuy 开通大航海，cmd 为 GUARD_BUY
+type GuardBuy struct {
+	Meta
+	Uid        int    `json:"uid"`
+	Username   string `json:"username"`
+	GuardLevel int    `json:"guard_level"`
+	Num        int    `json:"num"`
+	Price      int    `json:"price"`
+	GiftId     int    `json:"gift_id"`
+	GiftName   string `json:"gift_name"`
+	StartTime  int    `json:"start_time"`
+	EndTime    int    `json:"end_time"`
+	Raw        string `json:"-"` // 原始报文
+}
+
+func (g *GuardBuy) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	g.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, g)
+	if err != nil {
+		log.Error("parse GuardBuy failed")
+	}
+	return err
+}
+
+// Live 开播，cmd 为 LIVE
+type Live struct {
+	Meta
+	Cmd             string `json:"cmd"`
+	LiveKey         string `json:"live_key"`
+	VoiceBackground string `json:"voice_background"`
+	SubSessionKey   string `json:"sub_session_key"`
+	LivePlatform    string `json:"live_platform"`
+	LiveModel       int    `json:"live_model"`
+	LiveTime        int    `json:"live_time"`
+	Roomid          int    `json:"roomid"`
+	Raw             string `json:"-"` // 原始报文
+}
+
+func (l *Live) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	l.Raw = sb
+	err := utils.UnmarshalStr(sb, l)
+	if err != nil {
+		log.Error("parse Live failed")
+	}
+	return err
+}
+
+// Preparing 下播，cmd 为 PREPARING
+//
+// roomid 有时为字符串有时为数字，统一按字符串读取
+type Preparing struct {
+	Meta
+	Cmd    string `json:"cmd"`
+	Roomid string `json:"roomid"`
+	Raw    string `json:"-"` // 原始报文
+}
+
+func (p *Preparing) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	p.Raw = sb
+	return p.parse(sb)
+}
+
+// UserToast 开通大航海的提示，cmd 为 USER_TOAST_MSG
+type UserToast struct {
+	Meta
+	AnchorShow       bool   `json:"anchor_show"`
+	Color            string `json:"color"`
+	Dmscore          int    `json:"dmscore"`
+	EffectId         int    `json:"effect_id"`
+	EndTime          int    `json:"end_time"`
+	FaceEffectId     int    `json:"face_effect_id"`
+	GiftId           int    `json:"gift_id"`
+	GuardLevel       int    `json:"guard_level"`
+	IsShow           int    `json:"is_show"`
+	Num              int    `json:"num"`
+	OpType           int    `json:"op_type"`
+	PayflowId        string `json:"payflow_id"`
+	Price            int    `json:"price"`
+	RoleName         string `json:"role_name"`
+	RoomEffectId     int    `json:"room_effect_id"`
+	StartTime        int    `json:"start_time"`
+	SvgaBlock        int    `json:"svga_block"`
+	TargetGuardCount int    `json:"target_guard_count"`
+	ToastMsg         string `json:"toast_msg"`
+	Uid              int    `json:"uid"`
+	Unit             string `json:"unit"`
+	UserShow         bool   `json:"user_show"`
+	Username         string `json:"username"`
+	Raw              string `json:"-"` // 原始报文
+}
+
+func (u *UserToast) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	u.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, u)
+	if err != nil {
+		log.Error("parse UserToast failed")
+	}
+	return err
+}
+
+// MedalGain 获得粉丝勋章，cmd 为 MESSAGEBOX_USER_GAIN_MEDAL
+type MedalGain struct {
+	Meta
+	Type          int    `json:"type"`
+	Uid           int    `json:"uid"`
+	UpUid         int    `json:"up_uid"`
+	MedalId       int    `json:"medal_id"`
+	MedalName     string `json:"medal_name"`
+	MedalLevel    int    `json:"medal_level"`
+	MedalColor    int    `json:"medal_color"`
+	MsgTitle      string `json:"msg_title"`
+	MsgContent    string `json:"msg_content"`
+	Normal        int    `json:"normal"`
+	Highlight     int    `json:"highlight"`
+	Intimacy      int    `json:"intimacy"`
+	NextIntimacy  int    `json:"next_intimacy"`
+	TodayIntimacy int    `json:"today_intimacy"`
+	IsLighted     int    `json:"is_lighted"`
+	IsWear        int    `json:"is_wear"`
+	UpName        string `json:"up_name"`
+	TargetName    string `json:"target_name"`
+	Raw           string `json:"-"` // 原始报文
+}
+
+func (m *MedalGain) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	m.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, m)
+	if err != nil {
+		log.Error("parse MedalGain failed")
+	}
+	return err
+}
+
+// MedalChange 粉丝勋章变化(升级、点亮、熄灭)，cmd 为 MESSAGEBOX_USER_MEDAL_CHANGE
+type MedalChange struct {
+	Meta
+	Type              int    `json:"type"`
+	Uid               int    `json:"uid"`
+	UpUid             int    `json:"up_uid"`
+	MedalName         string `json:"medal_name"`
+	MedalLevel        int    `json:"medal_level"`
+	MedalColorStart   int    `json:"medal_color_start"`
+	MedalColorEnd     int    `json:"medal_color_end"`
+	MedalColorBorder  int    `json:"medal_color_border"`
+	IsLighted         int    `json:"is_lighted"`
+	GuardLevel        int    `json:"guard_level"`
+	Unlock            int    `json:"unlock"`
+	UnlockLevel       int    `json:"unlock_level"`
+	MultiUnlockLevel  string `json:"multi_unlock_level"`
+	UpperBoundContent string `json:"upper_bound_content"`
+	Raw               string `json:"-"` // 原始报文
+}
+
+func (m *MedalChange) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	m.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, m)
+	if err != nil {
+		log.Error("parse MedalChange failed")
+	}
+	return err
+}
+
+// SpecialGift 节奏风暴，cmd 为 SPECIAL_GIFT
+//
+// 开始时 Action 为 start，Content 为参与时需要发送的弹幕，Time 为可参与的秒数
+type SpecialGift struct {
+	Meta
+	GiftId   int    // 特殊礼物 ID，节奏风暴为 39
+	Id       string // 风暴 ID
+	Action   string // start 或 end
+	Content  string // 参与口令
+	Time     int    // 持续时间，秒
+	Num      int
+	HadJoin  bool
+	StormGif string
+	Raw      string `json:"-"` // 原始报文
+}
+
+func (s *SpecialGift) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	s.Raw = sb
+	return s.parse(sb)
+}
+
+// WidgetBanner 挂件横幅更新，cmd 为 WIDGET_BANNER
+//
+// widget_list 的 key 不固定，解析后放在 Widgets 中，值为 null 的 key 表示挂件被移除
+type WidgetBanner struct {
+	Meta
+	Timestamp int
+	Widgets   []*Widget
+	Removed   []string // 被移除的挂件 key
+	Raw       string   `json:"-"` // 原始报文
+}
+
+func (w *WidgetBanner) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	w.Raw = sb
+	return w.parse(sb)
+}
+
+// ActivityBanner 活动横幅更新，cmd 为 ACTIVITY_BANNER_UPDATE_V2
+type ActivityBanner struct {
+	Meta
+	Id         int    `json:"id"`
+	Title      string `json:"title"`
+	Cover      string `json:"cover"`
+	Background string `json:"background"`
+	JumpUrl    string `json:"jump_url"`
+	TitleColor string `json:"title_color"`
+	Closeable  int    `json:"closeable"`
+	BannerType int    `json:"banner_type"`
+	Weight     int    `json:"weight"`
+	AddBanner  int    `json:"add_banner"`
+	Raw        string `json:"-"` // 原始报文
+}
+
+func (a *ActivityBanner) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	a.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, a)
+	if err != nil {
+		log.Error("parse ActivityBanner failed")
+	}
+	return err
+}
+
+// RoomPunish 直播间被封禁、切断或警告，cmd 为 ROOM_LOCK、CUT_OFF、WARNING、ROOM_LIMIT
+//
+// Cmd 为 ROOM_LOCK(封禁)、CUT_OFF(切断直播)、WARNING(警告) 或 ROOM_LIMIT(限制)
+type RoomPunish struct {
+	Meta
+	Cmd    string
+	RoomId int
+	Reason string    // 原因，ROOM_LOCK 不携带原因
+	Type   string    // ROOM_LIMIT 的限制类型
+	Expire time.Time // ROOM_LOCK 的解封时间
+	Raw    string    `json:"-"` // 原始报文
+}
+
+func (r *RoomPunish) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	r.Raw = sb
+	return r.parse(sb)
+}
+
+// RoomChange 直播间标题或分区变化，cmd 为 ROOM_CHANGE
+type RoomChange struct {
+	Meta
+	Title          string `json:"title"`
+	AreaId         int    `json:"area_id"`
+	ParentAreaId   int    `json:"parent_area_id"`
+	AreaName       string `json:"area_name"`
+	ParentAreaName string `json:"parent_area_name"`
+	LiveKey        string `json:"live_key"`
+	SubSessionKey  string `json:"sub_session_key"`
+	Raw            string `json:"-"` // 原始报文
+}
+
+func (r *RoomChange) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	r.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, r)
+	if err != nil {
+		log.Error("parse RoomChange failed")
+	}
+	return err
+}
+
+// InteractWord 用户进入直播间、关注、分享，cmd 为 INTERACT_WORD
+type InteractWord struct {
+	Meta
+	Contribution struct {
+		Grade int `json:"grade"`
+	} `json:"contribution"`
+	Dmscore   int `json:"dmscore"`
+	FansMedal struct {
+		AnchorRoomid     int    `json:"anchor_roomid"`
+		GuardLevel       int    `json:"guard_level"`
+		IconId           int    `json:"icon_id"`
+		IsLighted        int    `json:"is_lighted"`
+		MedalColor       int    `json:"medal_color"`
+		MedalColorBorder int    `json:"medal_color_border"`
+		MedalColorEnd    int    `json:"medal_color_end"`
+		MedalColorStart  int    `json:"medal_color_start"`
+		MedalLevel       int    `json:"medal_level"`
+		MedalName        string `json:"medal_name"`
+		Score            int    `json:"score"`
+		Special          string `json:"special"`
+		TargetId         int    `json:"target_id"`
+	} `json:"fans_medal"`
+	Identities  []int  `json:"identities"`
+	IsSpread    int    `json:"is_spread"`
+	MsgType     int    `json:"msg_type"`
+	Roomid      int    `json:"roomid"`
+	Score       int64  `json:"score"`
+	SpreadDesc  string `json:"spread_desc"`
+	SpreadInfo  string `json:"spread_info"`
+	TailIcon    int    `json:"tail_icon"`
+	Timestamp   int    `json:"timestamp"`
+	TriggerTime int64  `json:"trigger_time"`
+	Uid         int    `json:"uid"`
+	Uname       string `json:"uname"`
+	UnameColor  string `json:"uname_color"`
+	Raw         string `json:"-"` // 原始报文
+}
+
+func (i *InteractWord) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	i.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, i)
+	if err != nil {
+		log.Error("parse InteractWord failed")
+	}
+	return err
+}
+
+// OnlineRankCount 高能用户数量变化，cmd 为 ONLINE_RANK_COUNT
+type OnlineRankCount struct {
+	Meta
+	Count       int    `json:"count"`        // 高能用户数量
+	OnlineCount int    `json:"online_count"` // 在线人数
+	Raw         string `json:"-"`            // 原始报文
+}
+
+func (o *OnlineRankCount) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	o.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, o)
+	if err != nil {
+		log.Error("parse OnlineRankCount failed")
+	}
+	return err
+}
+
+// WatchedChange 看过人数变化，cmd 为 WATCHED_CHANGE
+type WatchedChange struct {
+	Meta
+	Num       int    `json:"num"` // 看过的人数
+	TextSmall string `json:"text_small"`
+	TextLarge string `json:"text_large"` // 如 1.2万人看过
+	Raw       string `json:"-"`          // 原始报文
+}
+
+func (w *WatchedChange) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	w.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, w)
+	if err != nil {
+		log.Error("parse WatchedChange failed")
+	}
+	return err
+}
+
+// LikeInfoUpdate 点赞数变化，cmd 为 LIKE_INFO_V3_UPDATE
+type LikeInfoUpdate struct {
+	Meta
+	ClickCount int    `json:"click_count"` // 本场直播的点赞数
+	Raw        string `json:"-"`           // 原始报文
+}
+
+func (l *LikeInfoUpdate) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	l.Raw = sb
+	err := utils.UnmarshalStr(gjson.Get(sb, "data").Raw, l)
+	if err != nil {
+		log.Error("parse LikeInfoUpdate failed")
+	}
+	return err
+}
+
+func init() {
+	Register("DANMU_MSG", func() Event { return new(Danmaku) })
+	Register("SUPER_CHAT_MESSAGE", func() Event { return new(SuperChat) })
+	Register("SEND_GIFT", func() Event { return new(Gift) })
+	Register("GUARD_BUY", func() Event { return new(GuardBuy) })
+	Register("LIVE", func() Event { return new(Live) })
+	Register("PREPARING", func() Event { return new(Preparing) })
+	Register("USER_TOAST_MSG", func() Event { return new(UserToast) })
+	Register("MESSAGEBOX_USER_GAIN_MEDAL", func() Event { return new(MedalGain) })
+	Register("MESSAGEBOX_USER_MEDAL_CHANGE", func() Event { return new(MedalChange) })
+	Register("SPECIAL_GIFT", func() Event { return new(SpecialGift) })
+	Register("WIDGET_BANNER", func() Event { return new(WidgetBanner) })
+	Register("ACTIVITY_BANNER_UPDATE_V2", func() Event { return new(ActivityBanner) })
+	Register("ROOM_LOCK", func() Event { return new(RoomPunish) })
+	Register("CUT_OFF", func() Event { return new(RoomPunish) })
+	Register("WARNING", func() Event { return new(RoomPunish) })
+	Register("ROOM_LIMIT", func() Event { return new(RoomPunish) })
+	Register("ROOM_CHANGE", func() Event { return new(RoomChange) })
+	Register("INTERACT_WORD", func() Event { return new(InteractWord) })
+	Register("ONLINE_RANK_COUNT", func() Event { return new(OnlineRankCount) })
+	Register("WATCHED_CHANGE", func() Event { return new(WatchedChange) })
+	Register("LIKE_INFO_V3_UPDATE", func() Event { return new(LikeInfoUpdate) })
+}
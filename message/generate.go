@@ -0,0 +1,5 @@
+package message
+
+// 新增 cmd 时编辑 events.json 并执行 go generate，会生成消息结构体、注册表、测试以及 client 中的处理器注册方法
+//
+//go:generate go run ./internal/gen -schema events.json -message events_gen.go -test events_gen_test.go -client ../client/events_gen.go
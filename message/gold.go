@@ -0,0 +1,32 @@
+package message
+
+// GoldPerYuan 1元人民币对应的金瓜子数
+const GoldPerYuan = 1000
+
+// Coin 礼物的总价值，单位为 CoinType 对应的瓜子，total_coin 为 0 时按单价乘数量计算
+func (g *Gift) Coin() int64 {
+	if g.TotalCoin != 0 {
+		return int64(g.TotalCoin)
+	}
+	return int64(g.Price) * int64(g.Num)
+}
+
+// EventGold 获取礼物、醒目留言或上舰的金额，单位为金瓜子，醒目留言按人民币折算，银瓜子礼物与其他事件返回 false
+func EventGold(v interface{}) (int64, bool) {
+	switch m := v.(type) {
+	case *Gift:
+		if m.CoinType != "gold" {
+			return 0, false
+		}
+		return m.Coin(), true
+	case *SuperChat:
+		return int64(m.Price) * GoldPerYuan, true
+	case *GuardBuy:
+		num := m.Num
+		if num < 1 {
+			num = 1
+		}
+		return int64(m.Price) * int64(num), true
+	}
+	return 0, false
+}
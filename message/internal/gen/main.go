@@ -0,0 +1,468 @@
+// gen 根据 events.json 生成消息结构体、事件注册表、测试与 client 中的处理器注册方法
+//
+// 在 message 目录中执行 go generate 即可，每个事件都会生成结构体、在 Raw 中保留原始报文的 Parse 方法、
+// OnXxx 处理器注册方法、分发代码与 CatchUp 使用的处理器类型
+//
+// parser 为 custom 的事件由手写的 parse 方法解析，dispatch 为 pooled 的事件从对象池获取并在处理器返回后放回，
+// dispatch 为 custom 的事件由手写的 handleXxx 方法分发
+//
+// 每个事件都需要在 sample 中给出一条示例报文，生成的测试会用它检查注册表、Parse 解析出的字段与保留的原始报文，
+// 示例报文中出现的基本类型字段会自动检查，其余字段按 expect 检查，expect 的 key 为结构体中的字段，如 Sender.Uname
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type field struct {
+	Name   string  `json:"name"`
+	JSON   string  `json:"json"` // 为空时不生成 json tag
+	Type   string  `json:"type"` // 为 struct 时按 fields 生成匿名结构体
+	Doc    string  `json:"doc"`
+	Fields []field `json:"fields"`
+}
+
+type event struct {
+	Cmds     []string `json:"cmds"`
+	Type     string   `json:"type"`
+	Doc      string   `json:"doc"`      // 结构体的说明，可以有多段
+	Handler  string   `json:"handler"`  // OnXxx 注释中的事件名，默认为 doc 的第一段
+	Payload  string   `json:"payload"`  // 结构体对应的 JSON 路径，默认为 data，为 . 时是整个报文
+	Parser   string   `json:"parser"`   // 为 custom 时由手写的 parse(sb string) error 解析
+	Dispatch string   `json:"dispatch"` // 为 pooled 时使用对象池，为 custom 时由手写的 handleXxx 分发
+	Fields   []field  `json:"fields"`
+
+	Sample json.RawMessage        `json:"sample"` // 示例报文
+	Expect map[string]interface{} `json:"expect"` // 解析示例报文后各字段的值
+
+	SampleLiteral string  `json:"-"`
+	Checks        []check `json:"-"`
+}
+
+// check 生成的测试中检查的一个字段
+type check struct {
+	Expr       string // 相对事件的字段表达式
+	Want       string // 期望的值，Go 字面量
+	WantQuoted string // 用于错误信息
+}
+
+// Custom 是否由手写的 parse 方法解析
+func (e event) Custom() bool {
+	return e.Parser == "custom"
+}
+
+// Handlers client 中处理器切片的字段名
+func (e event) Handlers() string {
+	r := []rune(e.Type)
+	r[0] = unicode.ToLower(r[0])
+	return string(r) + "Handlers"
+}
+
+// Receiver 方法接收者的名字
+func (e event) Receiver() string {
+	return strings.ToLower(e.Type[:1])
+}
+
+// CmdList cmd 列表，用于 switch 的 case
+func (e event) CmdList() string {
+	q := make([]string, len(e.Cmds))
+	for i, c := range e.Cmds {
+		q[i] = `"` + c + `"`
+	}
+	return strings.Join(q, ", ")
+}
+
+// Comment 结构体的注释
+func (e event) Comment() string {
+	paras := strings.Split(e.Doc, "\n\n")
+	paras[0] = e.Type + " " + paras[0] + "，cmd 为 " + strings.Join(e.Cmds, "、")
+	return comment(strings.Join(paras, "\n\n"), "")
+}
+
+// StructFields 结构体的字段定义
+func (e event) StructFields() string {
+	return renderFields(e.Fields, "\t")
+}
+
+// ParseExpr Parse 中结构体对应的 JSON
+func (e event) ParseExpr() string {
+	if e.Payload == "." {
+		return "sb"
+	}
+	return `gjson.Get(sb, "` + e.Payload + `").Raw`
+}
+
+func comment(doc, indent string) string {
+	lines := strings.Split(doc, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(indent+"// "+l, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderFields(fields []field, indent string) string {
+	var b strings.Builder
+	for _, f := range fields {
+		typ := f.Type
+		if typ == "struct" {
+			typ = "struct {\n" + renderFields(f.Fields, indent+"\t") + indent + "}"
+		}
+		b.WriteString(indent + f.Name + " " + typ)
+		if f.JSON != "" {
+			b.WriteString(" `json:\"" + f.JSON + "\"`")
+		}
+		if f.Doc != "" {
+			b.WriteString(" // " + f.Doc)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+type schema struct {
+	Events []event `json:"events"`
+}
+
+// StdImports 生成的结构体需要导入的标准库
+func (s schema) StdImports() []string {
+	for _, e := range s.Events {
+		if usesTime(e.Fields) {
+			return []string{"time"}
+		}
+	}
+	return nil
+}
+
+// Generated 是否有事件使用生成的解析代码
+func (s schema) Generated() bool {
+	for _, e := range s.Events {
+		if !e.Custom() {
+			return true
+		}
+	}
+	return false
+}
+
+func usesTime(fields []field) bool {
+	for _, f := range fields {
+		if strings.Contains(f.Type, "time.") || usesTime(f.Fields) {
+			return true
+		}
+	}
+	return false
+}
+
+var messageTemplate = template.Must(template.New("message").Parse(`// Code generated by message/internal/gen from events.json; DO NOT EDIT.
+
+package message
+
+import (
+{{- range .StdImports}}
+	"{{.}}"
+{{end}}
+	"github.com/RemKeeper/blivedm-go/utils"
+{{- if .Generated}}
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+{{- end}}
+)
+{{range .Events}}
+{{.Comment}}
+type {{.Type}} struct {
+	Meta
+{{.StructFields}}	Raw string ` + "`" + `json:"-"` + "`" + ` // 原始报文
+}
+
+func ({{.Receiver}} *{{.Type}}) Parse(data []byte) error {
+	sb := utils.BytesToString(data)
+	{{.Receiver}}.Raw = sb
+{{- if .Custom}}
+	return {{.Receiver}}.parse(sb)
+{{- else}}
+	err := utils.UnmarshalStr({{.ParseExpr}}, {{.Receiver}})
+	if err != nil {
+		log.Error("parse {{.Type}} failed")
+	}
+	return err
+{{- end}}
+}
+{{end}}
+func init() {
+{{- range .Events}}{{$t := .Type}}{{range .Cmds}}
+	Register("{{.}}", func() Event { return new({{$t}}) })
+{{- end}}{{end}}
+}
+`))
+
+var testTemplate = template.Must(template.New("test").Parse(`// Code generated by message/internal/gen from events.json; DO NOT EDIT.
+
+package message
+
+import "testing"
+{{range .Events}}
+func TestParse{{.Type}}(t *testing.T) {
+	sample := []byte({{.SampleLiteral}})
+	for _, cmd := range []string{ {{- .CmdList -}} } {
+		e, ok := New(cmd)
+		if !ok {
+			t.Fatalf("%s is not registered", cmd)
+		}
+		if _, ok = e.(*{{.Type}}); !ok {
+			t.Fatalf("%s is registered as %T, want *{{.Type}}", cmd, e)
+		}
+	}
+	v := new({{.Type}})
+	if err := v.Parse(sample); err != nil {
+		t.Fatal(err)
+	}
+{{- range .Checks}}
+	if v.{{.Expr}} != {{.Want}} {
+		t.Errorf("{{.Expr}} = %v, want %s", v.{{.Expr}}, {{.WantQuoted}})
+	}
+{{- end}}
+	if v.Raw != string(sample) {
+		t.Errorf("Raw = %q, want the original message", v.Raw)
+	}
+}
+{{end}}`))
+
+var clientTemplate = template.Must(template.New("client").Parse(`// Code generated by message/internal/gen from events.json; DO NOT EDIT.
+
+package client
+
+import (
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/packet"
+)
+
+// eventHandlers 内置事件的处理器
+type eventHandlers struct {
+{{- range .Events}}
+	{{.Handlers}} []func(*message.{{.Type}})
+{{- end}}
+}
+
+// eventSource 内置事件的处理器注册方法
+type eventSource interface {
+{{- range .Events}}
+	On{{.Type}}(func(*message.{{.Type}}))
+{{- end}}
+}
+{{range .Events}}
+// On{{.Type}} 添加 {{.Handler}} 的处理器
+func (c *Client) On{{.Type}}(f func(*message.{{.Type}})) {
+	c.eventHandlers.{{.Handlers}} = append(c.eventHandlers.{{.Handlers}}, f)
+}
+{{end}}
+// handleEvent 解析并分发内置事件，cmd 不属于内置事件时返回 false
+//
+// 只有注册了对应处理器时才会解析，只关心礼物的房间不需要为每条弹幕付出解析的开销，
+// ParseStrict 模式下为了检查字段总是解析
+func (c *Client) handleEvent(cmd string, p packet.Packet) bool {
+	switch cmd {
+{{- range .Events}}
+	case {{.CmdList}}:
+{{- if eq .Dispatch "custom"}}
+		c.handle{{.Type}}(cmd, p)
+{{- else if eq .Dispatch "pooled"}}
+		handlers := c.eventHandlers.{{.Handlers}}
+		if len(handlers) == 0 && c.parseMode != ParseStrict {
+			return true
+		}
+		v := c.new{{.Type}}()
+		if !c.parse(cmd, p, v) {
+			c.release{{.Type}}(v)
+			return true
+		}
+		refs := c.refs(len(handlers))
+		for _, fn := range handlers {
+			fn := fn
+			c.dispatch(cmd, p, v, func() {
+				fn(v)
+				if refs.done() {
+					c.release{{.Type}}(v)
+				}
+			})
+		}
+		if len(handlers) == 0 {
+			c.release{{.Type}}(v)
+		}
+{{- else}}
+		if len(c.eventHandlers.{{.Handlers}}) == 0 && c.parseMode != ParseStrict {
+			return true
+		}
+		v := new(message.{{.Type}})
+		if !c.parse(cmd, p, v) {
+			return true
+		}
+		for _, fn := range c.eventHandlers.{{.Handlers}} {
+			fn := fn
+			c.dispatch(cmd, p, v, func() { fn(v) })
+		}
+{{- end}}
+		return true
+{{- end}}
+	}
+	return false
+}
+
+// newLateHandler 根据处理器的类型确定对应的事件，不支持的类型返回 nil
+func newLateHandler(handler interface{}) *lateHandler {
+	switch f := handler.(type) {
+{{- range .Events}}
+	case func(*message.{{.Type}}):
+		return &lateHandler{[]string{ {{- .CmdList -}} }, func() replayEvent { return new(message.{{.Type}}) }, func(v replayEvent) { f(v.(*message.{{.Type}})) }}
+{{- end}}
+	}
+	return nil
+}
+`))
+
+func main() {
+	schemaPath := flag.String("schema", "events.json", "schema file")
+	messageOut := flag.String("message", "events_gen.go", "generated message file")
+	testOut := flag.String("test", "events_gen_test.go", "generated test file")
+	clientOut := flag.String("client", "../client/events_gen.go", "generated client file")
+	flag.Parse()
+
+	b, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var s schema
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err = dec.Decode(&s); err != nil {
+		log.Fatal(err)
+	}
+	for i := range s.Events {
+		e := &s.Events[i]
+		if e.Payload == "" {
+			e.Payload = "data"
+		}
+		if e.Handler == "" {
+			e.Handler = strings.SplitN(e.Doc, "\n", 2)[0]
+		}
+		if e.Type == "" || len(e.Cmds) == 0 {
+			log.Fatalf("event %d: type and cmds are required", i)
+		}
+		if err = e.prepareTest(); err != nil {
+			log.Fatalf("event %s: %v", e.Type, err)
+		}
+	}
+	write(*messageOut, messageTemplate, s)
+	write(*testOut, testTemplate, s)
+	write(*clientOut, clientTemplate, s)
+}
+
+func write(path string, t *template.Template, s schema) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, s); err != nil {
+		log.Fatal(err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("format %s: %v\n%s", path, err, buf.Bytes())
+	}
+	if err = os.WriteFile(path, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// prepareTest 根据示例报文生成测试中检查的字段
+func (e *event) prepareTest() error {
+	if len(e.Sample) == 0 {
+		return fmt.Errorf("sample is required")
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, e.Sample); err != nil {
+		return fmt.Errorf("sample: %w", err)
+	}
+	sample := buf.String()
+	if strings.Contains(sample, "`") {
+		e.SampleLiteral = strconv.Quote(sample)
+	} else {
+		e.SampleLiteral = "`" + sample + "`"
+	}
+	var msg map[string]interface{}
+	dec := json.NewDecoder(strings.NewReader(sample))
+	dec.UseNumber()
+	if err := dec.Decode(&msg); err != nil {
+		return fmt.Errorf("sample: %w", err)
+	}
+	cmd, _ := msg["cmd"].(string)
+	if !strings.Contains(" "+strings.Join(e.Cmds, " ")+" ", " "+cmd+" ") {
+		return fmt.Errorf("sample cmd %q is not in cmds", cmd)
+	}
+	var payload interface{} = msg
+	if e.Payload != "." {
+		for _, k := range strings.Split(e.Payload, ".") {
+			obj, ok := payload.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("sample has no %s", e.Payload)
+			}
+			payload = obj[k]
+		}
+	}
+	covered := make(map[string]bool)
+	// parser 为 custom 的结构体字段与报文不是一一对应的，只按 expect 检查
+	if obj, ok := payload.(map[string]interface{}); ok && !e.Custom() {
+		for _, f := range e.Fields {
+			v, ok := obj[f.JSON]
+			if !ok || f.JSON == "" || !scalarTypes[f.Type] {
+				continue
+			}
+			if err := e.addCheck(f.Name, v); err != nil {
+				return err
+			}
+			covered[f.Name] = true
+		}
+	}
+	exprs := make([]string, 0, len(e.Expect))
+	for expr := range e.Expect {
+		if !covered[expr] {
+			exprs = append(exprs, expr)
+		}
+	}
+	sort.Strings(exprs)
+	for _, expr := range exprs {
+		if err := e.addCheck(expr, e.Expect[expr]); err != nil {
+			return err
+		}
+	}
+	if len(e.Checks) == 0 {
+		return fmt.Errorf("sample checks no fields, add fields to sample or expect")
+	}
+	return nil
+}
+
+// scalarTypes 可以直接与示例报文中的值比较的字段类型
+var scalarTypes = map[string]bool{"string": true, "int": true, "int64": true, "float64": true, "bool": true}
+
+func (e *event) addCheck(expr string, v interface{}) error {
+	var lit string
+	switch v := v.(type) {
+	case string:
+		lit = strconv.Quote(v)
+	case json.Number:
+		lit = v.String()
+	case bool:
+		lit = strconv.FormatBool(v)
+	default:
+		return fmt.Errorf("%s: unsupported value %v", expr, v)
+	}
+	e.Checks = append(e.Checks, check{Expr: expr, Want: lit, WantQuoted: strconv.Quote(lit)})
+	return nil
+}
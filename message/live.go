@@ -1,33 +1,22 @@
 package message
 
 import (
-	"encoding/json"
+	"errors"
+
 	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
 )
 
 type StopLiveRoomList struct {
 	RoomIdList []int `json:"room_id_list"`
 }
 
-type Live struct {
-	Cmd             string `json:"cmd"`
-	LiveKey         string `json:"live_key"`
-	VoiceBackground string `json:"voice_background"`
-	SubSessionKey   string `json:"sub_session_key"`
-	LivePlatform    string `json:"live_platform"`
-	LiveModel       int    `json:"live_model"`
-	LiveTime        int    `json:"live_time"`
-	Roomid          int    `json:"roomid"`
-}
-
-type Preparing struct {
-	Cmd    string `json:"cmd"`
-	Roomid string `json:"roomid"`
-}
-
-func (l *Live) Parse(data []byte) {
-	err := json.Unmarshal(data, l)
-	if err != nil {
-		log.Error("parse live failed")
+func (p *Preparing) parse(sb string) error {
+	if !gjson.Valid(sb) {
+		log.Error("parse preparing failed")
+		return errors.New("invalid PREPARING message")
 	}
+	p.Cmd = gjson.Get(sb, "cmd").String()
+	p.Roomid = gjson.Get(sb, "roomid").String()
+	return nil
 }
@@ -0,0 +1,33 @@
+package message
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Meta 事件的元信息，由 client 在分发前填充
+type Meta struct {
+	RoomID     int           `json:"-"` // 真实房间号
+	ReceivedAt time.Time     `json:"-"` // 收到消息的本地时间，开启时钟偏差校正时为校正到服务端时钟的时间
+	Latency    time.Duration `json:"-"` // 消息中的服务端时间到收到时的延迟，消息不带时间戳时为 0
+	Replayed   bool          `json:"-"` // 是否为注册处理器时回放的最近事件
+	Seq        uint64        `json:"-"` // 房间内单调递增的事件序号，不连续表示中间的事件被丢弃或过滤
+	retained   int32
+}
+
+// EventMeta 获取事件的元信息
+func (m *Meta) EventMeta() *Meta {
+	return m
+}
+
+// Retain 标记事件在处理器返回后仍会被使用，client 开启对象池时不会回收该事件
+//
+// 需要在处理器返回前调用，未开启对象池时没有作用
+func (m *Meta) Retain() {
+	atomic.StoreInt32(&m.retained, 1)
+}
+
+// Retained 是否已调用 Retain
+func (m *Meta) Retained() bool {
+	return atomic.LoadInt32(&m.retained) == 1
+}
@@ -0,0 +1,36 @@
+package message
+
+import "sync"
+
+var (
+	danmakuPool = sync.Pool{New: func() interface{} { return new(Danmaku) }}
+	giftPool    = sync.Pool{New: func() interface{} { return new(Gift) }}
+)
+
+// AcquireDanmaku 从对象池中获取一个清空的 Danmaku
+func AcquireDanmaku() *Danmaku {
+	return danmakuPool.Get().(*Danmaku)
+}
+
+// ReleaseDanmaku 清空 d 并放回对象池，已调用 Retain 的不会放回
+func ReleaseDanmaku(d *Danmaku) {
+	if d == nil || d.Retained() {
+		return
+	}
+	*d = Danmaku{}
+	danmakuPool.Put(d)
+}
+
+// AcquireGift 从对象池中获取一个清空的 Gift
+func AcquireGift() *Gift {
+	return giftPool.Get().(*Gift)
+}
+
+// ReleaseGift 清空 g 并放回对象池，已调用 Retain 的不会放回
+func ReleaseGift(g *Gift) {
+	if g == nil || g.Retained() {
+		return
+	}
+	*g = Gift{}
+	giftPool.Put(g)
+}
@@ -0,0 +1,33 @@
+package message
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// cst B 站接口返回的时间都是北京时间
+var cst = time.FixedZone("CST", 8*3600)
+
+func (r *RoomPunish) parse(sb string) error {
+	d := gjson.Parse(sb)
+	if !d.IsObject() {
+		log.Error("parse RoomPunish failed")
+		return errors.New("room punish is not an object")
+	}
+	r.Cmd = d.Get("cmd").String()
+	r.RoomId = int(d.Get("roomid").Int())
+	r.Reason = d.Get("msg").String()
+	r.Type = d.Get("type").String()
+	if expire := d.Get("expire").String(); expire != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", expire, cst)
+		if err != nil {
+			log.Error("parse RoomPunish expire failed")
+			return err
+		}
+		r.Expire = t
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package message
+
+import (
+	"sort"
+	"sync"
+)
+
+// Event 可以从原始报文解析的事件
+type Event interface {
+	Parse(data []byte) error
+	EventMeta() *Meta
+}
+
+var registry = struct {
+	sync.RWMutex
+	m map[string]func() Event
+}{m: make(map[string]func() Event)}
+
+// Register 注册 cmd 对应的事件类型，events.json 中的事件会在生成的代码中自动注册
+func Register(cmd string, f func() Event) {
+	registry.Lock()
+	registry.m[cmd] = f
+	registry.Unlock()
+}
+
+// New 创建 cmd 对应的空事件，cmd 未注册时返回 false
+func New(cmd string) (Event, bool) {
+	registry.RLock()
+	f, ok := registry.m[cmd]
+	registry.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return f(), true
+}
+
+// Cmds 获取已注册的全部 cmd
+func Cmds() []string {
+	registry.RLock()
+	cmds := make([]string, 0, len(registry.m))
+	for cmd := range registry.m {
+		cmds = append(cmds, cmd)
+	}
+	registry.RUnlock()
+	sort.Strings(cmds)
+	return cmds
+}
@@ -0,0 +1,274 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// errMalformed 内嵌的 JSON 格式错误
+var errMalformed = errors.New("malformed embedded json")
+
+// fieldSetter 依次接收对象中的字段，str 表示 val 是否为字符串，非字符串的值为原始 JSON
+type fieldSetter func(key, val string, str bool)
+
+// parseObject 解析一个对象的各个字段，raw 为原始 JSON，可以是对象，也可以是内容为 JSON 对象的字符串，如弹幕的 extra
+//
+// 字符串中的对象直接在转义后的原文上扫描，字段值中没有转义字符时不需要分配内存
+func parseObject(raw string, set fieldSetter) error {
+	switch {
+	case raw == "" || raw == "null":
+		return nil
+	case raw[0] == '{':
+		v := gjson.Parse(raw)
+		v.ForEach(func(k, f gjson.Result) bool {
+			if f.Type == gjson.String {
+				set(k.Str, f.Str, true)
+			} else {
+				set(k.Str, f.Raw, false)
+			}
+			return true
+		})
+		return nil
+	case raw[0] == '"' && len(raw) >= 2:
+		if scanEscaped(raw[1:len(raw)-1], set) {
+			return nil
+		}
+		// 字段值中也有转义字符时使用标准库解析
+		var str string
+		if err := json.Unmarshal([]byte(raw), &str); err != nil {
+			return err
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(str), &m); err != nil {
+			return err
+		}
+		for k, f := range m {
+			if s, ok := f.(string); ok {
+				set(k, s, true)
+				continue
+			}
+			b, _ := json.Marshal(f)
+			set(k, string(b), false)
+		}
+		return nil
+	}
+	return errMalformed
+}
+
+// rawField 获取对象 obj 中 key 字段的原始 JSON，不会像 gjson 一样解码字符串，不存在时返回空字符串
+func rawField(obj string, key string) string {
+	p := skipSpace(obj, 0)
+	if p >= len(obj) || obj[p] != '{' {
+		return ""
+	}
+	p = skipSpace(obj, p+1)
+	for p < len(obj) && obj[p] == '"' {
+		end, ok := skipValue(obj, p)
+		if !ok {
+			return ""
+		}
+		k := obj[p+1 : end-1]
+		p = skipSpace(obj, end)
+		if p >= len(obj) || obj[p] != ':' {
+			return ""
+		}
+		p = skipSpace(obj, p+1)
+		end, ok = skipValue(obj, p)
+		if !ok {
+			return ""
+		}
+		if k == key {
+			return obj[p:end]
+		}
+		p = skipSpace(obj, end)
+		if p >= len(obj) || obj[p] != ',' {
+			return ""
+		}
+		p = skipSpace(obj, p+1)
+	}
+	return ""
+}
+
+// forEachRaw 依次传入数组 arr 中各元素的原始 JSON，不会像 gjson 一样解码字符串，fn 返回 false 时停止
+func forEachRaw(arr string, fn func(i int, raw string) bool) bool {
+	p := skipSpace(arr, 0)
+	if p >= len(arr) || arr[p] != '[' {
+		return false
+	}
+	p = skipSpace(arr, p+1)
+	for i := 0; p < len(arr) && arr[p] != ']'; i++ {
+		end, ok := skipValue(arr, p)
+		if !ok {
+			return false
+		}
+		if !fn(i, arr[p:end]) {
+			return true
+		}
+		p = skipSpace(arr, end)
+		if p < len(arr) && arr[p] == ',' {
+			p = skipSpace(arr, p+1)
+		}
+	}
+	return true
+}
+
+// skipValue 跳过从 p 开始的一个 JSON 值，返回结束位置
+func skipValue(s string, p int) (int, bool) {
+	depth := 0
+	for p < len(s) {
+		switch s[p] {
+		case '"':
+			p++
+			for p < len(s) && s[p] != '"' {
+				if s[p] == '\\' {
+					p++
+				}
+				p++
+			}
+			if p >= len(s) {
+				return p, false
+			}
+			if depth == 0 {
+				return p + 1, true
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return p, true
+			}
+			depth--
+			if depth == 0 {
+				return p + 1, true
+			}
+		case ',':
+			if depth == 0 {
+				return p, true
+			}
+		}
+		p++
+	}
+	return p, depth == 0
+}
+
+// scanEscaped 扫描作为 JSON 字符串转义后的对象，如 {\"mode\":0,\"content\":\"哈哈\"}
+//
+// 只处理 \" 一种转义，出现其他转义或格式错误时返回 false
+func scanEscaped(s string, set fieldSetter) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && (i+1 >= len(s) || s[i+1] != '"') {
+			return false
+		}
+		if s[i] == '\\' {
+			i++
+		}
+	}
+	p := skipSpace(s, 0)
+	if p >= len(s) || s[p] != '{' {
+		return false
+	}
+	p = skipSpace(s, p+1)
+	if p < len(s) && s[p] == '}' {
+		return true
+	}
+	for p < len(s) {
+		key, end, ok := escapedString(s, p)
+		if !ok {
+			return false
+		}
+		p = skipSpace(s, end)
+		if p >= len(s) || s[p] != ':' {
+			return false
+		}
+		p = skipSpace(s, p+1)
+		if strings.HasPrefix(s[p:], `\"`) {
+			val, end, ok := escapedString(s, p)
+			if !ok {
+				return false
+			}
+			set(key, val, true)
+			p = end
+		} else {
+			end, ok := skipEscapedValue(s, p)
+			if !ok {
+				return false
+			}
+			set(key, s[p:end], false)
+			p = end
+		}
+		p = skipSpace(s, p)
+		if p >= len(s) {
+			return false
+		}
+		switch s[p] {
+		case ',':
+			p = skipSpace(s, p+1)
+		case '}':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// escapedString 读取从 p 开始的 \"...\"，返回其中的内容与结束位置
+func escapedString(s string, p int) (string, int, bool) {
+	if !strings.HasPrefix(s[p:], `\"`) {
+		return "", p, false
+	}
+	end := strings.Index(s[p+2:], `\"`)
+	if end < 0 {
+		return "", p, false
+	}
+	return s[p+2 : p+2+end], p + 4 + end, true
+}
+
+// skipEscapedValue 跳过从 p 开始的非字符串值，返回结束位置
+func skipEscapedValue(s string, p int) (int, bool) {
+	depth := 0
+	for p < len(s) {
+		switch c := s[p]; c {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return p, true
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return p, true
+			}
+		case '\\':
+			_, end, ok := escapedString(s, p)
+			if !ok {
+				return p, false
+			}
+			p = end
+			continue
+		}
+		p++
+	}
+	return p, depth == 0
+}
+
+func skipSpace(s string, p int) int {
+	for p < len(s) && (s[p] == ' ' || s[p] == '\t' || s[p] == '\n' || s[p] == '\r') {
+		p++
+	}
+	return p
+}
+
+// atoi 解析整数，失败时返回 0
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return int(gjson.Parse(s).Int())
+	}
+	return n
+}
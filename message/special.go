@@ -0,0 +1,34 @@
+package message
+
+import (
+	"errors"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	SpecialGiftStart = "start"
+	SpecialGiftEnd   = "end"
+)
+
+func (s *SpecialGift) parse(sb string) error {
+	d := gjson.Get(sb, "data")
+	if !d.IsObject() {
+		log.Error("parse SpecialGift failed")
+		return errors.New("special gift data is not an object")
+	}
+	d.ForEach(func(key, value gjson.Result) bool {
+		s.GiftId, _ = strconv.Atoi(key.String())
+		s.Id = value.Get("id").String()
+		s.Action = value.Get("action").String()
+		s.Content = value.Get("content").String()
+		s.Time = int(value.Get("time").Int())
+		s.Num = int(value.Get("num").Int())
+		s.HadJoin = value.Get("hadJoin").Int() == 1
+		s.StormGif = value.Get("storm_gif").String()
+		return false
+	})
+	return nil
+}
@@ -1,32 +1,5 @@
 package message
 
-// WidgetBanner
-// TODO: widget_list的code不定
-type WidgetBanner struct {
-	Timestamp  int `json:"timestamp"`
-	WidgetList struct {
-		Field1 struct {
-			Id             int      `json:"id"`
-			Title          string   `json:"title"`
-			Cover          string   `json:"cover"`
-			WebCover       string   `json:"web_cover"`
-			TipText        string   `json:"tip_text"`
-			TipTextColor   string   `json:"tip_text_color"`
-			TipBottomColor string   `json:"tip_bottom_color"`
-			JumpUrl        string   `json:"jump_url"`
-			Url            string   `json:"url"`
-			StayTime       int      `json:"stay_time"`
-			Site           int      `json:"site"`
-			PlatformIn     []string `json:"platform_in"`
-			Type           int      `json:"type"`
-			BandId         int      `json:"band_id"`
-			SubKey         string   `json:"sub_key"`
-			SubData        string   `json:"sub_data"`
-			IsAdd          bool     `json:"is_add"`
-		} `json:"58"`
-	} `json:"widget_list"`
-}
-
 type HotRankChanged struct {
 	Rank        int    `json:"rank"`
 	Trend       int    `json:"trend"`
@@ -69,44 +42,14 @@ type HotRankSettlementV2 struct {
 	DmMsg     string `json:"dm_msg"`
 }
 
-type InteractWord struct {
-	Contribution struct {
-		Grade int `json:"grade"`
-	} `json:"contribution"`
-	Dmscore   int `json:"dmscore"`
-	FansMedal struct {
-		AnchorRoomid     int    `json:"anchor_roomid"`
-		GuardLevel       int    `json:"guard_level"`
-		IconId           int    `json:"icon_id"`
-		IsLighted        int    `json:"is_lighted"`
-		MedalColor       int    `json:"medal_color"`
-		MedalColorBorder int    `json:"medal_color_border"`
-		MedalColorEnd    int    `json:"medal_color_end"`
-		MedalColorStart  int    `json:"medal_color_start"`
-		MedalLevel       int    `json:"medal_level"`
-		MedalName        string `json:"medal_name"`
-		Score            int    `json:"score"`
-		Special          string `json:"special"`
-		TargetId         int    `json:"target_id"`
-	} `json:"fans_medal"`
-	Identities  []int  `json:"identities"`
-	IsSpread    int    `json:"is_spread"`
-	MsgType     int    `json:"msg_type"`
-	Roomid      int    `json:"roomid"`
-	Score       int64  `json:"score"`
-	SpreadDesc  string `json:"spread_desc"`
-	SpreadInfo  string `json:"spread_info"`
-	TailIcon    int    `json:"tail_icon"`
-	Timestamp   int    `json:"timestamp"`
-	TriggerTime int64  `json:"trigger_time"`
-	Uid         int    `json:"uid"`
-	Uname       string `json:"uname"`
-	UnameColor  string `json:"uname_color"`
-}
-
-type OnlineRankCount struct {
-	Count int `json:"count"`
-}
+// 用户互动的类型 InteractWord.MsgType
+const (
+	InteractEnter         = 1 // 进入直播间
+	InteractFollow        = 2 // 关注
+	InteractShare         = 3 // 分享直播间
+	InteractSpecialFollow = 4 // 特别关注
+	InteractMutualFollow  = 5 // 互相关注
+)
 
 type LiveInteractiveGame struct {
 	Type           int         `json:"type"`
@@ -0,0 +1,261 @@
+// Package moderation 提供用户与关键词的黑名单、白名单，修改后可以保存到文件，重启后继续生效
+package moderation
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/sink"
+)
+
+// Blocklist 用户与关键词的黑名单、白名单
+//
+// 白名单中的用户不受关键词限制；开启白名单模式后只有白名单中的用户发送的事件会被分发
+type Blocklist struct {
+	mu            sync.RWMutex
+	path          string
+	users         map[int]bool
+	keywords      map[string]bool
+	allow         map[int]bool
+	allowlistOnly bool
+}
+
+// blocklistFile 保存到文件的内容
+type blocklistFile struct {
+	Users         []int    `json:"users"`
+	Keywords      []string `json:"keywords"`
+	AllowUsers    []int    `json:"allow_users"`
+	AllowlistOnly bool     `json:"allowlist_only,omitempty"`
+}
+
+// NewBlocklist 创建一个不保存到文件的空名单
+func NewBlocklist() *Blocklist {
+	return &Blocklist{
+		users:    make(map[int]bool),
+		keywords: make(map[string]bool),
+		allow:    make(map[int]bool),
+	}
+}
+
+// Load 从 path 读取名单，之后的每次修改都会写入该文件，文件不存在时返回空名单
+func Load(path string) (*Blocklist, error) {
+	b := NewBlocklist()
+	b.path = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return b, nil
+		}
+		return nil, err
+	}
+	var f blocklistFile
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	for _, uid := range f.Users {
+		b.users[uid] = true
+	}
+	for _, w := range f.Keywords {
+		b.keywords[strings.ToLower(w)] = true
+	}
+	for _, uid := range f.AllowUsers {
+		b.allow[uid] = true
+	}
+	b.allowlistOnly = f.AllowlistOnly
+	return b, nil
+}
+
+// BlockUser 将用户加入黑名单
+func (b *Blocklist) BlockUser(uids ...int) error {
+	return b.update(func() {
+		for _, uid := range uids {
+			b.users[uid] = true
+		}
+	})
+}
+
+// UnblockUser 将用户移出黑名单
+func (b *Blocklist) UnblockUser(uids ...int) error {
+	return b.update(func() {
+		for _, uid := range uids {
+			delete(b.users, uid)
+		}
+	})
+}
+
+// BlockKeyword 添加屏蔽词，不区分大小写
+func (b *Blocklist) BlockKeyword(words ...string) error {
+	return b.update(func() {
+		for _, w := range words {
+			if w != "" {
+				b.keywords[strings.ToLower(w)] = true
+			}
+		}
+	})
+}
+
+// UnblockKeyword 移除屏蔽词
+func (b *Blocklist) UnblockKeyword(words ...string) error {
+	return b.update(func() {
+		for _, w := range words {
+			delete(b.keywords, strings.ToLower(w))
+		}
+	})
+}
+
+// AllowUser 将用户加入白名单
+func (b *Blocklist) AllowUser(uids ...int) error {
+	return b.update(func() {
+		for _, uid := range uids {
+			b.allow[uid] = true
+		}
+	})
+}
+
+// DisallowUser 将用户移出白名单
+func (b *Blocklist) DisallowUser(uids ...int) error {
+	return b.update(func() {
+		for _, uid := range uids {
+			delete(b.allow, uid)
+		}
+	})
+}
+
+// SetAllowlistOnly 开启白名单模式，只分发白名单中的用户发送的事件，不带发送者的事件不受影响
+func (b *Blocklist) SetAllowlistOnly(on bool) error {
+	return b.update(func() {
+		b.allowlistOnly = on
+	})
+}
+
+// Users 获取黑名单中的用户
+func (b *Blocklist) Users() []int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return sortedInts(b.users)
+}
+
+// Keywords 获取全部屏蔽词
+func (b *Blocklist) Keywords() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	words := make([]string, 0, len(b.keywords))
+	for w := range b.keywords {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// AllowedUsers 获取白名单中的用户
+func (b *Blocklist) AllowedUsers() []int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return sortedInts(b.allow)
+}
+
+// Blocked 判断 uid 发送的内容为 text 的事件是否应被屏蔽，uid 为 0 表示事件没有发送者
+func (b *Blocklist) Blocked(uid int, text string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if uid != 0 {
+		if b.users[uid] {
+			return true
+		}
+		if b.allow[uid] {
+			return false
+		}
+		if b.allowlistOnly {
+			return true
+		}
+	}
+	if text == "" || len(b.keywords) == 0 {
+		return false
+	}
+	text = strings.ToLower(text)
+	for w := range b.keywords {
+		if strings.Contains(text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter 作为 client 的事件过滤器使用
+func (b *Blocklist) Filter() client.EventFilter {
+	return func(cmd string, body []byte) bool {
+		e := &record.Entry{Data: body}
+		return !b.Blocked(sink.UID(e), sink.Text(cmd, e))
+	}
+}
+
+// SinkFilter 作为 sink 的过滤器使用
+func (b *Blocklist) SinkFilter() sink.Filter {
+	return func(cmd string, e *record.Entry) bool {
+		return !b.Blocked(sink.UID(e), sink.Text(cmd, e))
+	}
+}
+
+// Attach 对 c 的全部处理器生效，需要在 c.Start 之前调用
+func (b *Blocklist) Attach(c *client.Client) {
+	c.AddEventFilter(b.Filter())
+}
+
+// update 修改名单并保存到文件
+func (b *Blocklist) update(f func()) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f()
+	return b.save()
+}
+
+// save 写入名单文件，调用时需要持有 b.mu
+func (b *Blocklist) save() error {
+	if b.path == "" {
+		return nil
+	}
+	f := blocklistFile{
+		Users:         sortedInts(b.users),
+		Keywords:      make([]string, 0, len(b.keywords)),
+		AllowUsers:    sortedInts(b.allow),
+		AllowlistOnly: b.allowlistOnly,
+	}
+	for w := range b.keywords {
+		f.Keywords = append(f.Keywords, w)
+	}
+	sort.Strings(f.Keywords)
+	data, err := json.MarshalIndent(&f, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 先写入临时文件再重命名，避免进程退出时名单文件损坏
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".blocklist-*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path)
+}
+
+func sortedInts(m map[int]bool) []int {
+	s := make([]int, 0, len(m))
+	for k := range m {
+		s = append(s, k)
+	}
+	sort.Ints(s)
+	return s
+}
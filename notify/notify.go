@@ -0,0 +1,250 @@
+// Package notify 将开播、醒目留言、上舰等事件推送到 Discord 或 Telegram
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	log "github.com/sirupsen/logrus"
+)
+
+// 可推送的事件
+const (
+	EventLive      = "live"
+	EventSuperChat = "super_chat"
+	EventGuardBuy  = "guard_buy"
+)
+
+const (
+	defaultQueueSize = 256
+	defaultInterval  = 3 * time.Second
+	defaultBurst     = 5
+	sendTimeout      = 10 * time.Second
+)
+
+// DefaultTemplates 各事件默认的消息模板，模板的数据为对应的 message 结构体
+var DefaultTemplates = map[string]string{
+	EventLive:      "直播间 {{.RoomID}} 开播了 https://live.bilibili.com/{{.RoomID}}",
+	EventSuperChat: "[醒目留言 ¥{{.Price}}] {{.UserInfo.Uname}}: {{.Message}}",
+	EventGuardBuy:  "{{.Username}} 开通了 {{.GiftName}} x{{.Num}}",
+}
+
+// ErrUnknownEvent 不支持的事件
+var ErrUnknownEvent = errors.New("unknown notify event")
+
+// Notifier 按模板生成消息并限速推送到全部渠道
+type Notifier struct {
+	senders   []Sender
+	mu        sync.Mutex
+	templates map[string]*template.Template
+	enabled   map[string]bool
+	minPrice  int
+	maxGuard  int
+	queue     chan string
+	interval  time.Duration
+	burst     int
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New 创建推送到 senders 的 Notifier，默认推送全部事件，每 3s 最多推送 1 条并允许 5 条突发
+func New(senders ...Sender) *Notifier {
+	n := &Notifier{
+		senders:   senders,
+		templates: make(map[string]*template.Template),
+		enabled:   map[string]bool{EventLive: true, EventSuperChat: true, EventGuardBuy: true},
+		queue:     make(chan string, defaultQueueSize),
+		interval:  defaultInterval,
+		burst:     defaultBurst,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for ev, t := range DefaultTemplates {
+		n.templates[ev] = template.Must(template.New(ev).Parse(t))
+	}
+	go n.loop()
+	return n
+}
+
+// SetTemplate 设置事件的消息模板，语法见 text/template
+func (n *Notifier) SetTemplate(event string, text string) error {
+	if _, ok := DefaultTemplates[event]; !ok {
+		return ErrUnknownEvent
+	}
+	t, err := template.New(event).Parse(text)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	n.templates[event] = t
+	n.mu.Unlock()
+	return nil
+}
+
+// SetEvents 设置需要推送的事件，默认推送全部事件
+func (n *Notifier) SetEvents(events ...string) {
+	n.mu.Lock()
+	n.enabled = make(map[string]bool, len(events))
+	for _, e := range events {
+		n.enabled[e] = true
+	}
+	n.mu.Unlock()
+}
+
+// SetSuperChatThreshold 只推送价格不低于 price 元的醒目留言
+func (n *Notifier) SetSuperChatThreshold(price int) {
+	n.mu.Lock()
+	n.minPrice = price
+	n.mu.Unlock()
+}
+
+// SetGuardLevel 只推送舰队等级不低于 level 的上舰，1:总督 2:提督 3:舰长，0 表示全部推送
+func (n *Notifier) SetGuardLevel(level int) {
+	n.mu.Lock()
+	n.maxGuard = level
+	n.mu.Unlock()
+}
+
+// SetRateLimit 设置推送速率，平均每 interval 推送一条，最多连续推送 burst 条，需要在推送开始前调用
+func (n *Notifier) SetRateLimit(interval time.Duration, burst int) {
+	n.mu.Lock()
+	n.interval = interval
+	if burst < 1 {
+		burst = 1
+	}
+	n.burst = burst
+	n.mu.Unlock()
+}
+
+// Attach 推送 src 中的开播、醒目留言与上舰事件
+func (n *Notifier) Attach(src client.DanmakuSource) {
+	src.OnLive(func(l *message.Live) {
+		n.notify(EventLive, l)
+	})
+	src.OnSuperChat(func(s *message.SuperChat) {
+		n.mu.Lock()
+		min := n.minPrice
+		n.mu.Unlock()
+		if s.Price >= min {
+			n.notify(EventSuperChat, s)
+		}
+	})
+	src.OnGuardBuy(func(g *message.GuardBuy) {
+		n.mu.Lock()
+		max := n.maxGuard
+		n.mu.Unlock()
+		if max == 0 || g.GuardLevel <= max {
+			n.notify(EventGuardBuy, g)
+		}
+	})
+}
+
+// notify 用事件的模板生成消息并放入推送队列
+func (n *Notifier) notify(event string, data interface{}) {
+	n.mu.Lock()
+	t, enabled := n.templates[event], n.enabled[event]
+	n.mu.Unlock()
+	if !enabled {
+		return
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		log.Error("execute notify template failed: ", err)
+		return
+	}
+	n.Notify(buf.String())
+}
+
+// Notify 推送一条消息，队列已满时丢弃
+func (n *Notifier) Notify(text string) {
+	select {
+	case <-n.stop:
+		return
+	default:
+	}
+	select {
+	case n.queue <- text:
+	default:
+		log.Warn("notify queue full, message dropped")
+	}
+}
+
+// loop 以令牌桶限速，依次推送队列中的消息
+func (n *Notifier) loop() {
+	defer close(n.done)
+	n.mu.Lock()
+	tokens := n.burst
+	n.mu.Unlock()
+	last := time.Now()
+	for {
+		var text string
+		select {
+		case <-n.stop:
+			return
+		case text = <-n.queue:
+		}
+		n.mu.Lock()
+		interval, burst := n.interval, n.burst
+		n.mu.Unlock()
+		if interval > 0 {
+			now := time.Now()
+			if tokens += int(now.Sub(last) / interval); tokens > burst {
+				tokens = burst
+			}
+			if tokens == 0 {
+				wait := interval - now.Sub(last)%interval
+				select {
+				case <-n.stop:
+					return
+				case <-time.After(wait):
+				}
+				tokens = 1
+				now = time.Now()
+			}
+			tokens--
+			last = now
+		}
+		n.send(text)
+	}
+}
+
+// send 推送到全部渠道，渠道要求等待时重试一次，Close 时不再等待
+func (n *Notifier) send(text string) {
+	for _, s := range n.senders {
+		err := n.sendOnce(s, text)
+		var ra *RetryAfterError
+		if errors.As(err, &ra) && ra.After < time.Minute {
+			select {
+			case <-n.stop:
+				return
+			case <-time.After(ra.After):
+			}
+			err = n.sendOnce(s, text)
+		}
+		if err != nil {
+			log.Error("send notification failed: ", err)
+		}
+	}
+}
+
+// sendOnce 推送一次，每次推送单独计算超时
+func (n *Notifier) sendOnce(s Sender, text string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	return s.Send(ctx, text)
+}
+
+// Close 停止推送，队列中未推送的消息会被丢弃
+func (n *Notifier) Close() {
+	n.closeOnce.Do(func() {
+		close(n.stop)
+		<-n.done
+	})
+}
@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sender 消息推送渠道
+type Sender interface {
+	Send(ctx context.Context, text string) error
+}
+
+// RetryAfterError 推送渠道要求等待后重试
+type RetryAfterError struct {
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.After)
+}
+
+// Discord 通过 Webhook 推送到 Discord 频道
+type Discord struct {
+	WebhookURL string
+	Username   string // 为空时使用 Webhook 的默认名称
+	HTTPClient *http.Client
+}
+
+func (d *Discord) Send(ctx context.Context, text string) error {
+	body := map[string]string{"content": text}
+	if d.Username != "" {
+		body["username"] = d.Username
+	}
+	resp, err := postJSON(ctx, d.HTTPClient, d.WebhookURL, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var r struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&r)
+		return &RetryAfterError{After: time.Duration(r.RetryAfter * float64(time.Second))}
+	}
+	return checkStatus(resp)
+}
+
+// DefaultTelegramAPI Telegram Bot API 地址
+const DefaultTelegramAPI = "https://api.telegram.org"
+
+// Telegram 通过 Bot 推送到 Telegram 会话
+type Telegram struct {
+	Token      string
+	ChatID     string
+	APIBase    string // 默认 DefaultTelegramAPI
+	HTTPClient *http.Client
+}
+
+func (t *Telegram) Send(ctx context.Context, text string) error {
+	base := t.APIBase
+	if base == "" {
+		base = DefaultTelegramAPI
+	}
+	resp, err := postJSON(ctx, t.HTTPClient, base+"/bot"+t.Token+"/sendMessage", map[string]interface{}{
+		"chat_id":                  t.ChatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var r struct {
+			Parameters struct {
+				RetryAfter int `json:"retry_after"`
+			} `json:"parameters"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&r)
+		return &RetryAfterError{After: time.Duration(r.Parameters.RetryAfter) * time.Second}
+	}
+	return checkStatus(resp)
+}
+
+func postJSON(ctx context.Context, c *http.Client, url string, body interface{}) (*http.Response, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return c.Do(req)
+}
+
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if sec, err := strconv.Atoi(s); err == nil {
+			return &RetryAfterError{After: time.Duration(sec) * time.Second}
+		}
+	}
+	return fmt.Errorf("notify: %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
@@ -16,13 +16,24 @@ type Enter struct {
 	Key  string `json:"key"`
 }
 
+// 进房时可以请求的协议版本，决定服务器下发的压缩方式
+const (
+	ProtoverZlib   = 2
+	ProtoverBrotli = 3
+)
+
 // NewEnterPacket 构造进入房间的包
 // uid 可以为 0, key 在使用 broadcastlv 服务器的时候不需要
 func NewEnterPacket(uid int, buvid string, roomID int, key string) []byte {
+	return NewEnterPacketWithProtover(uid, buvid, roomID, key, ProtoverZlib)
+}
+
+// NewEnterPacketWithProtover 构造进入房间的包，protover 为 ProtoverZlib 或 ProtoverBrotli
+func NewEnterPacketWithProtover(uid int, buvid string, roomID int, key string, protover int) []byte {
 	ent := &Enter{
 		UID:      uid,
 		RoomID:   roomID,
-		ProtoVer: 2,
+		ProtoVer: protover,
 		Buvid:    buvid,
 		Platform: "web",
 		//ClientVer: "1.14.3",
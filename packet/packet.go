@@ -5,9 +5,11 @@ import (
 	"compress/zlib"
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"github.com/andybalholm/brotli"
 	log "github.com/sirupsen/logrus"
 	"io"
+	"time"
 )
 
 const (
@@ -36,6 +38,8 @@ type Packet struct {
 	Operation       uint32
 	SequenceID      int
 	Body            []byte
+	ReceivedAt      time.Time // 收到包的本地时间，由 client 填充
+	Seq             uint64    // 房间内单调递增的事件序号，由 client 收到 Notification 包时填充，直接调用 Handle 时保持原值
 }
 
 func NewPacket(protocolVersion uint16, operation uint32, body []byte) Packet {
@@ -66,27 +70,34 @@ func NewPacketFromBytes(data []byte) Packet {
 }
 
 func (p Packet) Parse() []Packet {
+	pkts, err := p.Decode()
+	if err != nil {
+		log.Error(err)
+	}
+	return pkts
+}
+
+// Decode 解压并拆分包，与 Parse 相同但返回错误而不是记录日志
+func (p Packet) Decode() ([]Packet, error) {
 	switch p.ProtocolVersion {
 	case Popularity:
 		fallthrough
 	case Plain:
-		return []Packet{p}
+		return []Packet{p}, nil
 	case Zlib:
 		z, err := zlibParser(p.Body)
 		if err != nil {
-			log.Error("zlib error", err)
+			return nil, fmt.Errorf("zlib error: %w", err)
 		}
-		return Slice(z)
+		return Slice(z), nil
 	case Brotli:
 		b, err := brotliParser(p.Body)
 		if err != nil {
-			log.Error("brotli error", err)
+			return nil, fmt.Errorf("brotli error: %w", err)
 		}
-		return Slice(b)
-	default:
-		log.Error("unknown protocolVersion")
+		return Slice(b), nil
 	}
-	return nil
+	return nil, fmt.Errorf("unknown protocolVersion %d", p.ProtocolVersion)
 }
 
 func (p *Packet) Unmarshal(v interface{}) error {
@@ -131,6 +142,9 @@ func zlibParser(b []byte) ([]byte, error) {
 		return nil, err
 	}
 	rdBuf, err = io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
 	return rdBuf, nil
 }
 
@@ -0,0 +1,238 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/tidwall/gjson"
+)
+
+// ExportOptions 导出弹幕时的选项
+type ExportOptions struct {
+	Start  int64         // 视频开始的毫秒时间戳，为 0 时使用第一条弹幕的时间
+	Offset time.Duration // 额外的时间偏移，可以为负数
+	// Segments 视频由多个录制片段依次拼接而成时每个片段的起止时间，设置后忽略 Start
+	//
+	// 片段之间断线重连的间隔不计入视频时间，落在间隔中的事件不会导出
+	Segments []Segment
+	// ServerTime 使用弹幕中的服务端时间而不是收到的时间，避免重连后补发的弹幕堆积在同一时刻
+	ServerTime bool
+}
+
+// Segment 一个录制片段的起止时间，毫秒时间戳，End 为 0 表示直到录制结束
+type Segment struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// position 计算 t 时刻相对视频开始的时间，start 为未设置 Segments 时使用的视频开始时间
+func (o *ExportOptions) position(t int64, start int64) (time.Duration, bool) {
+	if len(o.Segments) == 0 {
+		at := time.Duration(t-start)*time.Millisecond + o.Offset
+		return at, at >= 0
+	}
+	var base int64
+	for _, s := range o.Segments {
+		if t < s.Start {
+			return 0, false
+		}
+		if s.End == 0 || t < s.End {
+			at := time.Duration(base+t-s.Start)*time.Millisecond + o.Offset
+			return at, at >= 0
+		}
+		base += s.End - s.Start
+	}
+	return 0, false
+}
+
+// exportedDanmaku 导出时使用的弹幕
+type exportedDanmaku struct {
+	At       time.Duration // 相对视频开始的时间
+	Danmaku  *message.Danmaku
+	Mode     int
+	FontSize int
+	Color    int
+}
+
+// readDanmaku 从录制文件中依次读取弹幕，并计算出相对视频开始的时间
+func readDanmaku(r *Reader, opt *ExportOptions, fn func(d *exportedDanmaku) error) error {
+	if opt == nil {
+		opt = &ExportOptions{}
+	}
+	start := opt.Start
+	for {
+		e, err := r.Next()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(gjson.GetBytes(e.Data, "cmd").String(), "DANMU_MSG") {
+			continue
+		}
+		d := new(message.Danmaku)
+		d.Parse(e.Data)
+		t := e.Time
+		if opt.ServerTime && d.Timestamp > 0 {
+			t = d.Timestamp
+		}
+		if start == 0 {
+			start = t
+		}
+		at, ok := opt.position(t, start)
+		if !ok {
+			continue
+		}
+		ed := &exportedDanmaku{At: at, Danmaku: d, Mode: 1, FontSize: 25, Color: 0xffffff}
+		if d.Extra != nil {
+			if d.Extra.Mode > 0 {
+				ed.Mode = d.Extra.Mode
+			}
+			if d.Extra.FontSize > 0 {
+				ed.FontSize = d.Extra.FontSize
+			}
+			ed.Color = d.Extra.Color
+		}
+		if err = fn(ed); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonlEntry ExportJSONL 导出的一行
+type jsonlEntry struct {
+	Offset int64           `json:"offset"` // 相对视频开始的毫秒数
+	Time   int64           `json:"time"`
+	RoomID int             `json:"room_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// ExportJSONL 将录制文件中的全部事件导出为 JSON Lines，每行带有相对视频开始的毫秒数 offset
+func ExportJSONL(r *Reader, w io.Writer, opt *ExportOptions) error {
+	if opt == nil {
+		opt = &ExportOptions{}
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	start := opt.Start
+	for {
+		e, err := r.Next()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		t := e.Time
+		if opt.ServerTime {
+			if ts := gjson.GetBytes(e.Data, "info.0.4").Int(); ts > 0 {
+				t = ts
+			}
+		}
+		if start == 0 {
+			start = t
+		}
+		at, ok := opt.position(t, start)
+		if !ok {
+			continue
+		}
+		if err = enc.Encode(&jsonlEntry{Offset: at.Milliseconds(), Time: e.Time, RoomID: e.RoomID, Data: e.Data}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ExportXML 将录制文件中的弹幕导出为 B 站视频 XML 弹幕格式
+func ExportXML(r *Reader, w io.Writer, opt *ExportOptions) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString("<i><chatserver>chat.bilibili.com</chatserver><chatid>0</chatid><mission>0</mission><maxlimit>0</maxlimit><state>0</state><real_name>0</real_name><source>k-v</source>\n")
+	err := readDanmaku(r, opt, func(d *exportedDanmaku) error {
+		var uhash string
+		if d.Danmaku.Extra != nil {
+			uhash = d.Danmaku.Extra.UserHash
+		}
+		fmt.Fprintf(bw, `<d p="%.5f,%d,%d,%d,%d,0,%s,0">`,
+			d.At.Seconds(), d.Mode, d.FontSize, d.Color, d.Danmaku.Timestamp/1000, uhash)
+		if err := xml.EscapeText(bw, []byte(d.Danmaku.Content)); err != nil {
+			return err
+		}
+		_, err := bw.WriteString("</d>\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString("</i>\n")
+	return bw.Flush()
+}
+
+// ASS 字幕的画布大小与滚动弹幕参数
+const (
+	assWidth    = 1920
+	assHeight   = 1080
+	assDuration = 8 * time.Second
+	assLanes    = 12
+)
+
+// ExportASS 将录制文件中的弹幕导出为 ASS 字幕，滚动弹幕会分配到不重叠的轨道
+func ExportASS(r *Reader, w io.Writer, opt *ExportOptions) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[Script Info]\nScriptType: v4.00+\nPlayResX: %d\nPlayResY: %d\nWrapStyle: 2\n\n", assWidth, assHeight)
+	bw.WriteString("[V4+ Styles]\nFormat: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+	bw.WriteString("Style: Danmaku,Microsoft YaHei,50,&H00FFFFFF,&H00FFFFFF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,1,0,7,0,0,0,1\n\n")
+	bw.WriteString("[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	laneHeight := assHeight / 2 / assLanes
+	// 每条轨道上一条弹幕完全进入画面的时间
+	var free [assLanes]time.Duration
+	err := readDanmaku(r, opt, func(d *exportedDanmaku) error {
+		size := d.FontSize * 2
+		width := utf8.RuneCountInString(d.Danmaku.Content) * size
+		speed := float64(assWidth+width) / assDuration.Seconds()
+		lane := 0
+		for i := range free {
+			if free[i] <= d.At {
+				lane = i
+				break
+			}
+			if free[i] < free[lane] {
+				lane = i
+			}
+		}
+		free[lane] = d.At + time.Duration(float64(width)/speed*float64(time.Second))
+		y := lane * laneHeight
+		_, err := fmt.Fprintf(bw, "Dialogue: 0,%s,%s,Danmaku,,0,0,0,,{\\move(%d,%d,%d,%d)\\fs%d\\c&H%s&}%s\n",
+			assTime(d.At), assTime(d.At+assDuration), assWidth, y, -width, y, size, assColor(d.Color), assEscape(d.Danmaku.Content))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// assTime 格式化为 ASS 使用的 h:mm:ss.cc
+func assTime(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// assColor 将 RGB 颜色转换为 ASS 使用的 BGR
+func assColor(rgb int) string {
+	return fmt.Sprintf("%02X%02X%02X", rgb&0xff, rgb>>8&0xff, rgb>>16&0xff)
+}
+
+var assReplacer = strings.NewReplacer("\\", "\\\\", "{", "\\{", "}", "\\}", "\n", " ", "\r", "")
+
+func assEscape(s string) string {
+	return assReplacer.Replace(s)
+}
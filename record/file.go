@@ -0,0 +1,129 @@
+package record
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// IndexSuffix 索引文件的后缀
+const IndexSuffix = ".idx"
+
+// File 写入到磁盘的录制文件，同时维护 path.idx 索引，开启 WAL 时同时维护 path.wal 预写日志
+type File struct {
+	*Writer
+	mu     sync.Mutex
+	f      *os.File
+	idx    *os.File
+	wal    *os.File
+	opts   FileOptions
+	synced time.Time
+}
+
+// Create 创建录制文件，path 以 .gz 结尾时使用 gzip 压缩
+func Create(path string) (*File, error) {
+	return CreateWithOptions(path, FileOptions{})
+}
+
+// Write 写入一条记录，开启 WAL 时同时追加到预写日志
+func (f *File) Write(e *Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// 开始新块时预写日志会被清空，因此在写入录制文件之后追加
+	if err := f.Writer.Write(e); err != nil {
+		return err
+	}
+	if f.wal != nil {
+		if err := f.appendWAL(e); err != nil {
+			return err
+		}
+	}
+	return f.maybeSync()
+}
+
+// Close 写入剩余内容并关闭录制文件与索引，成功时删除预写日志
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.Writer.Close()
+	if err == nil && (f.wal != nil || f.opts.Sync != SyncNone) {
+		if err = f.f.Sync(); err == nil {
+			err = f.idx.Sync()
+		}
+	}
+	if cerr := f.f.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.idx.Close(); err == nil {
+		err = cerr
+	}
+	if f.wal != nil {
+		if cerr := f.wal.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Remove(f.wal.Name())
+		}
+	}
+	return err
+}
+
+// Open 打开录制文件，自动识别是否压缩
+func Open(path string) (*Reader, error) {
+	return OpenAt(path, 0)
+}
+
+// OpenAt 打开录制文件，并从时间 t(毫秒时间戳) 开始读取
+//
+// 存在索引时直接跳转到 t 所在的块，否则从头读取并跳过更早的记录
+func OpenAt(path string, t int64) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if t > 0 {
+		offset, err := seekOffset(path+IndexSuffix, t)
+		if err != nil && !os.IsNotExist(err) {
+			f.Close()
+			return nil, err
+		}
+		if _, err = f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.after = t
+	r.closer = f
+	return r, nil
+}
+
+// seekOffset 在索引中查找 t 所在块的偏移
+func seekOffset(indexPath string, t int64) (int64, error) {
+	idx, err := os.Open(indexPath)
+	if err != nil {
+		return 0, err
+	}
+	defer idx.Close()
+	dec := json.NewDecoder(idx)
+	var offset int64
+	for {
+		var ie IndexEntry
+		if err = dec.Decode(&ie); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			return 0, err
+		}
+		if ie.Time > t {
+			return offset, nil
+		}
+		offset = ie.Offset
+	}
+}
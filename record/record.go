@@ -0,0 +1,205 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// DefaultBlockSize 默认每个块包含的记录数，每个块开始时写入一条索引
+const DefaultBlockSize = 1000
+
+// Entry 录制文件中的一条记录，每条记录占一行 JSON
+type Entry struct {
+	Time   int64           `json:"time"`    // 收到的时间，毫秒时间戳
+	RoomID int             `json:"room_id"` // 真实房间号
+	Data   json.RawMessage `json:"data"`    // 原始 Notification 报文
+}
+
+// IndexEntry 索引中的一条记录，指向一个块的起始位置
+type IndexEntry struct {
+	Time   int64 `json:"time"`   // 块内第一条记录的时间
+	Offset int64 `json:"offset"` // 块在录制文件中的字节偏移
+}
+
+// Writer 录制文件写入器，可以并发调用
+type Writer struct {
+	mu        sync.Mutex
+	cw        *countWriter
+	gz        *gzip.Writer
+	w         *bufio.Writer
+	index     *json.Encoder
+	blockSize int
+	count     int
+	onBlock   func() error // 上一个块写入完成后调用
+}
+
+// NewWriter 创建一个写入到 w 的录制写入器
+func NewWriter(w io.Writer) *Writer {
+	cw := &countWriter{w: w}
+	return &Writer{cw: cw, w: bufio.NewWriter(cw), blockSize: DefaultBlockSize}
+}
+
+// NewGzipWriter 创建一个写入到 w 的 gzip 压缩录制写入器
+//
+// 每个块是一个独立的 gzip member，配合索引可以从任意块开始解压
+func NewGzipWriter(w io.Writer) *Writer {
+	cw := &countWriter{w: w}
+	gz := gzip.NewWriter(cw)
+	return &Writer{cw: cw, gz: gz, w: bufio.NewWriter(gz), blockSize: DefaultBlockSize}
+}
+
+// SetIndex 设置索引的写入位置，每个块开始时写入一条 IndexEntry
+func (w *Writer) SetIndex(index io.Writer) {
+	w.mu.Lock()
+	w.index = json.NewEncoder(index)
+	w.mu.Unlock()
+}
+
+// SetBlockSize 设置每个块包含的记录数
+func (w *Writer) SetBlockSize(n int) {
+	w.mu.Lock()
+	if n > 0 {
+		w.blockSize = n
+	}
+	w.mu.Unlock()
+}
+
+// Write 写入一条记录
+func (w *Writer) Write(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.count%w.blockSize == 0 {
+		if err = w.startBlock(e.Time); err != nil {
+			return err
+		}
+	}
+	w.count++
+	if _, err = w.w.Write(b); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+// startBlock 结束上一个块并开始新块，记录新块的索引
+func (w *Writer) startBlock(t int64) error {
+	if w.count > 0 {
+		if err := w.w.Flush(); err != nil {
+			return err
+		}
+		if w.gz != nil {
+			if err := w.gz.Close(); err != nil {
+				return err
+			}
+			w.gz.Reset(w.cw)
+		}
+		if w.onBlock != nil {
+			if err := w.onBlock(); err != nil {
+				return err
+			}
+		}
+	}
+	if w.index == nil {
+		return nil
+	}
+	return w.index.Encode(&IndexEntry{Time: t, Offset: w.cw.n})
+}
+
+// Flush 将缓冲区中的内容写入底层 io.Writer
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Flush()
+	}
+	return nil
+}
+
+// Close 写入所有缓冲内容并结束压缩流，不会关闭底层 io.Writer
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+// Reader 录制文件读取器
+type Reader struct {
+	r      *bufio.Reader
+	after  int64
+	closer io.Closer
+}
+
+// NewReader 创建一个从 r 读取的录制读取器，会自动识别 gzip 压缩
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gz)
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next 读取下一条记录，读取完毕时返回 io.EOF，gzip 文件末尾不完整时返回 io.ErrUnexpectedEOF
+func (r *Reader) Next() (*Entry, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		e := new(Entry)
+		if jerr := json.Unmarshal(line, e); jerr != nil {
+			// 文件仍在写入时最后一行可能不完整，视为读取完毕
+			if err != nil {
+				return nil, io.EOF
+			}
+			return nil, jerr
+		}
+		if e.Time < r.after {
+			continue
+		}
+		return e, nil
+	}
+}
+
+// Close 关闭由 Open 打开的文件，对 NewReader 创建的读取器无作用
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,410 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// WALSuffix 预写日志文件的后缀
+const WALSuffix = ".wal"
+
+// 录制文件同步到磁盘的时机，见 FileOptions.Sync
+const (
+	SyncBlock    = iota // 每个块写入完成时同步，默认
+	SyncNone            // 只在 Close 时同步
+	SyncAlways          // 每条记录写入后同步
+	SyncInterval        // 写入时距上次同步超过 FileOptions.SyncInterval 则同步
+)
+
+// FileOptions CreateWithOptions 与 Resume 的配置
+type FileOptions struct {
+	// WAL 每条记录同时追加到 path.wal，块写入完成并同步后清空，进程崩溃后 Resume 用它恢复未完成的块
+	WAL bool
+	// Sync 同步的时机，开启 WAL 时同步预写日志，否则同步录制文件；开启 WAL 时块结束总会同步录制文件
+	Sync int
+	// SyncInterval Sync 为 SyncInterval 时两次同步的最短间隔
+	SyncInterval time.Duration
+}
+
+// walHeader 预写日志的第一行
+type walHeader struct {
+	Offset int64 `json:"offset"` // 预写日志中的记录所在块在录制文件中的起始偏移
+}
+
+// CreateWithOptions 使用指定的配置创建录制文件，path 以 .gz 结尾时使用 gzip 压缩
+func CreateWithOptions(path string, o FileOptions) (*File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := os.Create(path + IndexSuffix)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	file, err := newFile(path, f, idx, 0, o)
+	if err != nil {
+		f.Close()
+		idx.Close()
+		return nil, err
+	}
+	if !o.WAL {
+		// 之前的录制留下的预写日志不属于新文件，不删除的话之后的 Resume 会按它截断
+		if err = removeWAL(path); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// Resume 打开已有的录制文件继续追加，文件不存在时创建
+//
+// 存在预写日志时将录制文件截断到未完成的块之前并重新写入日志中的记录；否则校验文件末尾，
+// 丢弃不完整的行或 gzip 块。未同步到磁盘的记录可能丢失，但不会重复写入
+//
+// o.WAL 为 false 时恢复完成后删除遗留的预写日志
+func Resume(path string, o FileOptions) (*File, error) {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return CreateWithOptions(path, o)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, offset, err := readWAL(path + WALSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset > fi.Size() {
+		// 没有预写日志，或录制文件在块同步前丢失了内容
+		if offset, err = validTail(path, fi.Size()); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err = f.Truncate(offset); err == nil {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	idx, err := truncateIndex(path+IndexSuffix, offset)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	file, err := newFile(path, f, idx, offset, o)
+	if err != nil {
+		f.Close()
+		idx.Close()
+		return nil, err
+	}
+	for _, e := range entries {
+		if err := file.Write(e); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if !o.WAL {
+		// 未开启 WAL 时只有这里会清理预写日志，重新写入的记录同步后再删除，
+		// 否则之后的 Resume 会按旧的日志头截断，丢弃这之后写入的全部记录
+		if err = file.syncReplayed(); err == nil {
+			err = removeWAL(path)
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// syncReplayed 将 Resume 重新写入的记录同步到磁盘
+func (f *File) syncReplayed() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.Writer.Flush(); err != nil {
+		return err
+	}
+	if err := f.f.Sync(); err != nil {
+		return err
+	}
+	return f.idx.Sync()
+}
+
+// removeWAL 删除 path 的预写日志，不存在时忽略
+func removeWAL(path string) error {
+	if err := os.Remove(path + WALSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func newFile(path string, f, idx *os.File, offset int64, o FileOptions) (*File, error) {
+	var w *Writer
+	if strings.HasSuffix(path, ".gz") {
+		w = NewGzipWriter(f)
+	} else {
+		w = NewWriter(f)
+	}
+	w.cw.n = offset
+	w.SetIndex(idx)
+	file := &File{Writer: w, f: f, idx: idx, opts: o, synced: time.Now()}
+	w.onBlock = file.blockDone
+	if o.WAL {
+		wal, err := os.Create(path + WALSuffix)
+		if err != nil {
+			return nil, err
+		}
+		file.wal = wal
+		if err = file.resetWAL(offset); err != nil {
+			wal.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// blockDone 一个块写入完成，同步录制文件并清空预写日志，调用时持有 Writer 的锁
+func (f *File) blockDone() error {
+	if f.wal == nil && f.opts.Sync == SyncNone {
+		return nil
+	}
+	if err := f.f.Sync(); err != nil {
+		return err
+	}
+	if err := f.idx.Sync(); err != nil {
+		return err
+	}
+	if f.wal == nil {
+		f.synced = time.Now()
+		return nil
+	}
+	return f.resetWAL(f.Writer.cw.n)
+}
+
+// resetWAL 清空预写日志，之后的记录属于从 offset 开始的块
+func (f *File) resetWAL(offset int64) error {
+	if err := f.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	b, _ := json.Marshal(&walHeader{Offset: offset})
+	if _, err := f.wal.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	f.synced = time.Now()
+	return f.wal.Sync()
+}
+
+// appendWAL 将记录追加到预写日志
+func (f *File) appendWAL(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = f.wal.Write(append(b, '\n'))
+	return err
+}
+
+// maybeSync 按同步策略在写入后同步
+func (f *File) maybeSync() error {
+	switch f.opts.Sync {
+	case SyncAlways:
+	case SyncInterval:
+		if time.Since(f.synced) < f.opts.SyncInterval {
+			return nil
+		}
+	default:
+		return nil
+	}
+	f.synced = time.Now()
+	if f.wal != nil {
+		return f.wal.Sync()
+	}
+	if err := f.Writer.Flush(); err != nil {
+		return err
+	}
+	return f.f.Sync()
+}
+
+// readWAL 读取预写日志，不存在或日志头不完整时 offset 为 -1，末尾不完整的记录会被丢弃
+func readWAL(path string) ([]*Entry, int64, error) {
+	wal, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, -1, nil
+	}
+	if err != nil {
+		return nil, -1, err
+	}
+	defer wal.Close()
+	br := bufio.NewReader(wal)
+	line, err := br.ReadBytes('\n')
+	var h walHeader
+	if err != nil || json.Unmarshal(line, &h) != nil {
+		return nil, -1, nil
+	}
+	var entries []*Entry
+	for {
+		line, err := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			e := new(Entry)
+			if json.Unmarshal(line, e) != nil {
+				break
+			}
+			entries = append(entries, e)
+		}
+		if err != nil {
+			break
+		}
+	}
+	return entries, h.Offset, nil
+}
+
+// validTail 获取录制文件中最后一个完整的行或 gzip 块结束的偏移
+func validTail(path string, size int64) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if !strings.HasSuffix(path, ".gz") {
+		return lastLineEnd(f, size)
+	}
+	// 每个块是一个 gzip member，从最后一个索引开始逐个校验
+	offset, err := lastIndexOffset(path+IndexSuffix, size)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	c := &byteCounter{r: bufio.NewReader(f)}
+	gz, err := gzip.NewReader(c)
+	if err != nil {
+		return offset, nil
+	}
+	good := offset
+	for {
+		gz.Multistream(false)
+		if _, err := io.Copy(io.Discard, gz); err != nil {
+			return good, nil
+		}
+		good = offset + c.n
+		if err := gz.Reset(c); err != nil {
+			return good, nil
+		}
+	}
+}
+
+// lastLineEnd 获取最后一个换行符之后的偏移
+func lastLineEnd(f *os.File, size int64) (int64, error) {
+	buf := make([]byte, 64<<10)
+	for end := size; end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		n, err := f.ReadAt(buf[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// readIndex 读取索引中的全部记录，不完整的最后一条会被忽略
+func readIndex(path string) ([]IndexEntry, error) {
+	idx, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer idx.Close()
+	var entries []IndexEntry
+	dec := json.NewDecoder(idx)
+	for {
+		var ie IndexEntry
+		if dec.Decode(&ie) != nil {
+			return entries, nil
+		}
+		entries = append(entries, ie)
+	}
+}
+
+// lastIndexOffset 获取索引中不超过 size 的最后一个块的偏移
+func lastIndexOffset(path string, size int64) (int64, error) {
+	entries, err := readIndex(path)
+	if err != nil {
+		return 0, err
+	}
+	var offset int64
+	for _, ie := range entries {
+		if ie.Offset <= size {
+			offset = ie.Offset
+		}
+	}
+	return offset, nil
+}
+
+// truncateIndex 移除索引中从 offset 开始及之后的块，返回用于追加的索引文件
+func truncateIndex(path string, offset int64) (*os.File, error) {
+	entries, err := readIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(idx)
+	for i := range entries {
+		if entries[i].Offset >= offset {
+			break
+		}
+		if err := enc.Encode(&entries[i]); err != nil {
+			idx.Close()
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// byteCounter 统计 gzip 读取的字节数，实现 io.ByteReader 使 gzip 不会预读之后的内容
+type byteCounter struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *byteCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *byteCounter) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
@@ -0,0 +1,101 @@
+package record
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// crash 模拟进程崩溃，写出缓冲的内容后直接关闭文件，不结束块也不删除预写日志
+func crash(t *testing.T, f *File) {
+	t.Helper()
+	if err := f.Writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	f.f.Close()
+	f.idx.Close()
+	if f.wal != nil {
+		f.wal.Close()
+	}
+}
+
+func writeEntries(t *testing.T, f *File, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		e := &Entry{Time: int64(i + 1), RoomID: 1, Data: json.RawMessage(`{"cmd":"DANMU_MSG"}`)}
+		if err := f.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func countEntries(t *testing.T, path string) int {
+	t.Helper()
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	n := 0
+	for {
+		if _, err := r.Next(); err != nil {
+			if err == io.EOF {
+				return n
+			}
+			t.Fatal(err)
+		}
+		n++
+	}
+}
+
+func TestResumeWithoutWALRemovesStaleLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "room.jsonl")
+	f, err := CreateWithOptions(path, FileOptions{WAL: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeEntries(t, f, 3)
+	crash(t, f)
+
+	f, err = Resume(path, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeEntries(t, f, 5)
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countEntries(t, path); n != 8 {
+		t.Fatalf("after resume without WAL: %d entries, want 8", n)
+	}
+	f, err = Resume(path, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countEntries(t, path); n != 8 {
+		t.Fatalf("after second resume: %d entries, want 8", n)
+	}
+	if _, err = os.Stat(path + WALSuffix); !os.IsNotExist(err) {
+		t.Fatalf("stale %s left on disk: %v", WALSuffix, err)
+	}
+}
+
+func TestCreateRemovesStaleLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "room.jsonl")
+	if err := os.WriteFile(path+WALSuffix, []byte("{\"offset\":0}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := CreateWithOptions(path, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = os.Stat(path + WALSuffix); !os.IsNotExist(err) {
+		t.Fatalf("stale %s left on disk: %v", WALSuffix, err)
+	}
+}
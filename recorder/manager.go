@@ -0,0 +1,75 @@
+package recorder
+
+import (
+	"sync"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// Manager 为 RoomManager 中的每个房间创建录制器
+type Manager struct {
+	opts      Options
+	mu        sync.Mutex
+	recorders map[string]*Recorder
+	setups    []func(r *Recorder)
+}
+
+// NewManager 创建使用同一配置录制全部房间的 Manager
+func NewManager(opts Options) *Manager {
+	return &Manager{opts: opts, recorders: make(map[string]*Recorder)}
+}
+
+// OnRecorder 添加录制器创建后调用的处理器，可用于注册 OnFile
+func (m *Manager) OnRecorder(f func(r *Recorder)) {
+	m.mu.Lock()
+	m.setups = append(m.setups, f)
+	m.mu.Unlock()
+}
+
+// Attach 为之后添加到 rm 的每个房间创建录制器，房间已开播时立即开始录制
+func (m *Manager) Attach(rm *client.RoomManager) {
+	rm.OnClient(func(roomID string, c *client.Client) {
+		r := New(roomID, m.opts)
+		m.mu.Lock()
+		if old := m.recorders[roomID]; old != nil {
+			go old.Close()
+		}
+		m.recorders[roomID] = r
+		setups := m.setups
+		m.mu.Unlock()
+		for _, fn := range setups {
+			fn(r)
+		}
+		r.Attach(c)
+		go func() {
+			if err := r.Start(); err != nil {
+				log.Errorf("check live status of room %s failed: %v", roomID, err)
+			}
+		}()
+	})
+}
+
+// Recorder 获取房间的录制器，不存在时返回 nil
+func (m *Manager) Recorder(roomID string) *Recorder {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.recorders[roomID]
+}
+
+// Close 停止全部录制
+func (m *Manager) Close() {
+	m.mu.Lock()
+	recs := m.recorders
+	m.recorders = make(map[string]*Recorder)
+	m.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, r := range recs {
+		wg.Add(1)
+		go func(r *Recorder) {
+			defer wg.Done()
+			r.Close()
+		}(r)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,305 @@
+// Package recorder 在开播时调用 ffmpeg、streamlink 等外部程序录制直播流，下播时停止，进程异常退出后自动重启
+package recorder
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/api"
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	log "github.com/sirupsen/logrus"
+)
+
+// 常用的录制命令，{url} 为直播流地址，{output} 为输出文件路径
+var (
+	FFmpegCommand     = []string{"ffmpeg", "-nostdin", "-loglevel", "error", "-y", "-headers", "Referer: https://live.bilibili.com/\r\n", "-i", "{url}", "-c", "copy", "{output}"}
+	StreamlinkCommand = []string{"streamlink", "--http-header", "Referer=https://live.bilibili.com/", "-o", "{output}", "{url}", "best"}
+)
+
+const (
+	defaultOutput       = "{room_id}/{time}.flv"
+	defaultRestartDelay = 5 * time.Second
+	stopTimeout         = 10 * time.Second
+)
+
+// Options 录制配置
+type Options struct {
+	// Command 录制命令与参数，可以使用 {url}、{output}、{room_id} 占位符，默认 FFmpegCommand
+	Command []string `json:"command"`
+	// Dir 输出目录，默认为当前目录
+	Dir string `json:"dir"`
+	// Output 输出文件相对 Dir 的路径，可以使用 {room_id}、{time}、{seq} 占位符，默认 {room_id}/{time}.flv
+	Output string `json:"output"`
+	// Quality 画质，默认 api.QualityOriginal
+	Quality int `json:"quality"`
+	// Format 直播流格式，默认 flv
+	Format string `json:"format"`
+	// RestartDelay 录制进程退出后重新获取地址并重启前等待的时间，默认 5s
+	RestartDelay time.Duration `json:"restart_delay"`
+	// MaxRestarts 一场直播中最多重启的次数，0 表示不限制
+	MaxRestarts int `json:"max_restarts"`
+	// APIClient 获取直播状态与直播流地址使用的 api.Client，默认 api.DefaultClient
+	APIClient *api.Client `json:"-"`
+}
+
+// File 一个录制完成的文件，同一场直播中进程重启会产生多个文件
+type File struct {
+	RoomID string
+	Path   string
+	Seq    int // 本场直播中的序号，从 0 开始
+	Start  time.Time
+	End    time.Time
+	Err    error // 录制进程的退出错误，正常停止时为 nil
+}
+
+// Recorder 单个直播间的录制器
+type Recorder struct {
+	roomID  string
+	opts    Options
+	mu      sync.Mutex
+	stop    chan struct{} // 为 nil 时表示未在录制
+	done    chan struct{}
+	onFile  []func(*File)
+	onStart []func(*File)
+	closed  bool
+}
+
+// New 创建 roomID 直播间的录制器
+func New(roomID string, opts Options) *Recorder {
+	if len(opts.Command) == 0 {
+		opts.Command = FFmpegCommand
+	}
+	if opts.Output == "" {
+		opts.Output = defaultOutput
+	}
+	if opts.Quality == 0 {
+		opts.Quality = api.QualityOriginal
+	}
+	if opts.Format == "" {
+		opts.Format = "flv"
+	}
+	if opts.RestartDelay <= 0 {
+		opts.RestartDelay = defaultRestartDelay
+	}
+	if opts.APIClient == nil {
+		opts.APIClient = api.DefaultClient
+	}
+	return &Recorder{roomID: roomID, opts: opts}
+}
+
+// OnFileStart 添加开始录制一个文件时调用的处理器，此时 End 为零值
+func (r *Recorder) OnFileStart(f func(*File)) {
+	r.mu.Lock()
+	r.onStart = append(r.onStart, f)
+	r.mu.Unlock()
+}
+
+// OnFile 添加一个文件录制结束后调用的处理器
+func (r *Recorder) OnFile(f func(*File)) {
+	r.mu.Lock()
+	r.onFile = append(r.onFile, f)
+	r.mu.Unlock()
+}
+
+// Attach 收到 src 的开播事件时开始录制，下播事件时停止录制
+func (r *Recorder) Attach(src client.DanmakuSource) {
+	src.OnLive(func(*message.Live) {
+		if err := r.Record(); err != nil {
+			log.Errorf("start recording room %s failed: %v", r.roomID, err)
+		}
+	})
+	src.OnPreparing(func(*message.Preparing) { r.StopRecording() })
+}
+
+// Start 查询直播状态，已开播时立即开始录制
+func (r *Recorder) Start() error {
+	info, err := r.opts.APIClient.GetLiveRoomInfo(r.roomID)
+	if err != nil {
+		return err
+	}
+	if info.Data.LiveStatus != 1 {
+		return nil
+	}
+	return r.Record()
+}
+
+// Recording 是否正在录制
+func (r *Recorder) Recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stop != nil
+}
+
+// ErrClosed 录制器已关闭
+var ErrClosed = errors.New("recorder closed")
+
+// Record 开始录制，已在录制时直接返回 nil
+func (r *Recorder) Record() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if r.stop != nil {
+		return nil
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.loop(r.stop, r.done)
+	return nil
+}
+
+// StopRecording 停止录制并等待录制进程退出
+func (r *Recorder) StopRecording() {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Close 停止录制，之后不再响应开播事件
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	r.StopRecording()
+}
+
+// loop 一场直播的录制循环，进程退出后重新获取地址并重启，直到停止录制、下播或达到重启次数
+func (r *Recorder) loop(stop, done chan struct{}) {
+	defer close(done)
+	for seq := 0; ; seq++ {
+		if seq > 0 {
+			if r.opts.MaxRestarts > 0 && seq > r.opts.MaxRestarts {
+				log.Warnf("room %s recorder exceeded max restarts", r.roomID)
+				break
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(r.opts.RestartDelay):
+			}
+		}
+		urls, err := r.opts.APIClient.GetPlayURL(r.roomID, &api.PlayURLOptions{Quality: r.opts.Quality, Format: r.opts.Format})
+		if err == api.ErrNotLive {
+			log.Infof("room %s is not live, recording finished", r.roomID)
+			break
+		}
+		if err != nil || len(urls) == 0 {
+			log.Errorf("get play url of room %s failed: %v", r.roomID, err)
+			continue
+		}
+		f := &File{RoomID: r.roomID, Seq: seq, Start: time.Now()}
+		f.Path = uniquePath(filepath.Join(r.opts.Dir, r.expand(r.opts.Output, f)))
+		if err = os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+			log.Error("create record dir failed: ", err)
+			continue
+		}
+		stopped := r.run(urls[0].URL, f, stop)
+		if stopped {
+			return
+		}
+	}
+	// 下播或放弃重启，允许下一次开播时重新录制
+	r.mu.Lock()
+	if r.done == done {
+		r.stop, r.done = nil, nil
+	}
+	r.mu.Unlock()
+}
+
+// run 执行一次录制进程，返回 true 表示因停止录制而退出
+func (r *Recorder) run(url string, f *File, stop chan struct{}) bool {
+	args := make([]string, len(r.opts.Command))
+	for i, a := range r.opts.Command {
+		args[i] = strings.NewReplacer("{url}", url, "{output}", f.Path, "{room_id}", r.roomID).Replace(a)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		f.Err = err
+		f.End = time.Now()
+		r.emit(r.handlers(false), f)
+		return false
+	}
+	log.Infof("room %s recording to %s", r.roomID, f.Path)
+	r.emit(r.handlers(true), f)
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	stopped := false
+	select {
+	case f.Err = <-exited:
+	case <-stop:
+		stopped = true
+		f.Err = terminate(cmd, exited)
+	}
+	f.End = time.Now()
+	if !stopped {
+		log.Warnf("room %s recorder exited: %v", r.roomID, f.Err)
+	}
+	r.emit(r.handlers(false), f)
+	return stopped
+}
+
+// terminate 先发送中断信号让录制程序写完文件尾，超时后强制结束
+func terminate(cmd *exec.Cmd, exited chan error) error {
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		_ = cmd.Process.Kill()
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(stopTimeout):
+		_ = cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+func (r *Recorder) handlers(start bool) []func(*File) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if start {
+		return r.onStart
+	}
+	return r.onFile
+}
+
+func (r *Recorder) emit(handlers []func(*File), f *File) {
+	for _, fn := range handlers {
+		cp := *f
+		fn(&cp)
+	}
+}
+
+// expand 替换输出路径中的占位符
+func (r *Recorder) expand(s string, f *File) string {
+	return strings.NewReplacer(
+		"{room_id}", r.roomID,
+		"{time}", f.Start.Format("20060102-150405"),
+		"{seq}", strconv.Itoa(f.Seq),
+	).Replace(s)
+}
+
+// uniquePath 文件已存在时在扩展名前添加序号，避免覆盖之前的录制
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = base + "_" + strconv.Itoa(i) + ext
+	}
+}
@@ -0,0 +1,81 @@
+package recorder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/record"
+	log "github.com/sirupsen/logrus"
+)
+
+// SidecarOptions 录制结束后导出与视频对齐的弹幕文件的配置
+type SidecarOptions struct {
+	// Events 同时录制的事件文件路径，可以使用 {room_id} 占位符，见 record.Create
+	Events string `json:"events"`
+	// Formats 导出的格式，xml、ass 或 jsonl，默认 xml
+	Formats []string `json:"formats"`
+	// Delay 直播流画面相对实时的延迟，视频中的画面比收到的事件晚 Delay，默认为 0
+	Delay time.Duration `json:"delay"`
+	// ServerTime 使用弹幕中的服务端时间对齐，见 record.ExportOptions
+	ServerTime bool `json:"server_time"`
+}
+
+// SetSidecar 每个文件录制结束后，在视频文件旁写入同名的弹幕文件，如 xxx.flv 对应 xxx.xml
+func (r *Recorder) SetSidecar(o SidecarOptions) {
+	r.OnFile(func(f *File) {
+		if err := WriteSidecars(f, o); err != nil {
+			log.Errorf("write sidecar of %s failed: %v", f.Path, err)
+		}
+	})
+}
+
+// WriteSidecars 将 f 录制期间的事件按视频时间轴导出到 f 旁的弹幕文件
+func WriteSidecars(f *File, o SidecarOptions) error {
+	formats := o.Formats
+	if len(formats) == 0 {
+		formats = []string{"xml"}
+	}
+	events := strings.Replace(o.Events, "{room_id}", f.RoomID, -1)
+	seg := record.Segment{
+		Start: f.Start.Add(-o.Delay).UnixNano() / int64(time.Millisecond),
+		End:   f.End.Add(-o.Delay).UnixNano() / int64(time.Millisecond),
+	}
+	opt := &record.ExportOptions{Segments: []record.Segment{seg}, ServerTime: o.ServerTime}
+	base := strings.TrimSuffix(f.Path, filepath.Ext(f.Path))
+	for _, format := range formats {
+		var export func(*record.Reader, io.Writer, *record.ExportOptions) error
+		switch format {
+		case "xml":
+			export = record.ExportXML
+		case "ass":
+			export = record.ExportASS
+		case "jsonl":
+			export = record.ExportJSONL
+		default:
+			return fmt.Errorf("unknown sidecar format %q", format)
+		}
+		// 存在索引时直接跳转到片段开始的位置
+		rd, err := record.OpenAt(events, seg.Start)
+		if err != nil {
+			return err
+		}
+		out, err := os.Create(base + "." + format)
+		if err != nil {
+			rd.Close()
+			return err
+		}
+		err = export(rd, out, opt)
+		rd.Close()
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/config"
+)
+
+// DefaultShutdownTimeout 默认等待全部 client 停止的时长
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Config 守护进程的配置，房间、身份信息、重连策略与 Sink 见 config.Config
+type Config struct {
+	config.Config
+	Log             LogConfig     `config:"log"`
+	Manifest        string        `config:"manifest"`         // 房间清单文件，设置后启动时恢复其中的房间
+	Concurrency     int           `config:"concurrency"`      // 同时连接的房间数
+	ShutdownTimeout time.Duration `config:"shutdown_timeout"` // 退出时等待 client 停止的时长
+	ReloadInterval  time.Duration `config:"reload_interval"`  // 检查配置文件是否修改的间隔，0 表示只在收到 SIGHUP 时重新读取
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string `config:"level"`  // logrus 日志级别，默认 info
+	Format string `config:"format"` // text 或 json，默认 text
+	File   string `config:"file"`   // 日志文件，默认输出到 stderr
+}
+
+// LoadConfig 读取并校验 YAML、TOML 或 JSON 格式的配置文件
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if err := config.Load(path, cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,23 @@
+package runner
+
+import (
+	"net"
+	"os"
+)
+
+// notify 向 systemd 报告服务状态，未由 systemd 以 Type=notify 启动时不做任何事
+func notify(state string) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte(state))
+}
@@ -0,0 +1,254 @@
+// Package runner 将 RoomManager 包装为可由 systemd 管理的守护进程
+//
+// 负责读取配置、设置日志、处理退出信号并在退出前停止全部 client
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Runner 守护进程
+type Runner struct {
+	Config   *Config
+	Manager  *client.RoomManager
+	instance *config.Instance
+	path     string
+	modTime  time.Time
+	logFile  *os.File
+}
+
+// New 使用配置创建 Runner，并按配置设置 logrus
+//
+// 返回后可以通过 Manager 注册事件处理器，再调用 Run
+func New(cfg *Config) (*Runner, error) {
+	r := &Runner{Config: cfg}
+	if err := r.setupLog(); err != nil {
+		return nil, err
+	}
+	inst, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	r.instance = inst
+	r.Manager = inst.Manager
+	if cfg.Manifest != "" {
+		r.Manager.SetManifest(cfg.Manifest)
+	}
+	return r, nil
+}
+
+// Load 读取配置文件并创建 Runner
+func Load(path string) (*Runner, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.path = path
+	if fi, err := os.Stat(path); err == nil {
+		r.modTime = fi.ModTime()
+	}
+	return r, nil
+}
+
+func (r *Runner) setupLog() error {
+	if err := applyLogFormat(r.Config.Log); err != nil {
+		return err
+	}
+	if c := r.Config.Log; c.File != "" {
+		f, err := os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		log.SetOutput(f)
+		r.logFile = f
+	}
+	return nil
+}
+
+// applyLogFormat 设置日志级别与格式
+func applyLogFormat(c LogConfig) error {
+	if c.Level != "" {
+		level, err := log.ParseLevel(c.Level)
+		if err != nil {
+			return err
+		}
+		log.SetLevel(level)
+	}
+	switch strings.ToLower(c.Format) {
+	case "", "text":
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format %q", c.Format)
+	}
+	return nil
+}
+
+// Run 连接配置中的全部房间，直到 ctx 结束或收到 SIGINT、SIGTERM 后停止全部 client
+//
+// 由 Load 创建时，收到 SIGHUP 或配置文件修改后（需要设置 reload_interval）会调用 Reload
+// 在 systemd 下运行时会通过 NOTIFY_SOCKET 报告 READY、RELOADING 与 STOPPING 状态
+func (r *Runner) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if r.logFile != nil {
+		defer r.logFile.Close()
+	}
+
+	if r.Config.Manifest != "" {
+		failed, err := r.Manager.Restore(r.addOptions()...)
+		if err != nil {
+			return err
+		}
+		r.logFailed(failed)
+	}
+	var rooms []string
+	for _, id := range r.Config.RoomIDs() {
+		if r.Manager.Room(id) == nil {
+			rooms = append(rooms, id)
+		}
+	}
+	failed := r.Manager.AddRooms(rooms, r.addOptions()...)
+	r.logFailed(failed)
+	if len(rooms) > 0 && len(failed) == len(rooms) && len(r.Manager.Rooms()) == 0 {
+		return errors.New("no room started")
+	}
+
+	notify("READY=1")
+	log.Infof("runner started with %d rooms", len(r.Manager.Rooms()))
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	var poll <-chan time.Time
+	if r.path != "" && r.Config.ReloadInterval > 0 {
+		ticker := time.NewTicker(r.Config.ReloadInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-hup:
+			r.reload()
+		case <-poll:
+			if r.modified() {
+				r.reload()
+			}
+		}
+	}
+	log.Info("shutting down")
+	notify("STOPPING=1")
+	return r.shutdown()
+}
+
+// Reload 重新读取配置文件并应用，配置未变化的房间保持连接
+//
+// 日志文件与清单文件的修改需要重启后生效
+func (r *Runner) Reload() error {
+	if r.path == "" {
+		return errors.New("runner was not loaded from a config file")
+	}
+	if fi, err := os.Stat(r.path); err == nil {
+		r.modTime = fi.ModTime()
+	}
+	cfg, err := LoadConfig(r.path)
+	if err != nil {
+		return err
+	}
+	if err := applyLogFormat(cfg.Log); err != nil {
+		return err
+	}
+	res, err := r.instance.Reload(&cfg.Config, r.addOptions()...)
+	if err != nil {
+		return err
+	}
+	r.Config = cfg
+	log.WithField("added", res.Added).
+		WithField("removed", res.Removed).
+		WithField("restarted", res.Restarted).
+		WithField("sinks_changed", res.SinksChanged).
+		Info("config reloaded")
+	r.logFailed(res.Failed)
+	return nil
+}
+
+func (r *Runner) reload() {
+	notify("RELOADING=1")
+	if err := r.Reload(); err != nil {
+		log.Error("reload config failed: ", err)
+	}
+	notify("READY=1")
+}
+
+// modified 配置文件的修改时间是否晚于上次读取
+func (r *Runner) modified() bool {
+	fi, err := os.Stat(r.path)
+	return err == nil && !fi.ModTime().Equal(r.modTime)
+}
+
+func (r *Runner) addOptions() []client.AddRoomsOption {
+	if r.Config.Concurrency > 0 {
+		return []client.AddRoomsOption{client.WithConcurrency(r.Config.Concurrency)}
+	}
+	return nil
+}
+
+func (r *Runner) logFailed(failed map[string]error) {
+	for id, err := range failed {
+		log.WithField("room", id).Error("start room failed: ", err)
+	}
+}
+
+// shutdown 等待全部房间处理完已收到的事件后停止 client 并关闭 Sink，超过 ShutdownTimeout 时返回错误
+func (r *Runner) shutdown() error {
+	timeout := r.Config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	status, err := r.instance.Shutdown(ctx)
+	for id, err := range status {
+		if err != nil {
+			log.WithField("room", id).Warn("room shutdown incomplete: ", err)
+		}
+	}
+	if ctx.Err() != nil {
+		return errors.New("shutdown timed out")
+	}
+	if err != nil {
+		log.Error("close sink failed: ", err)
+	}
+	return nil
+}
+
+// Main 读取配置文件，调用 setup 注册处理器后运行，出错时退出进程
+func Main(path string, setup func(m *client.RoomManager)) {
+	r, err := Load(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if setup != nil {
+		setup(r.Manager)
+	}
+	if err := r.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,534 @@
+// Package clickhouse 通过 ClickHouse 的 HTTP 接口批量写入直播间事件
+//
+// 每种事件按 Table 的定义提取字段写入对应的表，适合大量房间、每天数百万条事件的分析场景
+package clickhouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/sink"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	DefaultURL           = "http://localhost:8123"
+	DefaultBatchSize     = 10000
+	DefaultFlushInterval = 5 * time.Second
+	DefaultMaxRetries    = 5
+	DefaultRetryDelay    = time.Second
+	DefaultMaxBuffer     = 1000000
+)
+
+// 列的特殊取值路径
+const (
+	PathTime   = "$time"    // 事件接收时间
+	PathRoomID = "$room_id" // 真实房间号
+	PathCmd    = "$cmd"     // 事件 cmd
+	PathRaw    = "$raw"     // 完整的事件 JSON
+)
+
+var errClosed = errors.New("clickhouse sink closed")
+
+// Column 表中的一列
+type Column struct {
+	Name string `config:"name"`
+	Type string `config:"type"` // ClickHouse 类型，用于建表与转换取值
+	Path string `config:"path"` // 事件 JSON 中的 gjson 路径，或 $time 等特殊路径
+}
+
+// Table 一张表与写入它的事件
+type Table struct {
+	Name    string   `config:"name"`
+	Cmds    []string `config:"cmds"` // 为空时写入全部事件
+	Columns []Column `config:"columns"`
+	OrderBy string   `config:"order_by"` // 建表时的排序键，默认 (room_id, time)
+}
+
+// Options Sink 的配置
+type Options struct {
+	URL           string        `config:"url"` // 默认 DefaultURL
+	Database      string        `config:"database"`
+	User          string        `config:"user"`
+	Password      string        `config:"password"`
+	Tables        []Table       `config:"tables"`         // 默认 DefaultTables()
+	BatchSize     int           `config:"batch_size"`     // 缓冲的行数达到该值时写入
+	FlushInterval time.Duration `config:"flush_interval"` // 最长写入间隔
+	MaxRetries    int           `config:"max_retries"`    // 每批写入失败后的重试次数
+	RetryDelay    time.Duration `config:"retry_delay"`    // 首次重试前的等待，之后每次翻倍
+	MaxBuffer     int           `config:"max_buffer"`     // 写入持续失败时最多缓冲的行数，超过后丢弃最旧的行
+	HTTPClient    *http.Client  `config:"-"`
+}
+
+// DefaultTables 弹幕、礼物、醒目留言三张表的默认定义
+func DefaultTables() []Table {
+	base := func(cols ...Column) []Column {
+		return append([]Column{
+			{Name: "time", Type: "DateTime64(3, 'UTC')", Path: PathTime},
+			{Name: "room_id", Type: "UInt64", Path: PathRoomID},
+		}, cols...)
+	}
+	return []Table{
+		{
+			Name: "danmaku",
+			Cmds: []string{"DANMU_MSG"},
+			Columns: base(
+				Column{Name: "uid", Type: "UInt64", Path: "info.2.0"},
+				Column{Name: "uname", Type: "String", Path: "info.2.1"},
+				Column{Name: "content", Type: "String", Path: "info.1"},
+				Column{Name: "medal_name", Type: "String", Path: "info.3.1"},
+				Column{Name: "medal_level", Type: "UInt8", Path: "info.3.0"},
+				Column{Name: "guard_level", Type: "UInt8", Path: "info.7"},
+			),
+		},
+		{
+			Name: "gift",
+			Cmds: []string{"SEND_GIFT"},
+			Columns: base(
+				Column{Name: "uid", Type: "UInt64", Path: "data.uid"},
+				Column{Name: "uname", Type: "String", Path: "data.uname"},
+				Column{Name: "gift_id", Type: "UInt32", Path: "data.giftId"},
+				Column{Name: "gift_name", Type: "String", Path: "data.giftName"},
+				Column{Name: "num", Type: "UInt32", Path: "data.num"},
+				Column{Name: "coin_type", Type: "LowCardinality(String)", Path: "data.coin_type"},
+				Column{Name: "total_coin", Type: "UInt64", Path: "data.total_coin"},
+			),
+		},
+		{
+			Name: "super_chat",
+			Cmds: []string{"SUPER_CHAT_MESSAGE"},
+			Columns: base(
+				Column{Name: "id", Type: "UInt64", Path: "data.id"},
+				Column{Name: "uid", Type: "UInt64", Path: "data.uid"},
+				Column{Name: "uname", Type: "String", Path: "data.user_info.uname"},
+				Column{Name: "price", Type: "UInt32", Path: "data.price"},
+				Column{Name: "message", Type: "String", Path: "data.message"},
+			),
+		},
+	}
+}
+
+// Sink ClickHouse 输出
+type Sink struct {
+	opts    Options
+	client  *http.Client
+	mu      sync.Mutex
+	buffers map[string][][]byte
+	rows    int
+	closed  bool
+	flushMu sync.Mutex
+	kick    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+var _ sink.Sink = (*Sink)(nil)
+
+// New 创建 ClickHouse Sink 并启动后台写入
+func New(opts Options) (*Sink, error) {
+	if opts.URL == "" {
+		opts.URL = DefaultURL
+	}
+	if _, err := url.Parse(opts.URL); err != nil {
+		return nil, err
+	}
+	if len(opts.Tables) == 0 {
+		opts.Tables = DefaultTables()
+	}
+	for _, t := range opts.Tables {
+		if t.Name == "" || len(t.Columns) == 0 {
+			return nil, fmt.Errorf("clickhouse table %q has no name or columns", t.Name)
+		}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = DefaultRetryDelay
+	}
+	if opts.MaxBuffer <= 0 {
+		opts.MaxBuffer = DefaultMaxBuffer
+	}
+	s := &Sink{
+		opts:    opts,
+		client:  opts.HTTPClient,
+		buffers: make(map[string][][]byte),
+		kick:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if s.client == nil {
+		s.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	go s.flushLoop()
+	return s, nil
+}
+
+// CreateTables 按 Table 的定义创建不存在的表，引擎为 MergeTree
+func (s *Sink) CreateTables(ctx context.Context) error {
+	for _, t := range s.opts.Tables {
+		cols := make([]string, 0, len(t.Columns))
+		for _, c := range t.Columns {
+			cols = append(cols, fmt.Sprintf("`%s` %s", c.Name, c.Type))
+		}
+		order := t.OrderBy
+		if order == "" {
+			order = "(room_id, time)"
+		}
+		q := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s) ENGINE = MergeTree ORDER BY %s",
+			s.table(t.Name), strings.Join(cols, ", "), order)
+		if err := s.exec(ctx, q, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write 将事件转换为对应表的行并缓冲
+func (s *Sink) Write(e *record.Entry) error {
+	cmd := sink.Cmd(e)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	for _, t := range s.opts.Tables {
+		if !matchCmd(t.Cmds, cmd) {
+			continue
+		}
+		row, err := buildRow(t, cmd, e)
+		if err != nil {
+			return err
+		}
+		s.buffers[t.Name] = append(s.buffers[t.Name], row)
+		s.rows++
+	}
+	s.trim()
+	if s.rows >= s.opts.BatchSize {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// trim 缓冲超过 MaxBuffer 时丢弃各表最旧的行，调用时需要持有 s.mu
+func (s *Sink) trim() {
+	if s.rows <= s.opts.MaxBuffer {
+		return
+	}
+	dropped := 0
+	for name, rows := range s.buffers {
+		n := len(rows) * (s.rows - s.opts.MaxBuffer) / s.rows
+		if n == 0 && len(rows) > 0 {
+			n = 1
+		}
+		s.buffers[name] = rows[n:]
+		dropped += n
+	}
+	s.rows -= dropped
+	log.Warnf("clickhouse buffer full, dropped %d rows", dropped)
+}
+
+func matchCmd(cmds []string, cmd string) bool {
+	if len(cmds) == 0 {
+		return true
+	}
+	for _, c := range cmds {
+		if c == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+// buildRow 按列定义生成一行 JSONEachRow
+func buildRow(t Table, cmd string, e *record.Entry) ([]byte, error) {
+	row := make(map[string]interface{}, len(t.Columns))
+	for _, c := range t.Columns {
+		switch c.Path {
+		case PathTime:
+			row[c.Name] = time.Unix(0, e.Time*int64(time.Millisecond)).UTC().Format("2006-01-02 15:04:05.000")
+		case PathRoomID:
+			row[c.Name] = e.RoomID
+		case PathCmd:
+			row[c.Name] = cmd
+		case PathRaw:
+			row[c.Name] = string(e.Data)
+		default:
+			row[c.Name] = convert(c.Type, gjson.GetBytes(e.Data, c.Path))
+		}
+	}
+	return json.Marshal(row)
+}
+
+// convert 按 ClickHouse 类型转换取值，缺失的字段使用类型的零值
+func convert(typ string, r gjson.Result) interface{} {
+	t := strings.TrimSuffix(strings.TrimPrefix(typ, "Nullable("), ")")
+	switch {
+	case strings.HasPrefix(t, "UInt"):
+		return r.Uint()
+	case strings.HasPrefix(t, "Int"):
+		return r.Int()
+	case strings.HasPrefix(t, "Float"), strings.HasPrefix(t, "Decimal"):
+		return r.Float()
+	case t == "Bool":
+		return r.Bool()
+	case strings.HasPrefix(t, "Array"), strings.HasPrefix(t, "Map"):
+		if !r.Exists() {
+			return nil
+		}
+		return json.RawMessage(r.Raw)
+	}
+	return r.String()
+}
+
+func (s *Sink) flushLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+		if err := s.Flush(); err != nil {
+			log.Error("flush clickhouse failed: ", err)
+		}
+	}
+}
+
+// Flush 立即写入全部缓冲的行，失败的行会放回缓冲等待下次写入
+func (s *Sink) Flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	s.mu.Lock()
+	buffers := s.buffers
+	s.buffers = make(map[string][][]byte)
+	s.rows = 0
+	s.mu.Unlock()
+	var first error
+	for name, rows := range buffers {
+		if len(rows) == 0 {
+			continue
+		}
+		failed, err := s.write(name, rows)
+		if err != nil && first == nil {
+			first = err
+		}
+		if len(failed) == 0 {
+			continue
+		}
+		s.mu.Lock()
+		s.buffers[name] = append(failed, s.buffers[name]...)
+		s.rows += len(failed)
+		s.trim()
+		s.mu.Unlock()
+	}
+	return first
+}
+
+// write 写入一批行，返回需要放回缓冲的行
+//
+// ClickHouse 无法解析其中的某些行时重试也不会成功，将其拆分为两半分别写入，找出被拒绝的行丢弃，
+// 避免一行错误的数据阻塞之后的全部行；认证失败、表不存在等其他错误保留全部行，在下次写入时重试
+func (s *Sink) write(table string, rows [][]byte) (failed [][]byte, err error) {
+	err = s.insert(table, rows)
+	if err == nil {
+		return nil, nil
+	}
+	if !badRows(err) {
+		return rows, err
+	}
+	if len(rows) == 1 {
+		log.Errorf("clickhouse table %s rejected row, dropped: %v: %.256s", table, err, rows[0])
+		return nil, err
+	}
+	mid := len(rows) / 2
+	failed, err = s.write(table, rows[:mid])
+	f, err2 := s.write(table, rows[mid:])
+	if err == nil {
+		err = err2
+	}
+	return append(failed, f...), err
+}
+
+// insert 写入一批行，失败时按 RetryDelay 指数退避重试，重试不会成功的错误或 Close 时不再重试
+func (s *Sink) insert(table string, rows [][]byte) error {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	for _, r := range rows {
+		_, _ = zw.Write(r)
+		_, _ = zw.Write([]byte{'\n'})
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	q := fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", s.table(table))
+	delay := s.opts.RetryDelay
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = s.exec(context.Background(), q, body.Bytes()); err == nil {
+			return nil
+		}
+		if attempt >= s.opts.MaxRetries || !retryable(err) {
+			return err
+		}
+		log.Warnf("insert into clickhouse table %s failed, retry in %s: %v", table, delay, err)
+		select {
+		case <-s.stop:
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (s *Sink) table(name string) string {
+	if s.opts.Database == "" {
+		return "`" + name + "`"
+	}
+	return "`" + s.opts.Database + "`.`" + name + "`"
+}
+
+// exec 执行一条语句，gzipBody 不为空时作为 INSERT 的数据发送
+func (s *Sink) exec(ctx context.Context, query string, gzipBody []byte) error {
+	u, _ := url.Parse(s.opts.URL)
+	v := u.Query()
+	v.Set("query", query)
+	if s.opts.Database != "" {
+		v.Set("database", s.opts.Database)
+	}
+	u.RawQuery = v.Encode()
+	var body io.Reader
+	if gzipBody != nil {
+		body = bytes.NewReader(gzipBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), body)
+	if err != nil {
+		return err
+	}
+	if gzipBody != nil {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.opts.User != "" {
+		req.Header.Set("X-ClickHouse-User", s.opts.User)
+		req.Header.Set("X-ClickHouse-Key", s.opts.Password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &statusError{
+			status: resp.StatusCode,
+			code:   exceptionCode(resp.Header.Get("X-ClickHouse-Exception-Code"), msg),
+			msg:    resp.Status + ": " + strings.TrimSpace(string(msg)),
+		}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// statusError ClickHouse 返回了非 200 的状态码，code 为 ClickHouse 的错误码，未知时为 0
+type statusError struct {
+	status int
+	code   int
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return "clickhouse: " + e.msg
+}
+
+// dataErrorCodes ClickHouse 无法解析或转换写入的数据时返回的错误码，这类错误由个别行引起
+var dataErrorCodes = map[int]bool{
+	6:   true, // CANNOT_PARSE_TEXT
+	25:  true, // CANNOT_PARSE_ESCAPE_SEQUENCE
+	26:  true, // CANNOT_PARSE_QUOTED_STRING
+	27:  true, // CANNOT_PARSE_INPUT_ASSERTION_FAILED
+	38:  true, // CANNOT_PARSE_DATE
+	41:  true, // CANNOT_PARSE_DATETIME
+	53:  true, // TYPE_MISMATCH
+	69:  true, // ARGUMENT_OUT_OF_BOUND
+	70:  true, // CANNOT_CONVERT_TYPE
+	72:  true, // CANNOT_PARSE_NUMBER
+	117: true, // INCORRECT_DATA
+	131: true, // TOO_LARGE_STRING_SIZE
+	349: true, // CANNOT_INSERT_NULL_IN_ORDINARY_COLUMN
+}
+
+// exceptionCode 从响应头或 "Code: 27. DB::Exception: ..." 格式的响应中读取错误码
+func exceptionCode(header string, body []byte) int {
+	if code, err := strconv.Atoi(header); err == nil {
+		return code
+	}
+	rest := strings.TrimPrefix(string(body), "Code: ")
+	if len(rest) == len(body) {
+		return 0
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+	if end <= 0 {
+		return 0
+	}
+	code, _ := strconv.Atoi(rest[:end])
+	return code
+}
+
+// badRows 是否为数据中个别行无法解析导致的错误
+func badRows(err error) bool {
+	var e *statusError
+	return errors.As(err, &e) && dataErrorCodes[e.code]
+}
+
+// retryable 立即重试是否可能成功，数据错误与超时、限流以外的 4xx 错误（如认证失败、表不存在）重试也不会成功
+func retryable(err error) bool {
+	var e *statusError
+	if !errors.As(err, &e) {
+		return true
+	}
+	if dataErrorCodes[e.code] {
+		return false
+	}
+	return e.status < 400 || e.status >= 500 || e.status == http.StatusRequestTimeout || e.status == http.StatusTooManyRequests
+}
+
+// Close 写入剩余的行并停止后台写入
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.stop)
+	<-s.done
+	return s.Flush()
+}
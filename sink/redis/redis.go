@@ -0,0 +1,246 @@
+// Package redis 将直播间事件发布到 Redis 频道或写入 Redis Stream
+//
+// 直接实现 RESP 协议，不依赖第三方客户端，适合作为采集端与 Web 应用之间的轻量桥接
+package redis
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/sink"
+	log "github.com/sirupsen/logrus"
+)
+
+// 写入方式
+const (
+	ModePublish = "publish" // PUBLISH 到频道
+	ModeStream  = "stream"  // XADD 到 Stream
+)
+
+const (
+	DefaultAddr      = "localhost:6379"
+	DefaultKey       = "blivedm:{room_id}"
+	DefaultPoolSize  = 4
+	DefaultQueueSize = 10000
+	DefaultTimeout   = 5 * time.Second
+
+	maxPipeline    = 128
+	maxRetryDelay  = 30 * time.Second
+	dropLogEvery   = 1000
+	firstRetryWait = 100 * time.Millisecond
+)
+
+// Options Sink 的配置
+type Options struct {
+	Addr        string        `config:"addr"` // 默认 DefaultAddr
+	Username    string        `config:"username"`
+	Password    string        `config:"password"`
+	DB          int           `config:"db"`
+	Mode        string        `config:"mode"`    // publish 或 stream，默认 publish
+	Key         string        `config:"key"`     // 频道或 Stream 的键，可以使用 {room_id} 与 {cmd}，默认 DefaultKey
+	MaxLen      int64         `config:"max_len"` // Stream 的近似最大长度，0 表示不限制
+	Schema      string        `config:"schema"`  // publish 时消息的格式，见 sink.SchemaEntry 等常量，默认 sink.SchemaEntry
+	PoolSize    int           `config:"pool_size"`
+	QueueSize   int           `config:"queue_size"` // 待写入事件的队列长度，队列满时丢弃新的事件
+	DialTimeout time.Duration `config:"dial_timeout"`
+	Timeout     time.Duration `config:"timeout"` // 每次读写的超时
+	TLSConfig   *tls.Config   `config:"-"`
+}
+
+// Sink Redis 输出
+type Sink struct {
+	opts    Options
+	enc     sink.Encoder
+	queue   chan *record.Entry
+	wg      sync.WaitGroup
+	closeMu sync.RWMutex
+	closed  bool
+	dropped uint64
+}
+
+var _ sink.Sink = (*Sink)(nil)
+
+// New 创建 Redis Sink，启动 PoolSize 个连接并行写入，连接断开后自动重连
+func New(opts Options) (*Sink, error) {
+	if opts.Addr == "" {
+		opts.Addr = DefaultAddr
+	}
+	switch opts.Mode {
+	case "":
+		opts.Mode = ModePublish
+	case ModePublish, ModeStream:
+	default:
+		return nil, fmt.Errorf("unknown redis sink mode %q", opts.Mode)
+	}
+	enc, err := sink.NewEncoder(opts.Schema)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Key == "" {
+		opts.Key = DefaultKey
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = DefaultPoolSize
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = DefaultTimeout
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	s := &Sink{opts: opts, enc: enc, queue: make(chan *record.Entry, opts.QueueSize)}
+	for i := 0; i < opts.PoolSize; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s, nil
+}
+
+// Write 将事件放入写入队列，队列满时丢弃并计数
+func (s *Sink) Write(e *record.Entry) error {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		return fmt.Errorf("redis sink closed")
+	}
+	select {
+	case s.queue <- e:
+	default:
+		if n := atomic.AddUint64(&s.dropped, 1); n%dropLogEvery == 1 {
+			log.Warnf("redis sink queue full, dropped %d events", n)
+		}
+	}
+	return nil
+}
+
+// Dropped 因队列已满丢弃的事件数
+func (s *Sink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close 写入队列中剩余的事件后关闭全部连接
+func (s *Sink) Close() error {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.closeMu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// key 展开键中的占位符
+func (s *Sink) key(e *record.Entry, cmd string) string {
+	return strings.NewReplacer("{room_id}", strconv.Itoa(e.RoomID), "{cmd}", cmd).Replace(s.opts.Key)
+}
+
+// command 生成写入一个事件的命令
+func (s *Sink) command(e *record.Entry) ([]string, error) {
+	cmd := sink.Cmd(e)
+	key := s.key(e, cmd)
+	if s.opts.Mode == ModePublish {
+		b, err := s.enc(e)
+		if err != nil {
+			return nil, err
+		}
+		return []string{"PUBLISH", key, string(b)}, nil
+	}
+	args := []string{"XADD", key}
+	if s.opts.MaxLen > 0 {
+		args = append(args, "MAXLEN", "~", strconv.FormatInt(s.opts.MaxLen, 10))
+	}
+	return append(args, "*",
+		"cmd", cmd,
+		"room_id", strconv.Itoa(e.RoomID),
+		"time", strconv.FormatInt(e.Time, 10),
+		"data", string(e.Data),
+	), nil
+}
+
+// worker 持有一个连接，从队列中批量取出事件以 pipeline 写入
+func (s *Sink) worker() {
+	defer s.wg.Done()
+	var (
+		c     *conn
+		delay time.Duration
+	)
+	defer func() {
+		if c != nil {
+			c.close()
+		}
+	}()
+	for e := range s.queue {
+		batch := []*record.Entry{e}
+	fill:
+		for len(batch) < maxPipeline {
+			select {
+			case e, ok := <-s.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, e)
+			default:
+				break fill
+			}
+		}
+		cmds := make([][]string, 0, len(batch))
+		for _, e := range batch {
+			args, err := s.command(e)
+			if err != nil {
+				log.Error("build redis command failed: ", err)
+				continue
+			}
+			cmds = append(cmds, args)
+		}
+		// 连接失败时重连并重试同一批事件，Close 之后只再尝试一次
+		for {
+			var err error
+			if c == nil {
+				c, err = dial(&s.opts)
+			}
+			if err == nil {
+				var replies []interface{}
+				if replies, err = c.pipeline(cmds); err == nil {
+					for _, r := range replies {
+						if re, ok := r.(Error); ok {
+							log.Error("redis sink: ", re)
+						}
+					}
+					delay = 0
+					break
+				}
+				c.close()
+				c = nil
+			}
+			if s.isClosed() && delay > 0 {
+				log.Error("redis sink dropped events after close: ", err)
+				break
+			}
+			if delay == 0 {
+				delay = firstRetryWait
+			} else if delay *= 2; delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+			log.Warnf("redis sink write failed, retry in %s: %v", delay, err)
+			time.Sleep(delay)
+		}
+	}
+}
+
+func (s *Sink) isClosed() bool {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	return s.closed
+}
@@ -0,0 +1,162 @@
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Error Redis 返回的错误回复
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// conn 一个 RESP 连接
+type conn struct {
+	nc      net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	timeout time.Duration
+}
+
+func dial(o *Options) (*conn, error) {
+	d := net.Dialer{Timeout: o.DialTimeout}
+	var (
+		nc  net.Conn
+		err error
+	)
+	if o.TLSConfig != nil {
+		nc, err = tls.DialWithDialer(&d, "tcp", o.Addr, o.TLSConfig)
+	} else {
+		nc, err = d.Dial("tcp", o.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{nc: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc), timeout: o.Timeout}
+	if o.Password != "" {
+		args := []string{"AUTH", o.Password}
+		if o.Username != "" {
+			args = []string{"AUTH", o.Username, o.Password}
+		}
+		if _, err := c.do(args...); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	if o.DB != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(o.DB)); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// do 发送一条命令并读取回复
+func (c *conn) do(args ...string) (interface{}, error) {
+	replies, err := c.pipeline([][]string{args})
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := replies[0].(Error); ok {
+		return nil, e
+	}
+	return replies[0], nil
+}
+
+// pipeline 一次发送多条命令再依次读取回复，Redis 的错误回复作为 Error 放在结果中
+func (c *conn) pipeline(cmds [][]string) ([]interface{}, error) {
+	if c.timeout > 0 {
+		_ = c.nc.SetDeadline(time.Now().Add(c.timeout))
+	}
+	for _, args := range cmds {
+		writeCommand(c.w, args)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		v, err := readReply(c.r)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = v
+	}
+	return replies, nil
+}
+
+func (c *conn) close() {
+	_ = c.nc.Close()
+}
+
+// writeCommand 以 RESP 数组写入命令
+func writeCommand(w *bufio.Writer, args []string) {
+	w.WriteByte('*')
+	w.WriteString(strconv.Itoa(len(args)))
+	w.WriteString("\r\n")
+	for _, a := range args {
+		w.WriteByte('$')
+		w.WriteString(strconv.Itoa(len(a)))
+		w.WriteString("\r\n")
+		w.WriteString(a)
+		w.WriteString("\r\n")
+	}
+}
+
+// readReply 读取一个 RESP 回复
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: malformed reply")
+	}
+	body := line[1 : len(line)-2]
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return Error(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+}
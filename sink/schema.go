@@ -0,0 +1,181 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/RemKeeper/blivedm-go/message"
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/tidwall/gjson"
+)
+
+// 事件 JSON 的输出格式
+const (
+	SchemaEntry   = "entry"   // record.Entry，time、room_id 与原始报文 data，默认
+	SchemaRaw     = "raw"     // 原样输出 B 站的原始报文
+	SchemaFlat    = "flat"    // 扁平的 snake_case 字段，见 Flatten
+	SchemaCompact = "compact" // 只包含常用字段的短键名，见 CompactEvent
+)
+
+// Encoder 将事件编码为一行 JSON
+type Encoder func(e *record.Entry) ([]byte, error)
+
+// NewEncoder 获取 schema 对应的 Encoder，schema 为空时使用 SchemaEntry
+func NewEncoder(schema string) (Encoder, error) {
+	switch strings.ToLower(schema) {
+	case "", SchemaEntry:
+		return encodeEntry, nil
+	case SchemaRaw:
+		return encodeRaw, nil
+	case SchemaFlat:
+		return encodeFlat, nil
+	case SchemaCompact:
+		return encodeCompact, nil
+	}
+	return nil, fmt.Errorf("unknown schema %q", schema)
+}
+
+func encodeEntry(e *record.Entry) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// encodeRaw 输出原始报文，去掉换行使其保持为一行
+func encodeRaw(e *record.Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, e.Data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeFlat(e *record.Entry) ([]byte, error) {
+	return json.Marshal(Flatten(e))
+}
+
+func encodeCompact(e *record.Entry) ([]byte, error) {
+	return json.Marshal(Compact(e))
+}
+
+// Flatten 将事件转为扁平的 snake_case 字段
+//
+// 包含 time、room_id 与 cmd；弹幕的 info 数组转为 uid、uname、text、medal_name、medal_level、guard_level 等字段；
+// 其他事件的 data 对象逐层展开，嵌套的键以 _ 连接，如 medal_info.medal_name 转为 medal_info_medal_name，数组保持原样，
+// 与 time、room_id、cmd 重名的字段被忽略
+func Flatten(e *record.Entry) map[string]interface{} {
+	cmd := Cmd(e)
+	out := map[string]interface{}{
+		"time":    e.Time,
+		"room_id": e.RoomID,
+		"cmd":     cmd,
+	}
+	set := func(k string, v interface{}) {
+		if _, ok := out[k]; !ok {
+			out[k] = v
+		}
+	}
+	if cmd == "DANMU_MSG" {
+		info := gjson.GetBytes(e.Data, "info")
+		set("uid", info.Get("2.0").Int())
+		set("uname", info.Get("2.1").String())
+		set("text", info.Get("1").String())
+		set("timestamp", info.Get("0.4").Int())
+		if medal := info.Get("3"); len(medal.Array()) > 0 {
+			set("medal_level", medal.Get("0").Int())
+			set("medal_name", medal.Get("1").String())
+			set("medal_anchor", medal.Get("2").String())
+			set("medal_room_id", medal.Get("3").Int())
+		}
+		set("user_level", info.Get("4.0").Int())
+		set("guard_level", info.Get("7").Int())
+		return out
+	}
+	data := gjson.GetBytes(e.Data, "data")
+	if !data.IsObject() {
+		return out
+	}
+	var walk func(prefix string, r gjson.Result)
+	walk = func(prefix string, r gjson.Result) {
+		r.ForEach(func(k, v gjson.Result) bool {
+			key := snakeCase(k.String())
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			switch {
+			case v.IsObject():
+				walk(key, v)
+			case v.Type == gjson.Number:
+				// 保留原始的数字，避免大整数转为 float64 后丢失精度
+				set(key, json.Number(v.Raw))
+			case v.IsArray():
+				set(key, json.RawMessage(v.Raw))
+			default:
+				set(key, v.Value())
+			}
+			return true
+		})
+	}
+	walk("", data)
+	return out
+}
+
+// snakeCase 将 giftName、GiftName 这类键转为 gift_name
+func snakeCase(s string) string {
+	var b strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && rs[i-1] != '_' && (unicode.IsLower(rs[i-1]) || i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// CompactEvent SchemaCompact 输出的事件
+type CompactEvent struct {
+	Time   int64  `json:"t"`
+	RoomID int    `json:"r"`
+	Cmd    string `json:"c"`
+	UID    int    `json:"u,omitempty"`
+	Uname  string `json:"n,omitempty"`
+	Text   string `json:"m,omitempty"` // 弹幕与醒目留言的内容
+	Gold   int64  `json:"g,omitempty"` // 礼物、醒目留言与大航海的金额，单位为金瓜子，银瓜子礼物为 0
+}
+
+// Compact 将事件转为 CompactEvent
+func Compact(e *record.Entry) *CompactEvent {
+	cmd := Cmd(e)
+	c := &CompactEvent{Time: e.Time, RoomID: e.RoomID, Cmd: cmd, UID: UID(e), Text: Text(cmd, e)}
+	data := gjson.GetBytes(e.Data, "data")
+	switch cmd {
+	case "DANMU_MSG":
+		c.Uname = gjson.GetBytes(e.Data, "info.2.1").String()
+	case "SEND_GIFT":
+		c.Uname = data.Get("uname").String()
+		c.Gold = eventGold(&message.Gift{}, e.Data)
+	case "SUPER_CHAT_MESSAGE", "SUPER_CHAT_MESSAGE_JPN":
+		c.Uname = data.Get("user_info.uname").String()
+		c.Gold = eventGold(&message.SuperChat{}, e.Data)
+	case "GUARD_BUY":
+		c.Uname = data.Get("username").String()
+		c.Gold = eventGold(&message.GuardBuy{}, e.Data)
+	default:
+		c.Uname = data.Get("uname").String()
+	}
+	return c
+}
+
+// eventGold 将原始报文解析为 v 后通过 message.EventGold 获取金额，解析失败或银瓜子礼物时为 0
+func eventGold(v message.Event, data []byte) int64 {
+	if v.Parse(data) != nil {
+		return 0
+	}
+	gold, _ := message.EventGold(v)
+	return gold
+}
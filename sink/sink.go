@@ -0,0 +1,161 @@
+// Package sink 将直播间事件写入外部存储
+//
+// 事件使用与录制文件相同的 record.Entry 表示，record.Writer 与 record.File 本身就是 Sink
+package sink
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/packet"
+	"github.com/RemKeeper/blivedm-go/record"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// Sink 事件输出
+type Sink interface {
+	Write(e *record.Entry) error
+	Close() error
+}
+
+// Filter 事件过滤器，返回 false 的事件不会写入 Sink
+type Filter func(cmd string, e *record.Entry) bool
+
+var (
+	_ Sink = (*record.Writer)(nil)
+	_ Sink = (*record.File)(nil)
+)
+
+// Attach 将 c 收到的 Notification 包写入 s，写入在读取循环中同步进行，耗时的 Sink 需要自行缓冲
+func Attach(c *client.Client, s Sink, filters ...Filter) {
+	c.OnPacket(func(p packet.Packet) {
+		if p.Operation != packet.Notification {
+			return
+		}
+		t := p.ReceivedAt
+		if t.IsZero() {
+			t = time.Now()
+		}
+		e := &record.Entry{
+			Time:   t.UnixNano() / int64(time.Millisecond),
+			RoomID: c.RoomID(),
+			Data:   append([]byte(nil), p.Body...),
+		}
+		cmd := Cmd(e)
+		for _, f := range filters {
+			if !f(cmd, e) {
+				return
+			}
+		}
+		if err := s.Write(e); err != nil {
+			log.Error("write sink failed: ", err)
+		}
+	})
+}
+
+// Cmd 获取事件的 cmd，去除 DANMU_MSG:4:0:2:2:2:0 这类后缀参数
+func Cmd(e *record.Entry) string {
+	cmd := gjson.GetBytes(e.Data, "cmd").String()
+	if i := strings.IndexByte(cmd, ':'); i >= 0 {
+		cmd = cmd[:i]
+	}
+	return cmd
+}
+
+// UID 获取事件发送者的 UID，弹幕取 info.2.0，其他事件取 data.uid
+func UID(e *record.Entry) int {
+	if r := gjson.GetBytes(e.Data, "info.2.0"); r.Exists() {
+		return int(r.Int())
+	}
+	return int(gjson.GetBytes(e.Data, "data.uid").Int())
+}
+
+// Text 获取弹幕或醒目留言的文本
+func Text(cmd string, e *record.Entry) string {
+	switch cmd {
+	case "DANMU_MSG":
+		return gjson.GetBytes(e.Data, "info.1").String()
+	case "SUPER_CHAT_MESSAGE", "SUPER_CHAT_MESSAGE_JPN":
+		return gjson.GetBytes(e.Data, "data.message").String()
+	}
+	return ""
+}
+
+// CmdFilter 只保留 cmds 中的事件
+func CmdFilter(cmds ...string) Filter {
+	set := make(map[string]bool, len(cmds))
+	for _, c := range cmds {
+		set[c] = true
+	}
+	return func(cmd string, _ *record.Entry) bool { return set[cmd] }
+}
+
+// ExcludeCmdFilter 丢弃 cmds 中的事件
+func ExcludeCmdFilter(cmds ...string) Filter {
+	keep := CmdFilter(cmds...)
+	return func(cmd string, e *record.Entry) bool { return !keep(cmd, e) }
+}
+
+// UserFilter 丢弃 uids 中的用户发送的事件
+func UserFilter(uids ...int) Filter {
+	set := make(map[int]bool, len(uids))
+	for _, u := range uids {
+		set[u] = true
+	}
+	return func(_ string, e *record.Entry) bool { return !set[UID(e)] }
+}
+
+// KeywordFilter 丢弃包含任一关键词的弹幕与醒目留言
+func KeywordFilter(words ...string) Filter {
+	return func(cmd string, e *record.Entry) bool {
+		text := Text(cmd, e)
+		if text == "" {
+			return true
+		}
+		for _, w := range words {
+			if strings.Contains(text, w) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// jsonLines 每行一个 JSON 的输出
+type jsonLines struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc Encoder
+}
+
+// NewJSONLines 创建每行写入一个 record.Entry JSON 的 Sink，w 实现 io.Closer 时 Close 会关闭 w
+func NewJSONLines(w io.Writer) Sink {
+	return NewJSONLinesEncoder(w, encodeEntry)
+}
+
+// NewJSONLinesEncoder 创建每行写入一个 enc 编码结果的 Sink，用于 NewEncoder 获取的其他输出格式
+func NewJSONLinesEncoder(w io.Writer, enc Encoder) Sink {
+	return &jsonLines{w: w, enc: enc}
+}
+
+func (j *jsonLines) Write(e *record.Entry) error {
+	b, err := j.enc(e)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.w.Write(append(b, '\n'))
+	return err
+}
+
+func (j *jsonLines) Close() error {
+	if c, ok := j.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
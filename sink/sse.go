@@ -0,0 +1,286 @@
+package sink
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/record"
+)
+
+const (
+	defaultSSEBuffer    = 256
+	defaultSSEKeepAlive = 15 * time.Second
+)
+
+// SSE 以 text/event-stream 转发事件的 Sink，同时也是 http.Handler
+//
+// 通过 Attach 写入事件，每个 HTTP 连接是一个订阅者，可以用查询参数过滤：
+// cmd 只接收的 cmd，逗号分隔；exclude 不接收的 cmd；room 只接收的房间号，逗号分隔；
+// schema 输出格式，见 SchemaEntry 等常量，默认为 SetSchema 设置的格式。
+// 每个事件的 event 为 cmd，data 为对应格式的 JSON，默认为 record.Entry
+type SSE struct {
+	mu        sync.Mutex
+	subs      map[*sseSubscriber]bool
+	filters   []Filter
+	buffer    int
+	keepAlive time.Duration
+	schema    string
+	id        uint64
+	dropped   uint64
+	closed    bool
+}
+
+// sseSubscriber 一个 SSE 连接
+type sseSubscriber struct {
+	ch      chan []byte
+	filters []Filter
+	rooms   map[int]bool
+	schema  string
+}
+
+// NewSSE 创建 SSE 转发，filters 对全部订阅者生效
+func NewSSE(filters ...Filter) *SSE {
+	return &SSE{
+		subs:      make(map[*sseSubscriber]bool),
+		filters:   filters,
+		buffer:    defaultSSEBuffer,
+		keepAlive: defaultSSEKeepAlive,
+	}
+}
+
+// SetBuffer 设置每个订阅者缓存的事件数，缓存满时丢弃新的事件，默认为 256
+func (s *SSE) SetBuffer(n int) {
+	if n > 0 {
+		s.mu.Lock()
+		s.buffer = n
+		s.mu.Unlock()
+	}
+}
+
+// SetKeepAlive 设置没有事件时发送注释行保持连接的间隔，避免被反向代理断开，为 0 时不发送，默认为 15s
+func (s *SSE) SetKeepAlive(d time.Duration) {
+	s.mu.Lock()
+	s.keepAlive = d
+	s.mu.Unlock()
+}
+
+// SetSchema 设置没有指定 schema 参数的订阅者使用的输出格式，默认为 SchemaEntry
+func (s *SSE) SetSchema(schema string) error {
+	if _, err := NewEncoder(schema); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.schema = strings.ToLower(schema)
+	s.mu.Unlock()
+	return nil
+}
+
+// Subscribers 获取当前的订阅者数量
+func (s *SSE) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
+
+// Dropped 获取因订阅者缓存已满被丢弃的事件数量
+func (s *SSE) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *SSE) Write(e *record.Entry) error {
+	cmd := Cmd(e)
+	for _, f := range s.filters {
+		if !f(cmd, e) {
+			return nil
+		}
+	}
+	// 同一格式的订阅者共用一条消息
+	var (
+		id   uint64
+		msgs map[string][]byte
+		err  error
+	)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		if !sub.accept(cmd, e) {
+			continue
+		}
+		if id == 0 {
+			s.id++
+			id = s.id
+			msgs = make(map[string][]byte, 1)
+		}
+		msg, ok := msgs[sub.schema]
+		if !ok {
+			enc, _ := NewEncoder(sub.schema)
+			b, encErr := enc(e)
+			if encErr != nil {
+				err = encErr
+			} else {
+				msg = sseMessage(id, cmd, b)
+			}
+			msgs[sub.schema] = msg
+		}
+		if msg == nil {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+	return err
+}
+
+// Close 断开全部订阅者，之后的连接返回 503
+func (s *SSE) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for sub := range s.subs {
+		close(sub.ch)
+		delete(s.subs, sub)
+	}
+	return nil
+}
+
+func (s *SSE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sub, err := newSSESubscriber(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		http.Error(w, "sse closed", http.StatusServiceUnavailable)
+		return
+	}
+	sub.ch = make(chan []byte, s.buffer)
+	if sub.schema == "" {
+		sub.schema = s.schema
+	}
+	s.subs[sub] = true
+	keepAlive := s.keepAlive
+	s.mu.Unlock()
+	defer s.remove(sub)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// 关闭 nginx 的响应缓冲
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var tick <-chan time.Time
+	if keepAlive > 0 {
+		t := time.NewTicker(keepAlive)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-sub.ch:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(msg); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-tick:
+			if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *SSE) remove(sub *sseSubscriber) {
+	s.mu.Lock()
+	if s.subs[sub] {
+		delete(s.subs, sub)
+		close(sub.ch)
+	}
+	s.mu.Unlock()
+}
+
+// newSSESubscriber 根据查询参数创建订阅者
+func newSSESubscriber(r *http.Request) (*sseSubscriber, error) {
+	q := r.URL.Query()
+	sub := &sseSubscriber{}
+	if cmds := splitParam(q.Get("cmd")); len(cmds) > 0 {
+		sub.filters = append(sub.filters, CmdFilter(cmds...))
+	}
+	if cmds := splitParam(q.Get("exclude")); len(cmds) > 0 {
+		sub.filters = append(sub.filters, ExcludeCmdFilter(cmds...))
+	}
+	if schema := q.Get("schema"); schema != "" {
+		if _, err := NewEncoder(schema); err != nil {
+			return nil, err
+		}
+		sub.schema = strings.ToLower(schema)
+	}
+	if rooms := splitParam(q.Get("room")); len(rooms) > 0 {
+		sub.rooms = make(map[int]bool, len(rooms))
+		for _, v := range rooms {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, err
+			}
+			sub.rooms[id] = true
+		}
+	}
+	return sub, nil
+}
+
+func (sub *sseSubscriber) accept(cmd string, e *record.Entry) bool {
+	if sub.rooms != nil && !sub.rooms[e.RoomID] {
+		return false
+	}
+	for _, f := range sub.filters {
+		if !f(cmd, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitParam 拆分逗号分隔的查询参数
+func splitParam(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// sseMessage 构造一条 SSE 消息，data 为单行 JSON
+func sseMessage(id uint64, event string, data []byte) []byte {
+	msg := make([]byte, 0, len(data)+len(event)+32)
+	msg = append(msg, "id: "...)
+	msg = strconv.AppendUint(msg, id, 10)
+	msg = append(msg, "\nevent: "...)
+	msg = append(msg, event...)
+	msg = append(msg, "\ndata: "...)
+	msg = append(msg, data...)
+	return append(msg, "\n\n"...)
+}
@@ -0,0 +1,281 @@
+// Package spam 按用户统计发言频率与重复内容，识别刷屏与复制粘贴的弹幕
+package spam
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+// Action 判定为刷屏后对弹幕的处理方式
+type Action int
+
+const (
+	// ActionDrop 不再分发给通过 Guard.OnDanmaku 注册的处理器，默认方式
+	ActionDrop Action = iota
+	// ActionTag 仍然分发，通过 Guard.OnTaggedDanmaku 注册的处理器可以得到判定结果
+	ActionTag
+	// ActionEmit 仍然分发，只调用通过 Guard.OnSpam 注册的处理器
+	ActionEmit
+)
+
+// 判定为刷屏的原因
+const (
+	ReasonRate   = "rate"   // 发言过于频繁
+	ReasonRepeat = "repeat" // 重复发送相同内容
+	ReasonFlood  = "flood"  // 多个用户在短时间内发送相同内容
+)
+
+// Verdict 一条弹幕的判定结果
+type Verdict struct {
+	Spam    bool
+	Score   float64  // 各项指标与阈值之比的最大值，不小于 1 时判定为刷屏
+	Reasons []string // 达到阈值的指标
+}
+
+var _ client.DanmakuSource = (*Guard)(nil)
+
+type userState struct {
+	times    []time.Time
+	contents []string
+	last     time.Time
+}
+
+type floodState struct {
+	users map[int]bool
+	first time.Time
+}
+
+// Guard 刷屏过滤，同时也是一个 DanmakuSource，可以代替原来的 client 传给其他组件
+//
+//	g := spam.NewGuard(c)
+//	router.Attach(g)
+type Guard struct {
+	client.DanmakuSource
+	mu          sync.Mutex
+	rateLimit   int
+	rateWindow  time.Duration
+	history     int
+	repeatRatio float64
+	floodUsers  int
+	floodWindow time.Duration
+	action      Action
+	exempt      func(u *message.User) bool
+	users       map[int]*userState
+	floods      map[string]*floodState
+	checks      int
+	handlers    []func(*message.Danmaku)
+	tagged      []func(*message.Danmaku, Verdict)
+	spam        []func(*message.Danmaku, Verdict)
+	attachOnce  sync.Once
+}
+
+// NewGuard 创建一个过滤 src 中弹幕的 Guard
+//
+// 默认 10s 内超过 5 条、最近 5 条中 60% 以上内容相同、30s 内 5 个以上用户发送相同内容时判定为刷屏，房管不受限制
+func NewGuard(src client.DanmakuSource) *Guard {
+	return &Guard{
+		DanmakuSource: src,
+		rateLimit:     5,
+		rateWindow:    10 * time.Second,
+		history:       5,
+		repeatRatio:   0.6,
+		floodUsers:    5,
+		floodWindow:   30 * time.Second,
+		exempt:        func(u *message.User) bool { return u.Admin },
+		users:         make(map[int]*userState),
+		floods:        make(map[string]*floodState),
+	}
+}
+
+// SetRateLimit 设置 window 时间内每个用户最多发送 n 条弹幕，n 为 0 时不限制
+func (g *Guard) SetRateLimit(n int, window time.Duration) {
+	g.mu.Lock()
+	g.rateLimit, g.rateWindow = n, window
+	g.mu.Unlock()
+}
+
+// SetRepeatThreshold 设置用户最近 history 条弹幕中相同内容的占比达到 ratio 时判定为刷屏，ratio 为 0 时不检查
+func (g *Guard) SetRepeatThreshold(ratio float64, history int) {
+	g.mu.Lock()
+	g.repeatRatio, g.history = ratio, history
+	g.mu.Unlock()
+}
+
+// SetFloodThreshold 设置 window 时间内 n 个用户发送相同内容时判定为刷屏，n 为 0 时不检查
+func (g *Guard) SetFloodThreshold(n int, window time.Duration) {
+	g.mu.Lock()
+	g.floodUsers, g.floodWindow = n, window
+	g.mu.Unlock()
+}
+
+// SetAction 设置判定为刷屏后的处理方式，默认 ActionDrop
+func (g *Guard) SetAction(a Action) {
+	g.mu.Lock()
+	g.action = a
+	g.mu.Unlock()
+}
+
+// SetExempt 设置不受限制的用户，默认为房管
+func (g *Guard) SetExempt(f func(u *message.User) bool) {
+	g.mu.Lock()
+	g.exempt = f
+	g.mu.Unlock()
+}
+
+// OnDanmaku 添加弹幕处理器，ActionDrop 时不会收到判定为刷屏的弹幕
+func (g *Guard) OnDanmaku(f func(*message.Danmaku)) {
+	g.mu.Lock()
+	g.handlers = append(g.handlers, f)
+	g.mu.Unlock()
+	g.attach()
+}
+
+// OnTaggedDanmaku 添加带判定结果的弹幕处理器，ActionDrop 时不会收到判定为刷屏的弹幕
+func (g *Guard) OnTaggedDanmaku(f func(*message.Danmaku, Verdict)) {
+	g.mu.Lock()
+	g.tagged = append(g.tagged, f)
+	g.mu.Unlock()
+	g.attach()
+}
+
+// OnSpam 添加判定为刷屏时调用的处理器，任何处理方式下都会调用
+func (g *Guard) OnSpam(f func(*message.Danmaku, Verdict)) {
+	g.mu.Lock()
+	g.spam = append(g.spam, f)
+	g.mu.Unlock()
+	g.attach()
+}
+
+func (g *Guard) attach() {
+	g.attachOnce.Do(func() {
+		g.DanmakuSource.OnDanmaku(g.handle)
+	})
+}
+
+func (g *Guard) handle(d *message.Danmaku) {
+	v := g.Check(d)
+	g.mu.Lock()
+	action, handlers, tagged, spam := g.action, g.handlers, g.tagged, g.spam
+	g.mu.Unlock()
+	if v.Spam {
+		for _, fn := range spam {
+			fn(d, v)
+		}
+		if action == ActionDrop {
+			return
+		}
+	}
+	for _, fn := range handlers {
+		fn(d)
+	}
+	for _, fn := range tagged {
+		fn(d, v)
+	}
+}
+
+// Check 记录并判定一条弹幕，不经过 Attach 也可以直接调用
+func (g *Guard) Check(d *message.Danmaku) Verdict {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if d.Sender == nil || (g.exempt != nil && g.exempt(d.Sender)) {
+		return Verdict{}
+	}
+	now := d.ReceivedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	content := normalize(d.Content)
+	if d.Type == message.EmoticonDanmaku && d.Emoticon != nil {
+		content = d.Emoticon.EmoticonUnique
+	}
+	s := g.users[d.Sender.Uid]
+	if s == nil {
+		s = &userState{}
+		g.users[d.Sender.Uid] = s
+	}
+	s.last = now
+	var v Verdict
+	if g.rateLimit > 0 {
+		s.times = append(s.times, now)
+		i := 0
+		for i < len(s.times) && now.Sub(s.times[i]) >= g.rateWindow {
+			i++
+		}
+		s.times = s.times[i:]
+		v.add(ReasonRate, float64(len(s.times))/float64(g.rateLimit+1))
+	}
+	if g.repeatRatio > 0 && g.history > 0 && content != "" {
+		same := 1
+		for _, c := range s.contents {
+			if c == content {
+				same++
+			}
+		}
+		if n := len(s.contents) + 1; n >= g.history || same >= 3 {
+			v.add(ReasonRepeat, float64(same)/float64(n)/g.repeatRatio)
+		}
+		s.contents = append(s.contents, content)
+		if len(s.contents) >= g.history {
+			s.contents = s.contents[len(s.contents)-g.history+1:]
+		}
+	}
+	if g.floodUsers > 0 && content != "" {
+		f := g.floods[content]
+		if f == nil || now.Sub(f.first) >= g.floodWindow {
+			f = &floodState{users: make(map[int]bool), first: now}
+			g.floods[content] = f
+		}
+		f.users[d.Sender.Uid] = true
+		v.add(ReasonFlood, float64(len(f.users))/float64(g.floodUsers))
+	}
+	g.checks++
+	if g.checks%1024 == 0 {
+		g.sweep(now)
+	}
+	return v
+}
+
+// sweep 清除过期的用户与内容记录，调用时需要持有 g.mu
+func (g *Guard) sweep(now time.Time) {
+	idle := g.rateWindow
+	if idle < 5*time.Minute {
+		idle = 5 * time.Minute
+	}
+	for uid, s := range g.users {
+		if now.Sub(s.last) >= idle {
+			delete(g.users, uid)
+		}
+	}
+	for c, f := range g.floods {
+		if now.Sub(f.first) >= g.floodWindow {
+			delete(g.floods, c)
+		}
+	}
+}
+
+func (v *Verdict) add(reason string, score float64) {
+	if score > v.Score {
+		v.Score = score
+	}
+	if score >= 1 {
+		v.Spam = true
+		v.Reasons = append(v.Reasons, reason)
+	}
+}
+
+// normalize 转为小写并去掉空白与标点，使只差标点、空格的内容视为相同
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
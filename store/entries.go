@@ -0,0 +1,31 @@
+package store
+
+import (
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/record"
+)
+
+// Entries 按时间顺序遍历房间在时间范围内保存的全部事件，roomID 为 0 时遍历全部房间，零值的时间不限制
+//
+// fn 返回错误时停止遍历并返回该错误
+func (s *Store) Entries(roomID int, start, end time.Time, fn func(e *record.Entry) error) error {
+	where, args := eventWhere(roomID, nil, start, end)
+	rows, err := s.db.Query(`SELECT room_id, time, data FROM events`+where+` ORDER BY time, id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e := &record.Entry{}
+		var data string
+		if err := rows.Scan(&e.RoomID, &e.Time, &data); err != nil {
+			return err
+		}
+		e.Data = []byte(data)
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
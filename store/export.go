@@ -0,0 +1,179 @@
+package store
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportColumns 可以导出的列
+var ExportColumns = []string{"id", "room_id", "cmd", "time", "uid", "uname", "text", "data"}
+
+// defaultExportColumns 未指定列时导出的列，不包括体积较大的原始报文 data
+var defaultExportColumns = []string{"id", "room_id", "cmd", "time", "uid", "uname", "text"}
+
+// ExportQuery 导出条件，零值的条件不生效
+type ExportQuery struct {
+	RoomID int
+	Cmds   []string  // 导出的事件，为空时导出全部事件
+	Start  time.Time // 包含
+	End    time.Time // 不包含
+	// Columns 导出的列，取值见 ExportColumns，为空时导出除 data 外的全部列
+	Columns []string
+}
+
+// eventWhere 构造按房间、事件与时间范围过滤的 WHERE 子句，条件为空时返回空字符串
+func eventWhere(roomID int, cmds []string, start, end time.Time) (string, []interface{}) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if roomID != 0 {
+		where = append(where, "room_id = ?")
+		args = append(args, roomID)
+	}
+	if len(cmds) > 0 {
+		where = append(where, "cmd IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(cmds)), ", ")+")")
+		for _, c := range cmds {
+			args = append(args, c)
+		}
+	}
+	if !start.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, unixMilli(start))
+	}
+	if !end.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, unixMilli(end))
+	}
+	if len(where) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(where, " AND "), args
+}
+
+// isTextColumn 列是否为文本类型
+func isTextColumn(name string) bool {
+	return name == "cmd" || name == "uname" || name == "text" || name == "data"
+}
+
+// exportColumns 获取并检查导出的列
+func exportColumns(q ExportQuery) ([]string, error) {
+	if len(q.Columns) == 0 {
+		return defaultExportColumns, nil
+	}
+	for _, c := range q.Columns {
+		valid := false
+		for _, e := range ExportColumns {
+			if c == e {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown export column %q", c)
+		}
+	}
+	return q.Columns, nil
+}
+
+// export 按条件查询事件，依次以列值调用 fn，整数列为 int64，文本列为 string
+func (s *Store) export(q ExportQuery, columns []string, fn func(values []interface{}) error) error {
+	where, args := eventWhere(q.RoomID, q.Cmds, q.Start, q.End)
+	rows, err := s.db.Query(`SELECT `+strings.Join(columns, ", ")+` FROM events`+where+` ORDER BY time, id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	ints := make([]int64, len(columns))
+	strs := make([]string, len(columns))
+	for i, c := range columns {
+		if isTextColumn(c) {
+			dest[i] = &strs[i]
+		} else {
+			dest[i] = &ints[i]
+		}
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		for i, c := range columns {
+			if isTextColumn(c) {
+				values[i] = strs[i]
+			} else {
+				values[i] = ints[i]
+			}
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// csvTimeLayout CSV 中 time 列的格式，可以被 pandas 与 Excel 识别
+const csvTimeLayout = "2006-01-02 15:04:05.000"
+
+// ExportCSV 将符合条件的事件按时间顺序导出为 CSV，第一行为列名，time 列为本地时间
+func (s *Store) ExportCSV(w io.Writer, q ExportQuery) error {
+	columns, err := exportColumns(q)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err = cw.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	err = s.export(q, columns, func(values []interface{}) error {
+		for i, v := range values {
+			switch v := v.(type) {
+			case string:
+				record[i] = v
+			case int64:
+				if columns[i] == "time" {
+					record[i] = time.Unix(0, v*int64(time.Millisecond)).Format(csvTimeLayout)
+				} else {
+					record[i] = strconv.FormatInt(v, 10)
+				}
+			}
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportParquet 将符合条件的事件按时间顺序导出为 Parquet，time 列为毫秒时间戳(TIMESTAMP_MILLIS)
+//
+// 文件不压缩，可以直接被 pandas、DuckDB 与 BigQuery 读取
+func (s *Store) ExportParquet(w io.Writer, q ExportQuery) error {
+	names, err := exportColumns(q)
+	if err != nil {
+		return err
+	}
+	columns := make([]*parquetColumn, len(names))
+	for i, name := range names {
+		c := &parquetColumn{name: name, typ: parquetInt64, converted: parquetNoConverted}
+		if isTextColumn(name) {
+			c.typ, c.converted = parquetByteArray, parquetUTF8
+		} else if name == "time" {
+			c.converted = parquetTimestampMillis
+		}
+		columns[i] = c
+	}
+	pw := newParquetWriter(w, columns)
+	if err = s.export(q, names, pw.writeRow); err != nil {
+		return err
+	}
+	return pw.close()
+}
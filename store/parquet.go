@@ -0,0 +1,283 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// 以下为写入 Parquet 文件所需的最小实现：全部列为 REQUIRED，PLAIN 编码，不压缩，
+// 每个行组的每一列只有一个数据页，元数据使用 thrift compact 协议编码
+
+const parquetMagic = "PAR1"
+
+// parquetRowGroupSize 每个行组最多包含的行数
+const parquetRowGroupSize = 64 * 1024
+
+// parquetRowGroupBytes 每个行组缓冲的数据达到该大小时提前写入
+const parquetRowGroupBytes = 64 << 20
+
+// Parquet 物理类型
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+)
+
+// Parquet ConvertedType，-1 表示不设置
+const (
+	parquetNoConverted     = -1
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+)
+
+// parquetColumn 一列的定义与当前行组中缓冲的值
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32
+	values    bytes.Buffer
+}
+
+// parquetChunk 已写入的一个列块
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// parquetRowGroup 已写入的一个行组
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	size   int64
+	rows   int64
+}
+
+// parquetWriter 按行写入 Parquet 文件
+type parquetWriter struct {
+	w       io.Writer
+	offset  int64
+	columns []*parquetColumn
+	rows    int64
+	groups  []parquetRowGroup
+	total   int64
+	err     error
+}
+
+func newParquetWriter(w io.Writer, columns []*parquetColumn) *parquetWriter {
+	p := &parquetWriter{w: w, columns: columns}
+	p.write([]byte(parquetMagic))
+	return p
+}
+
+func (p *parquetWriter) write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	p.err = err
+}
+
+// writeRow 写入一行，values 与列一一对应，INT64 列为 int64，BYTE_ARRAY 列为 string
+func (p *parquetWriter) writeRow(values []interface{}) error {
+	var buf [8]byte
+	size := 0
+	for i, c := range p.columns {
+		switch v := values[i].(type) {
+		case int64:
+			binary.LittleEndian.PutUint64(buf[:], uint64(v))
+			c.values.Write(buf[:])
+		case string:
+			binary.LittleEndian.PutUint32(buf[:4], uint32(len(v)))
+			c.values.Write(buf[:4])
+			c.values.WriteString(v)
+		}
+		size += c.values.Len()
+	}
+	p.rows++
+	p.total++
+	if p.rows >= parquetRowGroupSize || size >= parquetRowGroupBytes {
+		p.flush()
+	}
+	return p.err
+}
+
+// flush 将缓冲的行写为一个行组
+func (p *parquetWriter) flush() {
+	if p.rows == 0 {
+		return
+	}
+	g := parquetRowGroup{rows: p.rows}
+	for _, c := range p.columns {
+		var h thriftWriter
+		h.i32(1, 0) // DATA_PAGE
+		h.i32(2, int32(c.values.Len()))
+		h.i32(3, int32(c.values.Len()))
+		h.structBegin(5)
+		h.i32(1, int32(p.rows))
+		h.i32(2, 0) // PLAIN
+		h.i32(3, 3) // RLE
+		h.i32(4, 3)
+		h.structEnd()
+		h.stop()
+		chunk := parquetChunk{offset: p.offset, values: p.rows}
+		p.write(h.buf.Bytes())
+		p.write(c.values.Bytes())
+		chunk.size = p.offset - chunk.offset
+		g.size += chunk.size
+		g.chunks = append(g.chunks, chunk)
+		c.values.Reset()
+	}
+	p.groups = append(p.groups, g)
+	p.rows = 0
+}
+
+// close 写入剩余的行与文件元数据
+func (p *parquetWriter) close() error {
+	p.flush()
+	var t thriftWriter
+	t.i32(1, 1)
+	t.listBegin(2, thriftStruct, len(p.columns)+1)
+	t.elemBegin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(p.columns)))
+	t.elemEnd()
+	for _, c := range p.columns {
+		t.elemBegin()
+		t.i32(1, c.typ)
+		t.i32(3, 0) // REQUIRED
+		t.binary(4, c.name)
+		if c.converted != parquetNoConverted {
+			t.i32(6, c.converted)
+		}
+		t.elemEnd()
+	}
+	t.i64(3, p.total)
+	t.listBegin(4, thriftStruct, len(p.groups))
+	for _, g := range p.groups {
+		t.elemBegin()
+		t.listBegin(1, thriftStruct, len(g.chunks))
+		for i, chunk := range g.chunks {
+			c := p.columns[i]
+			t.elemBegin()
+			t.i64(2, chunk.offset)
+			t.structBegin(3)
+			t.i32(1, c.typ)
+			t.listBegin(2, thriftI32, 2)
+			t.elemI32(0) // PLAIN
+			t.elemI32(3) // RLE
+			t.listBegin(3, thriftBinary, 1)
+			t.elemBinary(c.name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, chunk.values)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.structEnd()
+			t.elemEnd()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.elemEnd()
+	}
+	t.binary(6, "blivedm-go")
+	t.stop()
+	p.write(t.buf.Bytes())
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(t.buf.Len()))
+	p.write(n[:])
+	p.write([]byte(parquetMagic))
+	return p.err
+}
+
+// thrift compact 协议的类型
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter thrift compact 协议编码
+type thriftWriter struct {
+	buf   bytes.Buffer
+	last  int16
+	stack []int16
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if d := id - t.last; d > 0 && d <= 15 {
+		t.buf.WriteByte(byte(d)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(uint64(uint16(id<<1 ^ id>>15)))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.elemI32(v)
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(uint64(v<<1 ^ v>>63))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.elemBinary(s)
+}
+
+func (t *thriftWriter) listBegin(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.varint(uint64(n))
+	}
+}
+
+func (t *thriftWriter) elemI32(v int32) {
+	t.varint(uint64(uint32(v<<1 ^ v>>31)))
+}
+
+func (t *thriftWriter) elemBinary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// elemBegin 开始一个列表中的结构体
+func (t *thriftWriter) elemBegin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) elemEnd() {
+	t.buf.WriteByte(0)
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+// structBegin 开始一个结构体字段
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+func (t *thriftWriter) structEnd() {
+	t.elemEnd()
+}
+
+// stop 结束最外层的结构体
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/record"
+)
+
+// DefaultQueryLimit Query 每页默认返回的最大条数
+const DefaultQueryLimit = 100
+
+// Event 查询到的一条事件
+type Event struct {
+	ID     int64           `json:"id"`
+	RoomID int             `json:"room_id"`
+	Cmd    string          `json:"cmd"`
+	Time   time.Time       `json:"time"`
+	UID    int             `json:"uid"`
+	Uname  string          `json:"uname"`
+	Text   string          `json:"text"`
+	Data   json.RawMessage `json:"data"` // 原始报文
+}
+
+// Entry 转换为录制文件中的一条记录
+func (e *Event) Entry() *record.Entry {
+	return &record.Entry{RoomID: e.RoomID, Time: unixMilli(e.Time), Data: e.Data}
+}
+
+// Page 一页查询结果
+type Page struct {
+	Events []*Event `json:"events"`
+	Cursor string   `json:"cursor,omitempty"` // 下一页的游标，没有更多结果时为空
+}
+
+// Query 按时间顺序查询房间在 [from, to) 内的事件，roomID 为 0 时查询全部房间，cmds 为空时查询全部事件，零值的时间不限制
+//
+// 每页最多返回 limit 条，默认 DefaultQueryLimit，cursor 为上一页返回的 Cursor，查询第一页时为空
+func (s *Store) Query(roomID int, cmds []string, from, to time.Time, limit int, cursor string) (*Page, error) {
+	where, args := eventWhere(roomID, cmds, from, to)
+	if cursor != "" {
+		ms, id, err := parseCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		cond := "(time > ? OR (time = ? AND id > ?))"
+		if where == "" {
+			where = ` WHERE ` + cond
+		} else {
+			where += ` AND ` + cond
+		}
+		args = append(args, ms, ms, id)
+	}
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	args = append(args, limit+1)
+	rows, err := s.db.Query(`SELECT id, room_id, cmd, time, uid, uname, text, data FROM events`+where+
+		` ORDER BY time, id LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := &Page{}
+	var lastTime int64
+	for rows.Next() {
+		if len(page.Events) == limit {
+			last := page.Events[limit-1]
+			page.Cursor = strconv.FormatInt(lastTime, 10) + "." + strconv.FormatInt(last.ID, 10)
+			break
+		}
+		e := &Event{}
+		var data string
+		if err := rows.Scan(&e.ID, &e.RoomID, &e.Cmd, &lastTime, &e.UID, &e.Uname, &e.Text, &data); err != nil {
+			return nil, err
+		}
+		e.Time = time.Unix(0, lastTime*int64(time.Millisecond))
+		e.Data = json.RawMessage(data)
+		page.Events = append(page.Events, e)
+	}
+	return page, rows.Err()
+}
+
+// parseCursor 解析 Query 返回的游标
+func parseCursor(cursor string) (ms int64, id int64, err error) {
+	i := strings.IndexByte(cursor, '.')
+	if i < 0 {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	if ms, err = strconv.ParseInt(cursor[:i], 10, 64); err == nil {
+		id, err = strconv.ParseInt(cursor[i+1:], 10, 64)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return ms, id, nil
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultSearchLimit Search 默认返回的最大条数
+const DefaultSearchLimit = 100
+
+// SearchQuery 弹幕检索条件，零值的条件不生效
+type SearchQuery struct {
+	RoomID  int
+	UID     int
+	Start   time.Time // 包含
+	End     time.Time // 不包含
+	Keyword string    // 弹幕中包含的文本
+	Cmds    []string  // 检索的事件，默认只检索 DANMU_MSG，可加上 SUPER_CHAT_MESSAGE
+	Limit   int       // 默认 DefaultSearchLimit
+	Desc    bool      // 按时间倒序
+}
+
+// Danmaku 检索到的一条弹幕
+type Danmaku struct {
+	ID     int64     `json:"id"`
+	RoomID int       `json:"room_id"`
+	Cmd    string    `json:"cmd"`
+	Time   time.Time `json:"time"`
+	UID    int       `json:"uid"`
+	Uname  string    `json:"uname"`
+	Text   string    `json:"text"`
+}
+
+// Search 按房间、用户、时间范围与关键词检索弹幕
+func (s *Store) Search(q SearchQuery) ([]*Danmaku, error) {
+	var (
+		where []string
+		args  []interface{}
+		from  = "events e"
+	)
+	if q.Keyword != "" {
+		if s.likeShort && utf8.RuneCountInString(q.Keyword) < 3 {
+			where = append(where, `e.text LIKE ? ESCAPE '\'`)
+			args = append(args, "%"+escapeLike(q.Keyword)+"%")
+		} else {
+			from = "events_fts f JOIN events e ON e.id = f.rowid"
+			where = append(where, "events_fts MATCH ?")
+			args = append(args, `"`+strings.Replace(q.Keyword, `"`, `""`, -1)+`"`)
+		}
+	}
+	cmds := q.Cmds
+	if len(cmds) == 0 {
+		cmds = []string{"DANMU_MSG"}
+	}
+	where = append(where, "e.cmd IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(cmds)), ", ")+")")
+	for _, c := range cmds {
+		args = append(args, c)
+	}
+	if q.RoomID != 0 {
+		where = append(where, "e.room_id = ?")
+		args = append(args, q.RoomID)
+	}
+	if q.UID != 0 {
+		where = append(where, "e.uid = ?")
+		args = append(args, q.UID)
+	}
+	if !q.Start.IsZero() {
+		where = append(where, "e.time >= ?")
+		args = append(args, unixMilli(q.Start))
+	}
+	if !q.End.IsZero() {
+		where = append(where, "e.time < ?")
+		args = append(args, unixMilli(q.End))
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	order := "ASC"
+	if q.Desc {
+		order = "DESC"
+	}
+	args = append(args, limit)
+	rows, err := s.db.Query(`SELECT e.id, e.room_id, e.cmd, e.time, e.uid, e.uname, e.text FROM `+from+
+		` WHERE `+strings.Join(where, " AND ")+` ORDER BY e.time `+order+`, e.id `+order+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*Danmaku
+	for rows.Next() {
+		d := &Danmaku{}
+		var ms int64
+		if err := rows.Scan(&d.ID, &d.RoomID, &d.Cmd, &ms, &d.UID, &d.Uname, &d.Text); err != nil {
+			return nil, err
+		}
+		d.Time = time.Unix(0, ms*int64(time.Millisecond))
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// SearchKeyword 检索房间内包含 keyword 的弹幕，roomID 为 0 时检索全部房间
+func (s *Store) SearchKeyword(roomID int, keyword string, limit int) ([]*Danmaku, error) {
+	return s.Search(SearchQuery{RoomID: roomID, Keyword: keyword, Limit: limit})
+}
+
+// SearchUser 检索用户在时间范围内的弹幕
+func (s *Store) SearchUser(uid int, start, end time.Time, limit int) ([]*Danmaku, error) {
+	return s.Search(SearchQuery{UID: uid, Start: start, End: end, Limit: limit})
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
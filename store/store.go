@@ -0,0 +1,257 @@
+// Package store 将直播间事件保存到 SQLite，并提供弹幕全文检索
+//
+// 使用标准库 database/sql，需要由调用方导入支持 FTS5 的 SQLite 驱动，如：
+//
+//	import _ "github.com/mattn/go-sqlite3" // 使用 -tags sqlite_fts5 编译
+//
+//	db, _ := sql.Open("sqlite3", "danmaku.db")
+//	s, _ := store.New(db)
+//	sink.Attach(c, s)
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/record"
+	"github.com/RemKeeper/blivedm-go/sink"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// DefaultBatchSize 默认每次事务写入的事件数
+	DefaultBatchSize = 200
+	// DefaultFlushInterval 默认的最长写入间隔
+	DefaultFlushInterval = time.Second
+	// DefaultMaxPending 默认最多缓冲的事件数
+	DefaultMaxPending = 100000
+)
+
+var errClosed = errors.New("store closed")
+
+// Options Store 的配置
+type Options struct {
+	BatchSize     int           // 缓冲的事件达到该数量时写入，默认 DefaultBatchSize
+	FlushInterval time.Duration // 缓冲的事件最多等待该时长后写入，默认 DefaultFlushInterval
+	MaxPending    int           // 写入持续失败时最多缓冲的事件数，超过后丢弃最旧的事件，默认 DefaultMaxPending
+	// Tokenizer FTS5 分词器，默认 trigram，可以按任意子串检索中文；
+	// trigram 需要 SQLite 3.34 以上，少于 3 个字的关键词会退化为 LIKE 查询
+	Tokenizer string
+}
+
+// Store 事件存储，同时实现了 sink.Sink
+type Store struct {
+	db        *sql.DB
+	opts      Options
+	mu        sync.Mutex
+	flushMu   sync.Mutex
+	pending   []*row
+	closed    bool
+	stop      chan struct{}
+	done      chan struct{}
+	likeShort bool
+}
+
+var _ sink.Sink = (*Store)(nil)
+
+// row 一条待写入的事件
+type row struct {
+	roomID int
+	cmd    string
+	time   int64
+	uid    int
+	uname  string
+	text   string
+	data   string
+}
+
+// New 使用默认配置创建 Store，并创建所需的表
+func New(db *sql.DB) (*Store, error) {
+	return NewWithOptions(db, Options{})
+}
+
+// NewWithOptions 使用指定的配置创建 Store，并创建所需的表
+func NewWithOptions(db *sql.DB, opts Options) (*Store, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = DefaultMaxPending
+	}
+	if opts.Tokenizer == "" {
+		opts.Tokenizer = "trigram"
+	}
+	s := &Store{
+		db:        db,
+		opts:      opts,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		likeShort: strings.HasPrefix(opts.Tokenizer, "trigram"),
+	}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	go s.flushLoop()
+	return s, nil
+}
+
+// DB 获取底层的 *sql.DB
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Store) migrate() error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS events (
+			id      INTEGER PRIMARY KEY AUTOINCREMENT,
+			room_id INTEGER NOT NULL,
+			cmd     TEXT    NOT NULL,
+			time    INTEGER NOT NULL,
+			uid     INTEGER NOT NULL DEFAULT 0,
+			uname   TEXT    NOT NULL DEFAULT '',
+			text    TEXT    NOT NULL DEFAULT '',
+			data    TEXT    NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS events_room_time ON events (room_id, time)`,
+		`CREATE INDEX IF NOT EXISTS events_cmd_time ON events (cmd, time)`,
+		`CREATE INDEX IF NOT EXISTS events_uid_time ON events (uid, time)`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS events_fts USING fts5 (
+			text, content = 'events', content_rowid = 'id', tokenize = '` + strings.Replace(s.opts.Tokenizer, "'", "''", -1) + `'
+		)`,
+		`CREATE TRIGGER IF NOT EXISTS events_ai AFTER INSERT ON events WHEN new.text != '' BEGIN
+			INSERT INTO events_fts (rowid, text) VALUES (new.id, new.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS events_ad AFTER DELETE ON events WHEN old.text != '' BEGIN
+			INSERT INTO events_fts (events_fts, rowid, text) VALUES ('delete', old.id, old.text);
+		END`,
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write 缓冲一个事件，达到 BatchSize 或经过 FlushInterval 后批量写入
+func (s *Store) Write(e *record.Entry) error {
+	cmd := sink.Cmd(e)
+	r := &row{
+		roomID: e.RoomID,
+		cmd:    cmd,
+		time:   e.Time,
+		uid:    sink.UID(e),
+		uname:  uname(e),
+		text:   sink.Text(cmd, e),
+		data:   string(e.Data),
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errClosed
+	}
+	s.pending = append(s.pending, r)
+	// 写入失败放回缓冲后，每增加 BatchSize 个事件再尝试一次
+	full := len(s.pending)%s.opts.BatchSize == 0
+	s.mu.Unlock()
+	if full {
+		return s.Flush()
+	}
+	return nil
+}
+
+// uname 获取事件发送者的用户名
+func uname(e *record.Entry) string {
+	if r := gjson.GetBytes(e.Data, "info.2.1"); r.Exists() {
+		return r.String()
+	}
+	for _, path := range []string{"data.uname", "data.user_info.uname", "data.username"} {
+		if r := gjson.GetBytes(e.Data, path); r.Exists() {
+			return r.String()
+		}
+	}
+	return ""
+}
+
+// Flush 立即写入缓冲的事件，写入失败时事件放回缓冲等待下次写入
+func (s *Store) Flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	s.mu.Lock()
+	rows := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	if len(rows) == 0 {
+		return nil
+	}
+	err := s.insert(rows)
+	if err == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.pending = append(rows, s.pending...)
+	if n := len(s.pending) - s.opts.MaxPending; n > 0 {
+		s.pending = append(s.pending[:0:0], s.pending[n:]...)
+		log.Warnf("store buffer full, dropped %d events", n)
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// insert 在一个事务中写入 rows
+func (s *Store) insert(rows []*row) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO events (room_id, cmd, time, uid, uname, text, data) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range rows {
+		if _, err = stmt.Exec(r.roomID, r.cmd, r.time, r.uid, r.uname, r.text, r.data); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) flushLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Error("flush store failed: ", err)
+			}
+		}
+	}
+}
+
+// Close 写入缓冲的事件并停止后台写入，不会关闭底层的 *sql.DB
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.stop)
+	<-s.done
+	return s.Flush()
+}
@@ -0,0 +1,302 @@
+// Package tts 将弹幕、礼物、醒目留言整理为带优先级的朗读队列，交给可替换的 TTS 引擎朗读
+package tts
+
+import (
+	"container/heap"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RemKeeper/blivedm-go/client"
+	"github.com/RemKeeper/blivedm-go/message"
+	log "github.com/sirupsen/logrus"
+)
+
+// Engine TTS 引擎，Speak 在朗读结束后返回
+type Engine interface {
+	Speak(ctx context.Context, u *Utterance) error
+}
+
+// EngineFunc 将函数作为 Engine 使用
+type EngineFunc func(ctx context.Context, u *Utterance) error
+
+func (f EngineFunc) Speak(ctx context.Context, u *Utterance) error {
+	return f(ctx, u)
+}
+
+const (
+	defaultCapacity    = 100
+	defaultMaxLength   = 40
+	defaultDedupWindow = 30 * time.Second
+)
+
+// Queue 朗读队列，按优先级从高到低、同优先级先进先出依次交给 Engine 朗读
+//
+// 队列满时丢弃优先级最低且最早的内容；同一用户连续赠送的同种礼物在朗读前会合并为一条
+type Queue struct {
+	engine      Engine
+	mu          sync.Mutex
+	cond        *sync.Cond
+	items       utteranceHeap
+	seq         uint64
+	priority    map[Kind]int
+	capacity    int
+	maxLength   int
+	maxAge      time.Duration
+	dedupWindow time.Duration
+	recent      map[string]time.Time
+	format      func(*Utterance) string
+	filter      func(*Utterance) bool
+	closed      bool
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// NewQueue 创建一个朗读队列，默认优先级为 醒目留言 > 上舰 > 礼物 > 弹幕
+func NewQueue(engine Engine) *Queue {
+	q := &Queue{
+		engine:      engine,
+		priority:    map[Kind]int{KindDanmaku: 0, KindGift: 1, KindGuard: 2, KindSuperChat: 3},
+		capacity:    defaultCapacity,
+		maxLength:   defaultMaxLength,
+		dedupWindow: defaultDedupWindow,
+		recent:      make(map[string]time.Time),
+		format:      DefaultFormat,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// SetPriority 设置一类内容的优先级
+func (q *Queue) SetPriority(kind Kind, priority int) {
+	q.mu.Lock()
+	q.priority[kind] = priority
+	q.mu.Unlock()
+}
+
+// SetCapacity 设置队列中最多等待朗读的条数，默认 100
+func (q *Queue) SetCapacity(n int) {
+	q.mu.Lock()
+	q.capacity = n
+	q.mu.Unlock()
+}
+
+// SetMaxLength 设置弹幕与醒目留言内容的最大字符数，超出部分不朗读，默认 40，0 表示不截断
+func (q *Queue) SetMaxLength(n int) {
+	q.mu.Lock()
+	q.maxLength = n
+	q.mu.Unlock()
+}
+
+// SetMaxAge 设置内容的有效期，等待超过 d 的内容不再朗读，默认 0 表示不过期
+func (q *Queue) SetMaxAge(d time.Duration) {
+	q.mu.Lock()
+	q.maxAge = d
+	q.mu.Unlock()
+}
+
+// SetDedupWindow 设置去重时间窗口，窗口内内容相同的弹幕与醒目留言只朗读一次，默认 30s，0 表示不去重
+func (q *Queue) SetDedupWindow(d time.Duration) {
+	q.mu.Lock()
+	q.dedupWindow = d
+	q.mu.Unlock()
+}
+
+// SetFormatter 设置生成朗读文本的函数，默认 DefaultFormat
+func (q *Queue) SetFormatter(f func(*Utterance) string) {
+	q.mu.Lock()
+	q.format = f
+	q.mu.Unlock()
+}
+
+// SetFilter 设置过滤函数，返回 false 的内容不会加入队列
+func (q *Queue) SetFilter(f func(*Utterance) bool) {
+	q.mu.Lock()
+	q.filter = f
+	q.mu.Unlock()
+}
+
+// Attach 朗读 src 中的弹幕、礼物、醒目留言与上舰
+func (q *Queue) Attach(src client.DanmakuSource) {
+	src.OnDanmaku(func(d *message.Danmaku) {
+		if d.Backfilled {
+			return
+		}
+		if u := FromDanmaku(d); u != nil {
+			q.Push(u)
+		}
+	})
+	src.OnGift(func(g *message.Gift) { q.Push(FromGift(g)) })
+	src.OnSuperChat(func(s *message.SuperChat) { q.Push(FromSuperChat(s)) })
+	src.OnGuardBuy(func(g *message.GuardBuy) { q.Push(FromGuardBuy(g)) })
+}
+
+// Push 将内容加入队列，被过滤、去重、合并或丢弃时返回 false
+//
+// u.Priority 为 0 时使用该类内容的优先级
+func (q *Queue) Push(u *Utterance) bool {
+	if u.Time.IsZero() {
+		u.Time = time.Now()
+	}
+	u.Content = Normalize(u.Content)
+	if (u.Kind == KindDanmaku || u.Kind == KindSuperChat) && u.Content == "" {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || (q.filter != nil && !q.filter(u)) {
+		return false
+	}
+	if u.Priority == 0 {
+		u.Priority = q.priority[u.Kind]
+	}
+	u.Content = trim(u.Content, q.maxLength)
+	if u.Kind == KindGift && q.merge(u) {
+		return false
+	}
+	if q.duplicate(u) {
+		return false
+	}
+	if q.capacity > 0 && len(q.items) >= q.capacity {
+		i := q.items.lowest()
+		if lo := q.items[i]; lo.Priority >= u.Priority {
+			return false
+		}
+		heap.Remove(&q.items, i)
+	}
+	q.seq++
+	heap.Push(&q.items, &queued{Utterance: u, seq: q.seq})
+	q.cond.Signal()
+	return true
+}
+
+// merge 将礼物合并到队列中同一用户的同种礼物，调用时需要持有 q.mu
+func (q *Queue) merge(u *Utterance) bool {
+	for _, it := range q.items {
+		if it.Kind == KindGift && it.Uid == u.Uid && it.GiftName == u.GiftName {
+			it.Num += u.Num
+			return true
+		}
+	}
+	return false
+}
+
+// duplicate 判断弹幕与醒目留言是否在去重窗口内出现过，调用时需要持有 q.mu
+func (q *Queue) duplicate(u *Utterance) bool {
+	if q.dedupWindow <= 0 || (u.Kind != KindDanmaku && u.Kind != KindSuperChat) {
+		return false
+	}
+	key := strconv.Itoa(int(u.Kind)) + ":" + u.Content
+	if t, ok := q.recent[key]; ok && u.Time.Sub(t) < q.dedupWindow {
+		return true
+	}
+	q.recent[key] = u.Time
+	if len(q.recent) > 4*q.capacity+64 {
+		for k, t := range q.recent {
+			if u.Time.Sub(t) >= q.dedupWindow {
+				delete(q.recent, k)
+			}
+		}
+	}
+	return false
+}
+
+// Len 获取等待朗读的条数
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Start 开始依次朗读队列中的内容
+func (q *Queue) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.mu.Lock()
+	q.cancel = cancel
+	q.done = make(chan struct{})
+	q.mu.Unlock()
+	go q.loop(ctx)
+}
+
+func (q *Queue) loop(ctx context.Context) {
+	defer close(q.done)
+	for {
+		u := q.pop()
+		if u == nil {
+			return
+		}
+		if err := q.engine.Speak(ctx, u); err != nil && ctx.Err() == nil {
+			log.Error("tts speak failed: ", err)
+		}
+	}
+}
+
+// pop 阻塞等待下一条未过期的内容并生成朗读文本，队列关闭后返回 nil
+func (q *Queue) pop() *Utterance {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		for len(q.items) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			return nil
+		}
+		u := heap.Pop(&q.items).(*queued).Utterance
+		if q.maxAge > 0 && time.Since(u.Time) > q.maxAge {
+			continue
+		}
+		u.Text = q.format(u)
+		return u
+	}
+}
+
+// Stop 停止朗读并清空队列，会取消正在进行的朗读
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	q.closed = true
+	q.items = nil
+	cancel, done := q.cancel, q.done
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+type queued struct {
+	*Utterance
+	seq uint64
+}
+
+// utteranceHeap 按优先级从高到低、序号从小到大排列
+type utteranceHeap []*queued
+
+func (h utteranceHeap) Len() int { return len(h) }
+func (h utteranceHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h utteranceHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *utteranceHeap) Push(x interface{}) { *h = append(*h, x.(*queued)) }
+func (h *utteranceHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// lowest 优先级最低且最早加入的元素的下标
+func (h utteranceHeap) lowest() int {
+	lo := 0
+	for i := 1; i < len(h); i++ {
+		if h[i].Priority < h[lo].Priority || (h[i].Priority == h[lo].Priority && h[i].seq < h[lo].seq) {
+			lo = i
+		}
+	}
+	return lo
+}
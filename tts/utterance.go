@@ -0,0 +1,130 @@
+package tts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/RemKeeper/blivedm-go/message"
+)
+
+// Kind 朗读内容的来源
+type Kind int
+
+const (
+	KindDanmaku Kind = iota
+	KindGift
+	KindSuperChat
+	KindGuard
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindDanmaku:
+		return "danmaku"
+	case KindGift:
+		return "gift"
+	case KindSuperChat:
+		return "super_chat"
+	case KindGuard:
+		return "guard"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Utterance 一条待朗读的内容
+type Utterance struct {
+	Kind     Kind
+	RoomID   int
+	Uid      int
+	Uname    string
+	Content  string // 弹幕与醒目留言的内容，已经过 Normalize
+	GiftName string // 礼物或舰队名称
+	Num      int
+	Price    int // 醒目留言的价格，单位为元
+	Priority int // 越大越先朗读
+	Time     time.Time
+	Text     string // 实际朗读的文本，由 Queue 的 Formatter 生成
+}
+
+// FromDanmaku 由弹幕创建，表情弹幕返回 nil
+func FromDanmaku(d *message.Danmaku) *Utterance {
+	if d.Type == message.EmoticonDanmaku || d.Sender == nil {
+		return nil
+	}
+	return &Utterance{Kind: KindDanmaku, RoomID: d.RoomID, Uid: d.Sender.Uid, Uname: d.Sender.Uname, Content: d.Content, Time: d.ReceivedAt}
+}
+
+// FromGift 由礼物创建
+func FromGift(g *message.Gift) *Utterance {
+	return &Utterance{Kind: KindGift, RoomID: g.RoomID, Uid: g.Uid, Uname: g.Uname, GiftName: g.GiftName, Num: g.Num, Time: g.ReceivedAt}
+}
+
+// FromSuperChat 由醒目留言创建
+func FromSuperChat(s *message.SuperChat) *Utterance {
+	return &Utterance{Kind: KindSuperChat, RoomID: s.RoomID, Uid: s.Uid, Uname: s.UserInfo.Uname, Content: s.Message, Price: s.Price, Time: s.ReceivedAt}
+}
+
+// FromGuardBuy 由上舰创建
+func FromGuardBuy(g *message.GuardBuy) *Utterance {
+	return &Utterance{Kind: KindGuard, RoomID: g.RoomID, Uid: g.Uid, Uname: g.Username, GiftName: g.GiftName, Num: g.Num, Time: g.ReceivedAt}
+}
+
+// DefaultFormat 默认的朗读文本
+func DefaultFormat(u *Utterance) string {
+	switch u.Kind {
+	case KindGift:
+		return fmt.Sprintf("感谢%s赠送的%d个%s", u.Uname, u.Num, u.GiftName)
+	case KindSuperChat:
+		return fmt.Sprintf("%s发送了%d元醒目留言：%s", u.Uname, u.Price, u.Content)
+	case KindGuard:
+		return fmt.Sprintf("感谢%s开通%s", u.Uname, u.GiftName)
+	}
+	return fmt.Sprintf("%s说：%s", u.Uname, u.Content)
+}
+
+// Normalize 去掉首尾与连续的空白、控制字符，并将连续重复超过 3 次的字符压缩为 3 个，如“哈哈哈哈哈”
+func Normalize(s string) string {
+	var b strings.Builder
+	var last rune
+	n := 0
+	space := false
+	for _, r := range strings.TrimSpace(s) {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if unicode.IsControl(r) {
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space, last = false, ' '
+		}
+		if r == last {
+			n++
+		} else {
+			last, n = r, 1
+		}
+		if n <= 3 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// trim 将 s 截断为最多 n 个字符，n <= 0 时不截断
+func trim(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	i := 0
+	for j := range s {
+		if i == n {
+			return s[:j]
+		}
+		i++
+	}
+	return s
+}